@@ -7,8 +7,23 @@ package cmd
 import (
 	"io/ioutil"
 
+	"github.com/caicloud/helm-registry/pkg/auth"
 	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/gc"
+	"github.com/caicloud/helm-registry/pkg/immutability"
 	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/mirror"
+	"github.com/caicloud/helm-registry/pkg/provenance"
+	"github.com/caicloud/helm-registry/pkg/quota"
+	"github.com/caicloud/helm-registry/pkg/ratelimit"
+	"github.com/caicloud/helm-registry/pkg/retention"
+	"github.com/caicloud/helm-registry/pkg/scan"
+	"github.com/caicloud/helm-registry/pkg/search"
+	"github.com/caicloud/helm-registry/pkg/stats"
+	"github.com/caicloud/helm-registry/pkg/tlsconfig"
+	"github.com/caicloud/helm-registry/pkg/trace"
+	"github.com/caicloud/helm-registry/pkg/watch"
+	"github.com/caicloud/helm-registry/pkg/webhook"
 	"github.com/ghodss/yaml"
 )
 
@@ -21,13 +36,70 @@ type Manager struct {
 	Parameters map[string]interface{} `yaml:"parameters"`
 }
 
+// Trash is a config of trashed versions
+type Trash struct {
+	// Retention is the retention period of trashed versions, like "168h"
+	Retention string `yaml:"retention"`
+}
+
 // Config is a config of the application
 type Config struct {
 	// Listen address
 	Listen string `yaml:"listen"`
 
+	// TLS config
+	TLS tlsconfig.Config `yaml:"tls"`
+
+	// Log config
+	Log log.Config `yaml:"log"`
+
+	// Trace config
+	Trace trace.Config `yaml:"trace"`
+
 	// Manager config
 	Manager Manager `yaml:"manager"`
+
+	// Webhook config
+	Webhook webhook.Config `yaml:"webhook"`
+
+	// Trash config
+	Trash Trash `yaml:"trash"`
+
+	// Provenance config
+	Provenance provenance.Config `yaml:"provenance"`
+
+	// Auth config
+	Auth auth.Config `yaml:"auth"`
+
+	// Retention config
+	Retention retention.Config `yaml:"retention"`
+
+	// Search config
+	Search search.Config `yaml:"search"`
+
+	// Stats config
+	Stats stats.Config `yaml:"stats"`
+
+	// GC config
+	GC gc.Config `yaml:"gc"`
+
+	// Mirror config
+	Mirror mirror.Config `yaml:"mirror"`
+
+	// Quota config
+	Quota quota.Config `yaml:"quota"`
+
+	// RateLimit config
+	RateLimit ratelimit.Config `yaml:"rateLimit"`
+
+	// Immutability config
+	Immutability immutability.Config `yaml:"immutability"`
+
+	// Watch config
+	Watch watch.Config `yaml:"watch"`
+
+	// Scan config
+	Scan scan.Config `yaml:"scan"`
 }
 
 // newDefaultConfig creates a default config
@@ -42,14 +114,17 @@ func newDefaultConfig() *Config {
 				common.ParameterResourceLocker:    "memory",
 			},
 		},
+		Trash: Trash{
+			Retention: common.DefaultTrashRetention,
+		},
 	}
 }
 
 // newConfig creates config from file
 func newConfig(filepath string) (*Config, error) {
 	config := newDefaultConfig()
-	if configPath != "" {
-		file, err := ioutil.ReadFile(configPath)
+	if filepath != "" {
+		file, err := ioutil.ReadFile(filepath)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,58 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/caicloud/helm-registry/pkg/backup"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/spf13/cobra"
+)
+
+// target config path
+var targetConfigPath = ""
+
+// migrateCmd copies all charts from the manager of config to the manager of target config
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "copies all charts from the storage backend of config to the storage backend of target config",
+	Long: `Versions which have been in target with identical data are skipped, so an interrupted migration can be
+run again to resume. Every copied version is verified by checksums after it's stored in target.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if targetConfigPath == "" {
+			log.Fatal("target config is required")
+		}
+		source, err := newSpaceManager(configPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		target, err := newSpaceManager(targetConfigPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err = backup.Migrate(context.Background(), source, target); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// newSpaceManager creates a SpaceManager by the manager of config
+func newSpaceManager(filepath string) (storage.SpaceManager, error) {
+	config, err := newConfig(filepath)
+	if err != nil {
+		return nil, err
+	}
+	return storage.Create(config.Manager.Name, config.Manager.Parameters)
+}
+
+func init() {
+	// bind variable configPath with flag --config or -c
+	migrateCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "path of config.yaml of source")
+	// bind variable targetConfigPath with flag --target or -t
+	migrateCmd.PersistentFlags().StringVarP(&targetConfigPath, "target", "t", "", "path of config.yaml of target")
+	rootCmd.AddCommand(migrateCmd)
+}
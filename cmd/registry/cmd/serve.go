@@ -5,11 +5,30 @@ Copyright 2017 caicloud authors. All rights reserved.
 package cmd
 
 import (
+	"context"
+	"net"
+	"net/http"
 	"time"
 
 	"github.com/caicloud/helm-registry/pkg/api"
+	"github.com/caicloud/helm-registry/pkg/auth"
 	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/gc"
+	"github.com/caicloud/helm-registry/pkg/immutability"
 	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/metrics"
+	"github.com/caicloud/helm-registry/pkg/mirror"
+	"github.com/caicloud/helm-registry/pkg/provenance"
+	"github.com/caicloud/helm-registry/pkg/quota"
+	"github.com/caicloud/helm-registry/pkg/ratelimit"
+	"github.com/caicloud/helm-registry/pkg/retention"
+	"github.com/caicloud/helm-registry/pkg/scan"
+	"github.com/caicloud/helm-registry/pkg/search"
+	"github.com/caicloud/helm-registry/pkg/stats"
+	"github.com/caicloud/helm-registry/pkg/tlsconfig"
+	"github.com/caicloud/helm-registry/pkg/trace"
+	"github.com/caicloud/helm-registry/pkg/watch"
+	"github.com/caicloud/helm-registry/pkg/webhook"
 	"github.com/emicklei/go-restful"
 	"github.com/go-openapi/spec"
 	"github.com/spf13/cobra"
@@ -36,14 +55,99 @@ var serveCmd = &cobra.Command{
 			log.Fatal(err)
 		}
 
+		// init logging
+		if err = log.Initialize(config.Log); err != nil {
+			log.Fatal(err)
+		}
+
+		// init tracing
+		if err = trace.Initialize(config.Trace); err != nil {
+			log.Fatal(err)
+		}
+
 		// init SpaceManager
 		common.Set(common.ContextNameSpaceManager, config.Manager.Name)
 		common.Set(common.ContextNameSpaceParameters, config.Manager.Parameters)
 		common.MustGetSpaceManager()
+		common.Set(common.ContextNameTrashRetention, config.Trash.Retention)
+
+		// init provenance verification
+		if err = provenance.Initialize(config.Provenance); err != nil {
+			log.Fatal(err)
+		}
+
+		// init authorization
+		if err = auth.Initialize(config.Auth); err != nil {
+			log.Fatal(err)
+		}
+
+		// init webhooks
+		if err = webhook.Initialize(config.Webhook); err != nil {
+			log.Fatal(err)
+		}
+
+		// init search index
+		if err = search.Initialize(config.Search); err != nil {
+			log.Fatal(err)
+		}
+
+		// init space quotas
+		if err = quota.Initialize(config.Quota); err != nil {
+			log.Fatal(err)
+		}
+
+		// init rate limiting
+		if err = ratelimit.Initialize(config.RateLimit); err != nil {
+			log.Fatal(err)
+		}
+
+		// init immutable spaces and charts
+		if err = immutability.Initialize(config.Immutability); err != nil {
+			log.Fatal(err)
+		}
+
+		// init watching changes
+		if err = watch.Initialize(config.Watch); err != nil {
+			log.Fatal(err)
+		}
+
+		// init scanning pushed charts
+		if err = scan.Initialize(config.Scan); err != nil {
+			log.Fatal(err)
+		}
+
+		// start flushing download statistics
+		if err = stats.Start(config.Stats); err != nil {
+			log.Fatal(err)
+		}
+
+		// start retention reaper
+		if err = retention.Start(config.Retention); err != nil {
+			log.Fatal(err)
+		}
+
+		// start garbage collector
+		if err = gc.Start(config.GC); err != nil {
+			log.Fatal(err)
+		}
+
+		// start syncing mirrors
+		if err = mirror.Start(config.Mirror); err != nil {
+			log.Fatal(err)
+		}
+
+		// start reloading certificates
+		tlsConfig, err := tlsconfig.Start(config.TLS)
+		if err != nil {
+			log.Fatal(err)
+		}
 
 		// start server
 		api.Initialize()
 
+		// install metrics path
+		restful.DefaultContainer.Handle("/metrics", metrics.Handler())
+
 		// install openapi path
 		restful.DefaultContainer.Add(restfulspec.NewOpenAPIService(
 			restfulspec.Config{
@@ -55,8 +159,26 @@ var serveCmd = &cobra.Command{
 		))
 
 		log.Infof("Listening address %s", config.Listen)
-		graceful.Run(config.Listen, 5*time.Minute, restful.DefaultContainer)
+		if tlsConfig == nil {
+			graceful.Run(config.Listen, 5*time.Minute, restful.DefaultContainer)
+		} else {
+			server := &graceful.Server{
+				Timeout:      5 * time.Minute,
+				TCPKeepAlive: 3 * time.Minute,
+				Server:       &http.Server{Addr: config.Listen, Handler: restful.DefaultContainer},
+			}
+			// like graceful.Run, errors of accepting are caused by stopping
+			if err = server.ListenAndServeTLSConfig(tlsConfig); err != nil {
+				if opErr, ok := err.(*net.OpError); !ok || opErr.Op != "accept" {
+					log.Fatal(err)
+				}
+			}
+		}
 		log.Error("Server stopped")
+		stats.Flush(context.Background())
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		trace.Flush(ctx)
+		cancel()
 	},
 }
 
@@ -8,6 +8,7 @@ import (
 	"github.com/caicloud/helm-registry/cmd/registry/cmd"
 	_ "github.com/caicloud/helm-registry/pkg/storage/simple"
 	_ "github.com/docker/distribution/registry/storage/driver/filesystem"
+	_ "github.com/docker/distribution/registry/storage/driver/s3-aws"
 )
 
 func main() {
@@ -0,0 +1,70 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/caicloud/helm-registry/pkg/client"
+	"github.com/ghodss/yaml"
+)
+
+// defaultConfigName is the name of config file in home directory
+const defaultConfigName = ".registryctl.yaml"
+
+// Config is the config of registryctl
+type Config struct {
+	// Endpoint is the address of registry
+	Endpoint string `yaml:"endpoint"`
+	// Token is the token to authenticate with registry
+	Token string `yaml:"token"`
+}
+
+// newConfig reads config from the file of flag --config. If the flag is not set, the
+// default config file is read if it exists. Flags override fields of config
+func newConfig() (*Config, error) {
+	config := &Config{}
+	path := configPath
+	if path == "" {
+		path = filepath.Join(os.Getenv("HOME"), defaultConfigName)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			path = ""
+		}
+	}
+	if path != "" {
+		file, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err = yaml.Unmarshal(file, config); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+		}
+	}
+	if endpoint != "" {
+		config.Endpoint = endpoint
+	}
+	if token != "" {
+		config.Token = token
+	}
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("endpoint of registry is required in config file or flag --endpoint")
+	}
+	return config, nil
+}
+
+// newClient creates a client by config
+func newClient() (*client.Client, error) {
+	if err := checkOutput(); err != nil {
+		return nil, err
+	}
+	config, err := newConfig()
+	if err != nil {
+		return nil, err
+	}
+	return client.New(config.Endpoint, client.Options{Token: config.Token})
+}
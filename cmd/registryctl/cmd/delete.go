@@ -0,0 +1,40 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package cmd
+
+import (
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/spf13/cobra"
+)
+
+// deleteCmd deletes a chart or a version
+var deleteCmd = &cobra.Command{
+	Use:   "delete SPACE CHART [VERSION]",
+	Short: "deletes a chart with all of its versions, or a version",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkArgs(cmd, args, 2, 3); err != nil {
+			return err
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		link := &models.ChartLink{Space: args[0], Chart: args[1]}
+		if len(args) == 3 {
+			link.Version = args[2]
+			err = c.DeleteVersion(link.Space, link.Chart, link.Version)
+		} else {
+			err = c.DeleteChart(link.Space, link.Chart)
+		}
+		if err != nil {
+			return err
+		}
+		return printLinks([]*models.ChartLink{link})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deleteCmd)
+}
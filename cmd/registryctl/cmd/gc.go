@@ -0,0 +1,42 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package cmd
+
+import (
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// gcCmd collects garbage in storage of registry
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "purges trash and removes incomplete versions, unfinished uploads and unreferenced blobs in registry",
+	Long:  `The token should be able to delete charts in all spaces.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkArgs(cmd, args, 0, 0); err != nil {
+			return err
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		result, err := c.CollectGarbage()
+		if err != nil {
+			return err
+		}
+		row := []string{
+			strconv.Itoa(len(result.Versions)),
+			strconv.Itoa(len(result.Blobs)),
+			strconv.Itoa(len(result.Uploads)),
+			strconv.FormatInt(result.ReclaimedBytes, 10),
+		}
+		return printResult(result, []string{"VERSIONS", "BLOBS", "UPLOADS", "RECLAIMED BYTES"}, [][]string{row})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+}
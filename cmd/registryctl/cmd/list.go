@@ -0,0 +1,48 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// listCmd lists spaces, charts in a space or versions of a chart
+var listCmd = &cobra.Command{
+	Use:   "list [SPACE [CHART]]",
+	Short: "lists spaces, charts in a space or versions of a chart",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkArgs(cmd, args, 0, 2); err != nil {
+			return err
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		switch len(args) {
+		case 0:
+			spaces, err := c.ListSpaces()
+			if err != nil {
+				return err
+			}
+			return printNames(spaces)
+		case 1:
+			charts, err := c.ListCharts(args[0])
+			if err != nil {
+				return err
+			}
+			return printNames(charts)
+		default:
+			metadata, err := c.ListMetadata(args[0], args[1])
+			if err != nil {
+				return err
+			}
+			return printMetadata(metadata)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+}
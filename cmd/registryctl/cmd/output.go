@@ -0,0 +1,72 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// formats of flag --output
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// checkOutput checks whether the format of flag --output is supported
+func checkOutput() error {
+	if output != outputTable && output != outputJSON {
+		return fmt.Errorf("unknown output format %q, it should be %s or %s", output, outputTable, outputJSON)
+	}
+	return nil
+}
+
+// printResult prints value as json, or prints header and rows as a table
+func printResult(value interface{}, header []string, rows [][]string) error {
+	if output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	return writer.Flush()
+}
+
+// printNames prints names in a table with column NAME
+func printNames(names []string) error {
+	rows := make([][]string, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, []string{name})
+	}
+	return printResult(names, []string{"NAME"}, rows)
+}
+
+// printLinks prints links of versions
+func printLinks(links []*models.ChartLink) error {
+	rows := make([][]string, 0, len(links))
+	for _, link := range links {
+		rows = append(rows, []string{link.Space, link.Chart, link.Version})
+	}
+	return printResult(links, []string{"SPACE", "CHART", "VERSION"}, rows)
+}
+
+// printMetadata prints metadata of versions
+func printMetadata(metadata []*storage.Metadata) error {
+	rows := make([][]string, 0, len(metadata))
+	for _, m := range metadata {
+		rows = append(rows, []string{m.Name, m.Version, m.AppVersion, m.Description})
+	}
+	return printResult(metadata, []string{"NAME", "VERSION", "APP VERSION", "DESCRIPTION"}, rows)
+}
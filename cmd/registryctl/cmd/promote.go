@@ -0,0 +1,51 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/api/v1/types"
+	"github.com/spf13/cobra"
+)
+
+// flags of promoteCmd
+var (
+	promoteTarget    = types.VersionTarget{}
+	promoteOverwrite = false
+)
+
+// promoteCmd copies a version to another space, chart or version
+var promoteCmd = &cobra.Command{
+	Use:   "promote SPACE CHART VERSION --to SPACE",
+	Short: "copies a version to another space, chart or version",
+	Long:  `The chart name and version number of source are used if flags --chart and --version are not set.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkArgs(cmd, args, 3, 3); err != nil {
+			return err
+		}
+		if promoteTarget.Space == "" {
+			return fmt.Errorf("target space is required in flag --to")
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		link, err := c.Promote(args[0], args[1], args[2], promoteTarget, promoteOverwrite)
+		if err != nil {
+			return err
+		}
+		return printLinks([]*models.ChartLink{link})
+	},
+}
+
+func init() {
+	promoteCmd.Flags().StringVar(&promoteTarget.Space, "to", "", "target space")
+	promoteCmd.Flags().StringVar(&promoteTarget.Chart, "chart", "", "target chart name")
+	promoteCmd.Flags().StringVar(&promoteTarget.Version, "version", "", "target version number")
+	promoteCmd.Flags().BoolVar(&promoteOverwrite, "overwrite", false, "replace the target version if it exists")
+	rootCmd.AddCommand(promoteCmd)
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// destination directory of pulled archives
+var pullDir = "."
+
+// pullCmd downloads the archive of a version
+var pullCmd = &cobra.Command{
+	Use:   "pull SPACE CHART VERSION",
+	Short: "downloads the archive of a version",
+	Long:  `The archive is saved as CHART-VERSION.tgz in the directory of flag --dir.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkArgs(cmd, args, 3, 3); err != nil {
+			return err
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		data, err := c.PullChart(args[0], args[1], args[2])
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(pullDir, fmt.Sprintf("%s-%s.tgz", args[1], args[2]))
+		if err = ioutil.WriteFile(path, data, 0644); err != nil {
+			return err
+		}
+		return printResult(map[string]string{"file": path}, []string{"FILE"}, [][]string{{path}})
+	},
+}
+
+func init() {
+	pullCmd.Flags().StringVarP(&pullDir, "dir", "d", pullDir, "directory to save the archive")
+	rootCmd.AddCommand(pullCmd)
+}
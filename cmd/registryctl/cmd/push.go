@@ -0,0 +1,45 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package cmd
+
+import (
+	"io/ioutil"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/spf13/cobra"
+)
+
+// pushCmd uploads chart archives to a space
+var pushCmd = &cobra.Command{
+	Use:   "push SPACE ARCHIVE...",
+	Short: "uploads chart archives to a space",
+	Long:  `Names and versions of charts are read from archives. Archives are uploaded in order and it stops at the first failure.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := checkArgs(cmd, args, 2, len(args)); err != nil {
+			return err
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		links := make([]*models.ChartLink, 0, len(args)-1)
+		for _, path := range args[1:] {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			link, err := c.PushChart(args[0], data)
+			if err != nil {
+				return err
+			}
+			links = append(links, link)
+		}
+		return printLinks(links)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pushCmd)
+}
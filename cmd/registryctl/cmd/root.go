@@ -0,0 +1,61 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// global flags
+var (
+	// configPath is the path of config file
+	configPath = ""
+	// endpoint overrides the endpoint in config file
+	endpoint = ""
+	// token overrides the token in config file
+	token = ""
+	// output is the format of results
+	output = outputTable
+)
+
+// rootCmd is a root command and shows help information
+var rootCmd = &cobra.Command{
+	Use:   "registryctl",
+	Short: "registryctl manages charts in a helm registry",
+	Long: `The endpoint and token of registry are read from config file $HOME/.registryctl.yaml or the file of flag
+--config, and flags --endpoint and --token override them. A config file looks like:
+
+  endpoint: http://localhost:8099
+  token: 5d41402abc4b2a76b9719d911017c592`,
+	SilenceUsage: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.HelpFunc()(cmd, args)
+	},
+}
+
+// Run executes rootCmd
+func Run() {
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// checkArgs checks whether the number of args is between min and max
+func checkArgs(cmd *cobra.Command, args []string, min, max int) error {
+	if len(args) < min || len(args) > max {
+		return fmt.Errorf("wrong number of arguments, usage: %s", cmd.UseLine())
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "path of config file, $HOME/.registryctl.yaml by default")
+	rootCmd.PersistentFlags().StringVarP(&endpoint, "endpoint", "e", "", "address of registry, like http://localhost:8099")
+	rootCmd.PersistentFlags().StringVar(&token, "token", "", "token to authenticate with registry")
+	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", outputTable, "format of results, table or json")
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package cmd
+
+import (
+	"strings"
+
+	"github.com/caicloud/helm-registry/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// query of searchCmd
+var searchQuery = client.SearchQuery{}
+
+// searchCmd searches versions in all readable spaces or a space
+var searchCmd = &cobra.Command{
+	Use:   "search [TEXT...]",
+	Short: "searches versions by names, descriptions, keywords and maintainers of charts",
+	Long:  `Every word of TEXT should match the name, description, keyword or maintainer of a chart. Results are in the order of relevance.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		query := searchQuery
+		query.Text = strings.Join(args, " ")
+		metadata, err := c.Search(query)
+		if err != nil {
+			return err
+		}
+		return printMetadata(metadata)
+	},
+}
+
+func init() {
+	searchCmd.Flags().StringVar(&searchQuery.Keyword, "keyword", "", "keyword which charts must have")
+	searchCmd.Flags().StringVar(&searchQuery.Maintainer, "maintainer", "", "name or email of a maintainer which charts must have")
+	searchCmd.Flags().StringVarP(&searchQuery.Space, "space", "s", "", "space to search in, all readable spaces by default")
+	searchCmd.Flags().BoolVar(&searchQuery.Latest, "latest", false, "only return the latest version of every chart")
+	searchCmd.Flags().BoolVar(&searchQuery.Prerelease, "prerelease", false, "include pre-release versions when resolving the latest version")
+	rootCmd.AddCommand(searchCmd)
+}
@@ -0,0 +1,13 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package main
+
+import (
+	"github.com/caicloud/helm-registry/cmd/registryctl/cmd"
+)
+
+func main() {
+	cmd.Run()
+}
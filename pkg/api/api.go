@@ -5,41 +5,89 @@ Copyright 2017 caicloud authors. All rights reserved.
 package api
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
 	"time"
 
 	"github.com/caicloud/helm-registry/pkg/api/v1"
 	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/trace"
 	"github.com/emicklei/go-restful"
 )
 
+// HeaderRequestID is the header of request id. A valid id sent by client is kept,
+// otherwise a new one is generated. It's returned in responses
+const HeaderRequestID = "X-Request-ID"
+
+// requestIDPattern matches valid request ids sent by clients
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
 // Initialize initializes apis of all versions
 func Initialize() {
 	v1.InstallRouters(restful.DefaultContainer)
+	v1.InstallOCIRouters(restful.DefaultContainer)
 	restful.EnableTracing(true)
-	restful.DefaultContainer.Filter(NCSACommonLogFormatLogger())
+	restful.DefaultContainer.Filter(RequestLogger())
 }
 
-// NCSACommonLogFormatLogger adds logs for every request using common log format.
-func NCSACommonLogFormatLogger() restful.FilterFunction {
+// RequestLogger adds a request id and a server span to the context of every request,
+// and logs requests with structured fields. Loggers from log.FromContext log the
+// request id and the trace id of request
+func RequestLogger() restful.FilterFunction {
 	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
 		r := req.Request
 		start := time.Now()
-		log.Printf("Started %s - [%s] %s %s",
-			req.Request.RemoteAddr,
-			time.Now().Format("02/Jan/2006:15:04:05 -0700"),
-			r.Method,
-			req.Request.URL.RequestURI(),
-		)
+		id := req.HeaderParameter(HeaderRequestID)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		resp.Header().Set(HeaderRequestID, id)
+		route := req.SelectedRoutePath()
+		if len(route) <= 0 {
+			route = r.URL.Path
+		}
+		ctx, span := trace.StartRequest(r.Context(), r.Method+" "+route, r.Header)
+		defer span.End()
+		span.SetAttribute("http.request.method", r.Method)
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("url.path", r.URL.Path)
+		span.SetAttribute("request.id", id)
+		fields := log.Fields{"request_id": id}
+		if span != nil {
+			fields["trace_id"] = span.TraceID()
+		}
+		ctx = log.NewContext(ctx, fields)
+		req.Request = r.WithContext(ctx)
+
+		log.WithFields(ctx, log.Fields{
+			"method": r.Method,
+			"uri":    r.URL.RequestURI(),
+			"remote": r.RemoteAddr,
+		}).Info("Started request")
 		chain.ProcessFilter(req, resp)
-		log.Printf("%s - [%s] \"%s %s %s\" %d %d %v",
-			req.Request.RemoteAddr,
-			time.Now().Format("02/Jan/2006:15:04:05 -0700"),
-			req.Request.Method,
-			req.Request.URL.RequestURI(),
-			req.Request.Proto,
-			resp.StatusCode(),
-			resp.ContentLength(),
-			time.Since(start),
-		)
+		span.SetAttribute("http.response.status_code", resp.StatusCode())
+		if resp.StatusCode() >= http.StatusInternalServerError {
+			span.SetError(fmt.Errorf("%d %s", resp.StatusCode(), http.StatusText(resp.StatusCode())))
+		}
+		log.WithFields(ctx, log.Fields{
+			"method":   r.Method,
+			"uri":      r.URL.RequestURI(),
+			"route":    route,
+			"remote":   r.RemoteAddr,
+			"proto":    r.Proto,
+			"status":   resp.StatusCode(),
+			"bytes":    resp.ContentLength(),
+			"duration": time.Since(start).Seconds(),
+		}).Info("Completed request")
 	}
 }
+
+// newRequestID generates a random request id
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
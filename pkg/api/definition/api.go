@@ -45,6 +45,9 @@ func GenerateRoutes(ws *restful.WebService, descriptors []Descriptor) *restful.W
 			for _, filter := range handler.Filters {
 				builder.Filter(filter)
 			}
+			if len(handler.Produces) > 0 {
+				builder.Produces(handler.Produces...)
+			}
 			ws.Route(builder)
 		}
 	}
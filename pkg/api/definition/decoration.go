@@ -6,12 +6,16 @@ package definition
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"reflect"
+	"time"
 
 	"github.com/caicloud/helm-registry/pkg/api/models"
 	"github.com/caicloud/helm-registry/pkg/errors"
 	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/metrics"
+	"github.com/caicloud/helm-registry/pkg/trace"
 	"github.com/emicklei/go-restful"
 )
 
@@ -27,6 +31,8 @@ const (
 	VerbCreate Verb = "create"
 	// VerbUpdate updates an object
 	VerbUpdate Verb = "update"
+	// VerbAccept accepts an object which will be processed later
+	VerbAccept Verb = "accept"
 	// VerbDelete deletes an object
 	VerbDelete Verb = "delete"
 )
@@ -37,6 +43,8 @@ type Key string
 const (
 	// KeyRequest is the key of request
 	KeyRequest Key = "Context.Request"
+	// KeyResponse is the key of response. Handlers can set response headers by it
+	KeyResponse Key = "Context.Response"
 )
 
 // HandlerDecoration defines a decoration of handler
@@ -47,9 +55,11 @@ const (
 // e.g.
 // func DeleteApplication(ctx context.Context) error
 //
-// VerbGet, VerbCreate, VerbUpdate definition (return 2 values):
+// VerbGet, VerbCreate, VerbUpdate, VerbAccept definition (return 2 values):
 // The first return value (type interface{}) can be any type which you like.
-// func(ctx context.Context) (interface{},error) -> response with 200/201 or error
+// If it's an io.Reader, the response body is copied from it and it's closed
+// if it's also an io.Closer.
+// func(ctx context.Context) (interface{},error) -> response with 200/201/202 or error
 // e.g.
 // func GetApplication(ctx context.Context) (*Application,error)
 //
@@ -71,6 +81,7 @@ var verbMapping = map[Verb]int{
 	VerbGet:    2,
 	VerbCreate: 2,
 	VerbUpdate: 2,
+	VerbAccept: 2,
 	VerbList:   3,
 }
 
@@ -108,9 +119,40 @@ func NewHandlerDecoration(verb Verb, handler interface{}) *HandlerDecoration {
 	}
 }
 
+// detachedContext keeps values of its parent, but it's never canceled and has no deadline
+type detachedContext struct {
+	parent context.Context
+}
+
+// Deadline returns no deadline
+func (c detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// Done returns nil, so the context is never canceled
+func (c detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err always returns nil
+func (c detachedContext) Err() error {
+	return nil
+}
+
+// Value returns the value of key in parent
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
 // Handle handles a request
 func (hd *HandlerDecoration) Handle(request *restful.Request, resp *restful.Response) {
-	ctx := context.WithValue(context.Background(), KeyRequest, request)
+	defer func(start time.Time) {
+		metrics.ObserveHandler(request.Request.Method, request.SelectedRoutePath(), resp.StatusCode(), start)
+	}(time.Now())
+	// values of request context like the request id are kept, but handlers are not
+	// canceled when clients go away
+	ctx := context.WithValue(detachedContext{request.Request.Context()}, KeyRequest, request)
+	ctx = context.WithValue(ctx, KeyResponse, resp)
 	result := hd.Value.Call([]reflect.Value{reflect.ValueOf(ctx)})
 	errValue := result[verbMapping[hd.Verb]-1]
 	if errValue.IsNil() {
@@ -118,17 +160,28 @@ func (hd *HandlerDecoration) Handle(request *restful.Request, resp *restful.Resp
 		case VerbDelete:
 			resp.WriteHeader(http.StatusNoContent)
 			return
-		case VerbCreate, VerbGet, VerbUpdate:
+		case VerbCreate, VerbGet, VerbUpdate, VerbAccept:
 			statusCode := http.StatusOK
 			if hd.Verb == VerbCreate {
 				statusCode = http.StatusCreated
+			} else if hd.Verb == VerbAccept {
+				statusCode = http.StatusAccepted
 			}
 			// check obj type
 			obj := result[0]
+			// if obj is io.Reader, copies it to resp
 			// if obj is []byte, writes by resp.Write()
 			// otherwise resp.WriteHeaderAndEntity()
 			objType := obj.Type()
-			if (objType.Kind() == reflect.Array || objType.Kind() == reflect.Slice) &&
+			if reader, ok := obj.Interface().(io.Reader); ok {
+				resp.WriteHeader(statusCode)
+				if _, err := io.Copy(resp, reader); err != nil {
+					log.FromContext(ctx).Errorf("Failed to write response: %v", err)
+				}
+				if closer, ok := reader.(io.Closer); ok {
+					closer.Close()
+				}
+			} else if (objType.Kind() == reflect.Array || objType.Kind() == reflect.Slice) &&
 				objType.Elem().AssignableTo(reflect.TypeOf(byte(0))) {
 				resp.WriteHeader(statusCode)
 				data := obj.Interface().([]byte)
@@ -149,12 +202,24 @@ func (hd *HandlerDecoration) Handle(request *restful.Request, resp *restful.Resp
 	// handle error
 	switch err := errValue.Interface().(type) {
 	case *errors.Error:
-		resp.WriteHeaderAndEntity(err.Code, map[string]string{
+		if err.Code >= http.StatusMultipleChoices && err.Code < http.StatusBadRequest {
+			// redirections are not errors, and a response with 304 must not have a body
+			resp.WriteHeader(err.Code)
+			return
+		}
+		metrics.CountError(err)
+		trace.FromContext(ctx).SetAttribute("error.reason", err.Reason)
+		entity := map[string]interface{}{
 			"message": err.Message,
 			"reason":  err.Reason,
-		})
+		}
+		if err.Details != nil {
+			entity["details"] = err.Details
+		}
+		resp.WriteHeaderAndEntity(err.Code, entity)
 	case error:
-		log.Infof("%s handler returns an error but the type is not custom error type", hd.Verb)
+		log.FromContext(ctx).Infof("%s handler returns an error but the type is not custom error type: %v", hd.Verb, err)
+		metrics.CountError(errors.ErrorInternalUnknown)
 		resp.WriteHeaderAndEntity(http.StatusInternalServerError, map[string]string{
 			"message": err.Error(),
 			"reason":  errors.ReasonInternal,
@@ -27,6 +27,10 @@ type Handler struct {
 	// Filters describes an array of filters
 	Filters []restful.FilterFunction
 
+	// Produces describes mime types of responses. It overrides mime types of the
+	// WebService if it's not empty
+	Produces []string
+
 	// Doc provides a short document for describing current descriptor
 	Doc string
 
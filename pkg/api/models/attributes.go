@@ -0,0 +1,12 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package models
+
+// AttributesPatch is a JSON merge patch of labels and annotations. A key with a null
+// value is removed, and other keys are set. Keys which are not in the patch are kept
+type AttributesPatch struct {
+	Labels      map[string]*string `json:"labels,omitempty"`
+	Annotations map[string]*string `json:"annotations,omitempty"`
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package models
+
+// BulkUploadStatus is the status of a file in bulk upload
+type BulkUploadStatus string
+
+const (
+	// BulkUploadSuccess means the version is stored
+	BulkUploadSuccess BulkUploadStatus = "success"
+	// BulkUploadSkippedDuplicate means a same version with same content exists
+	BulkUploadSkippedDuplicate BulkUploadStatus = "skipped-duplicate"
+	// BulkUploadFailed means the file can't be stored
+	BulkUploadFailed BulkUploadStatus = "failed"
+	// BulkUploadRolledBack means the file is valid but not stored because other files failed
+	BulkUploadRolledBack BulkUploadStatus = "rolled-back"
+)
+
+// BulkUploadFile describes the result of a file in bulk upload
+type BulkUploadFile struct {
+	// File is the name of uploaded file
+	File string `json:"file"`
+	// Chart is chart name. It's empty if the file is not a valid chart
+	Chart string `json:"chart,omitempty"`
+	// Version is chart version. It's empty if the file is not a valid chart
+	Version string `json:"version,omitempty"`
+	// Status is the status of file
+	Status BulkUploadStatus `json:"status"`
+	// Reason is the reason of failure
+	Reason string `json:"reason,omitempty"`
+}
+
+// BulkUploadResult describes the result of bulk upload
+type BulkUploadResult struct {
+	// Committed is true if all files are stored or skipped
+	Committed bool `json:"committed"`
+	// Files are results of all files in request order
+	Files []BulkUploadFile `json:"files"`
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package models
+
+// Change describes a value which is changed
+type Change struct {
+	// From is the value in the original version
+	From interface{} `json:"from"`
+	// To is the value in the target version
+	To interface{} `json:"to"`
+}
+
+// ValuesDiff describes differences between two values. Keys of nested
+// values are joined by dot, like `image.tag`.
+type ValuesDiff struct {
+	// Added contains keys only in the target version
+	Added map[string]interface{} `json:"added"`
+	// Removed contains keys only in the original version
+	Removed map[string]interface{} `json:"removed"`
+	// Changed contains keys with different values
+	Changed map[string]Change `json:"changed"`
+}
+
+// File statuses in FileDiff
+const (
+	FileAdded    = "added"
+	FileRemoved  = "removed"
+	FileModified = "modified"
+)
+
+// FileDiff describes differences of a template file between two versions
+type FileDiff struct {
+	// Name is the path of file in chart, like `templates/deployment.yaml`
+	Name string `json:"name"`
+	// Status is one of added, removed and modified
+	Status string `json:"status"`
+	// Diff is the unified diff of file. It only tells that files differ if they are too
+	// large to compare
+	Diff string `json:"diff,omitempty"`
+}
+
+// VersionDiff describes differences between two versions of chart
+type VersionDiff struct {
+	// From is the original version number
+	From string `json:"from"`
+	// To is the target version number
+	To string `json:"to"`
+	// ValuesDiff is the differences of values
+	ValuesDiff ValuesDiff `json:"valuesDiff"`
+	// MetadataDiff is the differences of metadata fields
+	MetadataDiff map[string]Change `json:"metadataDiff"`
+	// TemplatesDiff is the differences of template files, sorted by name
+	TemplatesDiff []FileDiff `json:"templatesDiff"`
+}
@@ -0,0 +1,35 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package models
+
+import (
+	"time"
+
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// IndexAPIVersion is the api version of helm repository indexes
+const IndexAPIVersion = "v1"
+
+// Index is a helm repository index of a space
+type Index struct {
+	// APIVersion is the version of index format
+	APIVersion string `json:"apiVersion"`
+	// Entries maps chart names to their versions
+	Entries map[string][]*IndexEntry `json:"entries"`
+	// Generated is the time when the index is generated
+	Generated time.Time `json:"generated"`
+}
+
+// IndexEntry describes a version of chart in index
+type IndexEntry struct {
+	*chart.Metadata
+	// URLs are urls for downloading the version
+	URLs []string `json:"urls"`
+	// Created is the time when the version is stored
+	Created time.Time `json:"created,omitempty"`
+	// Digest is the sha256 digest of chart archive in hex
+	Digest string `json:"digest,omitempty"`
+}
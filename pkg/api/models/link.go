@@ -4,6 +4,8 @@ Copyright 2017 caicloud authors. All rights reserved.
 
 package models
 
+import "github.com/caicloud/helm-registry/pkg/scan"
+
 // Link describes a normal self-link
 type Link struct {
 	// Name is object name
@@ -27,9 +29,13 @@ type ChartLink struct {
 	Version string `json:"version"`
 	// Link is the uri of object
 	Link string `json:"link"`
+	// Lint is the lint report of an uploaded chart
+	Lint []LintMessage `json:"lint,omitempty"`
+	// Scan is the scan report of an uploaded chart. It's nil if no check runs in the space
+	Scan *scan.Report `json:"scan,omitempty"`
 }
 
 // NewChartLink creates a chart self-link
 func NewChartLink(space, chart, version, link string) *ChartLink {
-	return &ChartLink{space, chart, version, link}
+	return &ChartLink{Space: space, Chart: chart, Version: version, Link: link}
 }
@@ -0,0 +1,15 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package models
+
+// LintMessage describes a problem found by linting a chart
+type LintMessage struct {
+	// Severity is one of unknown, info, warning and error
+	Severity string `json:"severity"`
+	// Path is the file which the message relates to
+	Path string `json:"path"`
+	// Message is the description of the problem
+	Message string `json:"message"`
+}
@@ -0,0 +1,23 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package models
+
+// PrunedChart describes pruned versions of a chart
+type PrunedChart struct {
+	// Chart is chart name
+	Chart string `json:"chart"`
+	// Versions are numbers of pruned versions
+	Versions []string `json:"versions"`
+}
+
+// PruneResult describes the result of pruning a space
+type PruneResult struct {
+	// Space is space name
+	Space string `json:"space"`
+	// DryRun is whether versions are only selected but not pruned
+	DryRun bool `json:"dryRun"`
+	// Charts are charts which have pruned versions
+	Charts []PrunedChart `json:"charts"`
+}
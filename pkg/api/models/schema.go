@@ -0,0 +1,15 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package models
+
+// SchemaError describes a field of values which fails validation against the values schema
+type SchemaError struct {
+	// Field is the path of the field, like `image.tag`. It's `(root)` for the whole values
+	Field string `json:"field"`
+	// Type is the type of the failure, like `required` or `invalid_type`
+	Type string `json:"type"`
+	// Description is the description of the failure
+	Description string `json:"description"`
+}
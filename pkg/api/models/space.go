@@ -0,0 +1,25 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package models
+
+import "github.com/caicloud/helm-registry/pkg/storage"
+
+// Space describes a space and its usage
+type Space struct {
+	// Name is space name
+	Name string `json:"name"`
+	// Description is a human readable description of space
+	Description string `json:"description,omitempty"`
+	// Owner is the person or team who is responsible for space
+	Owner string `json:"owner,omitempty"`
+	// Visibility is public or private
+	Visibility storage.Visibility `json:"visibility"`
+	// Charts is the number of charts in space
+	Charts int `json:"charts"`
+	// Versions is the number of versions in space
+	Versions int `json:"versions"`
+	// Bytes is the total size of archives of all versions in space
+	Bytes int64 `json:"bytes"`
+}
@@ -0,0 +1,25 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package models
+
+// VersionStats describes statistics of a version
+type VersionStats struct {
+	// Version is version number
+	Version string `json:"version"`
+	// Downloads is the number of times the version is downloaded
+	Downloads int64 `json:"downloads"`
+}
+
+// ChartStats describes statistics of a chart
+type ChartStats struct {
+	// Space is space name
+	Space string `json:"space"`
+	// Chart is chart name
+	Chart string `json:"chart"`
+	// Downloads is the number of times all existing versions are downloaded
+	Downloads int64 `json:"downloads"`
+	// Versions are statistics of existing versions in ascending semver order
+	Versions []VersionStats `json:"versions"`
+}
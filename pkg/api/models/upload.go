@@ -0,0 +1,30 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package models
+
+// Upload describes a resumable upload of a chart archive
+type Upload struct {
+	// Space is the space chart is uploaded to
+	Space string `json:"space"`
+	// ID is the id of upload
+	ID string `json:"id"`
+	// Offset is the number of bytes received. The next chunk starts from it
+	Offset int64 `json:"offset"`
+	// Link is the uri of upload
+	Link string `json:"link"`
+}
+
+// NewUpload creates an upload description
+func NewUpload(space, id string, offset int64, link string) *Upload {
+	return &Upload{Space: space, ID: id, Offset: offset, Link: link}
+}
+
+// OCITags describes tags of a repository in OCI distribution API
+type OCITags struct {
+	// Name is the name of repository, like "space/chart"
+	Name string `json:"name"`
+	// Tags are version numbers with "+" replaced by "_"
+	Tags []string `json:"tags"`
+}
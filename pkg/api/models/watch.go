@@ -0,0 +1,16 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package models
+
+import "github.com/caicloud/helm-registry/pkg/watch"
+
+// WatchResponse is a response of long polling changes
+type WatchResponse struct {
+	// ResourceVersion is the resource version to watch from in the next request
+	ResourceVersion string `json:"resourceVersion"`
+	// Events are changes after the requested resource version. It's empty if no change
+	// happens before timeout
+	Events []*watch.Event `json:"events"`
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package descriptor
+
+import (
+	"net/http"
+
+	"github.com/caicloud/helm-registry/pkg/api/definition"
+	"github.com/caicloud/helm-registry/pkg/api/v1/handlers"
+	"github.com/caicloud/helm-registry/pkg/backup"
+	"github.com/caicloud/helm-registry/pkg/mirror"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+func init() {
+	registerDescriptors(admin)
+}
+
+// admin descriptors
+var admin = []definition.Descriptor{
+	{
+		Path: "/admin/gc",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodPost,
+				Handler:    definition.NewHandlerDecoration(definition.VerbCreate, handlers.CollectGarbage).Handle,
+				Doc:        "Purge trash and remove incomplete versions, unfinished uploads and unreferenced blobs in storage",
+				Note: `Data modified within the grace period of registry config is kept, so uploads in progress are not affected.
+							Refcounts of blobs are repaired by references of versions.`,
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusCreated, Message: "Collect successfully",
+						Sample: &storage.GCResult{
+							Versions:       []string{"library/A/1.0.0"},
+							Blobs:          []string{"8f434346648f6b96df89dda901c5176b10a6d83961dd3c1ac88b59b2dc327aa4"},
+							Uploads:        []string{"library/5d41402abc4b2a76b9719d911017c592"},
+							ReclaimedBytes: 4096,
+						}},
+				},
+			},
+		},
+	},
+	{
+		Path: "/admin/backup",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.BackupRegistry).Handle,
+				Doc:        "Back up all spaces, charts and versions as a gzipped tar",
+				Note: `The backup has a manifest.json with retention policies, webhooks, download counts, attributes and sha256 checksums of all files,
+							and archives, provenances and OCI manifests of versions. Trashed versions are not backed up.
+							The backup is truncated if a version is modified while it's being written.`,
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Back up successfully"},
+				},
+			},
+		},
+	},
+	{
+		Path: "/admin/restore",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodPost,
+				Handler:    definition.NewHandlerDecoration(definition.VerbCreate, handlers.RestoreRegistry).Handle,
+				Doc:        "Restore a backup from request body into an empty registry",
+				Note:       "Every file is verified by its checksum in manifest.json before it's stored. A failed restore may leave restored data in registry.",
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusCreated, Message: "Restore successfully",
+						Sample: &backup.Manifest{
+							Version: backup.FormatVersion,
+							Spaces: []backup.Space{
+								{
+									Name: "library",
+									Charts: []backup.Chart{
+										{
+											Name: "mysql",
+											Versions: []backup.Version{
+												{
+													Version:   "1.0.0",
+													Files:     map[string]string{"chart.tgz": "8f434346648f6b96df89dda901c5176b10a6d83961dd3c1ac88b59b2dc327aa4"},
+													Downloads: 5,
+												},
+											},
+										},
+									},
+								},
+							},
+						}},
+					definition.StatusCode{Code: http.StatusConflict, Message: "Registry is not empty"},
+				},
+			},
+		},
+	},
+	{
+		Path: "/admin/mirrors",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbList, handlers.ListMirrors).Handle,
+				Doc:        "List sync statuses of mirrors",
+				Note:       "Mirrors are upstream helm repositories in registry config.",
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "List successfully",
+						Sample: []mirror.Status{
+							{
+								Name:   "stable",
+								URL:    "https://kubernetes-charts.storage.googleapis.com",
+								Space:  "stable",
+								Synced: []string{"redis/3.0.0"},
+							},
+						}},
+				},
+			},
+		},
+	},
+	{
+		Path: "/admin/mirrors/{mirror}",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.FetchMirror).Handle,
+				Doc:        "Get the sync status of a mirror",
+				PathParams: []definition.Param{
+					{
+						Name:     "mirror",
+						Type:     "string",
+						Doc:      "mirror name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Get successfully",
+						Sample: &mirror.Status{
+							Name:    "stable",
+							URL:     "https://kubernetes-charts.storage.googleapis.com",
+							Space:   "stable",
+							Syncing: false,
+							Synced:  []string{"redis/3.0.0"},
+						}},
+				},
+			},
+		},
+	},
+	{
+		Path: "/admin/mirrors/{mirror}/sync",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodPost,
+				Handler:    definition.NewHandlerDecoration(definition.VerbAccept, handlers.SyncMirror).Handle,
+				Doc:        "Sync a mirror in background",
+				Note:       "Versions in upstream which don't exist in the space of mirror are stored. The sync is not restarted if it's running.",
+				PathParams: []definition.Param{
+					{
+						Name:     "mirror",
+						Type:     "string",
+						Doc:      "mirror name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusAccepted, Message: "Sync started",
+						Sample: &mirror.Status{
+							Name:    "stable",
+							URL:     "https://kubernetes-charts.storage.googleapis.com",
+							Space:   "stable",
+							Syncing: true,
+							Synced:  []string{"redis/3.0.0"},
+						}},
+				},
+			},
+		},
+	},
+	{
+		Path: "/admin/proxies",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbList, handlers.ListProxies).Handle,
+				Doc:        "List statuses of proxies",
+				Note:       "Proxies are spaces which pull charts from upstream helm repositories in registry config when they are fetched.",
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "List successfully",
+						Sample: []mirror.ProxyStatus{
+							{
+								Name:   "central",
+								URL:    "https://registry.example.com/api/v1/spaces/library",
+								Space:  "library",
+								Pulled: 3,
+							},
+						}},
+				},
+			},
+		},
+	},
+}
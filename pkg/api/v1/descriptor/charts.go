@@ -11,6 +11,7 @@ import (
 	"github.com/caicloud/helm-registry/pkg/api/models"
 	"github.com/caicloud/helm-registry/pkg/api/v1/handlers"
 	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/storage"
 )
 
 func init() {
@@ -49,6 +50,12 @@ var charts = []definition.Descriptor{
 						Required: false,
 						Default:  common.DefaultPagingLimit,
 					},
+					{
+						Name:     "label",
+						Type:     "string",
+						Doc:      "Label selector like `team=payments` matched with labels of charts. Repeated selectors are all matched",
+						Required: false,
+					},
 				},
 				StatusCode: []definition.StatusCode{
 					definition.StatusCode{Code: http.StatusOK, Message: "Success and respond with an array of chart names",
@@ -130,6 +137,19 @@ below is a sample:
 						Doc:      "An archive file of chart. Only valid when upload a chart",
 						Required: true,
 					},
+					{
+						Name:     "provfile",
+						Type:     "multipart/form-data",
+						Doc:      "A provenance file of chart. Only valid when upload a chart. Required if the registry verifies provenance",
+						Required: false,
+					},
+					{
+						Name:     "strict",
+						Type:     "boolean",
+						Doc:      "Reject the chart if lint reports warnings. Errors are always rejected",
+						Required: false,
+						Default:  false,
+					},
 				},
 				StatusCode: []definition.StatusCode{
 					definition.StatusCode{Code: http.StatusCreated, Message: "Create successfully",
@@ -170,4 +190,282 @@ below is a sample:
 			},
 		},
 	},
+	{
+		Path: "/spaces/{space}/charts/{chart}/retention",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.FetchRetentionPolicy).Handle,
+				Doc:        "Fetch the retention policy of a chart",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Retention policy",
+						Sample: &storage.RetentionPolicy{
+							KeepLast:   10,
+							KeepWithin: "720h",
+						}},
+				},
+			},
+			{
+				HTTPMethod: http.MethodPut,
+				Handler:    definition.NewHandlerDecoration(definition.VerbUpdate, handlers.UpdateRetentionPolicy).Handle,
+				Doc:        "Set the retention policy of a chart",
+				Note: `A version is kept if it's one of the keepLast highest versions or it's stored within keepWithin.
+							The highest version is always kept. Other versions are moved to trash by the reaper.`,
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Set successfully",
+						Sample: &storage.RetentionPolicy{
+							KeepLast:   10,
+							KeepWithin: "720h",
+						}},
+				},
+			},
+			{
+				HTTPMethod: http.MethodDelete,
+				Handler:    definition.NewHandlerDecoration(definition.VerbDelete, handlers.DeleteRetentionPolicy).Handle,
+				Doc:        "Remove the retention policy of a chart",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusNoContent, Message: "Remove successfully"},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/charts/{chart}/attributes",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.FetchChartAttributes).Handle,
+				Doc:        "Fetch labels and annotations of a chart",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Attributes",
+						Sample: &storage.Attributes{
+							Labels:      map[string]string{"team": "payments", "stage": "production"},
+							Annotations: map[string]string{"example.com/owner": "payments@example.com"},
+						}},
+				},
+			},
+			{
+				HTTPMethod: http.MethodPatch,
+				Handler:    definition.NewHandlerDecoration(definition.VerbUpdate, handlers.UpdateChartAttributes).Handle,
+				Doc:        "Update labels and annotations of a chart",
+				Note: `The body is a JSON merge patch like {"labels": {"team": "payments", "stage": null}}. A key
+							with null is removed, and keys not in the patch are kept.`,
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Update successfully",
+						Sample: &storage.Attributes{
+							Labels:      map[string]string{"team": "payments", "stage": "production"},
+							Annotations: map[string]string{"example.com/owner": "payments@example.com"},
+						}},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/bulk",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodPost,
+				Handler:    definition.NewHandlerDecoration(definition.VerbUpdate, handlers.BulkUpload).Handle,
+				Doc:        "Upload many versions of charts in one request",
+				Note: `Either all versions are stored or none of them. If any file is invalid or conflicts with
+							an existing version, stored versions are rolled back and committed is false in response.
+							A version which exists with same content is skipped. Provenance files are matched with
+							chart files by name, like chart-1.0.0.tgz.prov for chart-1.0.0.tgz.`,
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+				},
+				QueryParams: []definition.Param{
+					{
+						Name:     "chartfile",
+						Type:     "multipart/form-data",
+						Doc:      "Archive files of charts",
+						Required: true,
+					},
+					{
+						Name:     "provfile",
+						Type:     "multipart/form-data",
+						Doc:      "Provenance files of charts",
+						Required: false,
+					},
+					{
+						Name:     "strict",
+						Type:     "boolean",
+						Doc:      "Reject the chart if lint reports warnings. Errors are always rejected",
+						Required: false,
+						Default:  false,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Success and respond with the status of every file",
+						Sample: &models.BulkUploadResult{
+							Committed: false,
+							Files: []models.BulkUploadFile{
+								{File: "a-1.0.0.tgz", Chart: "a", Version: "1.0.0", Status: models.BulkUploadRolledBack},
+								{File: "b-1.0.0.tgz", Chart: "b", Version: "1.0.0", Status: models.BulkUploadSkippedDuplicate},
+								{File: "c.tgz", Status: models.BulkUploadFailed, Reason: "chartfile should be chart, but got unknown"},
+							},
+						}},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/compose",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodPost,
+				Handler:    definition.NewHandlerDecoration(definition.VerbCreate, handlers.ComposeChart).Handle,
+				Doc:        "Compose an umbrella chart from versions in registry",
+				Note: `
+The request body is a json config which specifies the umbrella chart and its subcharts. Dependencies
+of every subchart are resolved from its space and packed with it. Values of the umbrella chart
+contain values of every subchart under its name, and values in config override them. Below is a sample:
+{
+    "save":{
+        "chart":"umbrella",                 // string, required
+        "version":"1.0.0",                  // string, required
+        "description":"description"         // string, optional
+    },
+    "components":[
+        {
+            "name":"db",                    // string, optional, default to the chart name
+            "space":"library",              // string, required
+            "chart":"mysql",                // string, required
+            "version":"1.2.0",              // string, required
+            "values":{"persistence":false}  // object, optional
+        }
+    ]
+}`,
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusCreated, Message: "Compose successfully",
+						Sample: &models.ChartLink{
+							Space:   "spaceName",
+							Chart:   "umbrella",
+							Version: "1.0.0",
+							Link:    "/api/v1/spaces/spaceName/charts/umbrella/versions/1.0.0",
+						}},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/charts/{chart}/stats",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.FetchChartStats).Handle,
+				Doc:        "Fetch download statistics of a chart",
+				Note:       "Downloads of archives, bundles and repository archives are counted. Trashed versions are not included.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Download statistics",
+						Sample: &models.ChartStats{
+							Space:     "library",
+							Chart:     "A",
+							Downloads: 12,
+							Versions: []models.VersionStats{
+								{Version: "1.0.0", Downloads: 2},
+								{Version: "1.1.0", Downloads: 10},
+							},
+						}},
+				},
+			},
+		},
+	},
 }
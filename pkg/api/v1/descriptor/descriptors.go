@@ -19,9 +19,12 @@ func registerDescriptors(descriptors []definition.Descriptor) {
 		for _, handler := range desc.Handlers {
 			handler.StatusCode = append(handler.StatusCode,
 				definition.StatusCode{Code: http.StatusBadRequest, Message: "Request params error"},
+				definition.StatusCode{Code: http.StatusUnauthorized, Message: "Bearer token is missing or invalid"},
+				definition.StatusCode{Code: http.StatusForbidden, Message: "Token has no permission on the space"},
 				definition.StatusCode{Code: http.StatusNotFound, Message: "Resource does not exist"},
 				definition.StatusCode{Code: http.StatusConflict, Message: "Conflict. See logs and response"},
 				definition.StatusCode{Code: http.StatusLocked, Message: "Resource locked. Can't read or write"},
+				definition.StatusCode{Code: http.StatusTooManyRequests, Message: "Rate limit exceeded. Retry after header Retry-After"},
 				definition.StatusCode{Code: http.StatusInternalServerError, Message: "Internal error. See logs"},
 			)
 		}
@@ -51,6 +51,19 @@ var manifests = []definition.Descriptor{
 						Required: false,
 						Default:  common.DefaultPagingLimit,
 					},
+					{
+						Name:     "label",
+						Type:     "string",
+						Doc:      "Label selector like `team=payments` matched with labels of versions and their charts. Repeated selectors are all matched",
+						Required: false,
+					},
+					{
+						Name:     "includeDeleted",
+						Type:     "boolean",
+						Doc:      "Include versions in trash",
+						Required: false,
+						Default:  false,
+					},
 				},
 				StatusCode: []definition.StatusCode{
 					definition.StatusCode{Code: http.StatusOK, Message: "Success and respond with an array of metadata",
@@ -112,6 +125,19 @@ var manifests = []definition.Descriptor{
 						Required: false,
 						Default:  common.DefaultPagingLimit,
 					},
+					{
+						Name:     "label",
+						Type:     "string",
+						Doc:      "Label selector like `team=payments` matched with labels of versions and their charts. Repeated selectors are all matched",
+						Required: false,
+					},
+					{
+						Name:     "prerelease",
+						Type:     "boolean",
+						Doc:      "Include pre-release versions like 1.0.0-rc.1 when resolving the latest version",
+						Required: false,
+						Default:  false,
+					},
 				},
 				StatusCode: []definition.StatusCode{
 					definition.StatusCode{Code: http.StatusOK, Message: "Success and respond with an array of latest metadata",
@@ -179,6 +205,31 @@ var manifests = []definition.Descriptor{
 						Required: false,
 						Default:  common.DefaultPagingLimit,
 					},
+					{
+						Name:     "label",
+						Type:     "string",
+						Doc:      "Label selector like `team=payments` matched with labels of versions and their charts. Repeated selectors are all matched",
+						Required: false,
+					},
+					{
+						Name:     "range",
+						Type:     "string",
+						Doc:      "A semver constraint for filtering versions, like ~1.2.0 or >=2.0.0 <3.0.0",
+						Required: false,
+					},
+					{
+						Name:     "sort",
+						Type:     "string",
+						Doc:      "Sort versions by semver. It can be asc or desc",
+						Required: false,
+					},
+					{
+						Name:     "includeDeleted",
+						Type:     "boolean",
+						Doc:      "Include versions in trash",
+						Required: false,
+						Default:  false,
+					},
 				},
 				StatusCode: []definition.StatusCode{
 					definition.StatusCode{Code: http.StatusOK, Message: "Success and respond with an array of metadata",
@@ -231,6 +282,15 @@ var manifests = []definition.Descriptor{
 						Required: true,
 					},
 				},
+				QueryParams: []definition.Param{
+					{
+						Name:     "prerelease",
+						Type:     "boolean",
+						Doc:      "Include pre-release versions like 1.0.0-rc.1 when resolving the latest version",
+						Required: false,
+						Default:  false,
+					},
+				},
 				StatusCode: []definition.StatusCode{
 					definition.StatusCode{Code: http.StatusOK, Message: "Success and respond with metadata of latest version",
 						Sample: &storage.Metadata{
@@ -280,6 +340,14 @@ var manifests = []definition.Descriptor{
 						Required: true,
 					},
 				},
+				HeaderParams: []definition.Param{
+					{
+						Name:     "If-None-Match",
+						Type:     "string",
+						Doc:      "Respond with 304 if it matches the etag of metadata",
+						Required: false,
+					},
+				},
 				StatusCode: []definition.StatusCode{
 					definition.StatusCode{Code: http.StatusOK, Message: "Success and respond with a metadata of a version",
 						Sample: &storage.Metadata{
@@ -298,6 +366,7 @@ var manifests = []definition.Descriptor{
 								},
 							},
 						}},
+					definition.StatusCode{Code: http.StatusNotModified, Message: "Not modified since the etag in If-None-Match"},
 				},
 			},
 			{
@@ -305,7 +374,8 @@ var manifests = []definition.Descriptor{
 				Handler:    definition.NewHandlerDecoration(definition.VerbUpdate, handlers.UpdateMetadata).Handle,
 				Doc:        "Update metadata for a version",
 				Note: `The api only can update metadata of root chart. Must not modify name and version of metadata.
-							Pass json format metadata by request body.`,
+							Pass json format metadata by request body. The updated chart is checked by helm lint rules.
+							Metadata of an immutable version can't be updated, and the request responds with 409.`,
 				PathParams: []definition.Param{
 					{
 						Name:     "space",
@@ -326,6 +396,29 @@ var manifests = []definition.Descriptor{
 						Required: true,
 					},
 				},
+				HeaderParams: []definition.Param{
+					{
+						Name:     "If-Match",
+						Type:     "string",
+						Doc:      "Update only when it matches the etag of metadata",
+						Required: false,
+					},
+					{
+						Name:     "X-Registry-Digest",
+						Type:     "string",
+						Doc:      "Update only when the archive has the sha256 digest. Respond with 409 if it's modified",
+						Required: false,
+					},
+				},
+				QueryParams: []definition.Param{
+					{
+						Name:     "strict",
+						Type:     "boolean",
+						Doc:      "Reject the update if lint reports warnings. Errors are always rejected",
+						Required: false,
+						Default:  false,
+					},
+				},
 				StatusCode: []definition.StatusCode{
 					definition.StatusCode{Code: http.StatusOK, Message: "Success and respond with a metadata of a version",
 						Sample: &storage.Metadata{
@@ -344,6 +437,7 @@ var manifests = []definition.Descriptor{
 								},
 							},
 						}},
+					definition.StatusCode{Code: http.StatusPreconditionFailed, Message: "Modified since the etag in If-Match"},
 				},
 			},
 		},
@@ -375,8 +469,17 @@ var manifests = []definition.Descriptor{
 						Required: true,
 					},
 				},
+				HeaderParams: []definition.Param{
+					{
+						Name:     "If-None-Match",
+						Type:     "string",
+						Doc:      "Respond with 304 if it matches the etag of values",
+						Required: false,
+					},
+				},
 				StatusCode: []definition.StatusCode{
 					definition.StatusCode{Code: http.StatusOK, Message: "Success and respond with values of a version"},
+					definition.StatusCode{Code: http.StatusNotModified, Message: "Not modified since the etag in If-None-Match"},
 				},
 			},
 			{
@@ -384,7 +487,9 @@ var manifests = []definition.Descriptor{
 				Handler:    definition.NewHandlerDecoration(definition.VerbUpdate, handlers.UpdateValues).Handle,
 				Doc:        "Update values for a version",
 				Note: `The values only stores in root chart. If you want to set values of subcharts, use overriding values.
-							Pass json format metadata by request body.`,
+							Pass json format metadata by request body. Values are validated against values.schema.json
+							of the chart, and fields which fail validation are returned in details of the error.
+							Values of an immutable version can't be updated, and the request responds with 409.`,
 				PathParams: []definition.Param{
 					{
 						Name:     "space",
@@ -405,8 +510,74 @@ var manifests = []definition.Descriptor{
 						Required: true,
 					},
 				},
+				HeaderParams: []definition.Param{
+					{
+						Name:     "If-Match",
+						Type:     "string",
+						Doc:      "Update only when it matches the etag of values",
+						Required: false,
+					},
+					{
+						Name:     "X-Registry-Digest",
+						Type:     "string",
+						Doc:      "Update only when the archive has the sha256 digest. Respond with 409 if it's modified",
+						Required: false,
+					},
+				},
+				QueryParams: []definition.Param{
+					{
+						Name:     "strict",
+						Type:     "boolean",
+						Doc:      "Reject the update if lint reports warnings. Errors are always rejected",
+						Required: false,
+						Default:  false,
+					},
+				},
 				StatusCode: []definition.StatusCode{
 					definition.StatusCode{Code: http.StatusOK, Message: "Success and respond with values of a version"},
+					definition.StatusCode{Code: http.StatusPreconditionFailed, Message: "Modified since the etag in If-Match"},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/charts/{chart}/versions/{version}/manifests/schema",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.FetchValuesSchema).Handle,
+				Doc:        "Get values.schema.json of a version",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "version",
+						Type:     "string",
+						Doc:      "version number",
+						Required: true,
+					},
+				},
+				HeaderParams: []definition.Param{
+					{
+						Name:     "If-None-Match",
+						Type:     "string",
+						Doc:      "Respond with 304 if it matches the etag of schema",
+						Required: false,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Success and respond with the json schema of values"},
+					definition.StatusCode{Code: http.StatusNotModified, Message: "Not modified since the etag in If-None-Match"},
 				},
 			},
 		},
@@ -0,0 +1,349 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package descriptor
+
+import (
+	"net/http"
+
+	"github.com/caicloud/helm-registry/pkg/api/definition"
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/api/v1/handlers"
+)
+
+// OCIDescriptors describes OCI distribution api info. A repository of OCI clients is
+// "{space}/{chart}", and tags of the repository are versions of the chart
+var OCIDescriptors = []definition.Descriptor{
+	{
+		Path: "/",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.OCIPing).Handle,
+				Doc:        "Check the version of OCI distribution API",
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Registry supports OCI distribution API"},
+				},
+			},
+		},
+	},
+	{
+		Path: "/{space}/{chart}/tags/list",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.OCIListTags).Handle,
+				Doc:        "List tags of a repository",
+				Note:       "Tags are version numbers with \"+\" replaced by \"_\".",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "List successfully",
+						Sample: &models.OCITags{
+							Name: "spaceName/chartName",
+							Tags: []string{"1.0.0", "1.0.1_build.1"},
+						}},
+				},
+			},
+		},
+	},
+	{
+		Path: "/{space}/{chart}/manifests/{reference}",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.OCIFetchManifest).Handle,
+				Doc:        "Fetch the manifest of a version",
+				Note:       "A manifest is generated for versions which are not pushed by OCI clients.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "reference",
+						Type:     "string",
+						Doc:      "tag or manifest digest",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Fetch successfully"},
+				},
+			},
+			{
+				HTTPMethod: http.MethodHead,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.OCIFetchManifest).Handle,
+				Doc:        "Check whether the manifest of a version exists",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "reference",
+						Type:     "string",
+						Doc:      "tag or manifest digest",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Manifest exists"},
+				},
+			},
+			{
+				HTTPMethod: http.MethodPut,
+				Handler:    definition.NewHandlerDecoration(definition.VerbCreate, handlers.OCIPutManifest).Handle,
+				Doc:        "Store the chart of a manifest as a version",
+				Note:       "Blobs of the manifest must have been uploaded, and the tag must be the version of chart.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "reference",
+						Type:     "string",
+						Doc:      "tag, which is the version of chart",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusCreated, Message: "Create successfully"},
+				},
+			},
+		},
+	},
+	{
+		Path: "/{space}/{chart}/blobs/{digest}",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.OCIFetchBlob).Handle,
+				Doc:        "Fetch a blob",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "digest",
+						Type:     "string",
+						Doc:      "blob digest, like sha256:<hex>",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Fetch successfully"},
+				},
+			},
+			{
+				HTTPMethod: http.MethodHead,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.OCIFetchBlob).Handle,
+				Doc:        "Check whether a blob exists",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "digest",
+						Type:     "string",
+						Doc:      "blob digest, like sha256:<hex>",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Blob exists"},
+				},
+			},
+		},
+	},
+	{
+		Path: "/{space}/{chart}/blobs/uploads/",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodPost,
+				Handler:    definition.NewHandlerDecoration(definition.VerbAccept, handlers.OCICreateUpload).Handle,
+				Doc:        "Start an upload of a blob",
+				Note:       "Blobs are shared by all charts in the space.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusAccepted, Message: "Upload started"},
+				},
+			},
+		},
+	},
+	{
+		Path: "/{space}/{chart}/blobs/uploads/{upload}",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodPatch,
+				Handler:    definition.NewHandlerDecoration(definition.VerbAccept, handlers.OCIAppendUpload).Handle,
+				Doc:        "Append a chunk to an upload",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "upload",
+						Type:     "string",
+						Doc:      "upload id",
+						Required: true,
+					},
+				},
+				HeaderParams: []definition.Param{
+					{
+						Name:     "Content-Range",
+						Type:     "string",
+						Doc:      "range of chunk, like 0-1023",
+						Required: false,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusAccepted, Message: "Append successfully"},
+				},
+			},
+			{
+				HTTPMethod: http.MethodPut,
+				Handler:    definition.NewHandlerDecoration(definition.VerbCreate, handlers.OCIFinishUpload).Handle,
+				Doc:        "Finish an upload and store the blob",
+				Note:       "The body is an optional last chunk.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "upload",
+						Type:     "string",
+						Doc:      "upload id",
+						Required: true,
+					},
+				},
+				QueryParams: []definition.Param{
+					{
+						Name:     "digest",
+						Type:     "string",
+						Doc:      "sha256 digest of blob",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusCreated, Message: "Create successfully"},
+				},
+			},
+			{
+				HTTPMethod: http.MethodDelete,
+				Handler:    definition.NewHandlerDecoration(definition.VerbDelete, handlers.CancelUpload).Handle,
+				Doc:        "Cancel an upload",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "upload",
+						Type:     "string",
+						Doc:      "upload id",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusNoContent, Message: "Cancel successfully"},
+				},
+			},
+		},
+	},
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package descriptor
+
+import (
+	"net/http"
+
+	"github.com/caicloud/helm-registry/pkg/api/definition"
+	"github.com/caicloud/helm-registry/pkg/api/v1/handlers"
+)
+
+func init() {
+	registerDescriptors(repository)
+}
+
+// repository descriptors
+var repository = []definition.Descriptor{
+	{
+		Path: "/spaces/{space}/index.yaml",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.FetchIndex).Handle,
+				Doc:        "Fetch a helm repository index of a space",
+				Note:       "The space can be added as a helm repository by `helm repo add name http://host/api/v1/spaces/space`.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Index in yaml"},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/archives/{file}",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.DownloadArchive).Handle,
+				Doc:        "Download a chart archive or its provenance file by file name",
+				Note:       "The file name is like chart-1.0.0.tgz or chart-1.0.0.tgz.prov. Urls in index point to it.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "file",
+						Type:     "string",
+						Doc:      "file name",
+						Required: true,
+					},
+				},
+				HeaderParams: []definition.Param{
+					{
+						Name:     "If-None-Match",
+						Type:     "string",
+						Doc:      "Respond with 304 if it matches the etag of file",
+						Required: false,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Download with an archive file or a provenance file"},
+					definition.StatusCode{Code: http.StatusNotModified, Message: "Not modified since the etag in If-None-Match"},
+				},
+			},
+		},
+	},
+}
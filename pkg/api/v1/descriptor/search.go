@@ -0,0 +1,115 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package descriptor
+
+import (
+	"net/http"
+
+	"github.com/caicloud/helm-registry/pkg/api/definition"
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/api/v1/handlers"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+func init() {
+	registerDescriptors(search)
+}
+
+// search descriptors
+var search = []definition.Descriptor{
+	{
+		Path: "/search",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbList, handlers.SearchMetadata).Handle,
+				Doc:        "Search metadata in spaces",
+				Note: `Search metadata by chart name, description, keyword and maintainer. The matching is case-insensitive.
+							If space is not specified, all spaces will be searched. Results are ordered by relevance to q.`,
+				QueryParams: []definition.Param{
+					{
+						Name:     "q",
+						Type:     "string",
+						Doc:      "Terms separated by spaces. Every term should match chart name, description, keyword or maintainer",
+						Required: false,
+					},
+					{
+						Name:     "keyword",
+						Type:     "string",
+						Doc:      "A keyword of chart",
+						Required: false,
+					},
+					{
+						Name:     "maintainer",
+						Type:     "string",
+						Doc:      "A substring of name or email of a chart maintainer",
+						Required: false,
+					},
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: false,
+					},
+					{
+						Name:     "latest",
+						Type:     "boolean",
+						Doc:      "Only return the latest matched version of every chart",
+						Required: false,
+						Default:  false,
+					},
+					{
+						Name:     "prerelease",
+						Type:     "boolean",
+						Doc:      "Include pre-release versions like 1.0.0-rc.1 when resolving the latest version",
+						Required: false,
+						Default:  false,
+					},
+					{
+						Name:     "start",
+						Type:     "number",
+						Doc:      "Query start index",
+						Required: false,
+						Default:  0,
+					},
+					{
+						Name:     "limit",
+						Type:     "number",
+						Doc:      "Specify the number of records to return",
+						Required: false,
+						Default:  common.DefaultPagingLimit,
+					},
+					{
+						Name:     "label",
+						Type:     "string",
+						Doc:      "Label selector like `team=payments` matched with labels of versions and their charts. Repeated selectors are all matched",
+						Required: false,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Success and respond with an array of matched metadata",
+						Sample: &models.ListResponse{
+							Metadata: models.Metadata{
+								Total:       10,
+								ItemsLength: 1,
+							},
+							Items: []*storage.Metadata{
+								{
+									Metadata: chart.Metadata{
+										Name:        "A",
+										Version:     "1.0.0",
+										Description: "A chart named A",
+										Keywords:    []string{"database"},
+									},
+								},
+							},
+						}},
+				},
+			},
+		},
+	},
+}
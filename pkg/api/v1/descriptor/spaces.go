@@ -11,6 +11,8 @@ import (
 	"github.com/caicloud/helm-registry/pkg/api/models"
 	"github.com/caicloud/helm-registry/pkg/api/v1/handlers"
 	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/quota"
+	"github.com/caicloud/helm-registry/pkg/storage"
 )
 
 func init() {
@@ -59,6 +61,8 @@ var spaces = []definition.Descriptor{
 				HTTPMethod: http.MethodPost,
 				Handler:    definition.NewHandlerDecoration(definition.VerbCreate, handlers.CreateSpace).Handle,
 				Doc:        "Create a space",
+				Note: `The body may be a json object of description, owner and visibility. Visibility is private or public, and public
+							spaces can be read by requests without token. Setting owner or visibility requires delete permission on the space.`,
 				QueryParams: []definition.Param{
 					{
 						Name:     "space",
@@ -80,6 +84,54 @@ var spaces = []definition.Descriptor{
 	{
 		Path: "/spaces/{space}",
 		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.FetchSpace).Handle,
+				Doc:        "Get info and usage of a space",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Space",
+						Sample: &models.Space{
+							Name:        "spaceName",
+							Description: "Charts of payment services",
+							Owner:       "payments",
+							Visibility:  storage.VisibilityPrivate,
+							Charts:      2,
+							Versions:    5,
+							Bytes:       20480,
+						}},
+				},
+			},
+			{
+				HTTPMethod: http.MethodPut,
+				Handler:    definition.NewHandlerDecoration(definition.VerbUpdate, handlers.UpdateSpace).Handle,
+				Doc:        "Replace info of a space",
+				Note: `The body is a json object of description, owner and visibility. It requires write permission on the space,
+							and delete permission if owner or visibility is changed.`,
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Set successfully",
+						Sample: &storage.SpaceInfo{
+							Description: "Charts of payment services",
+							Owner:       "payments",
+							Visibility:  storage.VisibilityPublic,
+						}},
+				},
+			},
 			{
 				HTTPMethod: http.MethodDelete,
 				Handler:    definition.NewHandlerDecoration(definition.VerbDelete, handlers.DeleteSpace).Handle,
@@ -98,4 +150,211 @@ var spaces = []definition.Descriptor{
 			},
 		},
 	},
+	{
+		Path: "/spaces/{space}/webhooks",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.FetchWebhooks).Handle,
+				Doc:        "Fetch webhooks of a space",
+				Note:       "Webhooks contain secrets, so it requires delete permission on the space.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Webhooks",
+						Sample: []storage.Webhook{
+							{
+								URL:     "https://ci.example.com/hooks/charts",
+								Secret:  "secret",
+								Actions: []string{"create", "delete"},
+							},
+						}},
+				},
+			},
+			{
+				HTTPMethod: http.MethodPut,
+				Handler:    definition.NewHandlerDecoration(definition.VerbUpdate, handlers.UpdateWebhooks).Handle,
+				Doc:        "Replace webhooks of a space",
+				Note: `The body is a json list of webhooks. Events of versions in the space are posted to url and signed with secret
+							like global webhooks. Actions are create, update, updateValues and delete. An empty actions list receives all events.`,
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Set successfully",
+						Sample: []storage.Webhook{
+							{
+								URL:     "https://ci.example.com/hooks/charts",
+								Secret:  "secret",
+								Actions: []string{"create", "delete"},
+							},
+						}},
+				},
+			},
+			{
+				HTTPMethod: http.MethodDelete,
+				Handler:    definition.NewHandlerDecoration(definition.VerbDelete, handlers.DeleteWebhooks).Handle,
+				Doc:        "Remove all webhooks of a space",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusNoContent, Message: "Remove successfully"},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/retention",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.FetchSpaceRetentionPolicy).Handle,
+				Doc:        "Fetch the retention policy of a space",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Retention policy",
+						Sample: &storage.RetentionPolicy{
+							KeepLast:   10,
+							KeepWithin: "720h",
+							Protect:    ">=1.0.0",
+						}},
+				},
+			},
+			{
+				HTTPMethod: http.MethodPut,
+				Handler:    definition.NewHandlerDecoration(definition.VerbUpdate, handlers.UpdateSpaceRetentionPolicy).Handle,
+				Doc:        "Set the retention policy of a space",
+				Note: `The policy applies to charts in the space which have no retention policy. Versions in the semver
+							range of protect are never pruned.`,
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Set successfully",
+						Sample: &storage.RetentionPolicy{
+							KeepLast:   10,
+							KeepWithin: "720h",
+							Protect:    ">=1.0.0",
+						}},
+				},
+			},
+			{
+				HTTPMethod: http.MethodDelete,
+				Handler:    definition.NewHandlerDecoration(definition.VerbDelete, handlers.DeleteSpaceRetentionPolicy).Handle,
+				Doc:        "Remove the retention policy of a space",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusNoContent, Message: "Remove successfully"},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/prune",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodPost,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.PruneSpace).Handle,
+				Doc:        "Prune charts in a space by retention policies",
+				Note:       "Pruned versions are moved to trash. A dry run only requires read permission.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+				},
+				QueryParams: []definition.Param{
+					{
+						Name:     "dryRun",
+						Type:     "boolean",
+						Doc:      "Only return versions which would be pruned",
+						Required: false,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Pruned versions",
+						Sample: &models.PruneResult{
+							Space:  "library",
+							DryRun: true,
+							Charts: []models.PrunedChart{
+								{Chart: "A", Versions: []string{"0.1.0-snapshot.1", "0.1.0-snapshot.2"}},
+							},
+						}},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/usage",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.FetchUsage).Handle,
+				Doc:        "Get the storage usage and quota limits of a space",
+				Note:       "Bytes are the total size of archives of all versions. Trashed versions are not counted. A limit which is 0 means no limit.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Get successfully",
+						Sample: &quota.Usage{
+							Space:           "library",
+							Charts:          2,
+							Versions:        5,
+							Bytes:           20480,
+							TrashedVersions: 1,
+							TrashedBytes:    4096,
+							Limits: quota.Limits{
+								MaxArchiveSize: 10485760,
+								MaxVersions:    50,
+								MaxSpaceBytes:  1073741824,
+							},
+						}},
+				},
+			},
+		},
+	},
 }
@@ -0,0 +1,186 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package descriptor
+
+import (
+	"net/http"
+
+	"github.com/caicloud/helm-registry/pkg/api/definition"
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/api/v1/handlers"
+)
+
+func init() {
+	registerDescriptors(uploads)
+}
+
+// uploads descriptors
+var uploads = []definition.Descriptor{
+	{
+		Path: "/spaces/{space}/uploads",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodPost,
+				Handler:    definition.NewHandlerDecoration(definition.VerbCreate, handlers.CreateUpload).Handle,
+				Doc:        "Start a resumable upload of a chart archive",
+				Note:       "Chunks of the archive are sent by PATCH to the link of upload, then the upload is finished by PUT.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusCreated, Message: "Create successfully",
+						Sample: &models.Upload{
+							Space:  "spaceName",
+							ID:     "5d41402abc4b2a76b9719d911017c592",
+							Offset: 0,
+							Link:   "/api/v1/spaces/spaceName/uploads/5d41402abc4b2a76b9719d911017c592",
+						}},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/uploads/{upload}",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.FetchUpload).Handle,
+				Doc:        "Get the offset of an upload",
+				Note:       "A broken upload is resumed by sending the rest of the archive from the offset.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "upload",
+						Type:     "string",
+						Doc:      "upload id",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Get successfully",
+						Sample: &models.Upload{
+							Space:  "spaceName",
+							ID:     "5d41402abc4b2a76b9719d911017c592",
+							Offset: 1048576,
+							Link:   "/api/v1/spaces/spaceName/uploads/5d41402abc4b2a76b9719d911017c592",
+						}},
+				},
+			},
+			{
+				HTTPMethod: http.MethodPatch,
+				Handler:    definition.NewHandlerDecoration(definition.VerbUpdate, handlers.AppendUpload).Handle,
+				Doc:        "Append a chunk to an upload",
+				Note:       "The request body is a chunk of the archive. It's streamed to storage and never held in memory.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "upload",
+						Type:     "string",
+						Doc:      "upload id",
+						Required: true,
+					},
+				},
+				QueryParams: []definition.Param{
+					{
+						Name:     "offset",
+						Type:     "integer",
+						Doc:      "The offset of chunk. The chunk is rejected if it's not the offset of upload",
+						Required: false,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Append successfully",
+						Sample: &models.Upload{
+							Space:  "spaceName",
+							ID:     "5d41402abc4b2a76b9719d911017c592",
+							Offset: 2097152,
+							Link:   "/api/v1/spaces/spaceName/uploads/5d41402abc4b2a76b9719d911017c592",
+						}},
+				},
+			},
+			{
+				HTTPMethod: http.MethodPut,
+				Handler:    definition.NewHandlerDecoration(definition.VerbCreate, handlers.FinishUpload).Handle,
+				Doc:        "Finish an upload and store the archive as a version",
+				Note:       "The request body is an optional provenance file of the archive. The upload is removed after storing.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "upload",
+						Type:     "string",
+						Doc:      "upload id",
+						Required: true,
+					},
+				},
+				QueryParams: []definition.Param{
+					{
+						Name:     "digest",
+						Type:     "string",
+						Doc:      "The sha256 digest of archive, like sha256:<hex>",
+						Required: true,
+					},
+					{
+						Name:     "strict",
+						Type:     "boolean",
+						Doc:      "Reject the chart if lint reports warnings. Errors are always rejected",
+						Required: false,
+						Default:  false,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusCreated, Message: "Upload successfully",
+						Sample: &models.ChartLink{
+							Space:   "spaceName",
+							Chart:   "chartName",
+							Version: "1.0.0",
+							Link:    "/api/v1/spaces/spaceName/charts/chartName/versions/1.0.0",
+						}},
+				},
+			},
+			{
+				HTTPMethod: http.MethodDelete,
+				Handler:    definition.NewHandlerDecoration(definition.VerbDelete, handlers.CancelUpload).Handle,
+				Doc:        "Cancel an upload",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "upload",
+						Type:     "string",
+						Doc:      "upload id",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusNoContent, Message: "Cancel successfully"},
+				},
+			},
+		},
+	},
+}
@@ -6,11 +6,15 @@ package descriptor
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/caicloud/helm-registry/pkg/api/definition"
 	"github.com/caicloud/helm-registry/pkg/api/models"
 	"github.com/caicloud/helm-registry/pkg/api/v1/handlers"
 	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/scan"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"k8s.io/helm/pkg/proto/hapi/chart"
 )
 
 func init() {
@@ -55,6 +59,19 @@ var versions = []definition.Descriptor{
 						Required: false,
 						Default:  common.DefaultPagingLimit,
 					},
+					{
+						Name:     "label",
+						Type:     "string",
+						Doc:      "Label selector like `team=payments` matched with labels of versions and their charts. Repeated selectors are all matched",
+						Required: false,
+					},
+					{
+						Name:     "sort",
+						Type:     "string",
+						Doc:      "Sort versions by semver. It can be asc or desc",
+						Required: false,
+						Default:  "asc",
+					},
 				},
 				StatusCode: []definition.StatusCode{
 					definition.StatusCode{Code: http.StatusOK, Message: "Success and respond with a array of version numbers",
@@ -78,6 +95,7 @@ var versions = []definition.Descriptor{
 				HTTPMethod: http.MethodGet,
 				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.DownloadVersion).Handle,
 				Doc:        "Download a version of a chart",
+				Note:       "The etag of archive is its sha256 digest.",
 				PathParams: []definition.Param{
 					{
 						Name:     "space",
@@ -98,14 +116,24 @@ var versions = []definition.Descriptor{
 						Required: true,
 					},
 				},
+				HeaderParams: []definition.Param{
+					{
+						Name:     "If-None-Match",
+						Type:     "string",
+						Doc:      "Respond with 304 if it matches the etag of archive",
+						Required: false,
+					},
+				},
 				StatusCode: []definition.StatusCode{
 					definition.StatusCode{Code: http.StatusOK, Message: "Download with an archive file of chart"},
+					definition.StatusCode{Code: http.StatusNotModified, Message: "Not modified since the etag in If-None-Match"},
 				},
 			},
 			{
 				HTTPMethod: http.MethodPut,
 				Handler:    definition.NewHandlerDecoration(definition.VerbUpdate, handlers.UpdateVersion).Handle,
 				Doc:        "Update a version of a chart",
+				Note:       "A version of an immutable space or chart can't be updated, and the request responds with 409.",
 				PathParams: []definition.Param{
 					{
 						Name:     "space",
@@ -133,6 +161,19 @@ var versions = []definition.Descriptor{
 						Doc:      "An archive file of chart",
 						Required: true,
 					},
+					{
+						Name:     "provfile",
+						Type:     "multipart/form-data",
+						Doc:      "A provenance file of chart. Required if the registry verifies provenance",
+						Required: false,
+					},
+					{
+						Name:     "strict",
+						Type:     "boolean",
+						Doc:      "Reject the chart if lint reports warnings. Errors are always rejected",
+						Required: false,
+						Default:  false,
+					},
 				},
 				StatusCode: []definition.StatusCode{
 					definition.StatusCode{Code: http.StatusOK, Message: "Update successfully",
@@ -147,7 +188,8 @@ var versions = []definition.Descriptor{
 			{
 				HTTPMethod: http.MethodDelete,
 				Handler:    definition.NewHandlerDecoration(definition.VerbDelete, handlers.DeleteVersion).Handle,
-				Doc:        "Delete a chart and all of its versions",
+				Doc:        "Delete a version of a chart",
+				Note:       "The version will be moved to trash and can be restored until the trash is purged.",
 				PathParams: []definition.Param{
 					{
 						Name:     "space",
@@ -174,4 +216,639 @@ var versions = []definition.Descriptor{
 			},
 		},
 	},
+	{
+		Path: "/spaces/{space}/charts/{chart}/versions/{version}/bundle",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.FetchWithDependencies).Handle,
+				Doc:        "Download a version of a chart with its dependencies",
+				Note: `Dependencies in requirements.yaml, or Chart.yaml of an apiVersion v2 chart, are resolved from the same space and packed under charts/.
+							Every dependency is resolved to the highest version which satisfies its version constraint.`,
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "version",
+						Type:     "string",
+						Doc:      "version number",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Download with an archive file of chart and its dependencies"},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/charts/{chart}/versions/{version}/provenance",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.FetchProvenance).Handle,
+				Doc:        "Download the provenance file of a version",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "version",
+						Type:     "string",
+						Doc:      "version number",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Download with a provenance file of chart"},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/charts/{chart}/versions/{version}/scan",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.FetchScanReport).Handle,
+				Doc:        "Get the scan report of a version",
+				Note: `Checks configured for the space run on every pushed version, and their results are stored with it.
+							A version pushed when no check runs in its space has no report.`,
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "version",
+						Type:     "string",
+						Doc:      "version number",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Success and respond with the scan report",
+						Sample: &scan.Report{
+							Passed:    false,
+							Timestamp: time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC),
+							Results: []scan.Result{
+								{
+									Check:    "deprecatedAPIs",
+									Blocking: false,
+									Passed:   false,
+									Findings: []scan.Finding{
+										{
+											Path:    "templates/deployment.yaml",
+											Message: "extensions/v1beta1 Deployment is deprecated, use apps/v1 instead",
+										},
+									},
+								},
+							},
+						}},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/charts/{chart}/versions/{version}/attributes",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.FetchVersionAttributes).Handle,
+				Doc:        "Fetch labels and annotations of a version",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "version",
+						Type:     "string",
+						Doc:      "version number",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Attributes",
+						Sample: &storage.Attributes{
+							Labels:      map[string]string{"team": "payments", "stage": "production"},
+							Annotations: map[string]string{"example.com/owner": "payments@example.com"},
+						}},
+				},
+			},
+			{
+				HTTPMethod: http.MethodPatch,
+				Handler:    definition.NewHandlerDecoration(definition.VerbUpdate, handlers.UpdateVersionAttributes).Handle,
+				Doc:        "Update labels and annotations of a version",
+				Note: `The body is a JSON merge patch like {"labels": {"team": "payments", "stage": null}}. A key
+							with null is removed, and keys not in the patch are kept. Labels of a version override labels of its chart in label selectors.`,
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "version",
+						Type:     "string",
+						Doc:      "version number",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Update successfully",
+						Sample: &storage.Attributes{
+							Labels:      map[string]string{"team": "payments", "stage": "production"},
+							Annotations: map[string]string{"example.com/owner": "payments@example.com"},
+						}},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/charts/{chart}/versions/{version}/readme",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.FetchReadme).Handle,
+				Doc:        "Fetch the README.md of a version",
+				Note:       "The readme is extracted when the version is pushed. It's rendered to html with a restrictive content security policy if format is html.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "version",
+						Type:     "string",
+						Doc:      "version number",
+						Required: true,
+					},
+				},
+				QueryParams: []definition.Param{
+					{
+						Name:    "format",
+						Type:    "string",
+						Doc:     "format of readme, markdown or html",
+						Default: "markdown",
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Content of README.md or rendered html"},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/charts/{chart}/versions/{version}/icon",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.FetchIcon).Handle,
+				Doc:        "Fetch the icon of a version",
+				Note:       "If the icon in metadata is an external url, the response is a redirection to the url.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "version",
+						Type:     "string",
+						Doc:      "version number",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Content of the icon file in chart"},
+					definition.StatusCode{Code: http.StatusFound, Message: "Redirect to the external icon url"},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/charts/{chart}/versions/{version}/render",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodPost,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.RenderTemplates).Handle,
+				Doc:        "Render templates of a version to kubernetes manifests",
+				Note: `The body is an optional json of values which overrides values of the chart.
+							Rendered manifests are returned as a multi-document yaml. Subcharts disabled by
+							conditions or tags are not rendered.`,
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "version",
+						Type:     "string",
+						Doc:      "version number",
+						Required: true,
+					},
+				},
+				QueryParams: []definition.Param{
+					{
+						Name:     "release",
+						Type:     "string",
+						Doc:      "release name",
+						Required: false,
+						Default:  "RELEASE-NAME",
+					},
+					{
+						Name:     "namespace",
+						Type:     "string",
+						Doc:      "release namespace",
+						Required: false,
+						Default:  "default",
+					},
+					{
+						Name:     "dependencies",
+						Type:     "boolean",
+						Doc:      "Resolve dependencies which are not in charts/ from the space",
+						Required: false,
+						Default:  false,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Rendered manifests"},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/charts/{chart}/versions/{version}/restore",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodPost,
+				Handler:    definition.NewHandlerDecoration(definition.VerbCreate, handlers.RestoreVersion).Handle,
+				Doc:        "Restore a deleted version of a chart from trash",
+				Note:       "If a version with same number exists, the request will be rejected.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "version",
+						Type:     "string",
+						Doc:      "version number",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusCreated, Message: "Restore successfully",
+						Sample: &models.ChartLink{
+							Space:   "spaceName",
+							Chart:   "chartName",
+							Version: "1.0.0",
+							Link:    "/api/v1/spaces/spaceName/charts/chartName/versions/1.0.0",
+						}},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/trash",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbList, handlers.ListTrash).Handle,
+				Doc:        "List metadata of trashed versions in a space",
+				Note:       "Trashed versions are purged by the garbage collector after the trash retention period.",
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+				},
+				QueryParams: []definition.Param{
+					{
+						Name:     "start",
+						Type:     "number",
+						Doc:      "Query start index",
+						Required: false,
+						Default:  0,
+					},
+					{
+						Name:     "limit",
+						Type:     "number",
+						Doc:      "Specify the number of records to return",
+						Required: false,
+						Default:  common.DefaultPagingLimit,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Success and respond with an array of metadata",
+						Sample: &models.ListResponse{
+							Metadata: models.Metadata{
+								Total:       1,
+								ItemsLength: 1,
+							},
+							Items: []*storage.Metadata{
+								{
+									Metadata: chart.Metadata{
+										Name:    "chartName",
+										Version: "1.0.0",
+									},
+								},
+							},
+						}},
+				},
+			},
+		},
+	},
+	{
+		Path: "/trash",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodDelete,
+				Handler:    definition.NewHandlerDecoration(definition.VerbDelete, handlers.PurgeTrash).Handle,
+				Doc:        "Purge trashed versions in all spaces",
+				QueryParams: []definition.Param{
+					{
+						Name:     "retention",
+						Type:     "string",
+						Doc:      "Versions deleted within the retention period will be kept, like 24h. Default to the config of registry",
+						Required: false,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusNoContent, Message: "Purge successfully"},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/charts/{chart}/diff",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.DiffVersions).Handle,
+				Doc:        "Compare values, metadata and templates of two versions in a chart",
+				Note: `Values are compared as parsed maps and keys of nested values are joined by dot. Templates of
+							subcharts are named by their paths in the archive, and modified templates have unified diffs.`,
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+				},
+				QueryParams: []definition.Param{
+					{
+						Name:     "from",
+						Type:     "string",
+						Doc:      "original version number",
+						Required: true,
+					},
+					{
+						Name:     "to",
+						Type:     "string",
+						Doc:      "target version number",
+						Required: true,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Success and respond with differences",
+						Sample: &models.VersionDiff{
+							From: "1.0.0",
+							To:   "1.1.0",
+							ValuesDiff: models.ValuesDiff{
+								Added:   map[string]interface{}{"service.port": 8080},
+								Removed: map[string]interface{}{"debug": true},
+								Changed: map[string]models.Change{"image.tag": {From: "1.0", To: "1.1"}},
+							},
+							MetadataDiff: map[string]models.Change{
+								"version": {From: "1.0.0", To: "1.1.0"},
+							},
+							TemplatesDiff: []models.FileDiff{
+								{
+									Name:   "templates/service.yaml",
+									Status: models.FileModified,
+									Diff: "--- 1.0.0/templates/service.yaml\n+++ 1.1.0/templates/service.yaml\n" +
+										"@@ -1,3 +1,3 @@\n kind: Service\n spec:\n-  port: 80\n+  port: {{ .Values.service.port }}\n",
+								},
+							},
+						}},
+				},
+			},
+		},
+	},
+	{
+		Path: "/spaces/{space}/charts/{chart}/versions/{version}/promote",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodPost,
+				Handler:    definition.NewHandlerDecoration(definition.VerbCreate, handlers.PromoteVersion).Handle,
+				Doc:        "Promote a version of chart to another space",
+				Note: `
+The request body is a json target which specifies where the version will be copied to.
+If target chart or version is not specified, the source chart name or version number
+will be used. The archive, metadata and values are copied as a whole, so the target
+never contains a partial version. Below is a sample:
+{
+    "space":"production",               // string, required
+    "chart":"new chart name",           // string, optional
+    "version":"1.0.1"                   // string, optional
+}
+`,
+				PathParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "space name",
+						Required: true,
+					},
+					{
+						Name:     "chart",
+						Type:     "string",
+						Doc:      "chart name",
+						Required: true,
+					},
+					{
+						Name:     "version",
+						Type:     "string",
+						Doc:      "version number",
+						Required: true,
+					},
+				},
+				QueryParams: []definition.Param{
+					{
+						Name:     "overwrite",
+						Type:     "boolean",
+						Doc:      "Overwrite the target version if it exists",
+						Required: false,
+						Default:  false,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusCreated, Message: "Promote successfully",
+						Sample: &models.ChartLink{
+							Space:   "production",
+							Chart:   "chartName",
+							Version: "1.0.1",
+							Link:    "/api/v1/spaces/production/charts/chartName/versions/1.0.1",
+						}},
+				},
+			},
+		},
+	},
+	{
+		Path: "/copy",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodPost,
+				Handler:    definition.NewHandlerDecoration(definition.VerbCreate, handlers.CopyVersion).Handle,
+				Doc:        "Copy a version of chart to another space or chart",
+				Note: `
+The request body is a json config which specifies the source version and the target space.
+If target chart is not specified, the source chart name will be used. Below is a sample:
+{
+    "source":{                          // key, required
+        "space":"staging",              // string, required
+        "chart":"chart name",           // string, required
+        "version":"1.0.0"               // string, required
+    },
+    "target":{                          // key, required
+        "space":"production",           // string, required
+        "chart":"new chart name",       // string, optional
+        "version":"1.0.1"               // string, optional
+    }
+}
+`,
+				QueryParams: []definition.Param{
+					{
+						Name:     "overwrite",
+						Type:     "boolean",
+						Doc:      "Overwrite the target version if it exists",
+						Required: false,
+						Default:  false,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusCreated, Message: "Copy successfully",
+						Sample: &models.ChartLink{
+							Space:   "production",
+							Chart:   "chartName",
+							Version: "1.0.0",
+							Link:    "/api/v1/spaces/production/charts/chartName/versions/1.0.0",
+						}},
+				},
+			},
+		},
+	},
+	{
+		Path: "/move",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodPost,
+				Handler:    definition.NewHandlerDecoration(definition.VerbCreate, handlers.MoveVersion).Handle,
+				Doc:        "Move a version of chart to another space or chart",
+				Note:       "The request body is same as copying a version. The source version is moved to trash after copying, and it can be restored.",
+				QueryParams: []definition.Param{
+					{
+						Name:     "overwrite",
+						Type:     "boolean",
+						Doc:      "Overwrite the target version if it exists",
+						Required: false,
+						Default:  false,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusCreated, Message: "Move successfully",
+						Sample: &models.ChartLink{
+							Space:   "production",
+							Chart:   "chartName",
+							Version: "1.0.0",
+							Link:    "/api/v1/spaces/production/charts/chartName/versions/1.0.0",
+						}},
+				},
+			},
+		},
+	},
 }
@@ -0,0 +1,89 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package descriptor
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/api/definition"
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/api/v1/handlers"
+	"github.com/caicloud/helm-registry/pkg/watch"
+	"github.com/emicklei/go-restful"
+)
+
+func init() {
+	registerDescriptors(watches)
+}
+
+// watch descriptors
+var watches = []definition.Descriptor{
+	{
+		Path: "/watch",
+		Handlers: []definition.Handler{
+			{
+				HTTPMethod: http.MethodGet,
+				Handler:    definition.NewHandlerDecoration(definition.VerbGet, handlers.Watch).Handle,
+				Produces:   []string{restful.MIME_JSON, handlers.MIMEEventStream},
+				Doc:        "Watch changes of versions",
+				Note: `Return events of versions which are created, updated or deleted after resourceVersion.
+							If the request accepts text/event-stream, events are streamed as server-sent events whose id
+							is the resource version and whose event is the action. Otherwise the request waits until
+							any event happens or timeout, and the response contains the resource version to watch from
+							next time. Without resourceVersion, events after the request are returned.
+							Recent events are kept in memory, and a resource version is expired with 410 when
+							events after it are dropped or the registry restarts. Clients should list again and
+							watch without resourceVersion then.`,
+				QueryParams: []definition.Param{
+					{
+						Name:     "space",
+						Type:     "string",
+						Doc:      "Only watch the space. If it's empty, all readable spaces are watched",
+						Required: false,
+					},
+					{
+						Name:     "resourceVersion",
+						Type:     "string",
+						Doc:      "Return events after the resource version",
+						Required: false,
+					},
+					{
+						Name:     "timeout",
+						Type:     "string",
+						Doc:      "The time to wait for events when long polling, like 30s. It's at most 5m",
+						Required: false,
+						Default:  handlers.DefaultWatchTimeout.String(),
+					},
+				},
+				HeaderParams: []definition.Param{
+					{
+						Name:     "Last-Event-ID",
+						Type:     "string",
+						Doc:      "Resource version sent by reconnecting event stream clients. It's used if resourceVersion is empty",
+						Required: false,
+					},
+				},
+				StatusCode: []definition.StatusCode{
+					definition.StatusCode{Code: http.StatusOK, Message: "Success and respond with events or an event stream",
+						Sample: &models.WatchResponse{
+							ResourceVersion: "jb2k7x1c-12",
+							Events: []*watch.Event{
+								{
+									ResourceVersion: "jb2k7x1c-12",
+									Space:           "library",
+									Chart:           "A",
+									Version:         "1.0.0",
+									Action:          "create",
+									Timestamp:       time.Date(2017, 6, 1, 0, 0, 0, 0, time.UTC),
+								},
+							},
+						}},
+					definition.StatusCode{Code: http.StatusGone, Message: "Resource version is expired. List again and watch without it"},
+				},
+			},
+		},
+	},
+}
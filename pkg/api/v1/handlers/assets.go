@@ -0,0 +1,111 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/markdown"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// formats of readme
+const (
+	readmeMarkdown = "markdown"
+	readmeHTML     = "html"
+)
+
+// content types of readme
+const (
+	readmeContentType     = "text/markdown; charset=utf-8"
+	readmeHTMLContentType = "text/html; charset=utf-8"
+)
+
+// readmeHTMLPolicy is the content security policy of rendered readme. Rendered html has
+// no scripts and styles, and only images from the web are loaded
+const readmeHTMLPolicy = "default-src 'none'; img-src http: https:; sandbox"
+
+// FetchReadme fetches the README.md of a version. It's rendered to html if query param
+// format is html. A version of proxied space is pulled from upstream if it doesn't exist
+func FetchReadme(ctx context.Context) (data []byte, err error) {
+	format, _ := getQueryParameter(ctx, "format")
+	if format != "" && format != readmeMarkdown && format != readmeHTML {
+		return nil, errors.ErrorParamValueError.Format("format", readmeMarkdown+" or "+readmeHTML, format)
+	}
+	err = managerHelper(ctx, func(space storage.Space, chart storage.Chart, version storage.Version) error {
+		if err := authorize(ctx, space.Name(), auth.PermissionRead); err != nil {
+			return err
+		}
+		if err := pullVersion(ctx, space.Name(), chart.Name(), version.Number()); err != nil {
+			return err
+		}
+		readme, err := version.Readme(ctx)
+		if err != nil {
+			return err
+		}
+		if readme == nil {
+			return errors.ErrorContentNotFound.Format(
+				fmt.Sprintf("%s of %s/%s/%s", storage.ReadmeName, space.Name(), chart.Name(), version.Number()))
+		}
+		if format == readmeHTML {
+			data = markdown.Render(readme)
+			setHeader(ctx, "Content-Type", readmeHTMLContentType)
+			setHeader(ctx, "Content-Security-Policy", readmeHTMLPolicy)
+			return nil
+		}
+		data = readme
+		setHeader(ctx, "Content-Type", readmeContentType)
+		return nil
+	})
+	return
+}
+
+// FetchIcon fetches the icon of a version. If the icon in metadata is an external url,
+// it responds with a redirection to the url. Otherwise the icon is a file in chart.
+// A version of proxied space is pulled from upstream if it doesn't exist
+func FetchIcon(ctx context.Context) (data []byte, err error) {
+	err = managerHelper(ctx, func(space storage.Space, chart storage.Chart, version storage.Version) error {
+		if err := authorize(ctx, space.Name(), auth.PermissionRead); err != nil {
+			return err
+		}
+		if err := pullVersion(ctx, space.Name(), chart.Name(), version.Number()); err != nil {
+			return err
+		}
+		metadata, err := version.Metadata(ctx)
+		if err != nil {
+			return err
+		}
+		name := fmt.Sprintf("icon of %s/%s/%s", space.Name(), chart.Name(), version.Number())
+		icon := metadata.Icon
+		if storage.ExternalIcon(icon) {
+			setHeader(ctx, "Location", icon)
+			return errors.ErrorRedirect.Format(icon)
+		}
+		filename, ok := storage.IconFile(icon)
+		if !ok {
+			return errors.ErrorContentNotFound.Format(name)
+		}
+		data, err = version.Icon(ctx)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			return errors.ErrorContentNotFound.Format(name)
+		}
+		contentType := mime.TypeByExtension(path.Ext(filename))
+		if len(contentType) <= 0 {
+			contentType = http.DetectContentType(data)
+		}
+		setHeader(ctx, "Content-Type", contentType)
+		return nil
+	})
+	return
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/search"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// FetchChartAttributes fetches labels and annotations of a chart
+func FetchChartAttributes(ctx context.Context) (*storage.Attributes, error) {
+	chart, err := getAttributesChart(ctx, auth.PermissionRead)
+	if err != nil {
+		return nil, err
+	}
+	return chart.Attributes(ctx)
+}
+
+// UpdateChartAttributes merges the patch in request body into labels and annotations of a chart
+func UpdateChartAttributes(ctx context.Context) (*storage.Attributes, error) {
+	chart, err := getAttributesChart(ctx, auth.PermissionWrite)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := getAttributesPatchFromBody(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := chart.UpdateAttributes(ctx, func(attributes *storage.Attributes) error {
+		applyAttributesPatch(attributes, patch)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	spaceName, chartName, _ := getSpaceAndChartName(ctx)
+	search.InvalidateChart(spaceName, chartName)
+	return attributes, nil
+}
+
+// FetchVersionAttributes fetches labels and annotations of a version
+func FetchVersionAttributes(ctx context.Context) (*storage.Attributes, error) {
+	version, err := getAttributesVersion(ctx, auth.PermissionRead)
+	if err != nil {
+		return nil, err
+	}
+	return version.Attributes(ctx)
+}
+
+// UpdateVersionAttributes merges the patch in request body into labels and annotations of a version
+func UpdateVersionAttributes(ctx context.Context) (*storage.Attributes, error) {
+	version, err := getAttributesVersion(ctx, auth.PermissionWrite)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := getAttributesPatchFromBody(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := version.UpdateAttributes(ctx, func(attributes *storage.Attributes) error {
+		applyAttributesPatch(attributes, patch)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	spaceName, chartName, _ := getSpaceAndChartName(ctx)
+	search.InvalidateChart(spaceName, chartName)
+	return attributes, nil
+}
+
+// getAttributesChart gets an existing chart from ctx and checks permission on its space
+func getAttributesChart(ctx context.Context, permission auth.Permission) (storage.Chart, error) {
+	spaceName, chartName, err := getSpaceAndChartName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(ctx, spaceName, permission); err != nil {
+		return nil, err
+	}
+	chart, err := common.GetChart(ctx, spaceName, chartName)
+	if err != nil {
+		return nil, err
+	}
+	if !chart.Exists(ctx) {
+		return nil, errors.ErrorContentNotFound.Format(spaceName + "/" + chartName)
+	}
+	return chart, nil
+}
+
+// getAttributesVersion gets an existing version from ctx and checks permission on its space
+func getAttributesVersion(ctx context.Context, permission auth.Permission) (storage.Version, error) {
+	spaceName, chartName, versionNumber, err := getSpaceChartNameAndVersionNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(ctx, spaceName, permission); err != nil {
+		return nil, err
+	}
+	version, err := common.GetVersion(ctx, spaceName, chartName, versionNumber)
+	if err != nil {
+		return nil, err
+	}
+	if !version.Exists(ctx) {
+		return nil, errors.ErrorContentNotFound.Format(spaceName + "/" + chartName + "/" + versionNumber)
+	}
+	return version, nil
+}
+
+// getAttributesPatchFromBody gets a merge patch of attributes from the body of request
+func getAttributesPatchFromBody(ctx context.Context) (*models.AttributesPatch, error) {
+	data, err := readDataFromBody(ctx)
+	if err != nil {
+		return nil, err
+	}
+	patch := &models.AttributesPatch{}
+	if err = json.Unmarshal(data, patch); err != nil {
+		return nil, errors.ErrorParamTypeError.Format("body", "attributes patch", "unknown")
+	}
+	return patch, nil
+}
+
+// applyAttributesPatch sets or removes keys of attributes by patch
+func applyAttributesPatch(attributes *storage.Attributes, patch *models.AttributesPatch) {
+	attributes.Labels = applyMapPatch(attributes.Labels, patch.Labels)
+	attributes.Annotations = applyMapPatch(attributes.Annotations, patch.Annotations)
+}
+
+// applyMapPatch sets keys with values and removes keys with nil in m
+func applyMapPatch(m map[string]string, patch map[string]*string) map[string]string {
+	if len(patch) > 0 && m == nil {
+		m = make(map[string]string, len(patch))
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(m, key)
+			continue
+		}
+		m[key] = *value
+	}
+	return m
+}
+
+// getSelector gets a label selector from query param label. Requirements in all values
+// of the param should be satisfied, like `label=team%3Dpayments&label=stage!%3Ddev`
+func getSelector(ctx context.Context) (storage.Selector, error) {
+	request, err := getRequestFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values := request.Request.URL.Query()["label"]
+	selector, err := storage.ParseSelector(strings.Join(values, ","))
+	if err != nil {
+		return nil, errors.ErrorInvalidParam.Format("label", err)
+	}
+	return selector, nil
+}
+
+// filterMetadataBySelector keeps metadata whose labels match selector. Trashed versions
+// have no labels
+func filterMetadataBySelector(metadata []*storage.Metadata, selector storage.Selector) []*storage.Metadata {
+	if selector.Empty() {
+		return metadata
+	}
+	result := make([]*storage.Metadata, 0, len(metadata))
+	for _, md := range metadata {
+		if selector.Matches(md.Labels) {
+			result = append(result, md)
+		}
+	}
+	return result
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/backup"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/quota"
+	"github.com/caicloud/helm-registry/pkg/search"
+	"github.com/caicloud/helm-registry/pkg/stats"
+)
+
+// BackupRegistry streams a backup of all spaces, charts and versions. It reads all spaces,
+// so the token of request must be able to delete in any space
+func BackupRegistry(ctx context.Context) (io.Reader, error) {
+	if err := authorize(ctx, auth.WildcardSpace, auth.PermissionDelete); err != nil {
+		return nil, err
+	}
+	spaceManager, err := common.GetSpaceManager()
+	if err != nil {
+		return nil, err
+	}
+	// pending download counts are stored before they are read
+	stats.Flush(ctx)
+	setHeader(ctx, "Content-Type", "application/gzip")
+	setHeader(ctx, "Content-Disposition",
+		fmt.Sprintf(`attachment; filename="registry-%s.tgz"`, time.Now().UTC().Format("20060102150405")))
+	reader, writer := io.Pipe()
+	go func() {
+		manifest, err := backup.Export(ctx, spaceManager, writer)
+		if err != nil {
+			log.FromContext(ctx).Errorf("Failed to back up registry: %v", err)
+			writer.CloseWithError(err)
+			return
+		}
+		log.FromContext(ctx).Infof("Backed up %d spaces", len(manifest.Spaces))
+		writer.Close()
+	}()
+	return reader, nil
+}
+
+// RestoreRegistry restores a backup from request body. The registry must have no space
+func RestoreRegistry(ctx context.Context) (*backup.Manifest, error) {
+	if err := authorize(ctx, auth.WildcardSpace, auth.PermissionDelete); err != nil {
+		return nil, err
+	}
+	spaceManager, err := common.GetSpaceManager()
+	if err != nil {
+		return nil, err
+	}
+	request, err := getRequestFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := backup.Import(ctx, spaceManager, request.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	for _, space := range manifest.Spaces {
+		search.Invalidate(space.Name)
+		quota.Invalidate(space.Name)
+	}
+	return manifest, nil
+}
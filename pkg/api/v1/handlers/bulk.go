@@ -0,0 +1,240 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/metrics"
+	"github.com/caicloud/helm-registry/pkg/provenance"
+	"github.com/caicloud/helm-registry/pkg/quota"
+	"github.com/caicloud/helm-registry/pkg/search"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/webhook"
+)
+
+// maxBulkUploadMemory is the max memory for parsing a bulk upload request. Larger files are stored in temporary files
+const maxBulkUploadMemory = 32 << 20
+
+// bulkUploadItem is a chart file in bulk upload
+type bulkUploadItem struct {
+	result   *models.BulkUploadFile
+	data     []byte
+	digest   string
+	prov     []byte
+	verified bool
+	checks   *chartChecks
+	chart    storage.Chart
+	version  storage.Version
+}
+
+// BulkUpload stores all chart files in request to a space. Either all versions are stored
+// or none of them. A version which exists with same content is skipped.
+func BulkUpload(ctx context.Context) (*models.BulkUploadResult, error) {
+	defer metrics.TrackUpload()()
+	spaceName, err := getSpaceName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(ctx, spaceName, auth.PermissionWrite); err != nil {
+		return nil, err
+	}
+	space, err := common.GetSpace(ctx, spaceName)
+	if err != nil {
+		return nil, err
+	}
+	if !space.Exists(ctx) {
+		return nil, errors.ErrorContentNotFound.Format(spaceName)
+	}
+	form, err := getMultipartForm(ctx)
+	if err != nil {
+		return nil, err
+	}
+	headers := form.File[common.HTTPRequestUploadFileName]
+	if len(headers) <= 0 {
+		return nil, errors.ErrorParamNotFound.Format(common.HTTPRequestUploadFileName)
+	}
+	// provenance files are matched with chart files by name, like "chart-1.0.0.tgz.prov"
+	provs := make(map[string]*multipart.FileHeader)
+	for _, header := range form.File[common.HTTPRequestUploadProvenanceName] {
+		provs[header.Filename] = header
+	}
+
+	result := &models.BulkUploadResult{
+		Committed: true,
+		Files:     make([]models.BulkUploadFile, len(headers)),
+	}
+	items := make([]*bulkUploadItem, 0, len(headers))
+	// digests of versions in request for finding duplicates
+	digests := make(map[string]string)
+	for i, header := range headers {
+		item := &bulkUploadItem{result: &result.Files[i]}
+		item.result.File = header.Filename
+		if err := prepareBulkUploadItem(ctx, space, item, header, provs, digests); err != nil {
+			item.result.Status = models.BulkUploadFailed
+			item.result.Reason = err.Error()
+			result.Committed = false
+			continue
+		}
+		if item.result.Status == models.BulkUploadSuccess {
+			items = append(items, item)
+		}
+	}
+
+	stored := make([]*bulkUploadItem, 0, len(items))
+	unlock := quota.Lock(space.Name())
+	defer unlock()
+	if result.Committed {
+		for _, item := range items {
+			err := quota.Check(ctx, space, item.chart, item.version, int64(len(item.data)))
+			if err == nil {
+				err = putContentAndProvenance(ctx, item.version, item.data, item.prov, item.verified)
+			}
+			if err == nil {
+				err = item.checks.putReports(ctx, item.version)
+			}
+			if err != nil {
+				item.result.Status = models.BulkUploadFailed
+				item.result.Reason = err.Error()
+				result.Committed = false
+				break
+			}
+			stored = append(stored, item)
+		}
+	}
+	// versions are stored before they are rolled back, so they may have been cached
+	search.Invalidate(space.Name())
+	if !result.Committed {
+		rollbackBulkUpload(ctx, space, stored)
+		for _, item := range items {
+			if item.result.Status == models.BulkUploadSuccess {
+				item.result.Status = models.BulkUploadRolledBack
+			}
+		}
+		return result, nil
+	}
+	for _, item := range stored {
+		metrics.Count(metrics.OperationUpload, space.Name())
+		webhook.Notify(space.Name(), item.result.Chart, item.result.Version, webhook.ActionCreate)
+	}
+	return result, nil
+}
+
+// getMultipartForm parses multipart form from ctx
+func getMultipartForm(ctx context.Context) (*multipart.Form, error) {
+	request, err := getRequestFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = request.Request.ParseMultipartForm(maxBulkUploadMemory); err != nil {
+		return nil, errors.ErrorParamTypeError.Format("body", "multipart/form-data", err.Error())
+	}
+	return request.Request.MultipartForm, nil
+}
+
+// prepareBulkUploadItem loads and checks a chart file without storing it. The status of
+// item is set to success if the version can be stored
+func prepareBulkUploadItem(ctx context.Context, space storage.Space, item *bulkUploadItem,
+	header *multipart.FileHeader, provs map[string]*multipart.FileHeader, digests map[string]string) error {
+	data, err := readFileHeader(header)
+	if err != nil {
+		return errors.ErrorInvalidParam.Format(header.Filename, err)
+	}
+	chrt, err := getChartFromArchiveData(data)
+	if err != nil {
+		return err
+	}
+	metadata := chrt.Metadata
+	item.data = data
+	item.result.Chart = metadata.Name
+	item.result.Version = metadata.Version
+	if item.checks, err = checkChart(ctx, space.Name(), chrt, bytes.NewReader(data)); err != nil {
+		return err
+	}
+	if prov, ok := provs[header.Filename+".prov"]; ok {
+		if item.prov, err = readFileHeader(prov); err != nil {
+			return errors.ErrorInvalidParam.Format(prov.Filename, err)
+		}
+	}
+	if item.verified, err = verifyProvenance(metadata, data, item.prov); err != nil {
+		return err
+	}
+	versionPath := fmt.Sprintf("%s/%s/%s", space.Name(), metadata.Name, metadata.Version)
+	// check versions in request
+	digest := provenance.Digest(data)
+	item.digest = digest
+	if previous, ok := digests[versionPath]; ok {
+		if previous != digest {
+			return errors.ErrorResourceExist.Format(versionPath)
+		}
+		item.result.Status = models.BulkUploadSkippedDuplicate
+		return nil
+	}
+	digests[versionPath] = digest
+	// check versions in storage
+	chart, err := space.Chart(ctx, metadata.Name)
+	if err != nil {
+		return err
+	}
+	version, err := chart.Version(ctx, metadata.Version)
+	if err != nil {
+		return err
+	}
+	if version.Exists(ctx) {
+		content, err := version.GetContent(ctx)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(content, data) {
+			return errors.ErrorResourceExist.Format(versionPath)
+		}
+		item.result.Status = models.BulkUploadSkippedDuplicate
+		return nil
+	}
+	item.chart = chart
+	item.version = version
+	item.result.Status = models.BulkUploadSuccess
+	return nil
+}
+
+// readFileHeader reads all data of a file in multipart form
+func readFileHeader(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ioutil.ReadAll(file)
+}
+
+// rollbackBulkUpload deletes stored versions permanently. A version which has been
+// replaced by another request since it was stored is kept
+func rollbackBulkUpload(ctx context.Context, space storage.Space, stored []*bulkUploadItem) {
+	for _, item := range stored {
+		name := fmt.Sprintf("%s/%s/%s", space.Name(), item.result.Chart, item.result.Version)
+		digest, err := item.version.Digest(ctx)
+		if err == nil && "sha256:"+digest != item.digest {
+			log.FromContext(ctx).Warnf("Skip rolling back %s which is replaced by another request", name)
+			continue
+		}
+		if err == nil {
+			err = item.chart.Delete(ctx, item.result.Version)
+		}
+		if err != nil {
+			log.FromContext(ctx).Errorf("Failed to roll back %s: %v", name, err)
+		}
+	}
+	// rolled back versions have been counted by quota checks
+	quota.Invalidate(space.Name())
+}
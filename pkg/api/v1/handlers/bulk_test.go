@@ -0,0 +1,173 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/provenance"
+	"github.com/caicloud/helm-registry/pkg/quota"
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+)
+
+// testFile is a file in a multipart form
+type testFile struct {
+	name string
+	data []byte
+}
+
+// newBulkUploadContext creates a handler context of a bulk upload request with chart files
+func newBulkUploadContext(t *testing.T, space string, files ...testFile) context.Context {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for _, file := range files {
+		part, err := writer.CreateFormFile(common.HTTPRequestUploadFileName, file.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = part.Write(file.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return newTestContext(http.MethodPost, "/", body.String(), map[string]string{
+		"space":               space,
+		"header:Content-Type": writer.FormDataContentType(),
+	})
+}
+
+// bulkUploadStatuses returns statuses of files in result
+func bulkUploadStatuses(result *models.BulkUploadResult) []models.BulkUploadStatus {
+	statuses := make([]models.BulkUploadStatus, 0, len(result.Files))
+	for _, file := range result.Files {
+		statuses = append(statuses, file.Status)
+	}
+	return statuses
+}
+
+// expectVersions checks whether versions of chart in library exist
+func expectVersions(t *testing.T, chart string, versions map[string]bool) {
+	ctx := context.Background()
+	for number, exists := range versions {
+		version, err := common.GetVersion(ctx, "library", chart, number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version.Exists(ctx) != exists {
+			t.Errorf("expected existence of library/%s/%s to be %v", chart, number, exists)
+		}
+	}
+}
+
+func TestBulkUpload(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	existing := storagetest.NewArchive(t, "app", "1.0.0")
+	putTestVersion(t, "library", "app", "1.0.0", existing)
+	archive := storagetest.NewArchive(t, "app", "1.1.0")
+
+	result, err := BulkUpload(newBulkUploadContext(t, "library",
+		testFile{"app-1.0.0.tgz", existing},
+		testFile{"app-1.1.0.tgz", archive},
+		testFile{"copy-of-app-1.1.0.tgz", archive},
+		testFile{"app-1.2.0.tgz", storagetest.NewArchive(t, "app", "1.2.0")},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []models.BulkUploadStatus{models.BulkUploadSkippedDuplicate, models.BulkUploadSuccess,
+		models.BulkUploadSkippedDuplicate, models.BulkUploadSuccess}
+	if statuses := bulkUploadStatuses(result); !result.Committed || !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("expected committed statuses %v, but got %v, %v", expected, result.Committed, statuses)
+	}
+	expectVersions(t, "app", map[string]bool{"1.1.0": true, "1.2.0": true})
+}
+
+func TestBulkUploadInvalidFile(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	putTestVersion(t, "library", "app", "1.0.0", storagetest.NewArchive(t, "app", "1.0.0"))
+
+	result, err := BulkUpload(newBulkUploadContext(t, "library",
+		testFile{"app-1.1.0.tgz", storagetest.NewArchive(t, "app", "1.1.0")},
+		testFile{"invalid.tgz", []byte("invalid")},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []models.BulkUploadStatus{models.BulkUploadRolledBack, models.BulkUploadFailed}
+	if statuses := bulkUploadStatuses(result); result.Committed || !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("expected uncommitted statuses %v, but got %v, %v", expected, result.Committed, statuses)
+	}
+	if result.Files[1].Reason == "" {
+		t.Errorf("expected a reason of the failed file")
+	}
+	expectVersions(t, "app", map[string]bool{"1.0.0": true, "1.1.0": false})
+}
+
+func TestBulkUploadRollback(t *testing.T) {
+	defer quota.Initialize(quota.Config{})
+	if err := quota.Initialize(quota.Config{Default: quota.Limits{MaxVersions: 2}}); err != nil {
+		t.Fatal(err)
+	}
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	putTestVersion(t, "library", "app", "1.0.0", storagetest.NewArchive(t, "app", "1.0.0"))
+
+	// the quota is exceeded by the second version after the first one is stored
+	result, err := BulkUpload(newBulkUploadContext(t, "library",
+		testFile{"app-1.1.0.tgz", storagetest.NewArchive(t, "app", "1.1.0")},
+		testFile{"app-1.2.0.tgz", storagetest.NewArchive(t, "app", "1.2.0")},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []models.BulkUploadStatus{models.BulkUploadRolledBack, models.BulkUploadFailed}
+	if statuses := bulkUploadStatuses(result); result.Committed || !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("expected uncommitted statuses %v, but got %v, %v", expected, result.Committed, statuses)
+	}
+	expectVersions(t, "app", map[string]bool{"1.0.0": true, "1.1.0": false, "1.2.0": false})
+}
+
+func TestRollbackReplacedVersion(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	ctx := context.Background()
+	archives := map[string][]byte{
+		"1.0.0": storagetest.NewArchive(t, "app", "1.0.0"),
+		"1.1.0": storagetest.NewArchive(t, "app", "1.1.0"),
+	}
+	stored := []*bulkUploadItem{}
+	for _, number := range []string{"1.0.0", "1.1.0"} {
+		putTestVersion(t, "library", "app", number, archives[number])
+		_, chart, version, err := common.GetSpaceChartAndVersion(ctx, "library", "app", number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored = append(stored, &bulkUploadItem{
+			result:  &models.BulkUploadFile{Chart: "app", Version: number},
+			digest:  provenance.Digest(archives[number]),
+			chart:   chart,
+			version: version,
+		})
+	}
+	// 1.1.0 is replaced by another request after the batch stored it
+	putTestVersion(t, "library", "app", "1.1.0", newTestArchive(t, "app", "1.1.0", "replaced: true\n"))
+	space, err := common.GetSpace(ctx, "library")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rollbackBulkUpload(ctx, space, stored)
+	expectVersions(t, "app", map[string]bool{"1.0.0": false, "1.1.0": true})
+}
@@ -5,15 +5,25 @@ Copyright 2017 caicloud authors. All rights reserved.
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"path"
 	"strings"
 
 	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/auth"
 	"github.com/caicloud/helm-registry/pkg/common"
 	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/immutability"
+	"github.com/caicloud/helm-registry/pkg/metrics"
 	"github.com/caicloud/helm-registry/pkg/orchestration"
+	"github.com/caicloud/helm-registry/pkg/quota"
+	"github.com/caicloud/helm-registry/pkg/search"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/webhook"
 	"gopkg.in/yaml.v2"
+	"k8s.io/helm/pkg/proto/hapi/chart"
 )
 
 // ListCharts lists charts in specified space
@@ -22,26 +32,84 @@ func ListCharts(ctx context.Context) (int, []string, error) {
 	if err != nil {
 		return 0, nil, err
 	}
+	if err = authorize(ctx, spaceName, auth.PermissionRead); err != nil {
+		return 0, nil, err
+	}
+	selector, err := getSelector(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
 	space, err := common.GetSpace(ctx, spaceName)
 	if err != nil {
 		return 0, nil, err
 	}
 	return listStrings(ctx, func() ([]string, error) {
-		return space.List(ctx)
+		names, err := space.List(ctx)
+		if err != nil || selector.Empty() {
+			return names, err
+		}
+		return filterChartsBySelector(ctx, space, names, selector)
 	})
 }
 
-// DeleteChart deletes specified chart
+// filterChartsBySelector keeps charts whose labels match selector. Labels of versions
+// are not matched
+func filterChartsBySelector(ctx context.Context, space storage.Space, names []string,
+	selector storage.Selector) ([]string, error) {
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		chart, err := space.Chart(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		attributes, err := chart.Attributes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if selector.Matches(attributes.Labels) {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+// DeleteChart deletes specified chart. Every version of the chart is moved to trash
+// and can be restored with attributes of the chart
 func DeleteChart(ctx context.Context) error {
 	spaceName, chartName, err := getSpaceAndChartName(ctx)
 	if err != nil {
 		return err
 	}
+	if err = authorize(ctx, spaceName, auth.PermissionDelete); err != nil {
+		return err
+	}
 	space, err := common.GetSpace(ctx, spaceName)
 	if err != nil {
 		return err
 	}
-	return space.Delete(ctx, chartName)
+	chart, err := space.Chart(ctx, chartName)
+	if err != nil {
+		return err
+	}
+	if !chart.Exists(ctx) {
+		return errors.ErrorContentNotFound.Format(spaceName + "/" + chartName)
+	}
+	if immutability.Immutable(spaceName, chartName) {
+		return errors.ErrorVersionUndeletable.Format(spaceName + "/" + chartName)
+	}
+	versions, err := chart.List(ctx)
+	if err != nil {
+		return err
+	}
+	defer search.InvalidateChart(spaceName, chartName)
+	for _, version := range versions {
+		if err = chart.Trash(ctx, version); err != nil {
+			return err
+		}
+		metrics.Count(metrics.OperationDelete, spaceName)
+		webhook.Notify(spaceName, chartName, version, webhook.ActionDelete)
+	}
+	return nil
 }
 
 // CreateChart creates a chart by a json config
@@ -50,7 +118,13 @@ func CreateChart(ctx context.Context) (*models.ChartLink, error) {
 	if err != nil {
 		return nil, err
 	}
-	space, _, version, err := common.GetSpaceChartAndVersion(ctx, config.Save.Space, config.Save.Chart, config.Save.Version)
+	if err = authorize(ctx, config.Save.Space, auth.PermissionWrite); err != nil {
+		return nil, err
+	}
+	if err = authorizePackages(ctx, config.Configs); err != nil {
+		return nil, err
+	}
+	space, chart, version, err := common.GetSpaceChartAndVersion(ctx, config.Save.Space, config.Save.Chart, config.Save.Version)
 	if err != nil {
 		return nil, err
 	}
@@ -65,7 +139,7 @@ func CreateChart(ctx context.Context) (*models.ChartLink, error) {
 		return nil, err
 	}
 	// create chart
-	newChart, err := orchestration.Create(configs)
+	newChart, err := orchestration.Create(ctx, configs)
 	if err != nil {
 		return nil, err
 	}
@@ -85,31 +159,142 @@ func CreateChart(ctx context.Context) (*models.ChartLink, error) {
 	if err != nil {
 		return nil, err
 	}
+	checks, err := checkChart(ctx, config.Save.Space, newChart, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	unlock := quota.Lock(space.Name())
+	defer unlock()
+	if err = quota.Check(ctx, space, chart, version, int64(len(data))); err != nil {
+		return nil, err
+	}
 	// save chart
 	err = version.PutContent(ctx, data)
 	if err != nil {
 		return nil, err
 	}
+	if err = checks.putReports(ctx, version); err != nil {
+		return nil, err
+	}
+	search.InvalidateChart(config.Save.Space, config.Save.Chart)
+	webhook.Notify(config.Save.Space, config.Save.Chart, config.Save.Version, webhook.ActionCreate)
 	// construct a chart self-link
 	path, err := getRequestPath(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return models.NewChartLink(config.Save.Space, config.Save.Chart, config.Save.Version,
-		fmt.Sprintf("%s/%s/versions/%s", path, config.Save.Chart, config.Save.Version)), nil
+	link := models.NewChartLink(config.Save.Space, config.Save.Chart, config.Save.Version,
+		fmt.Sprintf("%s/%s/versions/%s", path, config.Save.Chart, config.Save.Version))
+	link.Lint = checks.lint
+	link.Scan = checks.scan
+	return link, nil
+}
+
+// ComposeChart creates an umbrella chart whose subcharts are versions in registry
+func ComposeChart(ctx context.Context) (*models.ChartLink, error) {
+	config, err := getComposeConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(ctx, config.Save.Space, auth.PermissionWrite); err != nil {
+		return nil, err
+	}
+	components := make([]orchestration.Component, 0, len(config.Components))
+	for _, component := range config.Components {
+		if err = authorize(ctx, component.Space, auth.PermissionRead); err != nil {
+			return nil, err
+		}
+		components = append(components, orchestration.Component{
+			Name: component.Name,
+			Package: orchestration.Package{
+				Independent: true,
+				Space:       component.Space,
+				Chart:       component.Chart,
+				Version:     component.Version,
+			},
+			Values: component.Values,
+		})
+	}
+	space, target, version, err := common.GetSpaceChartAndVersion(ctx, config.Save.Space, config.Save.Chart, config.Save.Version)
+	if err != nil {
+		return nil, err
+	}
+	if !space.Exists(ctx) {
+		return nil, errors.ErrorContentNotFound.Format(config.Save.Space)
+	}
+	if version.Exists(ctx) {
+		return nil, errors.ErrorResourceExist.Format(config.Save.Path())
+	}
+	newChart, err := orchestration.Compose(ctx, &chart.Metadata{
+		ApiVersion:  "v1",
+		Name:        config.Save.Chart,
+		Version:     config.Save.Version,
+		Description: config.Save.Desc,
+	}, components)
+	if err != nil {
+		return nil, err
+	}
+	data, err := orchestration.Archive(newChart)
+	if err != nil {
+		return nil, err
+	}
+	checks, err := checkChart(ctx, config.Save.Space, newChart, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	unlock := quota.Lock(space.Name())
+	defer unlock()
+	if err = quota.Check(ctx, space, target, version, int64(len(data))); err != nil {
+		return nil, err
+	}
+	if err = version.PutContent(ctx, data); err != nil {
+		return nil, err
+	}
+	if err = checks.putReports(ctx, version); err != nil {
+		return nil, err
+	}
+	search.InvalidateChart(config.Save.Space, config.Save.Chart)
+	webhook.Notify(config.Save.Space, config.Save.Chart, config.Save.Version, webhook.ActionCreate)
+	requestPath, err := getRequestPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the request path is .../spaces/{space}/compose
+	link := models.NewChartLink(config.Save.Space, config.Save.Chart, config.Save.Version,
+		fmt.Sprintf("%s/charts/%s/versions/%s", path.Dir(requestPath), config.Save.Chart, config.Save.Version))
+	link.Lint = checks.lint
+	link.Scan = checks.scan
+	return link, nil
 }
 
 // UploadChart handles a request for storing a version of chart. Resource should not exist
 func UploadChart(ctx context.Context) (*models.ChartLink, error) {
+	defer metrics.TrackUpload()()
 	spaceName, err := getSpaceName(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if err = authorize(ctx, spaceName, auth.PermissionWrite); err != nil {
+		return nil, err
+	}
 	data, err := getChartFileData(ctx)
 	if err != nil {
 		return nil, err
 	}
-	metadata, err := getMetadataFromArchiveData(data)
+	chrt, err := getChartFromArchiveData(data)
+	if err != nil {
+		return nil, err
+	}
+	metadata := chrt.Metadata
+	checks, err := checkChart(ctx, spaceName, chrt, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	prov, err := getProvenanceFileData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	verified, err := verifyProvenance(metadata, data, prov)
 	if err != nil {
 		return nil, err
 	}
@@ -120,17 +305,31 @@ func UploadChart(ctx context.Context) (*models.ChartLink, error) {
 	if version.Exists(ctx) {
 		return nil, errors.ErrorResourceExist.Format(fmt.Sprintf("%s/%s/%s", space.Name(), chart.Name(), version.Number()))
 	}
-	err = version.PutContent(ctx, data)
+	unlock := quota.Lock(space.Name())
+	defer unlock()
+	if err = quota.Check(ctx, space, chart, version, int64(len(data))); err != nil {
+		return nil, err
+	}
+	err = putContentAndProvenance(ctx, version, data, prov, verified)
 	if err != nil {
 		return nil, err
 	}
+	if err = checks.putReports(ctx, version); err != nil {
+		return nil, err
+	}
+	metrics.Count(metrics.OperationUpload, space.Name())
+	search.InvalidateChart(space.Name(), chart.Name())
+	webhook.Notify(space.Name(), chart.Name(), version.Number(), webhook.ActionCreate)
 	// construct a chart self-link
 	path, err := getRequestPath(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return models.NewChartLink(spaceName, metadata.Name, metadata.Version,
-		fmt.Sprintf("%s/%s/versions/%s", path, metadata.Name, metadata.Version)), nil
+	link := models.NewChartLink(spaceName, metadata.Name, metadata.Version,
+		fmt.Sprintf("%s/%s/versions/%s", path, metadata.Name, metadata.Version))
+	link.Lint = checks.lint
+	link.Scan = checks.scan
+	return link, nil
 }
 
 // CreateOrUploadChart selects a handler to handle the request by request content type
@@ -142,6 +341,31 @@ func CreateOrUploadChart(ctx context.Context) (*models.ChartLink, error) {
 	return CreateChart(ctx)
 }
 
+// authorizePackages checks read permission on spaces of independent packages in configs
+func authorizePackages(ctx context.Context, configs map[string]interface{}) error {
+	for key, value := range configs {
+		config, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if key != packageName {
+			if err := authorizePackages(ctx, config); err != nil {
+				return err
+			}
+			continue
+		}
+		independent, _ := config["independent"].(bool)
+		space, _ := config["space"].(string)
+		if !independent || len(space) <= 0 {
+			continue
+		}
+		if err := authorize(ctx, space, auth.PermissionRead); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // valuesConfigName is the key of values
 const valuesConfigName = "_config"
 
@@ -0,0 +1,43 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"io"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/scan"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// chartChecks are the reports of checks which a chart passed before it's stored
+type chartChecks struct {
+	// lint is the lint report of chart
+	lint []models.LintMessage
+	// scan is the scan report of chart. It's nil if no check runs in the space
+	scan *scan.Report
+}
+
+// checkChart lints and scans a chart before it's stored in space. Every handler which
+// stores a version calls it, so the checks of a space can't be bypassed. archive is the
+// archive of chart and it's read by lint
+func checkChart(ctx context.Context, space string, chrt *chart.Chart, archive io.Reader) (*chartChecks, error) {
+	report, err := lintChart(ctx, chrt.Metadata, archive)
+	if err != nil {
+		return nil, err
+	}
+	scanReport, err := scan.Check(ctx, space, chrt)
+	if err != nil {
+		return nil, err
+	}
+	return &chartChecks{lint: report, scan: scanReport}, nil
+}
+
+// putReports stores the reports of checks after version is stored
+func (c *chartChecks) putReports(ctx context.Context, version storage.Version) error {
+	return scan.PutReport(ctx, version, c.scan)
+}
@@ -0,0 +1,345 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/ghodss/yaml"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// DiffVersions compares values, metadata and templates of two versions in a chart
+func DiffVersions(ctx context.Context) (*models.VersionDiff, error) {
+	spaceName, chartName, err := getSpaceAndChartName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(ctx, spaceName, auth.PermissionRead); err != nil {
+		return nil, err
+	}
+	from, err := getQueryParameter(ctx, "from")
+	if err != nil {
+		return nil, err
+	}
+	to, err := getQueryParameter(ctx, "to")
+	if err != nil {
+		return nil, err
+	}
+	origin, err := loadVersion(ctx, spaceName, chartName, from)
+	if err != nil {
+		return nil, err
+	}
+	target, err := loadVersion(ctx, spaceName, chartName, to)
+	if err != nil {
+		return nil, err
+	}
+	originValues, err := chartutil.ReadValues([]byte(origin.Values.GetRaw()))
+	if err != nil {
+		return nil, errors.ErrorInternalTypeError.Format(from, "values", "unknown")
+	}
+	targetValues, err := chartutil.ReadValues([]byte(target.Values.GetRaw()))
+	if err != nil {
+		return nil, errors.ErrorInternalTypeError.Format(to, "values", "unknown")
+	}
+	diff := &models.VersionDiff{
+		From: from,
+		To:   to,
+		ValuesDiff: models.ValuesDiff{
+			Added:   make(map[string]interface{}),
+			Removed: make(map[string]interface{}),
+			Changed: make(map[string]models.Change),
+		},
+	}
+	diffValues("", originValues, targetValues, &diff.ValuesDiff)
+	diff.MetadataDiff, err = diffMetadata(origin, target)
+	if err != nil {
+		return nil, err
+	}
+	diff.TemplatesDiff = diffTemplates(from, to, chartTemplates("", origin), chartTemplates("", target))
+	return diff, nil
+}
+
+// loadVersion loads a version of chart
+func loadVersion(ctx context.Context, spaceName, chartName, number string) (*chart.Chart, error) {
+	version, err := common.GetVersion(ctx, spaceName, chartName, number)
+	if err != nil {
+		return nil, err
+	}
+	if !version.Exists(ctx) {
+		return nil, errors.ErrorContentNotFound.Format(fmt.Sprintf("%s/%s/%s", spaceName, chartName, number))
+	}
+	data, err := version.GetContent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := storage.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.ErrorInternalTypeError.Format(fmt.Sprintf("%s/%s", chartName, number), "chart", "unknown")
+	}
+	return result, nil
+}
+
+// diffValues compares origin and target recursively and records differences in diff
+func diffValues(prefix string, origin, target map[string]interface{}, diff *models.ValuesDiff) {
+	for key, originValue := range origin {
+		path := prefix + key
+		targetValue, ok := target[key]
+		if !ok {
+			diff.Removed[path] = originValue
+			continue
+		}
+		originMap, originIsMap := originValue.(map[string]interface{})
+		targetMap, targetIsMap := targetValue.(map[string]interface{})
+		if originIsMap && targetIsMap {
+			diffValues(path+".", originMap, targetMap, diff)
+			continue
+		}
+		if !reflect.DeepEqual(originValue, targetValue) {
+			diff.Changed[path] = models.Change{From: originValue, To: targetValue}
+		}
+	}
+	for key, targetValue := range target {
+		if _, ok := origin[key]; !ok {
+			diff.Added[prefix+key] = targetValue
+		}
+	}
+}
+
+// diffMetadata compares fields of Chart.yaml, including fields of apiVersion v2 which
+// helm 2 doesn't know. Fields are named by their json names
+func diffMetadata(origin, target *chart.Chart) (map[string]models.Change, error) {
+	originFields, err := metadataFields(origin)
+	if err != nil {
+		return nil, err
+	}
+	targetFields, err := metadataFields(target)
+	if err != nil {
+		return nil, err
+	}
+	diff := make(map[string]models.Change)
+	for key, originValue := range originFields {
+		if targetValue := targetFields[key]; !reflect.DeepEqual(originValue, targetValue) {
+			diff[key] = models.Change{From: originValue, To: targetValue}
+		}
+	}
+	for key, targetValue := range targetFields {
+		if _, ok := originFields[key]; !ok {
+			diff[key] = models.Change{From: nil, To: targetValue}
+		}
+	}
+	return diff, nil
+}
+
+// metadataFields converts Chart.yaml of chrt to a map of fields
+func metadataFields(chrt *chart.Chart) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if chrt.Metadata == nil {
+		return fields, nil
+	}
+	data, err := storage.MarshalChartfile(chrt)
+	if err != nil {
+		return nil, errors.ErrorInternalUnknown.Format(err.Error())
+	}
+	if err = yaml.Unmarshal(data, &fields); err != nil {
+		return nil, errors.ErrorInternalUnknown.Format(err.Error())
+	}
+	return fields, nil
+}
+
+// maxDiffCells is the max product of line counts of two files which are compared line
+// by line. The comparison takes time and memory in proportion to the product
+const maxDiffCells = 1 << 16
+
+// diffContext is the number of unchanged lines around changes in a unified diff
+const diffContext = 3
+
+// chartTemplates returns templates of chrt and its subcharts by their paths in the archive
+func chartTemplates(prefix string, chrt *chart.Chart) map[string]string {
+	templates := make(map[string]string)
+	for _, template := range chrt.Templates {
+		templates[prefix+template.Name] = string(template.Data)
+	}
+	for _, dep := range chrt.Dependencies {
+		if dep.Metadata == nil {
+			continue
+		}
+		for name, data := range chartTemplates(prefix+"charts/"+dep.Metadata.Name+"/", dep) {
+			templates[name] = data
+		}
+	}
+	return templates
+}
+
+// diffTemplates compares templates of two versions
+func diffTemplates(from, to string, origin, target map[string]string) []models.FileDiff {
+	names := make([]string, 0, len(origin)+len(target))
+	for name := range origin {
+		names = append(names, name)
+	}
+	for name := range target {
+		if _, ok := origin[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	diff := make([]models.FileDiff, 0, len(names))
+	for _, name := range names {
+		originData, inOrigin := origin[name]
+		targetData, inTarget := target[name]
+		if inOrigin && inTarget && originData == targetData {
+			continue
+		}
+		file := models.FileDiff{Name: name, Status: models.FileModified}
+		if !inOrigin {
+			file.Status = models.FileAdded
+		} else if !inTarget {
+			file.Status = models.FileRemoved
+		}
+		file.Diff = unifiedDiff(from+"/"+name, to+"/"+name, splitLines(originData), splitLines(targetData))
+		diff = append(diff, file)
+	}
+	return diff
+}
+
+// splitLines splits data to lines without line breaks
+func splitLines(data string) []string {
+	if len(data) <= 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(data, "\n"), "\n")
+}
+
+// diffLine is a line in an edit script. op is one of ' ', '-' and '+'
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// unifiedDiff returns the unified diff from origin to target. It only tells that files
+// differ if they are too large to compare
+func unifiedDiff(originName, targetName string, origin, target []string) string {
+	if len(origin)*len(target) > maxDiffCells {
+		return fmt.Sprintf("Files %s and %s differ\n", originName, targetName)
+	}
+	// lcs[i][j] is the length of the longest common subsequence of origin[i:] and target[j:]
+	lcs := make([][]int, len(origin)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(target)+1)
+	}
+	for i := len(origin) - 1; i >= 0; i-- {
+		for j := len(target) - 1; j >= 0; j-- {
+			if origin[i] == target[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	lines := make([]diffLine, 0, len(origin)+len(target))
+	i, j := 0, 0
+	for i < len(origin) || j < len(target) {
+		switch {
+		case i < len(origin) && j < len(target) && origin[i] == target[j]:
+			lines = append(lines, diffLine{' ', origin[i]})
+			i++
+			j++
+		case j >= len(target) || (i < len(origin) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', origin[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', target[j]})
+			j++
+		}
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "--- %s\n+++ %s\n", originName, targetName)
+	// originLine and targetLine are the numbers of lines before lines[k]
+	originLine, targetLine := 0, 0
+	for k := 0; k < len(lines); {
+		if lines[k].op == ' ' {
+			originLine++
+			targetLine++
+			k++
+			continue
+		}
+		// a hunk starts with context before the change and ends when unchanged lines
+		// are more than twice of the context
+		start := k - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := k
+		for unchanged := 0; end < len(lines) && unchanged <= 2*diffContext; end++ {
+			if lines[end].op == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		for end > k && lines[end-1].op == ' ' && trailingContext(lines[k:end]) > diffContext {
+			end--
+		}
+		hunkOrigin, hunkTarget := originLine-(k-start), targetLine-(k-start)
+		originCount, targetCount := 0, 0
+		hunk := &bytes.Buffer{}
+		for _, line := range lines[start:end] {
+			if line.op != '+' {
+				originCount++
+			}
+			if line.op != '-' {
+				targetCount++
+			}
+			hunk.WriteByte(line.op)
+			hunk.WriteString(line.text)
+			hunk.WriteByte('\n')
+		}
+		fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(hunkOrigin, originCount), hunkRange(hunkTarget, targetCount))
+		buf.Write(hunk.Bytes())
+		for _, line := range lines[k:end] {
+			if line.op != '+' {
+				originLine++
+			}
+			if line.op != '-' {
+				targetLine++
+			}
+		}
+		k = end
+	}
+	return buf.String()
+}
+
+// trailingContext returns the number of unchanged lines at the end of lines
+func trailingContext(lines []diffLine) int {
+	count := 0
+	for i := len(lines) - 1; i >= 0 && lines[i].op == ' '; i-- {
+		count++
+	}
+	return count
+}
+
+// hunkRange formats the range of a hunk. before is the number of lines before the hunk
+func hunkRange(before, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
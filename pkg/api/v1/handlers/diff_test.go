@@ -0,0 +1,95 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	letters := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+	large := strings.Repeat("line\n", 300)
+	cases := []struct {
+		name   string
+		origin string
+		target string
+		diff   string
+	}{
+		{
+			name:   "inserted line",
+			origin: "x\ny\n",
+			target: "x\nz\ny\n",
+			diff:   "--- o\n+++ t\n@@ -1,2 +1,3 @@\n x\n+z\n y\n",
+		},
+		{
+			name:   "hunks with context",
+			origin: letters,
+			target: strings.Replace(letters, "b\n", "B\n", 1) + "added\n",
+			diff: "--- o\n+++ t\n" +
+				"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n" +
+				"@@ -12,3 +12,4 @@\n l\n m\n n\n+added\n",
+		},
+		{
+			name:   "added file",
+			origin: "",
+			target: "x\ny\n",
+			diff:   "--- o\n+++ t\n@@ -0,0 +1,2 @@\n+x\n+y\n",
+		},
+		{
+			name:   "removed file",
+			origin: "x\n",
+			target: "",
+			diff:   "--- o\n+++ t\n@@ -1 +0,0 @@\n-x\n",
+		},
+		{
+			name:   "too large to compare",
+			origin: large,
+			target: large + "added\n",
+			diff:   "Files o and t differ\n",
+		},
+	}
+	for _, c := range cases {
+		if diff := unifiedDiff("o", "t", splitLines(c.origin), splitLines(c.target)); diff != c.diff {
+			t.Errorf("%s: expected diff\n%s\nbut got\n%s", c.name, c.diff, diff)
+		}
+	}
+}
+
+func TestDiffTemplates(t *testing.T) {
+	origin := map[string]string{
+		"templates/removed.yaml":  "x\n",
+		"templates/same.yaml":     "x\n",
+		"templates/modified.yaml": "x\n",
+	}
+	target := map[string]string{
+		"templates/added.yaml":    "y\n",
+		"templates/same.yaml":     "x\n",
+		"templates/modified.yaml": "y\n",
+	}
+	expected := []models.FileDiff{
+		{
+			Name:   "templates/added.yaml",
+			Status: models.FileAdded,
+			Diff:   "--- 1.0.0/templates/added.yaml\n+++ 2.0.0/templates/added.yaml\n@@ -0,0 +1 @@\n+y\n",
+		},
+		{
+			Name:   "templates/modified.yaml",
+			Status: models.FileModified,
+			Diff:   "--- 1.0.0/templates/modified.yaml\n+++ 2.0.0/templates/modified.yaml\n@@ -1 +1 @@\n-x\n+y\n",
+		},
+		{
+			Name:   "templates/removed.yaml",
+			Status: models.FileRemoved,
+			Diff:   "--- 1.0.0/templates/removed.yaml\n+++ 2.0.0/templates/removed.yaml\n@@ -1 +0,0 @@\n-x\n",
+		},
+	}
+	if diff := diffTemplates("1.0.0", "2.0.0", origin, target); !reflect.DeepEqual(diff, expected) {
+		t.Errorf("expected template diff %+v, but got %+v", expected, diff)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// computeETag computes a strong etag of data
+func computeETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// metadataETag computes the etag of metadata
+func metadataETag(metadata *storage.Metadata) (string, error) {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return "", errors.ErrorInternalUnknown.Format(err)
+	}
+	return computeETag(data), nil
+}
+
+// archiveETag returns the etag of the archive of version. It's the digest of archive,
+// so it's computed without reading the archive
+func archiveETag(ctx context.Context, version storage.Version) (string, error) {
+	digest, err := version.Digest(ctx)
+	if err != nil {
+		return "", err
+	}
+	return `"` + digest + `"`, nil
+}
+
+// setETag sets header ETag of response in ctx
+func setETag(ctx context.Context, etag string) {
+	setHeader(ctx, "ETag", etag)
+}
+
+// matchETag returns whether etag matches a header value like `"a", W/"b"` or `*` by weak
+// comparison, which ignores the weak indicator. It's used by If-None-Match
+func matchETag(header, etag string) bool {
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimSpace(value)
+		if value == "*" || strings.TrimPrefix(value, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchStrongETag returns whether etag matches a header value like `"a", "b"` or `*` by
+// strong comparison, which a weak etag never matches. It's used by If-Match
+func matchStrongETag(header, etag string) bool {
+	weak := strings.HasPrefix(etag, "W/")
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimSpace(value)
+		if value == "*" || (!weak && value == etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNotModified sets etag to response and returns ErrorNotModified if header
+// If-None-Match matches etag
+func checkNotModified(ctx context.Context, etag string) error {
+	setETag(ctx, etag)
+	header, err := getHeaderParameter(ctx, "If-None-Match")
+	if err != nil {
+		return nil
+	}
+	if matchETag(header, etag) {
+		return errors.ErrorNotModified
+	}
+	return nil
+}
+
+// checkPrecondition returns ErrorPreconditionFailed if header If-Match exists and
+// does not match etag. name is the name of resource
+func checkPrecondition(ctx context.Context, name, etag string) error {
+	header, err := getHeaderParameter(ctx, "If-Match")
+	if err != nil {
+		return nil
+	}
+	if !matchStrongETag(header, etag) {
+		return errors.ErrorPreconditionFailed.Format(name, etag)
+	}
+	return nil
+}
+
+// getDigestPrecondition returns the archive digest in header X-Registry-Digest. A version is
+// written only when its archive has the digest, or the write conflicts with another one
+func getDigestPrecondition(ctx context.Context) string {
+	digest, err := getHeaderParameter(ctx, "X-Registry-Digest")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(digest, "sha256:")
+}
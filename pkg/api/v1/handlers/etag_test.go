@@ -0,0 +1,131 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/api/definition"
+	"github.com/caicloud/helm-registry/pkg/provenance"
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+	"github.com/emicklei/go-restful"
+)
+
+// responseHeader returns header name of response in ctx
+func responseHeader(ctx context.Context, name string) string {
+	return ctx.Value(definition.KeyResponse).(*restful.Response).Header().Get(name)
+}
+
+func TestDownloadETag(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	archive := storagetest.NewArchive(t, "app", "1.0.0")
+	putTestVersion(t, "library", "app", "1.0.0", archive)
+	etag := `"` + strings.TrimPrefix(provenance.Digest(archive), "sha256:") + `"`
+
+	downloads := []struct {
+		name     string
+		params   map[string]string
+		download func(ctx context.Context) (io.ReadCloser, error)
+	}{
+		{"version", map[string]string{"space": "library", "chart": "app", "version": "1.0.0"}, DownloadVersion},
+		{"archive", map[string]string{"space": "library", "file": "app-1.0.0.tgz"}, DownloadArchive},
+	}
+	cases := []struct {
+		name        string
+		ifNoneMatch string
+		modified    bool
+	}{
+		{"no tag", "", true},
+		{"matching tag", etag, false},
+		{"non-matching tag", `"0123456789abcdef"`, true},
+		{"weak tag", "W/" + etag, false},
+		{"tag in list", `"0123456789abcdef", ` + etag, false},
+		{"any tag", "*", false},
+	}
+	for _, d := range downloads {
+		for _, c := range cases {
+			params := map[string]string{}
+			for key, value := range d.params {
+				params[key] = value
+			}
+			if len(c.ifNoneMatch) > 0 {
+				params["header:If-None-Match"] = c.ifNoneMatch
+			}
+			ctx := newTestContext(http.MethodGet, "/", "", params)
+			reader, err := d.download(ctx)
+			if tag := responseHeader(ctx, "ETag"); tag != etag {
+				t.Errorf("%s with %s: expected etag %s, but got %s", d.name, c.name, etag, tag)
+			}
+			if !c.modified {
+				expectErrorCode(t, d.name+" with "+c.name, err, http.StatusNotModified)
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s with %s: unexpected error: %v", d.name, c.name, err)
+				continue
+			}
+			data, err := ioutil.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, archive) {
+				t.Errorf("%s with %s: expected the archive of version", d.name, c.name)
+			}
+		}
+	}
+}
+
+func TestMatchETag(t *testing.T) {
+	cases := []struct {
+		header string
+		etag   string
+		weak   bool
+		strong bool
+	}{
+		{`"a"`, `"a"`, true, true},
+		{`"b", "a"`, `"a"`, true, true},
+		{`*`, `"a"`, true, true},
+		{`W/"a"`, `"a"`, true, false},
+		{`"a"`, `W/"a"`, true, false},
+		{`W/"a"`, `W/"a"`, true, false},
+		{`"b"`, `"a"`, false, false},
+	}
+	for _, c := range cases {
+		if matched := matchETag(c.header, c.etag); matched != c.weak {
+			t.Errorf("%s with %s: expected weak comparison %v, but got %v", c.header, c.etag, c.weak, matched)
+		}
+		if matched := matchStrongETag(c.header, c.etag); matched != c.strong {
+			t.Errorf("%s with %s: expected strong comparison %v, but got %v", c.header, c.etag, c.strong, matched)
+		}
+	}
+}
+
+func TestUpdateValuesWeakETag(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	putTestVersion(t, "library", "app", "1.0.0", newTestArchive(t, "app", "1.0.0", "replicas: 1\n"))
+	params := map[string]string{"space": "library", "chart": "app", "version": "1.0.0"}
+	values, err := FetchValues(newTestContext(http.MethodGet, "/", "", params))
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := computeETag(values)
+
+	params["header:If-Match"] = "W/" + etag
+	_, err = UpdateValues(newTestContext(http.MethodPut, "/", `{"replicas": 2}`, params))
+	expectErrorCode(t, "update with a weak etag", err, http.StatusPreconditionFailed)
+	params["header:If-Match"] = etag
+	if _, err = UpdateValues(newTestContext(http.MethodPut, "/", `{"replicas": 2}`, params)); err != nil {
+		t.Errorf("expected values to be updated with a strong etag, but got %v", err)
+	}
+}
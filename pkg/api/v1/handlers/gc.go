@@ -0,0 +1,22 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/gc"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// CollectGarbage purges trash and removes incomplete versions and unreferenced blobs in storage.
+// It scans all spaces, so the token of request must be able to delete in any space
+func CollectGarbage(ctx context.Context) (*storage.GCResult, error) {
+	if err := authorize(ctx, auth.WildcardSpace, auth.PermissionDelete); err != nil {
+		return nil, err
+	}
+	return gc.Run(ctx)
+}
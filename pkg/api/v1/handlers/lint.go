@@ -0,0 +1,24 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"io"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/lint"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// lintChart lints a chart archive by helm lint rules and returns the report. Warnings
+// block the chart too if query param strict is true
+func lintChart(ctx context.Context, metadata *chart.Metadata, archive io.Reader) ([]models.LintMessage, error) {
+	strict, err := getBoolQueryParameter(ctx, "strict")
+	if err != nil {
+		return nil, err
+	}
+	return lint.Check(ctx, metadata, archive, strict)
+}
@@ -8,13 +8,23 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"sort"
 
+	"github.com/Masterminds/semver"
+	"github.com/caicloud/helm-registry/pkg/auth"
 	"github.com/caicloud/helm-registry/pkg/common"
 	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/immutability"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/metrics"
 	"github.com/caicloud/helm-registry/pkg/orchestration"
+	"github.com/caicloud/helm-registry/pkg/search"
+	"github.com/caicloud/helm-registry/pkg/stats"
 	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/trace"
+	"github.com/caicloud/helm-registry/pkg/webhook"
 	"github.com/ghodss/yaml"
-	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
 )
 
 // ListMetadataInSpace lists all metadata in a space
@@ -23,20 +33,37 @@ func ListMetadataInSpace(ctx context.Context) (int, []*storage.Metadata, error)
 	if err != nil {
 		return 0, nil, err
 	}
+	if err = authorize(ctx, spaceName, auth.PermissionRead); err != nil {
+		return 0, nil, err
+	}
 	start, limit, err := getPaging(ctx)
 	if err != nil {
 		return 0, nil, err
 	}
+	selector, err := getSelector(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
 	space, err := common.GetSpace(ctx, spaceName)
 	if err != nil {
 		return 0, nil, err
 	}
-	metadata, err := space.VersionMetadata(ctx)
+	cached, err := search.Metadata(ctx, spaceName)
+	if err != nil {
+		return 0, nil, err
+	}
+	// cached metadata are shared, so they are copied before appending
+	metadata := append(make([]*storage.Metadata, 0, len(cached)), cached...)
+	metadata, err = appendTrashedMetadata(ctx, metadata, space.TrashedVersionMetadata)
 	if err != nil {
 		return 0, nil, err
 	}
+	metadata = filterMetadataBySelector(metadata, selector)
 	total := len(metadata)
 	start, end := standardizeRange(total, start, limit)
+	if err = fillVersionStatus(ctx, spaceName, metadata[start:end]); err != nil {
+		return 0, nil, err
+	}
 	return total, metadata[start:end], nil
 }
 
@@ -46,29 +73,33 @@ func ListLatestMetadataInSpace(ctx context.Context) (int, []*storage.Metadata, e
 	if err != nil {
 		return 0, nil, err
 	}
+	if err = authorize(ctx, spaceName, auth.PermissionRead); err != nil {
+		return 0, nil, err
+	}
 	start, limit, err := getPaging(ctx)
 	if err != nil {
 		return 0, nil, err
 	}
-	space, err := common.GetSpace(ctx, spaceName)
+	prerelease, err := getBoolQueryParameter(ctx, "prerelease")
 	if err != nil {
 		return 0, nil, err
 	}
-	chartNames, err := space.List(ctx)
+	selector, err := getSelector(ctx)
 	if err != nil {
 		return 0, nil, err
 	}
-	metadata := make([]*storage.Metadata, 0, len(chartNames))
-	for _, chartName := range chartNames {
-		md, err := getLatestMetadata(ctx, spaceName, chartName)
-		if err != nil {
-			return 0, nil, err
-		}
-		metadata = append(metadata, md)
+	cached, err := search.Metadata(ctx, spaceName)
+	if err != nil {
+		return 0, nil, err
 	}
+	// the latest version is chosen from matched versions
+	metadata := latestMetadata(filterMetadataBySelector(cached, selector), prerelease)
 
 	total := len(metadata)
 	start, end := standardizeRange(total, start, limit)
+	if err = fillVersionStatus(ctx, spaceName, metadata[start:end]); err != nil {
+		return 0, nil, err
+	}
 	return total, metadata[start:end], nil
 }
 
@@ -78,21 +109,48 @@ func ListMetadataInChart(ctx context.Context) (int, []*storage.Metadata, error)
 	if err != nil {
 		return 0, nil, err
 	}
+	if err = authorize(ctx, spaceName, auth.PermissionRead); err != nil {
+		return 0, nil, err
+	}
 	start, limit, err := getPaging(ctx)
 	if err != nil {
 		return 0, nil, err
 	}
+	selector, err := getSelector(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
 	chart, err := common.GetChart(ctx, spaceName, chartName)
 	if err != nil {
 		return 0, nil, err
 	}
-	// get all metadata of versions
-	metadata, err := chart.VersionMetadata(ctx)
+	// a chart is removed with its last version, but its trashed versions are still listed
+	exists := chart.Exists(ctx)
+	var cached []*storage.Metadata
+	if exists {
+		// get all metadata of versions with their labels
+		cached, err = search.ChartMetadata(ctx, spaceName, chartName)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	metadata, err := appendTrashedMetadata(ctx, cached, chart.TrashedVersionMetadata)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !exists && len(metadata) <= 0 {
+		return 0, nil, errors.ErrorContentNotFound.Format(spaceName + "/" + chartName)
+	}
+	metadata = filterMetadataBySelector(metadata, selector)
+	metadata, err = filterMetadataByRange(ctx, metadata)
 	if err != nil {
 		return 0, nil, err
 	}
 	total := len(metadata)
 	start, end := standardizeRange(total, start, limit)
+	if err = fillVersionStatus(ctx, spaceName, metadata[start:end]); err != nil {
+		return 0, nil, err
+	}
 	return total, metadata[start:end], nil
 }
 
@@ -102,67 +160,143 @@ func GetLatestMetadataInChart(ctx context.Context) (metadata *storage.Metadata,
 	if err != nil {
 		return nil, err
 	}
+	if err = authorize(ctx, spaceName, auth.PermissionRead); err != nil {
+		return nil, err
+	}
 	return getLatestMetadata(ctx, spaceName, chartName)
 }
 
-// FetchMetadata fetches metadata of specified version
+// FetchMetadata fetches metadata of specified version. It responds with 304 if
+// header If-None-Match matches the etag of metadata. A version of proxied space is
+// pulled from upstream if it doesn't exist
 func FetchMetadata(ctx context.Context) (metadata *storage.Metadata, err error) {
 	err = managerHelper(ctx, func(space storage.Space, chart storage.Chart, version storage.Version) error {
+		if err := authorize(ctx, space.Name(), auth.PermissionRead); err != nil {
+			return err
+		}
+		if err := pullVersion(ctx, space.Name(), chart.Name(), version.Number()); err != nil {
+			return err
+		}
 		metadata, err = version.Metadata(ctx)
+		if err != nil {
+			return err
+		}
+		etag, err := metadataETag(metadata)
+		if err != nil {
+			return err
+		}
+		if err = checkNotModified(ctx, etag); err != nil {
+			return err
+		}
+		// the status is not a part of metadata, so it doesn't change the etag
+		metadata.Provenance, err = version.ProvenanceStatus(ctx)
 		return err
 	})
 	return
 }
 
-// UpdateMetadata updates metadata
+// UpdateMetadata updates metadata. If header If-Match is set, metadata is updated only
+// when it matches the etag of current metadata. If header X-Registry-Digest is set, metadata
+// is updated only when the archive has the digest. The updated chart must pass lint
 func UpdateMetadata(ctx context.Context) (metadata *storage.Metadata, err error) {
 	err = managerHelper(ctx, func(space storage.Space, chart storage.Chart, version storage.Version) error {
-		md, err := getMetadata(ctx)
-		if err != nil {
+		if err := authorize(ctx, space.Name(), auth.PermissionWrite); err != nil {
 			return err
 		}
-		data, err := version.GetContent(ctx)
-		if err != nil {
+		if err := immutability.Check(ctx, space.Name(), chart.Name(), version); err != nil {
 			return err
 		}
-		origin, err := chartutil.LoadArchive(bytes.NewReader(data))
+		md, err := getMetadata(ctx)
 		if err != nil {
-			return errors.ErrorInternalTypeError.Format(
-				fmt.Sprintf("%s/%s", chart.Name(), version.Number()), "chart", "unknown")
-		}
-		if origin.Metadata.Name != md.Name {
-			return errors.ErrorParamValueError.Format("name", origin.Metadata.Name, md.Name)
-		}
-		if origin.Metadata.Version != md.Version {
-			return errors.ErrorParamValueError.Format("version", origin.Metadata.Version, md.Version)
+			return err
 		}
-		*origin.Metadata = md.Metadata
-		data, err = orchestration.Archive(origin)
+		var checks *chartChecks
+		err = version.Update(ctx, getDigestPrecondition(ctx), func(data []byte) ([]byte, error) {
+			ctx, span := trace.StartSpan(ctx, "archive.rebuild")
+			defer span.End()
+			origin, err := storage.LoadArchive(bytes.NewReader(data))
+			if err != nil {
+				return nil, errors.ErrorInternalTypeError.Format(
+					fmt.Sprintf("%s/%s", chart.Name(), version.Number()), "chart", "unknown")
+			}
+			current, err := storage.CoalesceMetadata(origin)
+			if err != nil {
+				return nil, err
+			}
+			etag, err := metadataETag(current)
+			if err != nil {
+				return nil, err
+			}
+			if err = checkPrecondition(ctx, "metadata", etag); err != nil {
+				return nil, err
+			}
+			if origin.Metadata.Name != md.Name {
+				return nil, errors.ErrorParamValueError.Format("name", origin.Metadata.Name, md.Name)
+			}
+			if origin.Metadata.Version != md.Version {
+				return nil, errors.ErrorParamValueError.Format("version", origin.Metadata.Version, md.Version)
+			}
+			*origin.Metadata = md.Metadata
+			data, err = orchestration.Archive(origin)
+			if err != nil {
+				return nil, err
+			}
+			if checks, err = checkChart(ctx, space.Name(), origin, bytes.NewReader(data)); err != nil {
+				return nil, err
+			}
+			metadata, err = storage.CoalesceMetadata(origin)
+			return data, err
+		})
 		if err != nil {
 			return err
 		}
-		err = version.PutContent(ctx, data)
+		if err = checks.putReports(ctx, version); err != nil {
+			return err
+		}
+		metrics.Count(metrics.OperationUpdateMetadata, space.Name())
+		search.InvalidateChart(space.Name(), chart.Name())
+		webhook.Notify(space.Name(), chart.Name(), version.Number(), webhook.ActionUpdate)
+		etag, err := metadataETag(metadata)
 		if err != nil {
 			return err
 		}
-		metadata, err = storage.CoalesceMetadata(origin)
-		return err
+		setETag(ctx, etag)
+		return nil
 	})
 	return
 }
 
-// FetchValues fetches values of specified version
+// FetchValues fetches values of specified version. It responds with 304 if
+// header If-None-Match matches the etag of values. A version of proxied space is
+// pulled from upstream if it doesn't exist
 func FetchValues(ctx context.Context) (data []byte, err error) {
 	err = managerHelper(ctx, func(space storage.Space, chart storage.Chart, version storage.Version) error {
+		if err := authorize(ctx, space.Name(), auth.PermissionRead); err != nil {
+			return err
+		}
+		if err := pullVersion(ctx, space.Name(), chart.Name(), version.Number()); err != nil {
+			return err
+		}
 		data, err = version.Values(ctx)
-		return err
+		if err != nil {
+			return err
+		}
+		return checkNotModified(ctx, computeETag(data))
 	})
 	return
 }
 
-// UpdateValues updates values
+// UpdateValues updates values. If header If-Match is set, values are updated only
+// when it matches the etag of current values. If header X-Registry-Digest is set, values
+// are updated only when the archive has the digest. The updated chart must pass lint
 func UpdateValues(ctx context.Context) (values []byte, err error) {
 	err = managerHelper(ctx, func(space storage.Space, chart storage.Chart, version storage.Version) error {
+		if err := authorize(ctx, space.Name(), auth.PermissionWrite); err != nil {
+			return err
+		}
+		if err := immutability.Check(ctx, space.Name(), chart.Name(), version); err != nil {
+			return err
+		}
 		values, err = getValues(ctx)
 		if err != nil {
 			return err
@@ -171,45 +305,215 @@ func UpdateValues(ctx context.Context) (values []byte, err error) {
 		if err != nil {
 			return errors.ErrorParamTypeError.Format("values", "json", "unknown")
 		}
-		data, err := version.GetContent(ctx)
+		var etag string
+		var checks *chartChecks
+		err = version.Update(ctx, getDigestPrecondition(ctx), func(data []byte) ([]byte, error) {
+			ctx, span := trace.StartSpan(ctx, "archive.rebuild")
+			defer span.End()
+			origin, err := storage.LoadArchive(bytes.NewReader(data))
+			if err != nil {
+				return nil, errors.ErrorInternalTypeError.Format(
+					fmt.Sprintf("%s/%s", chart.Name(), version.Number()), "chart", "unknown")
+			}
+			current, err := storage.CoalesceValues(origin)
+			if err != nil {
+				return nil, err
+			}
+			if err = checkPrecondition(ctx, "values", computeETag(current)); err != nil {
+				return nil, err
+			}
+			if err = validateValues(origin, values); err != nil {
+				return nil, err
+			}
+			setRawValues(origin, string(yamlValues))
+			data, err = orchestration.Archive(origin)
+			if err != nil {
+				return nil, err
+			}
+			if checks, err = checkChart(ctx, space.Name(), origin, bytes.NewReader(data)); err != nil {
+				return nil, err
+			}
+			current, err = storage.CoalesceValues(origin)
+			etag = computeETag(current)
+			return data, err
+		})
 		if err != nil {
 			return err
 		}
-		origin, err := chartutil.LoadArchive(bytes.NewReader(data))
-		if err != nil {
-			return errors.ErrorInternalTypeError.Format(
-				fmt.Sprintf("%s/%s", chart.Name(), version.Number()), "chart", "unknown")
+		if err = checks.putReports(ctx, version); err != nil {
+			return err
+		}
+		metrics.Count(metrics.OperationUpdateValues, space.Name())
+		webhook.Notify(space.Name(), chart.Name(), version.Number(), webhook.ActionUpdateValues)
+		setETag(ctx, etag)
+		return nil
+	})
+	return
+}
+
+// setRawValues sets values of chrt. A chart without values.yaml has no values config
+func setRawValues(chrt *chart.Chart, raw string) {
+	if chrt.Values == nil {
+		chrt.Values = &chart.Config{}
+	}
+	chrt.Values.Raw = raw
+}
+
+// appendTrashedMetadata appends metadata of trashed versions if includeDeleted is true
+func appendTrashedMetadata(ctx context.Context, metadata []*storage.Metadata,
+	trashed func(ctx context.Context) ([]*storage.Metadata, error)) ([]*storage.Metadata, error) {
+	includeDeleted, err := getBoolQueryParameter(ctx, "includeDeleted")
+	if err != nil || !includeDeleted {
+		return metadata, err
+	}
+	trashedMetadata, err := trashed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(metadata, trashedMetadata...), nil
+}
+
+// fillVersionStatus sets the number of downloads and the provenance status in metadata
+// of existing versions. Metadata are replaced by copies, because they may be shared by
+// the search index
+func fillVersionStatus(ctx context.Context, spaceName string, metadata []*storage.Metadata) error {
+	for i, md := range metadata {
+		if md.DeletedAt != nil {
+			continue
 		}
-		origin.Values.Raw = string(yamlValues)
-		data, err = orchestration.Archive(origin)
+		copied := *md
+		md = &copied
+		metadata[i] = md
+		version, err := common.GetVersion(ctx, spaceName, md.Name, md.Version)
 		if err != nil {
 			return err
 		}
-		err = version.PutContent(ctx, data)
+		downloads, err := stats.Downloads(ctx, spaceName, md.Name, version)
 		if err != nil {
 			return err
 		}
-		return err
-	})
-	return
+		md.Downloads = &downloads
+		if md.Provenance, err = version.ProvenanceStatus(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// getLatestMetadata gets latest metadata in a chart
+// filterMetadataByRange filters metadata by semver constraint in query param `range`
+// and sorts them by semver if query param `sort` is specified. Versions which are not
+// valid semver are skipped.
+func filterMetadataByRange(ctx context.Context, metadata []*storage.Metadata) ([]*storage.Metadata, error) {
+	rangeStr, _ := getQueryParameter(ctx, "range")
+	order, err := getSortOrder(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(rangeStr) <= 0 && len(order) <= 0 {
+		return metadata, nil
+	}
+	var constraint *semver.Constraints
+	if len(rangeStr) > 0 {
+		c, err := storage.ParseRange(rangeStr)
+		if err != nil {
+			return nil, errors.ErrorInvalidParam.Format("range", err)
+		}
+		constraint = c
+	}
+	versions := make([]*semver.Version, 0, len(metadata))
+	result := make([]*storage.Metadata, 0, len(metadata))
+	for _, md := range metadata {
+		version, err := semver.NewVersion(md.Version)
+		if err != nil {
+			log.FromContext(ctx).Warnf("Skip version %s of chart %s: %v", md.Version, md.Name, err)
+			continue
+		}
+		if constraint != nil && !constraint.Check(version) {
+			continue
+		}
+		versions = append(versions, version)
+		result = append(result, md)
+	}
+	if len(order) > 0 {
+		sort.Stable(&metadataSorter{result, versions, order == sortDesc})
+	}
+	return result, nil
+}
+
+// sort orders of metadata
+const (
+	sortAsc  = "asc"
+	sortDesc = "desc"
+)
+
+// getSortOrder gets query param sort. It's empty if sort is not specified
+func getSortOrder(ctx context.Context) (string, error) {
+	order, _ := getQueryParameter(ctx, "sort")
+	if order != "" && order != sortAsc && order != sortDesc {
+		return "", errors.ErrorParamValueError.Format("sort", sortAsc+" or "+sortDesc, order)
+	}
+	return order, nil
+}
+
+// metadataSorter sorts metadata by semver
+type metadataSorter struct {
+	metadata []*storage.Metadata
+	versions []*semver.Version
+	desc     bool
+}
+
+func (s *metadataSorter) Len() int { return len(s.metadata) }
+func (s *metadataSorter) Less(i, j int) bool {
+	if s.desc {
+		return s.versions[j].LessThan(s.versions[i])
+	}
+	return s.versions[i].LessThan(s.versions[j])
+}
+func (s *metadataSorter) Swap(i, j int) {
+	s.metadata[i], s.metadata[j] = s.metadata[j], s.metadata[i]
+	s.versions[i], s.versions[j] = s.versions[j], s.versions[i]
+}
+
+// getLatestMetadata gets latest metadata in a chart by semver order. Pre-release versions
+// are returned only if query param prerelease is true or the chart has no other version.
+// Trashed versions are never returned because the search index only has existing versions.
 func getLatestMetadata(ctx context.Context, spaceName, chartName string) (metadata *storage.Metadata, err error) {
-	chart, err := common.GetChart(ctx, spaceName, chartName)
+	prerelease, err := getBoolQueryParameter(ctx, "prerelease")
 	if err != nil {
 		return nil, err
 	}
-	versionNumbers, err := chart.List(ctx)
+	cached, err := search.ChartMetadata(ctx, spaceName, chartName)
 	if err != nil {
 		return nil, err
 	}
-	if len(versionNumbers) <= 0 {
+	latest := latestMetadata(cached, prerelease)
+	if len(latest) <= 0 {
 		return nil, errors.ErrorContentNotFound.Format("metadata")
 	}
-	version, err := chart.Version(ctx, versionNumbers[len(versionNumbers)-1])
-	if err != nil {
-		return nil, err
+	return latest[0], nil
+}
+
+// latestMetadata returns metadata of the latest version of every chart in metadata, in
+// which versions of a chart are adjacent and in ascending order
+func latestMetadata(metadata []*storage.Metadata, prerelease bool) []*storage.Metadata {
+	result := make([]*storage.Metadata, 0)
+	for start := 0; start < len(metadata); {
+		end := start + 1
+		for end < len(metadata) && metadata[end].Name == metadata[start].Name {
+			end++
+		}
+		versions := make([]string, 0, end-start)
+		for _, md := range metadata[start:end] {
+			versions = append(versions, md.Version)
+		}
+		latest, _ := storage.LatestVersion(versions, prerelease)
+		for _, md := range metadata[start:end] {
+			if md.Version == latest {
+				result = append(result, md)
+				break
+			}
+		}
+		start = end
 	}
-	return version.Metadata(ctx)
+	return result
 }
@@ -0,0 +1,89 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+)
+
+func TestUpdateValuesPreconditions(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	putTestVersion(t, "library", "app", "1.0.0", newTestArchive(t, "app", "1.0.0", "replicas: 1\n"))
+	cases := []struct {
+		name    string
+		headers map[string]string
+		code    int
+	}{
+		{"mismatched etag", map[string]string{"If-Match": `"etag"`}, http.StatusPreconditionFailed},
+		{"mismatched digest", map[string]string{"X-Registry-Digest": "sha256:digest"}, http.StatusConflict},
+	}
+	for _, c := range cases {
+		params := map[string]string{"space": "library", "chart": "app", "version": "1.0.0"}
+		for key, value := range c.headers {
+			params["header:"+key] = value
+		}
+		_, err := UpdateValues(newTestContext(http.MethodPut, "/", `{"replicas": 2}`, params))
+		expectErrorCode(t, c.name, err, c.code)
+	}
+}
+
+func TestListMetadataInChartRange(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	for _, version := range []string{"1.0.0", "1.2.0", "2.0.0"} {
+		putTestVersion(t, "ranges", "app", version, storagetest.NewArchive(t, "app", version))
+	}
+	params := map[string]string{"space": "ranges", "chart": "app"}
+	list := func(query string) ([]string, error) {
+		_, metadata, err := ListMetadataInChart(newTestContext(http.MethodGet, "/?range="+url.QueryEscape(query), "", params))
+		versions := make([]string, 0, len(metadata))
+		for _, md := range metadata {
+			versions = append(versions, md.Version)
+		}
+		return versions, err
+	}
+
+	versions, err := list(">=1.1.0 <2.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(versions, []string{"1.2.0"}) {
+		t.Errorf("expected versions [1.2.0] in range, but got %v", versions)
+	}
+	for _, query := range []string{">=one", "1.0.0 ||"} {
+		_, err = list(query)
+		expectErrorCode(t, "range "+query, err, http.StatusBadRequest)
+	}
+}
+
+func TestListMetadataInChartIncludeDeleted(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	putTestVersion(t, "library", "app", "1.0.0", storagetest.NewArchive(t, "app", "1.0.0"))
+	params := map[string]string{"space": "library", "chart": "app", "version": "1.0.0"}
+	if err := DeleteVersion(newTestContext(http.MethodDelete, "/", "", params)); err != nil {
+		t.Fatal(err)
+	}
+
+	params = map[string]string{"space": "library", "chart": "app"}
+	_, _, err := ListMetadataInChart(newTestContext(http.MethodGet, "/", "", params))
+	expectErrorCode(t, "list a chart without versions", err, http.StatusNotFound)
+	total, metadata, err := ListMetadataInChart(newTestContext(http.MethodGet, "/?includeDeleted=true", "", params))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(metadata) != 1 || metadata[0].Version != "1.0.0" || metadata[0].DeletedAt == nil {
+		t.Errorf("expected trashed version 1.0.0, but got %d %+v", total, metadata)
+	}
+	params["chart"] = "missing"
+	_, _, err = ListMetadataInChart(newTestContext(http.MethodGet, "/?includeDeleted=true", "", params))
+	expectErrorCode(t, "list a missing chart", err, http.StatusNotFound)
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/mirror"
+)
+
+// ListMirrors lists sync statuses of all mirrors. Mirrors sync into different spaces,
+// so the token of request must be able to read any space
+func ListMirrors(ctx context.Context) (int, []mirror.Status, error) {
+	if err := authorize(ctx, auth.WildcardSpace, auth.PermissionRead); err != nil {
+		return 0, nil, err
+	}
+	statuses := mirror.List()
+	return len(statuses), statuses, nil
+}
+
+// FetchMirror fetches the sync status of a mirror
+func FetchMirror(ctx context.Context) (*mirror.Status, error) {
+	status, err := getMirrorStatus(ctx, auth.PermissionRead)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// SyncMirror starts a sync of a mirror in background. It requires write permission on
+// the space of mirror
+func SyncMirror(ctx context.Context) (*mirror.Status, error) {
+	status, err := getMirrorStatus(ctx, auth.PermissionWrite)
+	if err != nil {
+		return nil, err
+	}
+	status, _ = mirror.Trigger(status.Name)
+	return status, nil
+}
+
+// getMirrorStatus gets the status of a mirror from ctx and checks permission on its space
+func getMirrorStatus(ctx context.Context, permission auth.Permission) (*mirror.Status, error) {
+	name, err := getPathParameter(ctx, "mirror")
+	if err != nil {
+		return nil, err
+	}
+	status, ok := mirror.Get(name)
+	if !ok {
+		return nil, errors.ErrorContentNotFound.Format("mirror " + name)
+	}
+	if err = authorize(ctx, status.Space, permission); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// ListProxies lists statuses of all proxies. Proxies cache charts in different spaces,
+// so the token of request must be able to read any space
+func ListProxies(ctx context.Context) (int, []mirror.ProxyStatus, error) {
+	if err := authorize(ctx, auth.WildcardSpace, auth.PermissionRead); err != nil {
+		return 0, nil, err
+	}
+	statuses := mirror.ListProxies()
+	return len(statuses), statuses, nil
+}
+
+// pullVersion pulls a version from upstream if space is proxied and the version doesn't
+// exist. The permission on space must be checked by caller
+func pullVersion(ctx context.Context, space, chart, version string) error {
+	if err := mirror.Pull(ctx, space, chart, version); err != nil {
+		return errors.ErrorUpstreamUnavailable.Format(fmt.Sprintf("%s/%s/%s", space, chart, version), err)
+	}
+	return nil
+}
@@ -0,0 +1,492 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/api/v1/types"
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/metrics"
+	"github.com/caicloud/helm-registry/pkg/quota"
+	"github.com/caicloud/helm-registry/pkg/search"
+	"github.com/caicloud/helm-registry/pkg/stats"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/webhook"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// OCI distribution API maps a repository "{space}/{chart}" to a chart, and tags of the
+// repository to versions of the chart. "+" is not allowed in tags, so it's replaced by "_"
+
+// OCIPing responds to the version check of OCI clients
+func OCIPing(ctx context.Context) (map[string]string, error) {
+	setHeader(ctx, "Docker-Distribution-API-Version", "registry/2.0")
+	return map[string]string{}, nil
+}
+
+// OCIListTags lists tags of a repository
+func OCIListTags(ctx context.Context) (*models.OCITags, error) {
+	space, chart, err := getOCIChart(ctx, auth.PermissionRead)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := chart.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]string, len(versions))
+	for i, version := range versions {
+		tags[i] = ociTag(version)
+	}
+	return &models.OCITags{Name: space.Name() + "/" + chart.Name(), Tags: tags}, nil
+}
+
+// OCIFetchManifest fetches the manifest of a version by tag or digest. A manifest is
+// generated for versions which are not pushed by OCI clients
+func OCIFetchManifest(ctx context.Context) ([]byte, error) {
+	_, chart, err := getOCIChart(ctx, auth.PermissionRead)
+	if err != nil {
+		return nil, err
+	}
+	reference, err := getPathParameter(ctx, "reference")
+	if err != nil {
+		return nil, err
+	}
+	_, manifest, _, err := getOCIVersion(ctx, chart, reference)
+	if err != nil {
+		return nil, err
+	}
+	setHeader(ctx, "Content-Type", types.MediaTypeOCIManifest)
+	setHeader(ctx, "Content-Length", strconv.Itoa(len(manifest)))
+	setHeader(ctx, "Docker-Content-Digest", ociDigest(manifest))
+	return manifest, nil
+}
+
+// OCIPutManifest stores the chart referenced by a manifest as a version. Blobs of the
+// manifest must have been put in the space, and the tag must be the version of chart
+func OCIPutManifest(ctx context.Context) ([]byte, error) {
+	space, chart, err := getOCIChart(ctx, auth.PermissionWrite)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := getPathParameter(ctx, "reference")
+	if err != nil {
+		return nil, err
+	}
+	data, err := readDataFromBody(ctx)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &types.OCIManifest{}
+	if err = json.Unmarshal(data, manifest); err != nil {
+		return nil, errors.ErrorParamTypeError.Format("body", "manifest", "unknown")
+	}
+	if err = manifest.Validate(); err != nil {
+		return nil, err
+	}
+	layer := manifest.Layer(types.MediaTypeHelmChart)
+	chrt, err := loadBlobChart(ctx, space, layer.Hex())
+	if err != nil {
+		return nil, err
+	}
+	metadata := chrt.Metadata
+	if metadata.Name != chart.Name() {
+		return nil, errors.ErrorParamValueError.Format("chart name", chart.Name(), metadata.Name)
+	}
+	if ociTag(metadata.Version) != tag {
+		return nil, errors.ErrorParamValueError.Format("tag", ociTag(metadata.Version), tag)
+	}
+	reader, size, err := space.Blob(ctx, layer.Hex())
+	if err != nil {
+		return nil, err
+	}
+	checks, err := checkChart(ctx, space.Name(), chrt, reader)
+	reader.Close()
+	if err != nil {
+		return nil, err
+	}
+	var prov []byte
+	if provLayer := manifest.Layer(types.MediaTypeHelmProvenance); provLayer != nil {
+		if prov, err = readBlob(ctx, space, provLayer.Hex()); err != nil {
+			return nil, err
+		}
+	}
+	verified, err := verifyProvenanceDigest(metadata, layer.Digest, prov)
+	if err != nil {
+		return nil, err
+	}
+	config, err := readBlob(ctx, space, manifest.Config.Hex())
+	if err != nil {
+		return nil, err
+	}
+	version, err := chart.Version(ctx, metadata.Version)
+	if err != nil {
+		return nil, err
+	}
+	if version.Exists(ctx) {
+		return nil, errors.ErrorResourceExist.Format(fmt.Sprintf("%s/%s/%s", space.Name(), chart.Name(), version.Number()))
+	}
+	unlock := quota.Lock(space.Name())
+	defer unlock()
+	if err = quota.Check(ctx, space, chart, version, size); err != nil {
+		return nil, err
+	}
+	if err = version.PutBlob(ctx, layer.Hex()); err != nil {
+		return nil, err
+	}
+	if len(prov) > 0 {
+		if err = version.PutProvenance(ctx, prov, verified); err != nil {
+			return nil, err
+		}
+	}
+	if err = version.PutManifest(ctx, data, config); err != nil {
+		return nil, err
+	}
+	if err = checks.putReports(ctx, version); err != nil {
+		return nil, err
+	}
+	metrics.Count(metrics.OperationUpload, space.Name())
+	search.InvalidateChart(space.Name(), chart.Name())
+	webhook.Notify(space.Name(), chart.Name(), version.Number(), webhook.ActionCreate)
+	requestPath, err := getRequestPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	digest := ociDigest(data)
+	setHeader(ctx, "Location", path.Join(path.Dir(requestPath), digest))
+	setHeader(ctx, "Docker-Content-Digest", digest)
+	return []byte{}, nil
+}
+
+// OCIFetchBlob fetches a blob by digest. It can be a blob put in the space, or a blob
+// referenced by the manifest of a version
+func OCIFetchBlob(ctx context.Context) (io.Reader, error) {
+	space, chart, err := getOCIChart(ctx, auth.PermissionRead)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := getPathParameter(ctx, "digest")
+	if err != nil {
+		return nil, err
+	}
+	reader, size, err := getOCIBlob(ctx, space, chart, digest)
+	if err != nil {
+		return nil, err
+	}
+	setHeader(ctx, "Content-Type", "application/octet-stream")
+	setHeader(ctx, "Content-Length", strconv.FormatInt(size, 10))
+	setHeader(ctx, "Docker-Content-Digest", digest)
+	request, err := getRequestFromContext(ctx)
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+	if request.Request.Method == "HEAD" {
+		reader.Close()
+		return bytes.NewReader(nil), nil
+	}
+	return reader, nil
+}
+
+// OCICreateUpload starts an upload of a blob in the space of repository
+func OCICreateUpload(ctx context.Context) ([]byte, error) {
+	space, err := getUploadSpace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	upload, err := space.CreateUpload(ctx)
+	if err != nil {
+		return nil, err
+	}
+	requestPath, err := getRequestPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	setOCIUploadHeaders(ctx, path.Join(requestPath, upload.ID()), upload, 0)
+	return []byte{}, nil
+}
+
+// OCIAppendUpload appends the body of request to an upload as a chunk. If header
+// Content-Range is set, it must start from the offset of upload
+func OCIAppendUpload(ctx context.Context) ([]byte, error) {
+	defer metrics.TrackUpload()()
+	space, upload, err := getUpload(ctx)
+	if err != nil {
+		return nil, err
+	}
+	request, err := getRequestFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if value := request.HeaderParameter("Content-Range"); len(value) > 0 {
+		start, err := strconv.ParseInt(strings.SplitN(value, "-", 2)[0], 10, 64)
+		if err != nil {
+			return nil, errors.ErrorParamTypeError.Format("Content-Range", "range", value)
+		}
+		size, err := upload.Size(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if start != size {
+			return nil, errors.ErrorInvalidStatus.Format("upload "+upload.ID(), fmt.Sprintf("offset is %d", size))
+		}
+	}
+	size, err := appendUpload(ctx, space, upload, request.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	requestPath, err := getRequestPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	setOCIUploadHeaders(ctx, requestPath, upload, size)
+	return []byte{}, nil
+}
+
+// OCIFinishUpload appends the body of request to an upload and stores received data as
+// a blob of the space. Query param digest must be the sha256 digest of the blob
+func OCIFinishUpload(ctx context.Context) ([]byte, error) {
+	defer metrics.TrackUpload()()
+	space, upload, err := getUpload(ctx)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := getRawQueryParameter(ctx, "digest")
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(digest, "sha256:") {
+		return nil, errors.ErrorParamValueError.Format("digest", "a sha256 digest", digest)
+	}
+	request, err := getRequestFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = appendUpload(ctx, space, upload, request.Request.Body); err != nil {
+		return nil, err
+	}
+	if err = space.PutBlob(ctx, upload, strings.TrimPrefix(digest, "sha256:")); err != nil {
+		return nil, err
+	}
+	requestPath, err := getRequestPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the request path is .../blobs/uploads/{upload}
+	setHeader(ctx, "Location", path.Join(path.Dir(path.Dir(requestPath)), digest))
+	setHeader(ctx, "Docker-Content-Digest", digest)
+	return []byte{}, nil
+}
+
+// getOCIChart gets the space and chart of repository from ctx and checks permission on
+// the space. The chart may not exist
+func getOCIChart(ctx context.Context, permission auth.Permission) (storage.Space, storage.Chart, error) {
+	spaceName, chartName, err := getSpaceAndChartName(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = authorize(ctx, spaceName, permission); err != nil {
+		return nil, nil, err
+	}
+	space, chart, err := common.GetSpaceAndChart(ctx, spaceName, chartName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !space.Exists(ctx) {
+		return nil, nil, errors.ErrorContentNotFound.Format(spaceName)
+	}
+	return space, chart, nil
+}
+
+// getOCIVersion gets a version of chart by tag or manifest digest. It returns the
+// version with its manifest and config
+func getOCIVersion(ctx context.Context, chart storage.Chart, reference string) (storage.Version, []byte, []byte, error) {
+	if !strings.HasPrefix(reference, "sha256:") {
+		version, err := chart.Version(ctx, strings.Replace(reference, "_", "+", -1))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		manifest, config, err := ociManifest(ctx, version)
+		return version, manifest, config, err
+	}
+	versions, err := chart.List(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, number := range versions {
+		version, err := chart.Version(ctx, number)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		manifest, config, err := ociManifest(ctx, version)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if ociDigest(manifest) == reference {
+			return version, manifest, config, nil
+		}
+	}
+	return nil, nil, nil, errors.ErrorContentNotFound.Format("manifest " + reference)
+}
+
+// getOCIBlob gets a blob by digest from space or the manifests of versions of chart
+func getOCIBlob(ctx context.Context, space storage.Space, chart storage.Chart, digest string) (io.ReadCloser, int64, error) {
+	if reader, size, err := space.Blob(ctx, strings.TrimPrefix(digest, "sha256:")); err == nil {
+		return reader, size, nil
+	}
+	versions, err := chart.List(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, number := range versions {
+		version, err := chart.Version(ctx, number)
+		if err != nil {
+			return nil, 0, err
+		}
+		manifestData, config, err := ociManifest(ctx, version)
+		if err != nil {
+			return nil, 0, err
+		}
+		manifest := &types.OCIManifest{}
+		if err = json.Unmarshal(manifestData, manifest); err != nil {
+			return nil, 0, errors.ErrorInternalUnknown.Format(err)
+		}
+		if manifest.Config.Digest == digest {
+			return ioutil.NopCloser(bytes.NewReader(config)), int64(len(config)), nil
+		}
+		for _, layer := range manifest.Layers {
+			if layer.Digest != digest {
+				continue
+			}
+			if layer.MediaType == types.MediaTypeHelmProvenance {
+				prov, err := version.GetProvenance(ctx)
+				return ioutil.NopCloser(bytes.NewReader(prov)), int64(len(prov)), err
+			}
+			reader, size, err := version.StreamContent(ctx)
+			if err != nil {
+				return nil, 0, err
+			}
+			metrics.Count(metrics.OperationDownload, space.Name())
+			stats.CountDownload(space.Name(), chart.Name(), version.Number())
+			return reader, size, nil
+		}
+	}
+	return nil, 0, errors.ErrorContentNotFound.Format("blob " + digest)
+}
+
+// ociManifest returns the manifest and config of a version. They are generated from the
+// version if it's not pushed by an OCI client
+func ociManifest(ctx context.Context, version storage.Version) ([]byte, []byte, error) {
+	manifest, config, err := version.Manifest(ctx)
+	if err != nil || manifest != nil {
+		return manifest, config, err
+	}
+	metadata, err := version.Metadata(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	config, err = json.Marshal(&metadata.Metadata)
+	if err != nil {
+		return nil, nil, errors.ErrorInternalUnknown.Format(err)
+	}
+	digest, err := version.Digest(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader, size, err := version.StreamContent(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader.Close()
+	generated := &types.OCIManifest{
+		SchemaVersion: 2,
+		MediaType:     types.MediaTypeOCIManifest,
+		Config:        ociDescriptor(types.MediaTypeHelmConfig, config),
+		Layers: []types.OCIDescriptor{
+			{MediaType: types.MediaTypeHelmChart, Digest: "sha256:" + digest, Size: size},
+		},
+	}
+	status, err := version.ProvenanceStatus(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if status != storage.ProvenanceNone {
+		prov, err := version.GetProvenance(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		generated.Layers = append(generated.Layers, ociDescriptor(types.MediaTypeHelmProvenance, prov))
+	}
+	manifest, err = json.Marshal(generated)
+	if err != nil {
+		return nil, nil, errors.ErrorInternalUnknown.Format(err)
+	}
+	return manifest, config, nil
+}
+
+// ociDescriptor creates a descriptor of data
+func ociDescriptor(mediaType string, data []byte) types.OCIDescriptor {
+	return types.OCIDescriptor{MediaType: mediaType, Digest: ociDigest(data), Size: int64(len(data))}
+}
+
+// ociDigest returns the sha256 digest of data, like "sha256:<hex>"
+func ociDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ociTag converts a version number to a tag
+func ociTag(version string) string {
+	return strings.Replace(version, "+", "_", -1)
+}
+
+// setOCIUploadHeaders sets headers which describe the progress of an upload
+func setOCIUploadHeaders(ctx context.Context, location string, upload storage.Upload, size int64) {
+	end := size - 1
+	if end < 0 {
+		end = 0
+	}
+	setHeader(ctx, "Location", location)
+	setHeader(ctx, "Range", fmt.Sprintf("0-%d", end))
+	setHeader(ctx, "Docker-Upload-UUID", upload.ID())
+}
+
+// readBlob reads a blob put in space
+func readBlob(ctx context.Context, space storage.Space, digest string) ([]byte, error) {
+	reader, _, err := space.Blob(ctx, digest)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, errors.ErrorInternalUnknown.Format(err)
+	}
+	return data, nil
+}
+
+// loadBlobChart loads the chart stored in a blob put in space
+func loadBlobChart(ctx context.Context, space storage.Space, digest string) (*chart.Chart, error) {
+	reader, _, err := space.Blob(ctx, digest)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return getChartFromArchive(reader)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/provenance"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// FetchProvenance handles a request for getting the provenance file of a version
+func FetchProvenance(ctx context.Context) (data []byte, err error) {
+	err = managerHelper(ctx, func(space storage.Space, chart storage.Chart, version storage.Version) error {
+		if err := authorize(ctx, space.Name(), auth.PermissionRead); err != nil {
+			return err
+		}
+		data, err = version.GetProvenance(ctx)
+		return err
+	})
+	return
+}
+
+// getProvenanceFileData gets provenance file from ctx. It returns nil if the request has no provenance file
+func getProvenanceFileData(ctx context.Context) ([]byte, error) {
+	request, err := getRequestFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	file, _, err := request.Request.FormFile(common.HTTPRequestUploadProvenanceName)
+	if err == http.ErrMissingFile {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.ErrorInvalidParam.Format(common.HTTPRequestUploadProvenanceName, err)
+	}
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, errors.ErrorInvalidParam.Format(common.HTTPRequestUploadProvenanceName, err)
+	}
+	return data, nil
+}
+
+// verifyProvenance verifies provenance data of chart data if a keyring is configured.
+// It returns whether prov is verified
+func verifyProvenance(metadata *chart.Metadata, data, prov []byte) (bool, error) {
+	return verifyProvenanceDigest(metadata, provenance.Digest(data), prov)
+}
+
+// verifyProvenanceDigest is like verifyProvenance, but verifies an archive by its digest
+func verifyProvenanceDigest(metadata *chart.Metadata, digest string, prov []byte) (bool, error) {
+	if !provenance.Enabled() {
+		return false, nil
+	}
+	name := fmt.Sprintf("%s/%s", metadata.Name, metadata.Version)
+	if len(prov) <= 0 {
+		return false, errors.ErrorUnverifiedProvenance.Format(name, "provenance file is required")
+	}
+	// helm signs an archive with the name of `helm package`
+	filename := fmt.Sprintf("%s-%s.tgz", metadata.Name, metadata.Version)
+	if err := provenance.VerifyDigest(filename, digest, prov); err != nil {
+		return false, errors.ErrorUnverifiedProvenance.Format(name, err)
+	}
+	return true, nil
+}
+
+// putContentAndProvenance stores chart data and its provenance data. prov can be nil
+func putContentAndProvenance(ctx context.Context, version storage.Version, data, prov []byte, verified bool) error {
+	if err := version.PutContent(ctx, data); err != nil {
+		return err
+	}
+	if len(prov) <= 0 {
+		return nil
+	}
+	return version.PutProvenance(ctx, prov, verified)
+}
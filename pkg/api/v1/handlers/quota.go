@@ -0,0 +1,58 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"io"
+
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/quota"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// FetchUsage returns the storage usage and quota limits of a space
+func FetchUsage(ctx context.Context) (*quota.Usage, error) {
+	spaceName, err := getSpaceName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(ctx, spaceName, auth.PermissionRead); err != nil {
+		return nil, err
+	}
+	space, err := common.GetSpace(ctx, spaceName)
+	if err != nil {
+		return nil, err
+	}
+	if !space.Exists(ctx) {
+		return nil, errors.ErrorContentNotFound.Format(spaceName)
+	}
+	return quota.GetUsage(ctx, space)
+}
+
+// appendUpload appends data read from reader to upload. An upload which becomes larger
+// than the archive size limit of space is cancelled
+func appendUpload(ctx context.Context, space storage.Space, upload storage.Upload, reader io.Reader) (int64, error) {
+	limit := quota.SpaceLimits(space.Name()).MaxArchiveSize
+	if limit <= 0 {
+		return upload.Append(ctx, reader)
+	}
+	size, err := upload.Size(ctx)
+	if err != nil {
+		return 0, err
+	}
+	// one more byte is read to find an upload which exceeds the limit
+	size, err = upload.Append(ctx, io.LimitReader(reader, limit-size+1))
+	if err != nil {
+		return 0, err
+	}
+	if err = quota.CheckArchiveSize(space.Name(), "upload "+upload.ID(), size); err != nil {
+		upload.Delete(ctx)
+		return 0, err
+	}
+	return size, nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/orchestration"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/ghodss/yaml"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// default release info for rendering templates
+const (
+	defaultReleaseName      = "RELEASE-NAME"
+	defaultReleaseNamespace = "default"
+)
+
+// RenderTemplates renders templates of a version with values in body and returns
+// manifests as a multi-document yaml. Values in body override values of the chart.
+// With query param dependencies=true, missing dependencies are resolved from the space
+// like bundles
+func RenderTemplates(ctx context.Context) (data []byte, err error) {
+	err = managerHelper(ctx, func(space storage.Space, chart storage.Chart, version storage.Version) error {
+		if err := authorize(ctx, space.Name(), auth.PermissionRead); err != nil {
+			return err
+		}
+		override, err := getValues(ctx)
+		if err != nil {
+			return err
+		}
+		resolve, err := getBoolQueryParameter(ctx, "dependencies")
+		if err != nil {
+			return err
+		}
+		content, err := version.GetContent(ctx)
+		if err != nil {
+			return err
+		}
+		chrt, err := storage.LoadArchive(bytes.NewReader(content))
+		if err != nil {
+			return errors.ErrorInternalTypeError.Format(
+				fmt.Sprintf("%s/%s", chart.Name(), version.Number()), "chart", "unknown")
+		}
+		if resolve {
+			if err = orchestration.ResolveDependencies(ctx, space.Name(), chrt); err != nil {
+				return err
+			}
+		}
+		config, err := mergeValues(chrt, override)
+		if err != nil {
+			return err
+		}
+		options := chartutil.ReleaseOptions{
+			Name:      defaultReleaseName,
+			Namespace: defaultReleaseNamespace,
+			IsInstall: true,
+			Revision:  1,
+		}
+		if name, err := getQueryParameter(ctx, "release"); err == nil {
+			options.Name = name
+		}
+		if namespace, err := getQueryParameter(ctx, "namespace"); err == nil {
+			options.Namespace = namespace
+		}
+		data, err = orchestration.Render(chrt, config, options)
+		return err
+	})
+	return
+}
+
+// mergeValues merges json values onto values of chrt and validates the result by
+// values schema. It returns values of chrt if override is empty
+func mergeValues(chrt *chart.Chart, override []byte) (*chart.Config, error) {
+	if len(bytes.TrimSpace(override)) <= 0 {
+		return &chart.Config{Raw: "{}"}, nil
+	}
+	yamlValues, err := yaml.JSONToYAML(override)
+	if err != nil {
+		return nil, errors.ErrorParamTypeError.Format("values", "json", "unknown")
+	}
+	config := &chart.Config{Raw: string(yamlValues)}
+	merged, err := chartutil.CoalesceValues(chrt, config)
+	if err != nil {
+		return nil, errors.ErrorInvalidParam.Format("values", err)
+	}
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return nil, errors.ErrorInternalUnknown.Format(err)
+	}
+	if err = validateValues(chrt, data); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
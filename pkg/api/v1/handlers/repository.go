@@ -0,0 +1,247 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/metrics"
+	"github.com/caicloud/helm-registry/pkg/mirror"
+	"github.com/caicloud/helm-registry/pkg/stats"
+	"github.com/ghodss/yaml"
+)
+
+// indexContentType is the content type of helm repository indexes
+const indexContentType = "application/x-yaml"
+
+// archive file extensions used by helm clients
+const (
+	archiveExtension    = ".tgz"
+	provenanceExtension = ".prov"
+)
+
+// FetchIndex generates a helm repository index of a space. Urls in the index point to
+// DownloadArchive, so the space can be added by `helm repo add`. The index of a proxied
+// space also has versions in upstream which are not cached
+func FetchIndex(ctx context.Context) ([]byte, error) {
+	spaceName, err := getSpaceName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(ctx, spaceName, auth.PermissionRead); err != nil {
+		return nil, err
+	}
+	upstream := mirror.Entries(ctx, spaceName)
+	space, err := common.GetSpace(ctx, spaceName)
+	if err != nil {
+		return nil, err
+	}
+	if !space.Exists(ctx) {
+		return nil, errors.ErrorContentNotFound.Format(spaceName)
+	}
+	metadata, err := space.VersionMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	repositoryURL, err := getRepositoryURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index := &models.Index{
+		APIVersion: models.IndexAPIVersion,
+		Entries:    make(map[string][]*models.IndexEntry),
+		Generated:  time.Now().UTC(),
+	}
+	for _, md := range metadata {
+		version, err := common.GetVersion(ctx, spaceName, md.Name, md.Version)
+		if err != nil {
+			return nil, err
+		}
+		digest, err := version.Digest(ctx)
+		if err != nil {
+			return nil, err
+		}
+		created, err := version.ModTime(ctx)
+		if err != nil {
+			return nil, err
+		}
+		chartMetadata := md.Metadata
+		entry := &models.IndexEntry{
+			Metadata: &chartMetadata,
+			URLs:     []string{repositoryURL + "/archives/" + archiveName(md.Name, md.Version)},
+			Created:  created.UTC(),
+			Digest:   digest,
+		}
+		// versions of a chart are sorted in ascending order, but helm expects the
+		// highest version first
+		index.Entries[md.Name] = append([]*models.IndexEntry{entry}, index.Entries[md.Name]...)
+	}
+	addUpstreamEntries(index, upstream, repositoryURL)
+	data, err := yaml.Marshal(index)
+	if err != nil {
+		return nil, errors.ErrorInternalUnknown.Format(err)
+	}
+	setHeader(ctx, "Content-Type", indexContentType)
+	return data, nil
+}
+
+// DownloadArchive downloads a chart archive or its provenance file by file name like
+// "chart-1.0.0.tgz" or "chart-1.0.0.tgz.prov". It's the download url in index. It
+// responds with 304 if header If-None-Match matches the etag of file. A version of
+// proxied space is pulled from upstream if it doesn't exist
+func DownloadArchive(ctx context.Context) (io.ReadCloser, error) {
+	spaceName, err := getSpaceName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(ctx, spaceName, auth.PermissionRead); err != nil {
+		return nil, err
+	}
+	file, err := getPathParameter(ctx, "file")
+	if err != nil {
+		return nil, err
+	}
+	chartName, versionNumber, prov, err := parseArchiveName(file)
+	if err != nil {
+		return nil, err
+	}
+	if err = pullVersion(ctx, spaceName, chartName, versionNumber); err != nil {
+		return nil, err
+	}
+	version, err := common.GetVersion(ctx, spaceName, chartName, versionNumber)
+	if err != nil {
+		return nil, err
+	}
+	if prov {
+		data, err := version.GetProvenance(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkNotModified(ctx, computeETag(data)); err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	etag, err := archiveETag(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkNotModified(ctx, etag); err != nil {
+		return nil, err
+	}
+	reader, size, err := version.StreamContent(ctx)
+	if err != nil {
+		return nil, err
+	}
+	setHeader(ctx, "Content-Type", chartContentType)
+	setHeader(ctx, "Content-Length", strconv.FormatInt(size, 10))
+	metrics.Count(metrics.OperationDownload, spaceName)
+	stats.CountDownload(spaceName, chartName, versionNumber)
+	return reader, nil
+}
+
+// addUpstreamEntries adds versions in upstream which are not in index. Their urls point
+// to DownloadArchive, which pulls them from upstream
+func addUpstreamEntries(index *models.Index, upstream map[string][]*models.IndexEntry, repositoryURL string) {
+	for name, entries := range upstream {
+		local := make(map[string]bool, len(index.Entries[name]))
+		for _, entry := range index.Entries[name] {
+			local[entry.Version] = true
+		}
+		merged := false
+		for _, entry := range entries {
+			if local[entry.Version] {
+				continue
+			}
+			index.Entries[name] = append(index.Entries[name], &models.IndexEntry{
+				Metadata: entry.Metadata,
+				URLs:     []string{repositoryURL + "/archives/" + archiveName(name, entry.Version)},
+				Created:  entry.Created,
+				Digest:   entry.Digest,
+			})
+			merged = true
+		}
+		if merged {
+			sortIndexEntries(index.Entries[name])
+		}
+	}
+}
+
+// sortIndexEntries sorts versions of a chart by semver with the highest version first.
+// Invalid versions are the last
+func sortIndexEntries(entries []*models.IndexEntry) {
+	versions := make(map[*models.IndexEntry]*semver.Version, len(entries))
+	for _, entry := range entries {
+		if v, err := semver.NewVersion(entry.Version); err == nil {
+			versions[entry] = v
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := versions[entries[i]], versions[entries[j]]
+		if a == nil || b == nil {
+			return b == nil && a != nil
+		}
+		return a.GreaterThan(b)
+	})
+}
+
+// getRepositoryURL returns the absolute url of request without the last path element
+func getRepositoryURL(ctx context.Context) (string, error) {
+	request, err := getRequestFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	scheme := "http"
+	if request.Request.TLS != nil {
+		scheme = "https"
+	}
+	// the registry may be behind a proxy which terminates tls
+	if proto := request.HeaderParameter("X-Forwarded-Proto"); len(proto) > 0 {
+		scheme = proto
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, request.Request.Host, path.Dir(request.Request.URL.Path)), nil
+}
+
+// archiveName returns the archive file name of a version
+func archiveName(chart, version string) string {
+	return fmt.Sprintf("%s-%s%s", chart, version, archiveExtension)
+}
+
+// parseArchiveName parses chart name and version number from an archive file name. A chart
+// name may contain hyphens, so the version starts after the first hyphen followed by a
+// valid semver. prov is true if it's the name of a provenance file
+func parseArchiveName(file string) (chart string, version string, prov bool, err error) {
+	name := file
+	if strings.HasSuffix(name, provenanceExtension) {
+		prov = true
+		name = strings.TrimSuffix(name, provenanceExtension)
+	}
+	if strings.HasSuffix(name, archiveExtension) {
+		name = strings.TrimSuffix(name, archiveExtension)
+		for i, c := range name {
+			if c != '-' {
+				continue
+			}
+			if _, e := semver.NewVersion(name[i+1:]); e == nil && i > 0 {
+				return name[:i], name[i+1:], prov, nil
+			}
+		}
+	}
+	return "", "", false, errors.ErrorParamValueError.Format("file", "a name like chart-1.0.0.tgz", file)
+}
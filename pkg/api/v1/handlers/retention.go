@@ -0,0 +1,197 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/retention"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// FetchRetentionPolicy fetches the retention policy of a chart
+func FetchRetentionPolicy(ctx context.Context) (*storage.RetentionPolicy, error) {
+	chart, err := getRetentionChart(ctx, auth.PermissionRead)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := chart.RetentionPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, errors.ErrorContentNotFound.Format("retention policy of " + chart.Name())
+	}
+	return policy, nil
+}
+
+// UpdateRetentionPolicy sets the retention policy of a chart. Versions which are not kept
+// by the policy will be moved to trash by the reaper, so it requires delete permission
+func UpdateRetentionPolicy(ctx context.Context) (*storage.RetentionPolicy, error) {
+	chart, err := getRetentionChart(ctx, auth.PermissionDelete)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := getRetentionPolicyFromBody(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = chart.SetRetentionPolicy(ctx, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// DeleteRetentionPolicy removes the retention policy of a chart. The policy of its space
+// applies to the chart then, so it requires delete permission like setting a policy
+func DeleteRetentionPolicy(ctx context.Context) error {
+	chart, err := getRetentionChart(ctx, auth.PermissionDelete)
+	if err != nil {
+		return err
+	}
+	return chart.SetRetentionPolicy(ctx, nil)
+}
+
+// getRetentionChart gets an existing chart from ctx and checks permission on its space
+func getRetentionChart(ctx context.Context, permission auth.Permission) (storage.Chart, error) {
+	spaceName, chartName, err := getSpaceAndChartName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(ctx, spaceName, permission); err != nil {
+		return nil, err
+	}
+	chart, err := common.GetChart(ctx, spaceName, chartName)
+	if err != nil {
+		return nil, err
+	}
+	if !chart.Exists(ctx) {
+		return nil, errors.ErrorContentNotFound.Format(spaceName + "/" + chartName)
+	}
+	return chart, nil
+}
+
+// FetchSpaceRetentionPolicy fetches the retention policy of a space
+func FetchSpaceRetentionPolicy(ctx context.Context) (*storage.RetentionPolicy, error) {
+	space, err := getRetentionSpace(ctx, auth.PermissionRead)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := space.RetentionPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		return nil, errors.ErrorContentNotFound.Format("retention policy of " + space.Name())
+	}
+	return policy, nil
+}
+
+// UpdateSpaceRetentionPolicy sets the retention policy of a space. It applies to charts
+// which have no policy
+func UpdateSpaceRetentionPolicy(ctx context.Context) (*storage.RetentionPolicy, error) {
+	space, err := getRetentionSpace(ctx, auth.PermissionDelete)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := getRetentionPolicyFromBody(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = space.SetRetentionPolicy(ctx, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// DeleteSpaceRetentionPolicy removes the retention policy of a space. It requires delete
+// permission like setting a policy
+func DeleteSpaceRetentionPolicy(ctx context.Context) error {
+	space, err := getRetentionSpace(ctx, auth.PermissionDelete)
+	if err != nil {
+		return err
+	}
+	return space.SetRetentionPolicy(ctx, nil)
+}
+
+// PruneSpace prunes all charts in a space by their retention policies. With query param
+// dryRun, it only returns versions which would be pruned
+func PruneSpace(ctx context.Context) (*models.PruneResult, error) {
+	dryRun, err := getBoolQueryParameter(ctx, "dryRun")
+	if err != nil {
+		return nil, err
+	}
+	permission := auth.PermissionDelete
+	if dryRun {
+		permission = auth.PermissionRead
+	}
+	space, err := getRetentionSpace(ctx, permission)
+	if err != nil {
+		return nil, err
+	}
+	chartNames, err := space.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.PruneResult{
+		Space:  space.Name(),
+		DryRun: dryRun,
+		Charts: make([]models.PrunedChart, 0),
+	}
+	for _, chartName := range chartNames {
+		chart, err := space.Chart(ctx, chartName)
+		if err != nil {
+			return nil, err
+		}
+		pruned, err := retention.Prune(ctx, space, chart, dryRun)
+		if err != nil {
+			return nil, err
+		}
+		if len(pruned) > 0 {
+			result.Charts = append(result.Charts, models.PrunedChart{Chart: chartName, Versions: pruned})
+		}
+	}
+	return result, nil
+}
+
+// getRetentionPolicyFromBody gets a valid retention policy from the body of request
+func getRetentionPolicyFromBody(ctx context.Context) (*storage.RetentionPolicy, error) {
+	data, err := readDataFromBody(ctx)
+	if err != nil {
+		return nil, err
+	}
+	policy := &storage.RetentionPolicy{}
+	if err = json.Unmarshal(data, policy); err != nil {
+		return nil, errors.ErrorParamTypeError.Format("body", "retention policy", "unknown")
+	}
+	if err = policy.Validate(); err != nil {
+		return nil, errors.ErrorInvalidParam.Format("retention policy", err)
+	}
+	return policy, nil
+}
+
+// getRetentionSpace gets an existing space from ctx and checks permission on it
+func getRetentionSpace(ctx context.Context, permission auth.Permission) (storage.Space, error) {
+	spaceName, err := getSpaceName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(ctx, spaceName, permission); err != nil {
+		return nil, err
+	}
+	space, err := common.GetSpace(ctx, spaceName)
+	if err != nil {
+		return nil, err
+	}
+	if !space.Exists(ctx) {
+		return nil, errors.ErrorContentNotFound.Format(spaceName)
+	}
+	return space, nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/api/definition"
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+	"github.com/emicklei/go-restful"
+)
+
+// newAuthorizedTestContext creates a context like newTestContext, whose request is passed
+// through the auth filter with a bearer token
+func newAuthorizedTestContext(t *testing.T, method, token string, params map[string]string) context.Context {
+	headers := map[string]string{"header:Authorization": "Bearer " + token}
+	for key, value := range params {
+		headers[key] = value
+	}
+	ctx := newTestContext(method, "/", "", headers)
+	passed := false
+	chain := &restful.FilterChain{Target: func(*restful.Request, *restful.Response) { passed = true }}
+	auth.Filter()(ctx.Value(definition.KeyRequest).(*restful.Request), ctx.Value(definition.KeyResponse).(*restful.Response), chain)
+	if !passed {
+		t.Fatalf("token %s is not authenticated", token)
+	}
+	return ctx
+}
+
+func TestDeleteRetentionPolicyPermission(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	err := auth.Initialize(auth.Config{Tokens: []auth.Token{
+		{Token: "push", Roles: map[string]auth.Role{"library": auth.RolePush}},
+		{Token: "admin", Roles: map[string]auth.Role{"library": auth.RoleAdmin}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auth.Initialize(auth.Config{})
+	putTestVersion(t, "library", "app", "1.0.0", storagetest.NewArchive(t, "app", "1.0.0"))
+
+	cases := []struct {
+		name   string
+		params map[string]string
+		delete func(ctx context.Context) error
+	}{
+		{"chart policy", map[string]string{"space": "library", "chart": "app"}, DeleteRetentionPolicy},
+		{"space policy", map[string]string{"space": "library"}, DeleteSpaceRetentionPolicy},
+	}
+	for _, c := range cases {
+		ctx := newAuthorizedTestContext(t, http.MethodDelete, "push", c.params)
+		expectErrorCode(t, c.name+" deleted by a pusher", c.delete(ctx), http.StatusForbidden)
+		ctx = newAuthorizedTestContext(t, http.MethodDelete, "admin", c.params)
+		if err = c.delete(ctx); err != nil {
+			t.Errorf("%s: unexpected error of deleting by an admin: %v", c.name, err)
+		}
+	}
+}
+
+func TestUpdateRetentionPolicyPermission(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	err := auth.Initialize(auth.Config{Tokens: []auth.Token{
+		{Token: "push", Roles: map[string]auth.Role{"library": auth.RolePush}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer auth.Initialize(auth.Config{})
+	putTestVersion(t, "library", "app", "1.0.0", storagetest.NewArchive(t, "app", "1.0.0"))
+
+	cases := []struct {
+		name   string
+		params map[string]string
+		update func(ctx context.Context) (*storage.RetentionPolicy, error)
+	}{
+		{"chart policy", map[string]string{"space": "library", "chart": "app"}, UpdateRetentionPolicy},
+		{"space policy", map[string]string{"space": "library"}, UpdateSpaceRetentionPolicy},
+	}
+	for _, c := range cases {
+		_, err = c.update(newAuthorizedTestContext(t, http.MethodPut, "push", c.params))
+		expectErrorCode(t, c.name+" set by a pusher", err, http.StatusForbidden)
+	}
+}
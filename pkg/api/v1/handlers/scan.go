@@ -0,0 +1,38 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/scan"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// FetchScanReport handles a request for getting the scan report of a version
+func FetchScanReport(ctx context.Context) (report *scan.Report, err error) {
+	err = managerHelper(ctx, func(space storage.Space, chart storage.Chart, version storage.Version) error {
+		if err := authorize(ctx, space.Name(), auth.PermissionRead); err != nil {
+			return err
+		}
+		data, err := version.ScanReport(ctx)
+		if err != nil {
+			return err
+		}
+		if len(data) <= 0 {
+			return errors.ErrorContentNotFound.Format(fmt.Sprintf("scan report of %s/%s/%s", space.Name(), chart.Name(), version.Number()))
+		}
+		report = &scan.Report{}
+		if err = json.Unmarshal(data, report); err != nil {
+			return errors.ErrorInternalUnknown.Format(err)
+		}
+		return nil
+	})
+	return
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/ghodss/yaml"
+	"github.com/xeipuuv/gojsonreference"
+	"github.com/xeipuuv/gojsonschema"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// validateChartValues validates values.yaml of chart against its values schema
+func validateChartValues(chrt *chart.Chart) error {
+	values := []byte("{}")
+	if chrt.Values != nil && len(strings.TrimSpace(chrt.Values.Raw)) > 0 {
+		data, err := yaml.YAMLToJSON([]byte(chrt.Values.Raw))
+		if err != nil {
+			return errors.ErrorParamTypeError.Format("values", "yaml", "unknown")
+		}
+		values = data
+	}
+	return validateValues(chrt, values)
+}
+
+// localSchemaLoader loads a schema which can only refer to itself. A schema comes from an
+// uploaded archive, so its references must not make the registry read files or send requests
+type localSchemaLoader struct {
+	gojsonschema.JSONLoader
+}
+
+// newLocalSchemaLoader creates a loader of schema in json
+func newLocalSchemaLoader(schema []byte) gojsonschema.JSONLoader {
+	return &localSchemaLoader{gojsonschema.NewBytesLoader(schema)}
+}
+
+// LoaderFactory returns a factory of loaders which refuse to load referenced documents
+func (l *localSchemaLoader) LoaderFactory() gojsonschema.JSONLoaderFactory {
+	return refusingLoaderFactory{}
+}
+
+// refusingLoaderFactory creates loaders which refuse to load documents
+type refusingLoaderFactory struct{}
+
+// New creates a loader which fails to load source
+func (refusingLoaderFactory) New(source string) gojsonschema.JSONLoader {
+	return &refusingLoader{source: source}
+}
+
+// refusingLoader is a loader of an external document which always fails
+type refusingLoader struct {
+	source string
+}
+
+// JsonSource returns the source of document
+func (l *refusingLoader) JsonSource() interface{} {
+	return l.source
+}
+
+// LoadJSON refuses to load the document
+func (l *refusingLoader) LoadJSON() (interface{}, error) {
+	return nil, fmt.Errorf("reference %s is not in %s", l.source, storage.ValuesSchemaName)
+}
+
+// JsonReference returns the reference of document
+func (l *refusingLoader) JsonReference() (gojsonreference.JsonReference, error) {
+	return gojsonreference.NewJsonReference(l.source)
+}
+
+// LoaderFactory returns a factory of loaders which refuse to load documents
+func (l *refusingLoader) LoaderFactory() gojsonschema.JSONLoaderFactory {
+	return refusingLoaderFactory{}
+}
+
+// validateValues validates json values against the values schema of chart.
+// If the chart does not have a schema, skip validation.
+func validateValues(chrt *chart.Chart, values []byte) error {
+	schema := storage.ExtractValuesSchema(chrt)
+	if schema == nil {
+		return nil
+	}
+	result, err := gojsonschema.Validate(newLocalSchemaLoader(schema), gojsonschema.NewBytesLoader(values))
+	if err != nil {
+		return errors.ErrorInvalidParam.Format(storage.ValuesSchemaName, err)
+	}
+	if result.Valid() {
+		return nil
+	}
+	// properties are validated in random order, so errors are sorted by field
+	resultErrors := result.Errors()
+	sort.SliceStable(resultErrors, func(i, j int) bool {
+		return resultErrors[i].Field() < resultErrors[j].Field()
+	})
+	failures := make([]string, 0, len(resultErrors))
+	details := make([]models.SchemaError, 0, len(resultErrors))
+	for _, e := range resultErrors {
+		failures = append(failures, fmt.Sprintf("%s: %s", e.Field(), e.Description()))
+		details = append(details, models.SchemaError{Field: e.Field(), Type: e.Type(), Description: e.Description()})
+	}
+	return errors.ErrorParamValueError.Format("values", "valid against "+storage.ValuesSchemaName,
+		strings.Join(failures, "; ")).WithDetails(details)
+}
+
+// FetchValuesSchema fetches values.schema.json of a version. It responds with 304 if
+// header If-None-Match matches the etag of schema. A version of proxied space is pulled
+// from upstream if it doesn't exist
+func FetchValuesSchema(ctx context.Context) (data []byte, err error) {
+	err = managerHelper(ctx, func(space storage.Space, chart storage.Chart, version storage.Version) error {
+		if err := authorize(ctx, space.Name(), auth.PermissionRead); err != nil {
+			return err
+		}
+		if err := pullVersion(ctx, space.Name(), chart.Name(), version.Number()); err != nil {
+			return err
+		}
+		data, err = version.ValuesSchema(ctx)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			return errors.ErrorContentNotFound.Format(
+				fmt.Sprintf("%s of %s/%s/%s", storage.ValuesSchemaName, space.Name(), chart.Name(), version.Number()))
+		}
+		return checkNotModified(ctx, computeETag(data))
+	})
+	return
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+)
+
+// testValuesSchema requires an integer replicas and a string image.tag
+const testValuesSchema = `{
+	"type": "object",
+	"required": ["replicas"],
+	"properties": {
+		"replicas": {"type": "integer", "minimum": 1},
+		"image": {"type": "object", "properties": {"tag": {"type": "string"}}}
+	}
+}`
+
+// newTestSchemaArchive creates an archive of chart with values and testValuesSchema
+func newTestSchemaArchive(t *testing.T, values string) []byte {
+	return storagetest.NewArchiveFiles(t, map[string]string{
+		"app/Chart.yaml":         "apiVersion: v1\nname: app\nversion: 1.0.0\n",
+		"app/values.yaml":        values,
+		"app/values.schema.json": testValuesSchema,
+	})
+}
+
+func TestValidateChartValues(t *testing.T) {
+	cases := []struct {
+		name     string
+		values   string
+		expected []models.SchemaError
+	}{
+		{"valid values", "replicas: 1\nimage:\n  tag: latest\n", nil},
+		{"missing field", "image:\n  tag: latest\n", []models.SchemaError{
+			{Field: "(root)", Type: "required", Description: "replicas is required"},
+		}},
+		{"invalid fields", "replicas: 0\nimage:\n  tag: 1\n", []models.SchemaError{
+			{Field: "image.tag", Type: "invalid_type", Description: "Invalid type. Expected: string, given: integer"},
+			{Field: "replicas", Type: "number_gte", Description: "Must be greater than or equal to 1"},
+		}},
+	}
+	for _, c := range cases {
+		chrt, err := storage.LoadArchive(bytes.NewReader(newTestSchemaArchive(t, c.values)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = validateChartValues(chrt)
+		if c.expected == nil {
+			if err != nil {
+				t.Errorf("%s: expected no error, but got %v", c.name, err)
+			}
+			continue
+		}
+		if !errors.ErrorParamValueError.Equal(err) {
+			t.Errorf("%s: expected a param value error, but got %v", c.name, err)
+			continue
+		}
+		details, _ := err.(*errors.Error).Details.([]models.SchemaError)
+		if !reflect.DeepEqual(details, c.expected) {
+			t.Errorf("%s: expected field errors %+v, but got %+v", c.name, c.expected, details)
+		}
+	}
+}
+
+func TestFetchValuesSchema(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	putTestVersion(t, "library", "app", "1.0.0", newTestSchemaArchive(t, "replicas: 1\n"))
+	putTestVersion(t, "library", "plain", "1.0.0", storagetest.NewArchive(t, "plain", "1.0.0"))
+
+	params := map[string]string{"space": "library", "chart": "app", "version": "1.0.0"}
+	schema, err := FetchValuesSchema(newTestContext(http.MethodGet, "/", "", params))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(schema) != testValuesSchema {
+		t.Errorf("expected the values schema of chart, but got %s", schema)
+	}
+	params["header:If-None-Match"] = computeETag(schema)
+	_, err = FetchValuesSchema(newTestContext(http.MethodGet, "/", "", params))
+	expectErrorCode(t, "fetch an unmodified schema", err, http.StatusNotModified)
+
+	params = map[string]string{"space": "library", "chart": "plain", "version": "1.0.0"}
+	_, err = FetchValuesSchema(newTestContext(http.MethodGet, "/", "", params))
+	expectErrorCode(t, "fetch a missing schema", err, http.StatusNotFound)
+}
+
+func TestValidateValuesReferences(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"type": "integer"}`))
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		name   string
+		schema string
+		valid  bool
+	}{
+		{"local reference", `{
+			"definitions": {"replicas": {"type": "integer"}},
+			"properties": {"replicas": {"$ref": "#/definitions/replicas"}}
+		}`, true},
+		{"remote reference", `{"properties": {"replicas": {"$ref": "` + server.URL + `/schema.json"}}}`, false},
+		{"file reference", `{"properties": {"replicas": {"$ref": "file:///etc/passwd"}}}`, false},
+	}
+	for _, c := range cases {
+		chrt, err := storage.LoadArchive(bytes.NewReader(storagetest.NewArchiveFiles(t, map[string]string{
+			"app/Chart.yaml":         "apiVersion: v1\nname: app\nversion: 1.0.0\n",
+			"app/values.schema.json": c.schema,
+		})))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = validateValues(chrt, []byte(`{"replicas": 1}`))
+		if c.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+		}
+		if !c.valid && !errors.ErrorInvalidParam.Equal(err) {
+			t.Errorf("%s: expected an invalid param error, but got %v", c.name, err)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("expected no request of remote reference, but got %d", requests)
+	}
+}
+
+func TestUpdateValuesSchema(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"type": "integer"}`))
+	}))
+	defer server.Close()
+	putTestVersion(t, "library", "app", "1.0.0", newTestSchemaArchive(t, "replicas: 1\n"))
+	// versions stored before their schemas are validated may have any references
+	putTestVersion(t, "library", "remote", "1.0.0", storagetest.NewArchiveFiles(t, map[string]string{
+		"remote/Chart.yaml":         "apiVersion: v1\nname: remote\nversion: 1.0.0\n",
+		"remote/values.yaml":        "replicas: 1\n",
+		"remote/values.schema.json": `{"properties": {"replicas": {"$ref": "` + server.URL + `/schema.json"}}}`,
+	}))
+
+	params := map[string]string{"space": "library", "chart": "app", "version": "1.0.0"}
+	if _, err := UpdateValues(newTestContext(http.MethodPut, "/", `{"replicas": 2}`, params)); err != nil {
+		t.Errorf("unexpected error of valid values: %v", err)
+	}
+	_, err := UpdateValues(newTestContext(http.MethodPut, "/", `{"replicas": 0, "image": {"tag": 1}}`, params))
+	if !errors.ErrorParamValueError.Equal(err) {
+		t.Fatalf("expected a param value error, but got %v", err)
+	}
+	expected := []models.SchemaError{
+		{Field: "image.tag", Type: "invalid_type", Description: "Invalid type. Expected: string, given: integer"},
+		{Field: "replicas", Type: "number_gte", Description: "Must be greater than or equal to 1"},
+	}
+	if details, _ := err.(*errors.Error).Details.([]models.SchemaError); !reflect.DeepEqual(details, expected) {
+		t.Errorf("expected field errors %+v, but got %+v", expected, details)
+	}
+
+	params = map[string]string{"space": "library", "chart": "remote", "version": "1.0.0"}
+	_, err = UpdateValues(newTestContext(http.MethodPut, "/", `{"replicas": 2}`, params))
+	if !errors.ErrorInvalidParam.Equal(err) {
+		t.Errorf("expected an invalid param error of remote reference, but got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("expected no request of remote reference, but got %d", requests)
+	}
+}
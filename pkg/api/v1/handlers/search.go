@@ -0,0 +1,68 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/search"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// SearchMetadata searches metadata by chart name, description, keyword and maintainer.
+// If space is not specified, it searches all spaces. Query param keyword must be exactly
+// one of the chart keywords, while terms of q are matched regardless of case.
+func SearchMetadata(ctx context.Context) (int, []*storage.Metadata, error) {
+	start, limit, err := getPaging(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	query := search.Query{}
+	query.Text, _ = getQueryParameter(ctx, "q")
+	query.Keyword, _ = getQueryParameter(ctx, "keyword")
+	query.Maintainer, _ = getQueryParameter(ctx, "maintainer")
+	query.Latest, err = getBoolQueryParameter(ctx, "latest")
+	if err != nil {
+		return 0, nil, err
+	}
+	query.Prerelease, err = getBoolQueryParameter(ctx, "prerelease")
+	if err != nil {
+		return 0, nil, err
+	}
+	query.Selector, err = getSelector(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	spaceNames, err := getSearchSpaces(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	metadata, err := search.Search(ctx, spaceNames, query)
+	if err != nil {
+		return 0, nil, err
+	}
+	total := len(metadata)
+	start, end := standardizeRange(total, start, limit)
+	return total, metadata[start:end], nil
+}
+
+// getSearchSpaces gets the space specified in request. If there is no
+// space in request, it returns all spaces which the token of request can read.
+func getSearchSpaces(ctx context.Context) ([]string, error) {
+	spaceName, err := getQueryParameter(ctx, "space")
+	if err == nil {
+		if err = authorize(ctx, spaceName, auth.PermissionRead); err != nil {
+			return nil, err
+		}
+		return []string{spaceName}, nil
+	}
+	spaces, err := common.MustGetSpaceManager().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return readableSpaces(ctx, spaces), nil
+}
@@ -5,30 +5,71 @@ Copyright 2017 caicloud authors. All rights reserved.
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"path"
 
 	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/auth"
 	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/immutability"
+	"github.com/caicloud/helm-registry/pkg/quota"
+	"github.com/caicloud/helm-registry/pkg/search"
+	"github.com/caicloud/helm-registry/pkg/storage"
 )
 
-// ListSpaces lists spaces
+// ListSpaces lists spaces which the token of request can read
 func ListSpaces(ctx context.Context) (int, []string, error) {
 	return listStrings(ctx, func() ([]string, error) {
-		return common.MustGetSpaceManager().List(ctx)
+		spaces, err := common.MustGetSpaceManager().List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return readableSpaces(ctx, spaces), nil
 	})
 }
 
-// CreateSpace creates a specified space
+// readableSpaces filters spaces by read permission
+func readableSpaces(ctx context.Context, spaces []string) []string {
+	result := make([]string, 0, len(spaces))
+	for _, space := range spaces {
+		if authorize(ctx, space, auth.PermissionRead) == nil {
+			result = append(result, space)
+		}
+	}
+	return result
+}
+
+// CreateSpace creates a specified space. The body may be the info of space. Setting
+// the owner or visibility requires delete permission on the space
 func CreateSpace(ctx context.Context) (*models.Link, error) {
 	name, err := getSpaceName(ctx)
 	if err != nil {
 		return nil, err
 	}
-	_, err = common.MustGetSpaceManager().Create(ctx, name)
+	if err = authorize(ctx, name, auth.PermissionWrite); err != nil {
+		return nil, err
+	}
+	info, err := getSpaceInfo(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	if info != nil && (len(info.Owner) > 0 || info.Public()) {
+		if err = authorize(ctx, name, auth.PermissionDelete); err != nil {
+			return nil, err
+		}
+	}
+	space, err := common.MustGetSpaceManager().Create(ctx, name)
 	if err != nil {
 		return nil, err
 	}
+	if info != nil {
+		if err = space.SetInfo(ctx, info); err != nil {
+			return nil, err
+		}
+	}
 	link, err := getRequestPath(ctx)
 	if err != nil {
 		return nil, err
@@ -36,11 +77,126 @@ func CreateSpace(ctx context.Context) (*models.Link, error) {
 	return models.NewLink(name, path.Join(link, name)), nil
 }
 
+// FetchSpace fetches the info and usage of a space
+func FetchSpace(ctx context.Context) (*models.Space, error) {
+	name, err := getSpaceName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(ctx, name, auth.PermissionRead); err != nil {
+		return nil, err
+	}
+	space, err := getExistingSpace(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := space.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := quota.GetUsage(ctx, space)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.Space{
+		Name:       name,
+		Visibility: storage.VisibilityPrivate,
+		Charts:     usage.Charts,
+		Versions:   usage.Versions,
+		Bytes:      usage.Bytes,
+	}
+	if info != nil {
+		result.Description = info.Description
+		result.Owner = info.Owner
+		if info.Public() {
+			result.Visibility = storage.VisibilityPublic
+		}
+	}
+	return result, nil
+}
+
+// UpdateSpace replaces the info of a space. It requires write permission on the space,
+// and delete permission if the owner or visibility is changed
+func UpdateSpace(ctx context.Context) (*storage.SpaceInfo, error) {
+	name, err := getSpaceName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(ctx, name, auth.PermissionWrite); err != nil {
+		return nil, err
+	}
+	info, err := getSpaceInfo(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	space, err := getExistingSpace(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	current, err := space.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		current = &storage.SpaceInfo{}
+	}
+	if info.Owner != current.Owner || info.Public() != current.Public() {
+		if err = authorize(ctx, name, auth.PermissionDelete); err != nil {
+			return nil, err
+		}
+	}
+	if err = space.SetInfo(ctx, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// getSpaceInfo reads and validates the info of space in body. It returns nil if optional
+// is true and the body is empty
+func getSpaceInfo(ctx context.Context, optional bool) (*storage.SpaceInfo, error) {
+	data, err := readDataFromBody(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if optional && len(bytes.TrimSpace(data)) <= 0 {
+		return nil, nil
+	}
+	info := &storage.SpaceInfo{}
+	if err = json.Unmarshal(data, info); err != nil {
+		return nil, errors.ErrorParamTypeError.Format("body", "space info", "unknown")
+	}
+	if err = info.Validate(); err != nil {
+		return nil, errors.ErrorInvalidParam.Format("space info", err)
+	}
+	return info, nil
+}
+
+// getExistingSpace gets a space which exists
+func getExistingSpace(ctx context.Context, name string) (storage.Space, error) {
+	space, err := common.GetSpace(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if !space.Exists(ctx) {
+		return nil, errors.ErrorContentNotFound.Format(name)
+	}
+	return space, nil
+}
+
 // DeleteSpace deletes a specified space
 func DeleteSpace(ctx context.Context) error {
 	name, err := getSpaceName(ctx)
 	if err != nil {
 		return err
 	}
-	return common.MustGetSpaceManager().Delete(ctx, name)
+	if err = authorize(ctx, name, auth.PermissionDelete); err != nil {
+		return err
+	}
+	if immutability.HasImmutable(name) {
+		return errors.ErrorVersionUndeletable.Format(name)
+	}
+	err = common.MustGetSpaceManager().Delete(ctx, name)
+	search.Invalidate(name)
+	quota.Invalidate(name)
+	return err
 }
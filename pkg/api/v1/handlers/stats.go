@@ -0,0 +1,55 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/stats"
+)
+
+// FetchChartStats fetches download statistics of all versions in a chart
+func FetchChartStats(ctx context.Context) (*models.ChartStats, error) {
+	spaceName, chartName, err := getSpaceAndChartName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(ctx, spaceName, auth.PermissionRead); err != nil {
+		return nil, err
+	}
+	chart, err := common.GetChart(ctx, spaceName, chartName)
+	if err != nil {
+		return nil, err
+	}
+	if !chart.Exists(ctx) {
+		return nil, errors.ErrorContentNotFound.Format(spaceName + "/" + chartName)
+	}
+	numbers, err := chart.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.ChartStats{
+		Space:    spaceName,
+		Chart:    chartName,
+		Versions: make([]models.VersionStats, 0, len(numbers)),
+	}
+	for _, number := range numbers {
+		version, err := chart.Version(ctx, number)
+		if err != nil {
+			return nil, err
+		}
+		downloads, err := stats.Downloads(ctx, spaceName, chartName, version)
+		if err != nil {
+			return nil, err
+		}
+		result.Downloads += downloads
+		result.Versions = append(result.Versions, models.VersionStats{Version: number, Downloads: downloads})
+	}
+	return result, nil
+}
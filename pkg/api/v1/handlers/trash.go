@@ -0,0 +1,73 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/gc"
+	"github.com/caicloud/helm-registry/pkg/quota"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// ListTrash lists metadata of trashed versions in a space. They can be restored until
+// the trash is purged
+func ListTrash(ctx context.Context) (int, []*storage.Metadata, error) {
+	spaceName, err := getSpaceName(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err = authorize(ctx, spaceName, auth.PermissionRead); err != nil {
+		return 0, nil, err
+	}
+	start, limit, err := getPaging(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	space, err := common.GetSpace(ctx, spaceName)
+	if err != nil {
+		return 0, nil, err
+	}
+	metadata, err := space.TrashedVersionMetadata(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	total := len(metadata)
+	start, end := standardizeRange(total, start, limit)
+	return total, metadata[start:end], nil
+}
+
+// PurgeTrash permanently deletes trashed versions which are older than the retention period.
+// It purges all spaces, so the token of request must be able to delete in any space
+func PurgeTrash(ctx context.Context) error {
+	if err := authorize(ctx, auth.WildcardSpace, auth.PermissionDelete); err != nil {
+		return err
+	}
+	retention, err := getTrashRetention(ctx)
+	if err != nil {
+		return err
+	}
+	err = common.MustGetSpaceManager().PurgeTrash(ctx, time.Now().Add(-retention))
+	quota.InvalidateAll()
+	return err
+}
+
+// getTrashRetention gets retention period from query or config
+func getTrashRetention(ctx context.Context) (time.Duration, error) {
+	const field = "retention"
+	value, err := getQueryParameter(ctx, field)
+	if err != nil {
+		return gc.TrashRetention()
+	}
+	retention, err := time.ParseDuration(value)
+	if err != nil || retention < 0 {
+		return 0, errors.ErrorParamTypeError.Format(field, "duration", value)
+	}
+	return retention, nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/storage/simple"
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+)
+
+// trashTestVersion puts a version of chart in space and trashes it at deleted
+func trashTestVersion(t *testing.T, sm storage.SpaceManager, space, chart, version string, deleted time.Time) {
+	ctx := context.Background()
+	putTestVersion(t, space, chart, version, storagetest.NewArchive(t, chart, version))
+	c, err := common.GetChart(ctx, space, chart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Trash(ctx, version); err != nil {
+		t.Fatal(err)
+	}
+	key := "/" + space + "/.trash/" + chart + "/" + version + "/.deleted"
+	err = sm.(*simple.SpaceManager).Backend.PutContent(ctx, key, []byte(deleted.UTC().Format(time.RFC3339)))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// metadataVersions returns names and versions in metadata
+func metadataVersions(metadata []*storage.Metadata) []string {
+	versions := make([]string, 0, len(metadata))
+	for _, md := range metadata {
+		versions = append(versions, md.Name+"-"+md.Version)
+	}
+	return versions
+}
+
+func TestListTrash(t *testing.T) {
+	sm, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	now := time.Now()
+	trashTestVersion(t, sm, "library", "app", "1.0.0", now)
+	trashTestVersion(t, sm, "library", "app", "1.1.0", now)
+	trashTestVersion(t, sm, "library", "db", "1.0.0", now)
+	putTestVersion(t, "library", "app", "2.0.0", storagetest.NewArchive(t, "app", "2.0.0"))
+	trashTestVersion(t, sm, "other", "web", "1.0.0", now)
+
+	cases := []struct {
+		name     string
+		target   string
+		total    int
+		expected []string
+	}{
+		{"all", "/", 3, []string{"app-1.0.0", "app-1.1.0", "db-1.0.0"}},
+		{"page", "/?start=1&limit=1", 3, []string{"app-1.1.0"}},
+		{"out of range", "/?start=5", 3, []string{}},
+	}
+	for _, c := range cases {
+		ctx := newTestContext(http.MethodGet, c.target, "", map[string]string{"space": "library"})
+		total, metadata, err := ListTrash(ctx)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+		if total != c.total {
+			t.Errorf("%s: expected total %d, but got %d", c.name, c.total, total)
+		}
+		if versions := metadataVersions(metadata); !reflect.DeepEqual(versions, c.expected) {
+			t.Errorf("%s: expected trashed versions %v, but got %v", c.name, c.expected, versions)
+		}
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	sm, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	common.Set(common.ContextNameTrashRetention, "24h")
+	defer common.Set(common.ContextNameTrashRetention, "")
+	now := time.Now()
+	trashTestVersion(t, sm, "library", "app", "1.0.0", now.Add(-72*time.Hour))
+	trashTestVersion(t, sm, "library", "app", "1.1.0", now.Add(-36*time.Hour))
+	trashTestVersion(t, sm, "library", "app", "1.2.0", now.Add(-time.Hour))
+
+	listTrash := func() []string {
+		_, metadata, err := ListTrash(newTestContext(http.MethodGet, "/", "", map[string]string{"space": "library"}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return metadataVersions(metadata)
+	}
+	cases := []struct {
+		name     string
+		target   string
+		expected []string
+	}{
+		{"retention in query", "/?retention=48h", []string{"app-1.1.0", "app-1.2.0"}},
+		{"configured retention", "/", []string{"app-1.2.0"}},
+		{"no retention", "/?retention=0s", []string{}},
+	}
+	for _, c := range cases {
+		if err := PurgeTrash(newTestContext(http.MethodPost, c.target, "", nil)); err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+		if versions := listTrash(); !reflect.DeepEqual(versions, c.expected) {
+			t.Errorf("%s: expected trashed versions %v, but got %v", c.name, c.expected, versions)
+		}
+	}
+
+	err := PurgeTrash(newTestContext(http.MethodPost, "/?retention=-1h", "", nil))
+	expectErrorCode(t, "purge with a negative retention", err, http.StatusBadRequest)
+}
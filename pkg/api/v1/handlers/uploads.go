@@ -0,0 +1,239 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/metrics"
+	"github.com/caicloud/helm-registry/pkg/quota"
+	"github.com/caicloud/helm-registry/pkg/search"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/webhook"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// CreateUpload starts a resumable upload of a chart archive in a space
+func CreateUpload(ctx context.Context) (*models.Upload, error) {
+	space, err := getUploadSpace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	upload, err := space.CreateUpload(ctx)
+	if err != nil {
+		return nil, err
+	}
+	requestPath, err := getRequestPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewUpload(space.Name(), upload.ID(), 0, path.Join(requestPath, upload.ID())), nil
+}
+
+// FetchUpload returns the offset of an upload. A broken upload is resumed from the offset
+func FetchUpload(ctx context.Context) (*models.Upload, error) {
+	space, upload, err := getUpload(ctx)
+	if err != nil {
+		return nil, err
+	}
+	size, err := upload.Size(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return getUploadLink(ctx, space, upload, size)
+}
+
+// AppendUpload appends the body of request to an upload as a chunk. The body is streamed
+// to storage. If query param offset is set, it must be the offset of upload
+func AppendUpload(ctx context.Context) (*models.Upload, error) {
+	defer metrics.TrackUpload()()
+	space, upload, err := getUpload(ctx)
+	if err != nil {
+		return nil, err
+	}
+	request, err := getRequestFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// offset is read from url because parsing a form body would consume the chunk
+	if value, _ := getRawQueryParameter(ctx, "offset"); len(value) > 0 {
+		offset, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errors.ErrorParamTypeError.Format("offset", "int", value)
+		}
+		size, err := upload.Size(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if offset != size {
+			return nil, errors.ErrorInvalidStatus.Format("upload "+upload.ID(), fmt.Sprintf("offset is %d", size))
+		}
+	}
+	size, err := appendUpload(ctx, space, upload, request.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	return getUploadLink(ctx, space, upload, size)
+}
+
+// FinishUpload stores the archive received by an upload as a version. Query param digest
+// must be the sha256 digest of the archive. The body is an optional provenance file
+func FinishUpload(ctx context.Context) (*models.ChartLink, error) {
+	defer metrics.TrackUpload()()
+	space, upload, err := getUpload(ctx)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := getQueryParameter(ctx, "digest")
+	if err != nil {
+		return nil, err
+	}
+	digest, err := upload.Digest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimPrefix(expected, "sha256:") != digest {
+		return nil, errors.ErrorParamValueError.Format("digest", "sha256:"+digest, expected)
+	}
+	chrt, err := loadUploadChart(ctx, upload)
+	if err != nil {
+		return nil, err
+	}
+	metadata := chrt.Metadata
+	checks, err := checkUpload(ctx, space.Name(), chrt, upload)
+	if err != nil {
+		return nil, err
+	}
+	prov, err := readDataFromBody(ctx)
+	if err != nil {
+		return nil, err
+	}
+	verified, err := verifyProvenanceDigest(metadata, "sha256:"+digest, prov)
+	if err != nil {
+		return nil, err
+	}
+	_, chart, version, err := common.GetSpaceChartAndVersion(ctx, space.Name(), metadata.Name, metadata.Version)
+	if err != nil {
+		return nil, err
+	}
+	if version.Exists(ctx) {
+		return nil, errors.ErrorResourceExist.Format(fmt.Sprintf("%s/%s/%s", space.Name(), chart.Name(), version.Number()))
+	}
+	size, err := upload.Size(ctx)
+	if err != nil {
+		return nil, err
+	}
+	unlock := quota.Lock(space.Name())
+	defer unlock()
+	if err = quota.Check(ctx, space, chart, version, size); err != nil {
+		return nil, err
+	}
+	if err = version.PutUpload(ctx, upload); err != nil {
+		return nil, err
+	}
+	if len(prov) > 0 {
+		if err = version.PutProvenance(ctx, prov, verified); err != nil {
+			return nil, err
+		}
+	}
+	if err = checks.putReports(ctx, version); err != nil {
+		return nil, err
+	}
+	metrics.Count(metrics.OperationUpload, space.Name())
+	search.InvalidateChart(space.Name(), chart.Name())
+	webhook.Notify(space.Name(), chart.Name(), version.Number(), webhook.ActionCreate)
+	requestPath, err := getRequestPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the request path is .../spaces/{space}/uploads/{upload}
+	link := models.NewChartLink(space.Name(), metadata.Name, metadata.Version,
+		fmt.Sprintf("%s/charts/%s/versions/%s", path.Dir(path.Dir(requestPath)), metadata.Name, metadata.Version))
+	link.Lint = checks.lint
+	link.Scan = checks.scan
+	return link, nil
+}
+
+// CancelUpload cancels an upload and removes received data
+func CancelUpload(ctx context.Context) error {
+	_, upload, err := getUpload(ctx)
+	if err != nil {
+		return err
+	}
+	return upload.Delete(ctx)
+}
+
+// getUploadSpace gets an existing space from ctx and checks write permission on it
+func getUploadSpace(ctx context.Context) (storage.Space, error) {
+	spaceName, err := getSpaceName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(ctx, spaceName, auth.PermissionWrite); err != nil {
+		return nil, err
+	}
+	space, err := common.GetSpace(ctx, spaceName)
+	if err != nil {
+		return nil, err
+	}
+	if !space.Exists(ctx) {
+		return nil, errors.ErrorContentNotFound.Format(spaceName)
+	}
+	return space, nil
+}
+
+// getUpload gets an upload in progress from ctx and checks write permission on its space
+func getUpload(ctx context.Context) (storage.Space, storage.Upload, error) {
+	space, err := getUploadSpace(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	id, err := getPathParameter(ctx, "upload")
+	if err != nil {
+		return nil, nil, err
+	}
+	upload, err := space.Upload(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return space, upload, nil
+}
+
+// getUploadLink constructs a self-link of upload
+func getUploadLink(ctx context.Context, space storage.Space, upload storage.Upload, offset int64) (*models.Upload, error) {
+	requestPath, err := getRequestPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewUpload(space.Name(), upload.ID(), offset, requestPath), nil
+}
+
+// loadUploadChart loads the chart received by upload
+func loadUploadChart(ctx context.Context, upload storage.Upload) (*chart.Chart, error) {
+	reader, err := upload.Reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return getChartFromArchive(reader)
+}
+
+// checkUpload checks the chart received by upload
+func checkUpload(ctx context.Context, space string, chrt *chart.Chart, upload storage.Upload) (*chartChecks, error) {
+	reader, err := upload.Reader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return checkChart(ctx, space, chrt, reader)
+}
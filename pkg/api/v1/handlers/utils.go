@@ -12,6 +12,7 @@ import (
 
 	"github.com/caicloud/helm-registry/pkg/api/definition"
 	"github.com/caicloud/helm-registry/pkg/api/v1/types"
+	"github.com/caicloud/helm-registry/pkg/auth"
 	"github.com/caicloud/helm-registry/pkg/common"
 	"github.com/caicloud/helm-registry/pkg/errors"
 	"github.com/caicloud/helm-registry/pkg/storage"
@@ -27,6 +28,47 @@ func getRequestFromContext(ctx context.Context) (*restful.Request, error) {
 	return nil, errors.ErrorUnknownNotFoundError.Format(definition.KeyRequest)
 }
 
+// authorize checks whether the token of request has permission on space. Anonymous
+// requests which are unauthorized are asked for credentials
+func authorize(ctx context.Context, space string, permission auth.Permission) error {
+	request, err := getRequestFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	err = authorizeRequest(ctx, request, space, permission)
+	if errors.ErrorUnauthorized.Equal(err) {
+		setHeader(ctx, "WWW-Authenticate", auth.Challenge(request))
+	}
+	return err
+}
+
+// authorizeRequest checks whether the token of request has permission on space. Public
+// spaces can be read by all requests
+func authorizeRequest(ctx context.Context, request *restful.Request, space string, permission auth.Permission) error {
+	err := auth.Authorize(request, space, permission)
+	if err == nil || permission != auth.PermissionRead || !isPublic(ctx, space) {
+		return err
+	}
+	return nil
+}
+
+// isPublic returns whether space is a public space
+func isPublic(ctx context.Context, spaceName string) bool {
+	space, err := common.GetSpace(ctx, spaceName)
+	if err != nil {
+		return false
+	}
+	info, err := space.Info(ctx)
+	return err == nil && info.Public()
+}
+
+// setHeader sets a header of response in ctx
+func setHeader(ctx context.Context, name, value string) {
+	if resp, ok := ctx.Value(definition.KeyResponse).(*restful.Response); ok {
+		resp.Header().Set(name, value)
+	}
+}
+
 // getPathParameter gets value from request.PathParameter
 func getPathParameter(ctx context.Context, name string) (string, error) {
 	request, err := getRequestFromContext(ctx)
@@ -66,6 +108,38 @@ func getQueryParameter(ctx context.Context, name string) (string, error) {
 	return value, nil
 }
 
+// getRawQueryParameter gets value from the url of request. Unlike getQueryParameter,
+// it never parses a form body, so the body is left to be read as data
+func getRawQueryParameter(ctx context.Context, name string) (string, error) {
+	request, err := getRequestFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	value := request.Request.URL.Query().Get(name)
+	if len(value) <= 0 {
+		return "", errors.ErrorParamNotFound.Format(name)
+	}
+	return value, nil
+}
+
+// getBoolQueryParameter gets a bool value from request.QueryParameter.
+// It returns false if the param does not exist
+func getBoolQueryParameter(ctx context.Context, name string) (bool, error) {
+	request, err := getRequestFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	value := request.QueryParameter(name)
+	if len(value) <= 0 {
+		return false, nil
+	}
+	result, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.ErrorParamTypeError.Format(name, "bool", value)
+	}
+	return result, nil
+}
+
 // getSpaceName gets space name
 func getSpaceName(ctx context.Context) (string, error) {
 	const field = "space"
@@ -215,6 +289,72 @@ func getChartConfig(ctx context.Context) (*types.OrchestrationConfig, error) {
 	return config, err
 }
 
+// getComposeConfig gets a config for composing an umbrella chart
+func getComposeConfig(ctx context.Context) (*types.ComposeConfig, error) {
+	data, err := readDataFromBody(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config := &types.ComposeConfig{}
+	err = json.Unmarshal(data, config)
+	if err != nil {
+		return nil, errors.ErrorParamTypeError.Format("config", "compose config", "unknown")
+	}
+	if err = config.Validate(); err != nil {
+		return nil, err
+	}
+	space, err := getSpaceName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config.Save.Space = space
+	return config, nil
+}
+
+// getCopyConfig gets a config for copying version
+func getCopyConfig(ctx context.Context) (*types.CopyConfig, error) {
+	data, err := readDataFromBody(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config := &types.CopyConfig{}
+	err = json.Unmarshal(data, config)
+	if err != nil {
+		return nil, errors.ErrorParamTypeError.Format("config", "copy config", "unknown")
+	}
+	if err = config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// getPromoteConfig gets a copy config whose source is the version in path and target
+// is in the body of request
+func getPromoteConfig(ctx context.Context) (*types.CopyConfig, error) {
+	spaceName, chartName, versionNumber, err := getSpaceChartNameAndVersionNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readDataFromBody(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config := &types.CopyConfig{
+		Source: types.VersionSource{
+			Space:   spaceName,
+			Chart:   chartName,
+			Version: versionNumber,
+		},
+	}
+	if err = json.Unmarshal(data, &config.Target); err != nil {
+		return nil, errors.ErrorParamTypeError.Format("target", "version target", "unknown")
+	}
+	if err = config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
 // getMetadata gets metadata
 func getMetadata(ctx context.Context) (*storage.Metadata, error) {
 	data, err := readDataFromBody(ctx)
@@ -0,0 +1,74 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/api/definition"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+	"github.com/emicklei/go-restful"
+
+	_ "github.com/caicloud/helm-registry/pkg/storage/simple"
+)
+
+// newTestContext creates a handler context of a request with body, headers and path
+// params. Keys of headers are prefixed by "header:" in params
+func newTestContext(method, target, body string, params map[string]string) context.Context {
+	req := restful.NewRequest(httptest.NewRequest(method, target, strings.NewReader(body)))
+	for key, value := range params {
+		if strings.HasPrefix(key, "header:") {
+			req.Request.Header.Set(strings.TrimPrefix(key, "header:"), value)
+			continue
+		}
+		req.PathParameters()[key] = value
+	}
+	resp := restful.NewResponse(httptest.NewRecorder())
+	ctx := context.WithValue(context.Background(), definition.KeyRequest, req)
+	return context.WithValue(ctx, definition.KeyResponse, resp)
+}
+
+// putTestVersion creates space and puts archive as a version of chart in the global
+// SpaceManager
+func putTestVersion(t *testing.T, space, chart, version string, archive []byte) {
+	ctx := context.Background()
+	manager, err := common.GetSpaceManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err := manager.Space(ctx, space); err != nil || !s.Exists(ctx) {
+		if _, err = manager.Create(ctx, space); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v, err := common.GetVersion(ctx, space, chart, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = v.PutContent(ctx, archive); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestArchive creates an archive of chart with values
+func newTestArchive(t *testing.T, chart, version, values string) []byte {
+	return storagetest.NewArchiveFiles(t, map[string]string{
+		chart + "/Chart.yaml":  "apiVersion: v1\nname: " + chart + "\nversion: " + version + "\n",
+		chart + "/values.yaml": values,
+	})
+}
+
+// expectErrorCode checks that err is an error with code
+func expectErrorCode(t *testing.T, name string, err error, code int) {
+	errx, ok := err.(*errors.Error)
+	if !ok || errx.Code != code {
+		t.Errorf("%s: expected an error with code %d, but got %v", name, code, err)
+	}
+}
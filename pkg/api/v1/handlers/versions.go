@@ -8,62 +8,174 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
 
 	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/api/v1/types"
+	"github.com/caicloud/helm-registry/pkg/auth"
 	"github.com/caicloud/helm-registry/pkg/common"
 	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/immutability"
+	"github.com/caicloud/helm-registry/pkg/metrics"
+	"github.com/caicloud/helm-registry/pkg/orchestration"
+	"github.com/caicloud/helm-registry/pkg/provenance"
+	"github.com/caicloud/helm-registry/pkg/quota"
+	"github.com/caicloud/helm-registry/pkg/search"
+	"github.com/caicloud/helm-registry/pkg/stats"
 	"github.com/caicloud/helm-registry/pkg/storage"
-	"k8s.io/helm/pkg/chartutil"
+	"github.com/caicloud/helm-registry/pkg/webhook"
 	"k8s.io/helm/pkg/proto/hapi/chart"
 )
 
-// ListVersions lists versions in specified chart
+// ListVersions lists versions in specified chart in ascending semver order, or in
+// descending order if query param sort is desc
 func ListVersions(ctx context.Context) (int, []string, error) {
 	spaceName, chartName, err := getSpaceAndChartName(ctx)
 	if err != nil {
 		return 0, nil, err
 	}
+	if err = authorize(ctx, spaceName, auth.PermissionRead); err != nil {
+		return 0, nil, err
+	}
+	order, err := getSortOrder(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	selector, err := getSelector(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
 	chart, err := common.GetChart(ctx, spaceName, chartName)
 	if err != nil {
 		return 0, nil, err
 	}
 	return listStrings(ctx, func() ([]string, error) {
-		return chart.List(ctx)
+		versions, err := chart.List(ctx)
+		if err == nil && !selector.Empty() {
+			versions, err = filterVersionsBySelector(ctx, spaceName, chartName, selector)
+		}
+		if err != nil || order != sortDesc {
+			return versions, err
+		}
+		for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+			versions[i], versions[j] = versions[j], versions[i]
+		}
+		return versions, nil
 	})
 }
 
-// DownloadVersion handles a request for getting a version of chart
-func DownloadVersion(ctx context.Context) (data []byte, err error) {
+// filterVersionsBySelector returns versions of a chart in ascending order whose labels
+// match selector. Labels of a version include labels of its chart
+func filterVersionsBySelector(ctx context.Context, spaceName, chartName string, selector storage.Selector) ([]string, error) {
+	metadata, err := search.ChartMetadata(ctx, spaceName, chartName)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]string, 0, len(metadata))
+	for _, md := range filterMetadataBySelector(metadata, selector) {
+		versions = append(versions, md.Version)
+	}
+	return versions, nil
+}
+
+// chartContentType is the content type of chart archives
+const chartContentType = "application/x-gzip"
+
+// DownloadVersion handles a request for getting a version of chart. The archive is
+// streamed to response without loading it into memory. It responds with 304 if header
+// If-None-Match matches the etag of archive. A version of proxied space is pulled from
+// upstream if it doesn't exist
+func DownloadVersion(ctx context.Context) (reader io.ReadCloser, err error) {
 	err = managerHelper(ctx, func(space storage.Space, chart storage.Chart, version storage.Version) error {
-		data, err = version.GetContent(ctx)
+		if err := authorize(ctx, space.Name(), auth.PermissionRead); err != nil {
+			return err
+		}
+		if err := pullVersion(ctx, space.Name(), chart.Name(), version.Number()); err != nil {
+			return err
+		}
+		etag, err := archiveETag(ctx, version)
+		if err != nil {
+			return err
+		}
+		if err = checkNotModified(ctx, etag); err != nil {
+			return err
+		}
+		var size int64
+		reader, size, err = version.StreamContent(ctx)
+		if err != nil {
+			return err
+		}
+		setHeader(ctx, "Content-Type", chartContentType)
+		setHeader(ctx, "Content-Length", strconv.FormatInt(size, 10))
+		metrics.Count(metrics.OperationDownload, space.Name())
+		stats.CountDownload(space.Name(), chart.Name(), version.Number())
+		return nil
+	})
+	return
+}
+
+// FetchWithDependencies downloads a version of chart with its dependencies. Dependencies
+// in requirements.yaml or apiVersion v2 Chart.yaml are resolved from the same space and
+// packed under charts/.
+func FetchWithDependencies(ctx context.Context) (data []byte, err error) {
+	err = managerHelper(ctx, func(space storage.Space, chart storage.Chart, version storage.Version) error {
+		if err := authorize(ctx, space.Name(), auth.PermissionRead); err != nil {
+			return err
+		}
+		content, err := version.GetContent(ctx)
+		if err != nil {
+			return err
+		}
+		chrt, err := storage.LoadArchive(bytes.NewReader(content))
+		if err != nil {
+			return errors.ErrorInternalTypeError.Format(
+				fmt.Sprintf("%s/%s", chart.Name(), version.Number()), "chart", "unknown")
+		}
+		if err = orchestration.ResolveDependencies(ctx, space.Name(), chrt); err != nil {
+			return err
+		}
+		data, err = orchestration.Archive(chrt)
+		if err == nil {
+			metrics.Count(metrics.OperationDownload, space.Name())
+			stats.CountDownload(space.Name(), chart.Name(), version.Number())
+		}
 		return err
 	})
 	return
 }
 
 // UpdateVersion handles a request for updating a version of chart. Resource must exist
+// and must not be immutable
 func UpdateVersion(ctx context.Context) (*models.ChartLink, error) {
 	return putVersion(ctx, func(space storage.Space, chart storage.Chart, version storage.Version) error {
 		if !version.Exists(ctx) {
 			return errors.ErrorContentNotFound.Format(fmt.Sprintf("%s/%s/%s", space.Name(), chart.Name(), version.Number()))
 		}
-		return nil
+		return immutability.Check(ctx, space.Name(), chart.Name(), version)
 	})
 }
 
 // putVersion handles a version of chart from ctx. canSave is a function and decides whether
 // saves the version. If canSave returns nil, putVersion saves the version.
 func putVersion(ctx context.Context, canSave managerCallback) (link *models.ChartLink, errx error) {
+	defer metrics.TrackUpload()()
 	errx = managerHelper(ctx, func(space storage.Space, chart storage.Chart, version storage.Version) error {
+		if err := authorize(ctx, space.Name(), auth.PermissionWrite); err != nil {
+			return err
+		}
 		data, err := getChartFileData(ctx)
 		if err != nil {
 			return err
 		}
-		metadata, err := getMetadataFromArchiveData(data)
+		chrt, err := getChartFromArchiveData(data)
 		if err != nil {
 			return err
 		}
+		metadata := chrt.Metadata
 		// check chart name and version number
 		if metadata.Name != chart.Name() {
 			return errors.ErrorParamValueError.Format("chart", chart.Name(), metadata.Name)
@@ -71,32 +183,252 @@ func putVersion(ctx context.Context, canSave managerCallback) (link *models.Char
 		if metadata.Version != version.Number() {
 			return errors.ErrorParamValueError.Format("version", version.Number(), metadata.Version)
 		}
+		checks, err := checkChart(ctx, space.Name(), chrt, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		prov, err := getProvenanceFileData(ctx)
+		if err != nil {
+			return err
+		}
+		verified, err := verifyProvenance(metadata, data, prov)
+		if err != nil {
+			return err
+		}
 		// check whether can save
 		if err = canSave(space, chart, version); err != nil {
 			return err
 		}
-		err = version.PutContent(ctx, data)
+		unlock := quota.Lock(space.Name())
+		defer unlock()
+		if err = quota.Check(ctx, space, chart, version, int64(len(data))); err != nil {
+			return err
+		}
+		err = putContentAndProvenance(ctx, version, data, prov, verified)
 		if err != nil {
 			return err
 		}
+		if err = checks.putReports(ctx, version); err != nil {
+			return err
+		}
+		metrics.Count(metrics.OperationUpload, space.Name())
+		search.InvalidateChart(space.Name(), chart.Name())
+		webhook.Notify(space.Name(), chart.Name(), version.Number(), webhook.ActionUpdate)
 		// construct a chart self-link
 		path, err := getRequestPath(ctx)
 		if err != nil {
 			return err
 		}
 		link = models.NewChartLink(space.Name(), chart.Name(), version.Number(), path)
+		link.Lint = checks.lint
+		link.Scan = checks.scan
 		return nil
 	})
 	return
 }
 
-// DeleteVersion deletes specified version
+// DeleteVersion moves specified version to trash. It can be restored before purging trash
 func DeleteVersion(ctx context.Context) error {
 	return managerHelper(ctx, func(space storage.Space, chart storage.Chart, version storage.Version) error {
-		return chart.Delete(ctx, version.Number())
+		if err := authorize(ctx, space.Name(), auth.PermissionDelete); err != nil {
+			return err
+		}
+		if err := immutability.CheckDelete(space.Name(), chart.Name(), version.Number()); err != nil {
+			return err
+		}
+		if err := chart.Trash(ctx, version.Number()); err != nil {
+			return err
+		}
+		metrics.Count(metrics.OperationDelete, space.Name())
+		search.InvalidateChart(space.Name(), chart.Name())
+		webhook.Notify(space.Name(), chart.Name(), version.Number(), webhook.ActionDelete)
+		return nil
 	})
 }
 
+// RestoreVersion restores specified version from trash. If a version with same
+// number has been uploaded after deleting, the request will be rejected.
+func RestoreVersion(ctx context.Context) (link *models.ChartLink, err error) {
+	err = managerHelper(ctx, func(space storage.Space, chart storage.Chart, version storage.Version) error {
+		if err := authorize(ctx, space.Name(), auth.PermissionWrite); err != nil {
+			return err
+		}
+		if err := chart.Restore(ctx, version.Number()); err != nil {
+			return err
+		}
+		search.InvalidateChart(space.Name(), chart.Name())
+		webhook.Notify(space.Name(), chart.Name(), version.Number(), webhook.ActionCreate)
+		requestPath, err := getRequestPath(ctx)
+		if err != nil {
+			return err
+		}
+		link = models.NewChartLink(space.Name(), chart.Name(), version.Number(), path.Dir(requestPath))
+		return nil
+	})
+	return
+}
+
+// CopyVersion copies a version of chart to another space or chart. If the target
+// version exists, the request will be rejected unless overwrite is true.
+func CopyVersion(ctx context.Context) (*models.ChartLink, error) {
+	config, err := getCopyConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = copyVersion(ctx, config); err != nil {
+		return nil, err
+	}
+	return getCopyLink(ctx, config)
+}
+
+// MoveVersion moves a version of chart to another space or chart. The source
+// version is moved to trash after copying successfully, so it can be restored.
+func MoveVersion(ctx context.Context) (*models.ChartLink, error) {
+	config, err := getCopyConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(ctx, config.Source.Space, auth.PermissionDelete); err != nil {
+		return nil, err
+	}
+	err = immutability.CheckDelete(config.Source.Space, config.Source.Chart, config.Source.Version)
+	if err != nil {
+		return nil, err
+	}
+	if err = copyVersion(ctx, config); err != nil {
+		return nil, err
+	}
+	chart, err := common.GetChart(ctx, config.Source.Space, config.Source.Chart)
+	if err != nil {
+		return nil, err
+	}
+	if err = chart.Trash(ctx, config.Source.Version); err != nil {
+		return nil, err
+	}
+	search.InvalidateChart(config.Source.Space, config.Source.Chart)
+	webhook.Notify(config.Source.Space, config.Source.Chart, config.Source.Version, webhook.ActionDelete)
+	return getCopyLink(ctx, config)
+}
+
+// PromoteVersion copies a version of chart to another space. The target in body can
+// rename the chart and renumber the version. If the target version exists, the
+// request will be rejected unless overwrite is true.
+func PromoteVersion(ctx context.Context) (*models.ChartLink, error) {
+	config, err := getPromoteConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = copyVersion(ctx, config); err != nil {
+		return nil, err
+	}
+	return getCopyLink(ctx, config)
+}
+
+// copyVersion copies a version by config
+func copyVersion(ctx context.Context, config *types.CopyConfig) error {
+	overwrite, err := getBoolQueryParameter(ctx, "overwrite")
+	if err != nil {
+		return err
+	}
+	if err = authorize(ctx, config.Source.Space, auth.PermissionRead); err != nil {
+		return err
+	}
+	if err = authorize(ctx, config.Target.Space, auth.PermissionWrite); err != nil {
+		return err
+	}
+	source, err := common.GetVersion(ctx, config.Source.Space, config.Source.Chart, config.Source.Version)
+	if err != nil {
+		return err
+	}
+	space, chart, target, err := common.GetSpaceChartAndVersion(ctx, config.Target.Space, config.Target.Chart, config.Target.Version)
+	if err != nil {
+		return err
+	}
+	if !space.Exists(ctx) {
+		return errors.ErrorContentNotFound.Format(config.Target.Space)
+	}
+	if target.Exists(ctx) && !overwrite {
+		return errors.ErrorParamValueError.Format("target", "a nonexistent version unless overwrite is true", config.TargetPath())
+	}
+	if err = immutability.Check(ctx, config.Target.Space, config.Target.Chart, target); err != nil {
+		return err
+	}
+	data, err := source.GetContent(ctx)
+	if err != nil {
+		return err
+	}
+	var prov []byte
+	if config.Target.Chart != config.Source.Chart || config.Target.Version != config.Source.Version {
+		// chart name and version in metadata must be same as the target. The
+		// provenance of source chart can't verify a rewritten chart
+		if provenance.Enabled() {
+			return errors.ErrorUnverifiedProvenance.Format(config.TargetPath(), "a renamed or renumbered chart has no provenance")
+		}
+		data, err = rewriteChart(data, config.Target.Chart, config.Target.Version)
+		if err != nil {
+			return err
+		}
+	} else {
+		// a version may have no provenance
+		prov, _ = source.GetProvenance(ctx)
+	}
+	// the target space may have its own checks
+	chrt, err := getChartFromArchiveData(data)
+	if err != nil {
+		return err
+	}
+	// the provenance is verified again, so a target is never more trusted than an upload
+	verified, err := verifyProvenance(chrt.Metadata, data, prov)
+	if err != nil {
+		return err
+	}
+	checks, err := checkChart(ctx, config.Target.Space, chrt, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	unlock := quota.Lock(space.Name())
+	defer unlock()
+	if err = quota.Check(ctx, space, chart, target, int64(len(data))); err != nil {
+		return err
+	}
+	if err = putContentAndProvenance(ctx, target, data, prov, verified); err != nil {
+		return err
+	}
+	if err = checks.putReports(ctx, target); err != nil {
+		return err
+	}
+	search.InvalidateChart(config.Target.Space, config.Target.Chart)
+	webhook.Notify(config.Target.Space, config.Target.Chart, config.Target.Version, webhook.ActionCreate)
+	return nil
+}
+
+// getCopyLink constructs a chart self-link of the copy target
+func getCopyLink(ctx context.Context, config *types.CopyConfig) (*models.ChartLink, error) {
+	requestPath, err := getRequestPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// the request path is /{api}/copy, /{api}/move or /{api}/spaces/.../promote
+	root := path.Dir(requestPath)
+	if index := strings.Index(requestPath, "/spaces/"); index >= 0 {
+		root = requestPath[:index]
+	}
+	return models.NewChartLink(config.Target.Space, config.Target.Chart, config.Target.Version,
+		fmt.Sprintf("%s/spaces/%s/charts/%s/versions/%s", root,
+			config.Target.Space, config.Target.Chart, config.Target.Version)), nil
+}
+
+// rewriteChart changes the chart name and version in chart data
+func rewriteChart(data []byte, name, version string) ([]byte, error) {
+	chart, err := storage.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.ErrorInternalTypeError.Format(name, "chart", "unknown")
+	}
+	chart.Metadata.Name = name
+	chart.Metadata.Version = version
+	return orchestration.Archive(chart)
+}
+
 // getChartFileData gets chart file from ctx
 func getChartFileData(ctx context.Context) ([]byte, error) {
 	request, err := getRequestFromContext(ctx)
@@ -114,12 +446,19 @@ func getChartFileData(ctx context.Context) ([]byte, error) {
 	return data, nil
 }
 
-// getMetadataFromArchiveData gets metadata from chart data
-func getMetadataFromArchiveData(data []byte) (*chart.Metadata, error) {
-	// TODO(optimization): Need not load whole chart
-	chart, err := chartutil.LoadArchive(bytes.NewReader(data))
+// getChartFromArchiveData loads chart from chart data and validates its values
+func getChartFromArchiveData(data []byte) (*chart.Chart, error) {
+	return getChartFromArchive(bytes.NewReader(data))
+}
+
+// getChartFromArchive loads a chart from an archive and validates its values
+func getChartFromArchive(archive io.Reader) (*chart.Chart, error) {
+	chart, err := storage.LoadArchive(archive)
 	if err != nil {
 		return nil, errors.ErrorParamTypeError.Format(common.HTTPRequestUploadFileName, "chart", "unknown")
 	}
-	return chart.Metadata, nil
+	if err = validateChartValues(chart); err != nil {
+		return nil, err
+	}
+	return chart, nil
 }
@@ -0,0 +1,134 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/immutability"
+	"github.com/caicloud/helm-registry/pkg/provenance"
+	"github.com/caicloud/helm-registry/pkg/provenance/provenancetest"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+)
+
+func TestRestoreVersionConflict(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	putTestVersion(t, "library", "app", "1.0.0", storagetest.NewArchive(t, "app", "1.0.0"))
+	params := map[string]string{"space": "library", "chart": "app", "version": "1.0.0"}
+	if err := DeleteVersion(newTestContext(http.MethodDelete, "/", "", params)); err != nil {
+		t.Fatal(err)
+	}
+	putTestVersion(t, "library", "app", "1.0.0", storagetest.NewArchive(t, "app", "1.0.0"))
+
+	_, err := RestoreVersion(newTestContext(http.MethodPost, "/", "", params))
+	expectErrorCode(t, "restore over an uploaded version", err, http.StatusConflict)
+}
+
+func TestCopyVersionConflict(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	putTestVersion(t, "library", "app", "1.0.0", storagetest.NewArchive(t, "app", "1.0.0"))
+	putTestVersion(t, "production", "app", "1.0.0", storagetest.NewArchive(t, "app", "1.0.0"))
+	config := `{"source": {"space": "library", "chart": "app", "version": "1.0.0"},
+		"target": {"space": "production", "chart": "app", "version": "1.0.0"}}`
+
+	_, err := CopyVersion(newTestContext(http.MethodPost, "/", config, nil))
+	expectErrorCode(t, "copy to an existing version", err, http.StatusBadRequest)
+	_, err = CopyVersion(newTestContext(http.MethodPost, "/?overwrite=yes", config, nil))
+	expectErrorCode(t, "copy with an invalid overwrite", err, http.StatusBadRequest)
+}
+
+func TestCopyVersionProvenance(t *testing.T) {
+	manager, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	key := provenancetest.NewKey(t)
+	keyring, cleanupKeyring := provenancetest.WriteKeyring(t, key)
+	defer cleanupKeyring()
+	if err := provenance.Initialize(provenance.Config{Keyring: keyring}); err != nil {
+		t.Fatal(err)
+	}
+	defer provenance.Initialize(provenance.Config{})
+	ctx := context.Background()
+	if _, err := manager.Create(ctx, "production"); err != nil {
+		t.Fatal(err)
+	}
+	archive := storagetest.NewArchive(t, "app", "1.0.0")
+	putTestVersion(t, "library", "app", "1.0.0", archive)
+	putTestVersion(t, "library", "unsigned", "1.0.0", storagetest.NewArchive(t, "unsigned", "1.0.0"))
+	source, err := common.GetVersion(ctx, "library", "app", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prov := provenancetest.Sign(t, key, "name: app\nversion: 1.0.0", map[string]string{
+		"app-1.0.0.tgz": provenance.Digest(archive),
+	})
+	if err = source.PutProvenance(ctx, prov, true); err != nil {
+		t.Fatal(err)
+	}
+
+	copyConfig := func(chart, targetChart, targetVersion string) string {
+		return `{"source": {"space": "library", "chart": "` + chart + `", "version": "1.0.0"},
+			"target": {"space": "production", "chart": "` + targetChart + `", "version": "` + targetVersion + `"}}`
+	}
+	_, err = CopyVersion(newTestContext(http.MethodPost, "/", copyConfig("unsigned", "unsigned", "1.0.0"), nil))
+	expectErrorCode(t, "copy an unsigned version", err, http.StatusBadRequest)
+	_, err = CopyVersion(newTestContext(http.MethodPost, "/", copyConfig("app", "app", "2.0.0"), nil))
+	expectErrorCode(t, "copy a renumbered version", err, http.StatusBadRequest)
+	_, err = CopyVersion(newTestContext(http.MethodPost, "/", copyConfig("app", "renamed", "1.0.0"), nil))
+	expectErrorCode(t, "copy a renamed version", err, http.StatusBadRequest)
+	for _, name := range []string{"unsigned", "renamed"} {
+		if v, err := common.GetVersion(ctx, "production", name, "1.0.0"); err != nil || v.Exists(ctx) {
+			t.Errorf("expected production/%s/1.0.0 not to be copied", name)
+		}
+	}
+
+	if _, err = CopyVersion(newTestContext(http.MethodPost, "/", copyConfig("app", "app", "1.0.0"), nil)); err != nil {
+		t.Fatal(err)
+	}
+	target, err := common.GetVersion(ctx, "production", "app", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, err := target.ProvenanceStatus(ctx); err != nil || status != storage.ProvenanceVerified {
+		t.Errorf("expected verified provenance, but got %s, %v", status, err)
+	}
+}
+
+func TestDeleteImmutableVersion(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	if err := immutability.Initialize(immutability.Config{Charts: []string{"library/app"}}); err != nil {
+		t.Fatal(err)
+	}
+	defer immutability.Initialize(immutability.Config{})
+	putTestVersion(t, "library", "app", "1.0.0", storagetest.NewArchive(t, "app", "1.0.0"))
+	putTestVersion(t, "library", "db", "1.0.0", storagetest.NewArchive(t, "db", "1.0.0"))
+
+	params := map[string]string{"space": "library", "chart": "app", "version": "1.0.0"}
+	err := DeleteVersion(newTestContext(http.MethodDelete, "/", "", params))
+	expectErrorCode(t, "delete an immutable version", err, http.StatusConflict)
+	err = DeleteChart(newTestContext(http.MethodDelete, "/", "", map[string]string{"space": "library", "chart": "app"}))
+	expectErrorCode(t, "delete an immutable chart", err, http.StatusConflict)
+	err = DeleteSpace(newTestContext(http.MethodDelete, "/", "", map[string]string{"space": "library"}))
+	expectErrorCode(t, "delete a space with an immutable chart", err, http.StatusConflict)
+	config := `{"source": {"space": "library", "chart": "app", "version": "1.0.0"},
+		"target": {"space": "staging", "chart": "app", "version": "1.0.0"}}`
+	_, err = MoveVersion(newTestContext(http.MethodPost, "/", config, nil))
+	expectErrorCode(t, "move an immutable version", err, http.StatusConflict)
+	version, err := common.GetVersion(context.Background(), "library", "app", "1.0.0")
+	if err != nil || !version.Exists(context.Background()) {
+		t.Errorf("expected the immutable version to exist, but got %v", err)
+	}
+
+	params = map[string]string{"space": "library", "chart": "db", "version": "1.0.0"}
+	if err = DeleteVersion(newTestContext(http.MethodDelete, "/", "", params)); err != nil {
+		t.Errorf("expected a mutable version to be deleted, but got %v", err)
+	}
+}
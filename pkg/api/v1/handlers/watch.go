@@ -0,0 +1,233 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/watch"
+	"github.com/emicklei/go-restful"
+)
+
+// MIMEEventStream is the mime type of server-sent events
+const MIMEEventStream = "text/event-stream"
+
+const (
+	// DefaultWatchTimeout is the time to wait for changes when long polling
+	DefaultWatchTimeout = 30 * time.Second
+	// maxWatchTimeout is the max time to wait for changes when long polling
+	maxWatchTimeout = 5 * time.Minute
+)
+
+// watchHeartbeat is the period of comments which keep an idle event stream alive
+var watchHeartbeat = 15 * time.Second
+
+// Watch returns changes of versions after a resource version. Changes are streamed as
+// server-sent events if the request accepts text/event-stream, otherwise the request
+// waits until any change happens or timeout
+func Watch(ctx context.Context) (interface{}, error) {
+	request, err := getRequestFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	w, err := newWatcher(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(request.HeaderParameter("Accept"), MIMEEventStream) {
+		setHeader(ctx, "Content-Type", MIMEEventStream)
+		setHeader(ctx, "Cache-Control", "no-cache")
+		// proxies like nginx should not buffer events
+		setHeader(ctx, "X-Accel-Buffering", "no")
+		return &eventStream{w}, nil
+	}
+	timeout, err := getWatchTimeout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return w.poll(timeout)
+}
+
+// getWatchTimeout gets the time to wait for changes from query
+func getWatchTimeout(ctx context.Context) (time.Duration, error) {
+	const field = "timeout"
+	value, err := getQueryParameter(ctx, field)
+	if err != nil {
+		return DefaultWatchTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, errors.ErrorParamTypeError.Format(field, "duration", value)
+	}
+	if timeout > maxWatchTimeout {
+		timeout = maxWatchTimeout
+	}
+	return timeout, nil
+}
+
+// watcher returns events which can be read by a request
+type watcher struct {
+	ctx     context.Context
+	request *restful.Request
+	// space is the only space watched. All readable spaces are watched if it's empty
+	space string
+	// resourceVersion is the resource version of the last returned event
+	resourceVersion string
+	// readable caches whether spaces can be read by the token of request
+	readable map[string]bool
+}
+
+// newWatcher creates a watcher from query params space and resourceVersion. The resource
+// version can also be set by header Last-Event-ID, which is sent by reconnecting clients
+func newWatcher(ctx context.Context, request *restful.Request) (*watcher, error) {
+	space, err := getQueryParameter(ctx, "space")
+	if err == nil {
+		if err = authorize(ctx, space, auth.PermissionRead); err != nil {
+			return nil, err
+		}
+	}
+	resourceVersion, err := getQueryParameter(ctx, "resourceVersion")
+	if err != nil {
+		resourceVersion = request.HeaderParameter("Last-Event-ID")
+	}
+	// an invalid or expired resource version is rejected before responding
+	_, current, _, err := watch.Since(resourceVersion)
+	if err != nil {
+		return nil, err
+	}
+	if len(resourceVersion) <= 0 {
+		resourceVersion = current
+	}
+	return &watcher{
+		ctx:             ctx,
+		request:         request,
+		space:           space,
+		resourceVersion: resourceVersion,
+		readable:        make(map[string]bool),
+	}, nil
+}
+
+// next returns events after the resource version of watcher and advances it. The returned
+// channel is closed when newer events are published
+func (w *watcher) next() ([]*watch.Event, <-chan struct{}, error) {
+	events, current, changed, err := watch.Since(w.resourceVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	w.resourceVersion = current
+	result := make([]*watch.Event, 0, len(events))
+	for _, event := range events {
+		if w.visible(event) {
+			result = append(result, event)
+		}
+	}
+	return result, changed, nil
+}
+
+// visible returns whether event is in the watched space and can be read
+func (w *watcher) visible(event *watch.Event) bool {
+	if len(w.space) > 0 {
+		return event.Space == w.space
+	}
+	readable, ok := w.readable[event.Space]
+	if !ok {
+		readable = authorizeRequest(w.ctx, w.request, event.Space, auth.PermissionRead) == nil
+		w.readable[event.Space] = readable
+	}
+	return readable
+}
+
+// poll waits until there are events or timeout. It returns no event if the client is gone
+func (w *watcher) poll(timeout time.Duration) (*models.WatchResponse, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		events, changed, err := w.next()
+		if err != nil {
+			return nil, err
+		}
+		if len(events) > 0 {
+			return &models.WatchResponse{ResourceVersion: w.resourceVersion, Events: events}, nil
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return &models.WatchResponse{ResourceVersion: w.resourceVersion, Events: events}, nil
+		case <-w.request.Request.Context().Done():
+			return &models.WatchResponse{ResourceVersion: w.resourceVersion, Events: events}, nil
+		}
+	}
+}
+
+// eventStream writes events of a watcher as server-sent events until the client is gone.
+// It's copied to response by WriteTo, so every event can be flushed
+type eventStream struct {
+	watcher *watcher
+}
+
+// Read is not used because io.Copy prefers WriteTo
+func (s *eventStream) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+// WriteTo writes events to writer and flushes them
+func (s *eventStream) WriteTo(writer io.Writer) (int64, error) {
+	flusher, _ := writer.(http.Flusher)
+	written := int64(0)
+	write := func(format string, args ...interface{}) error {
+		n, err := fmt.Fprintf(writer, format, args...)
+		written += int64(n)
+		if err == nil && flusher != nil {
+			flusher.Flush()
+		}
+		return err
+	}
+	// the first comment sends response headers, so clients know the stream is established
+	if err := write(": watching from %s\n\n", s.watcher.resourceVersion); err != nil {
+		return written, err
+	}
+	ticker := time.NewTicker(watchHeartbeat)
+	defer ticker.Stop()
+	done := s.watcher.request.Request.Context().Done()
+	for {
+		events, changed, err := s.watcher.next()
+		if err != nil {
+			// the stream falls behind dropped events, and clients should list again
+			data, _ := json.Marshal(map[string]string{"message": err.Error()})
+			return written, write("event: error\ndata: %s\n\n", data)
+		}
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return written, err
+			}
+			if err = write("id: %s\nevent: %s\ndata: %s\n\n", event.ResourceVersion, event.Action, data); err != nil {
+				return written, err
+			}
+		}
+	wait:
+		for {
+			select {
+			case <-changed:
+				break wait
+			case <-ticker.C:
+				if err = write(": ping\n\n"); err != nil {
+					return written, err
+				}
+			case <-done:
+				return written, nil
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/webhook"
+)
+
+// FetchWebhooks fetches the webhooks of a space. Webhooks contain secrets, so it
+// requires delete permission
+func FetchWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	space, err := getWebhookSpace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return space.Webhooks(ctx)
+}
+
+// UpdateWebhooks replaces the webhooks of a space
+func UpdateWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	space, err := getWebhookSpace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := readDataFromBody(ctx)
+	if err != nil {
+		return nil, err
+	}
+	webhooks := make([]storage.Webhook, 0)
+	if err = json.Unmarshal(data, &webhooks); err != nil {
+		return nil, errors.ErrorParamTypeError.Format("body", "webhooks", "unknown")
+	}
+	for i := range webhooks {
+		if err = webhook.ValidateWebhook(&webhooks[i]); err != nil {
+			return nil, errors.ErrorInvalidParam.Format("webhook", err)
+		}
+	}
+	if err = space.SetWebhooks(ctx, webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhooks removes all webhooks of a space
+func DeleteWebhooks(ctx context.Context) error {
+	space, err := getWebhookSpace(ctx)
+	if err != nil {
+		return err
+	}
+	return space.SetWebhooks(ctx, nil)
+}
+
+// getWebhookSpace gets an existing space from ctx and checks delete permission on it
+func getWebhookSpace(ctx context.Context) (storage.Space, error) {
+	spaceName, err := getSpaceName(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = authorize(ctx, spaceName, auth.PermissionDelete); err != nil {
+		return nil, err
+	}
+	space, err := common.GetSpace(ctx, spaceName)
+	if err != nil {
+		return nil, err
+	}
+	if !space.Exists(ctx) {
+		return nil, errors.ErrorContentNotFound.Format(spaceName)
+	}
+	return space, nil
+}
@@ -7,6 +7,8 @@ package v1
 import (
 	"github.com/caicloud/helm-registry/pkg/api/definition"
 	"github.com/caicloud/helm-registry/pkg/api/v1/descriptor"
+	"github.com/caicloud/helm-registry/pkg/auth"
+	"github.com/caicloud/helm-registry/pkg/ratelimit"
 	"github.com/emicklei/go-restful"
 )
 
@@ -17,8 +19,25 @@ func InstallRouters(containers *restful.Container) *restful.WebService {
 		Path("/api/v1").
 		Doc("v1 API").
 		Consumes("*/*", "application/x-www-form-urlencoded", "multipart/form-data", restful.MIME_JSON, restful.MIME_XML).
-		Produces(restful.MIME_JSON, restful.MIME_XML)
+		Produces(restful.MIME_JSON, restful.MIME_XML).
+		Filter(ratelimit.Filter()).
+		Filter(auth.Filter())
 	service = definition.GenerateRoutes(service, descriptor.Descriptors)
 	containers.Add(service)
 	return service
 }
+
+// InstallOCIRouters installs OCI distribution api WebService, so OCI clients like helm 3
+// can push and pull charts
+func InstallOCIRouters(containers *restful.Container) *restful.WebService {
+	service := (&restful.WebService{}).
+		Path("/v2").
+		Doc("OCI distribution API").
+		Consumes("*/*").
+		Produces(restful.MIME_JSON, "*/*").
+		Filter(ratelimit.Filter()).
+		Filter(auth.BasicFilter())
+	service = definition.GenerateRoutes(service, descriptor.OCIDescriptors)
+	containers.Add(service)
+	return service
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package types
+
+import (
+	"fmt"
+
+	"github.com/caicloud/helm-registry/pkg/errors"
+)
+
+// Component describes a version of chart which is composed as a subchart
+type Component struct {
+	// Name is the name of subchart. If it's empty, use the chart name
+	Name string `json:"name,omitempty"`
+	// Source is where the version is stored
+	VersionSource `json:",inline"`
+	// Values override values of the version
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+// ComposeConfig describes a config for composing an umbrella chart from charts in registry
+type ComposeConfig struct {
+	// Save contains the info of the umbrella chart
+	Save Save `json:"save"`
+	// Components are subcharts of the umbrella chart
+	Components []Component `json:"components"`
+}
+
+// Validate validates whether the config is valid. Empty names of components are set
+// to their chart names
+func (cc *ComposeConfig) Validate() error {
+	if err := cc.Save.Validate(); err != nil {
+		return err
+	}
+	if len(cc.Components) <= 0 {
+		return errors.ErrorParamNotFound.Format("components")
+	}
+	names := make(map[string]bool, len(cc.Components))
+	for i := range cc.Components {
+		component := &cc.Components[i]
+		if err := component.VersionSource.Validate(); err != nil {
+			return err
+		}
+		if len(component.Name) <= 0 {
+			component.Name = component.Chart
+		}
+		if names[component.Name] {
+			return errors.ErrorInvalidParam.Format(fmt.Sprintf("components[%d].name", i),
+				"duplicated name "+component.Name)
+		}
+		names[component.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package types
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver"
+	"github.com/caicloud/helm-registry/pkg/errors"
+)
+
+// VersionSource describes where a version of chart is stored
+type VersionSource struct {
+	// Space name
+	Space string `json:"space"`
+	// Chart name
+	Chart string `json:"chart"`
+	// Version number
+	Version string `json:"version"`
+}
+
+// Validate validates whether the source is valid
+func (vs *VersionSource) Validate() error {
+	if len(vs.Space) <= 0 {
+		return errors.ErrorParamNotFound.Format("source.space")
+	}
+	if len(vs.Chart) <= 0 {
+		return errors.ErrorParamNotFound.Format("source.chart")
+	}
+	if len(vs.Version) <= 0 {
+		return errors.ErrorParamNotFound.Format("source.version")
+	}
+	return nil
+}
+
+// Path returns the path of source version
+func (vs *VersionSource) Path() string {
+	return fmt.Sprintf("%s/%s/%s", vs.Space, vs.Chart, vs.Version)
+}
+
+// VersionTarget describes where a version of chart will be stored
+type VersionTarget struct {
+	// Space name
+	Space string `json:"space"`
+	// Chart name. If it's empty, use the chart name of source
+	Chart string `json:"chart"`
+	// Version number. If it's empty, use the version number of source
+	Version string `json:"version,omitempty"`
+}
+
+// CopyConfig describes a config for copying a version of chart
+type CopyConfig struct {
+	// Source is the version to copy
+	Source VersionSource `json:"source"`
+	// Target is the destination of the copy
+	Target VersionTarget `json:"target"`
+}
+
+// Validate validates whether the config is valid and fills default values
+func (cc *CopyConfig) Validate() error {
+	if err := cc.Source.Validate(); err != nil {
+		return err
+	}
+	if len(cc.Target.Space) <= 0 {
+		return errors.ErrorParamNotFound.Format("target.space")
+	}
+	if len(cc.Target.Chart) <= 0 {
+		cc.Target.Chart = cc.Source.Chart
+	}
+	if len(cc.Target.Version) <= 0 {
+		cc.Target.Version = cc.Source.Version
+	} else if _, err := semver.NewVersion(cc.Target.Version); err != nil {
+		return errors.ErrorParamValueError.Format("target.version", "a semantic version", cc.Target.Version)
+	}
+	if cc.Source.Space == cc.Target.Space && cc.Source.Chart == cc.Target.Chart && cc.Source.Version == cc.Target.Version {
+		return errors.ErrorParamValueError.Format("target", "a different space, chart or version", cc.TargetPath())
+	}
+	return nil
+}
+
+// TargetPath returns the path of target version
+func (cc *CopyConfig) TargetPath() string {
+	return fmt.Sprintf("%s/%s/%s", cc.Target.Space, cc.Target.Chart, cc.Target.Version)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package types
+
+import (
+	"strings"
+
+	"github.com/caicloud/helm-registry/pkg/errors"
+)
+
+// Media types of charts in OCI registries
+const (
+	// MediaTypeOCIManifest is the media type of OCI image manifests
+	MediaTypeOCIManifest = "application/vnd.oci.image.manifest.v1+json"
+	// MediaTypeHelmConfig is the media type of chart metadata in json
+	MediaTypeHelmConfig = "application/vnd.cncf.helm.config.v1+json"
+	// MediaTypeHelmChart is the media type of chart archives
+	MediaTypeHelmChart = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+	// MediaTypeHelmProvenance is the media type of provenance files
+	MediaTypeHelmProvenance = "application/vnd.cncf.helm.chart.provenance.v1.prov"
+)
+
+// OCIDescriptor describes a blob referenced by an OCI manifest
+type OCIDescriptor struct {
+	// MediaType is the media type of blob
+	MediaType string `json:"mediaType"`
+	// Digest is the digest of blob, like "sha256:<hex>"
+	Digest string `json:"digest"`
+	// Size is the size of blob
+	Size int64 `json:"size"`
+}
+
+// Hex returns the hex of a sha256 digest. It returns an empty string if the digest
+// is not a sha256 digest
+func (d *OCIDescriptor) Hex() string {
+	if !strings.HasPrefix(d.Digest, "sha256:") {
+		return ""
+	}
+	return strings.TrimPrefix(d.Digest, "sha256:")
+}
+
+// OCIManifest is an OCI image manifest of a version
+type OCIManifest struct {
+	// SchemaVersion must be 2
+	SchemaVersion int `json:"schemaVersion"`
+	// MediaType is the media type of manifest
+	MediaType string `json:"mediaType,omitempty"`
+	// Config describes the blob of chart metadata
+	Config OCIDescriptor `json:"config"`
+	// Layers describe the blobs of chart archive and provenance file
+	Layers []OCIDescriptor `json:"layers"`
+	// Annotations are optional annotations of manifest
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Layer returns the first layer with specific media type. It returns nil if there is no such layer
+func (m *OCIManifest) Layer(mediaType string) *OCIDescriptor {
+	for i := range m.Layers {
+		if m.Layers[i].MediaType == mediaType {
+			return &m.Layers[i]
+		}
+	}
+	return nil
+}
+
+// Validate validates whether the manifest is a manifest of chart
+func (m *OCIManifest) Validate() error {
+	if m.SchemaVersion != 2 {
+		return errors.ErrorParamValueError.Format("schemaVersion", "2", m.SchemaVersion)
+	}
+	if len(m.Config.Hex()) <= 0 {
+		return errors.ErrorParamValueError.Format("config.digest", "a sha256 digest", m.Config.Digest)
+	}
+	chart := m.Layer(MediaTypeHelmChart)
+	if chart == nil {
+		return errors.ErrorParamNotFound.Format("layer " + MediaTypeHelmChart)
+	}
+	for _, layer := range m.Layers {
+		if len(layer.Hex()) <= 0 {
+			return errors.ErrorParamValueError.Format("layer digest", "a sha256 digest", layer.Digest)
+		}
+	}
+	return nil
+}
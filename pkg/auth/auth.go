@@ -0,0 +1,253 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/emicklei/go-restful"
+)
+
+// Permission is the type of an operation on a space
+type Permission string
+
+const (
+	// PermissionRead allows reading charts in a space
+	PermissionRead Permission = "read"
+	// PermissionWrite allows creating and updating charts in a space
+	PermissionWrite Permission = "write"
+	// PermissionDelete allows deleting charts in a space
+	PermissionDelete Permission = "delete"
+)
+
+// Role is a named set of permissions
+type Role string
+
+const (
+	// RoleRead can read charts in a space
+	RoleRead Role = "read"
+	// RolePush can read and push charts to a space
+	RolePush Role = "push"
+	// RoleAdmin can do all operations in a space
+	RoleAdmin Role = "admin"
+)
+
+// rolePermissions maps roles to their permissions
+var rolePermissions = map[Role][]Permission{
+	RoleRead:  {PermissionRead},
+	RolePush:  {PermissionRead, PermissionWrite},
+	RoleAdmin: {PermissionRead, PermissionWrite, PermissionDelete},
+}
+
+// Permissions returns permissions of the role. It returns nil if the role is unknown
+func (r Role) Permissions() []Permission {
+	return rolePermissions[r]
+}
+
+// WildcardSpace matches all spaces in grants. Operations which are not scoped to a
+// space, like purging trash, require a grant on it
+const WildcardSpace = "*"
+
+// attributeGrants is the attribute name of request which stores grants of the token
+const attributeGrants = "auth.grants"
+
+// attributeChallenge is the attribute name of request which stores the challenge of an
+// anonymous request
+const attributeChallenge = "auth.challenge"
+
+// Grants maps space names to permissions. Space `*` matches all spaces
+type Grants map[string]map[Permission]bool
+
+// Grant adds permissions on space
+func (g Grants) Grant(space string, permissions ...Permission) {
+	if g[space] == nil {
+		g[space] = make(map[Permission]bool, len(permissions))
+	}
+	for _, permission := range permissions {
+		g[space][permission] = true
+	}
+}
+
+// Allows returns whether permission on space is granted
+func (g Grants) Allows(space string, permission Permission) bool {
+	return g[space][permission] || g[WildcardSpace][permission]
+}
+
+// Config is a config of authorization. If there are no tokens, certificates or
+// authenticators, authorization is disabled
+type Config struct {
+	// Tokens are static tokens
+	Tokens []Token `yaml:"tokens"`
+	// Certificates map client certificates to grants. They require client CAs in TLS config
+	Certificates []Certificate `yaml:"certificates"`
+	// Authenticators authenticate tokens which are not static tokens, in order
+	Authenticators []AuthenticatorConfig `yaml:"authenticators"`
+}
+
+// AuthenticatorConfig is a config of a registered authenticator
+type AuthenticatorConfig struct {
+	// Name is the registered name of authenticator
+	Name string `yaml:"name"`
+	// Parameters are passed to the factory of authenticator
+	Parameters map[string]interface{} `yaml:"parameters"`
+}
+
+// authenticators authenticate tokens in order. It's nil if authorization is disabled
+var authenticators []Authenticator
+
+// Initialize creates authenticators in config
+func Initialize(config Config) error {
+	result := make([]Authenticator, 0, len(config.Authenticators)+1)
+	if len(config.Tokens) > 0 {
+		static, err := newStaticAuthenticator(config.Tokens)
+		if err != nil {
+			return err
+		}
+		result = append(result, static)
+	}
+	for _, c := range config.Authenticators {
+		authenticator, err := Create(c.Name, c.Parameters)
+		if err != nil {
+			return fmt.Errorf("can't create authenticator %s: %v", c.Name, err)
+		}
+		result = append(result, authenticator)
+	}
+	certs, err := newCertificates(config.Certificates)
+	if err != nil {
+		return err
+	}
+	if len(result) <= 0 && len(certs) <= 0 {
+		// authorization is disabled
+		authenticators = nil
+		certificates = nil
+		return nil
+	}
+	authenticators = result
+	certificates = certs
+	log.Infof("Authorizing requests with %d static tokens, %d certificates and %d authenticators",
+		len(config.Tokens), len(config.Certificates), len(config.Authenticators))
+	return nil
+}
+
+// Enabled returns whether requests should be authorized
+func Enabled() bool {
+	return authenticators != nil
+}
+
+// Filter rejects requests without a valid bearer token or client certificate with 401.
+// Grants of the token or certificate are stored in request for Authorize. GET and HEAD
+// requests without credentials are passed as anonymous requests, which may only read
+// public spaces
+func Filter() restful.FilterFunction {
+	return filter("Bearer")
+}
+
+// BasicFilter is like Filter, but asks for basic credentials whose password is a token.
+// Clients like `helm registry login` only send basic credentials
+func BasicFilter() restful.FilterFunction {
+	return filter(`Basic realm="helm-registry"`)
+}
+
+// filter rejects requests without a valid token and asks for a token by challenge
+func filter(challenge string) restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		if !Enabled() {
+			chain.ProcessFilter(req, resp)
+			return
+		}
+		token, ok := requestToken(req)
+		if !ok {
+			// a request without token may be authenticated by its client certificate
+			if g, ok := certificateGrants(req); ok {
+				req.SetAttribute(attributeGrants, g)
+				chain.ProcessFilter(req, resp)
+				return
+			}
+			if req.Request.Method == http.MethodGet || req.Request.Method == http.MethodHead {
+				req.SetAttribute(attributeChallenge, challenge)
+				chain.ProcessFilter(req, resp)
+				return
+			}
+			unauthorized(resp, challenge, errors.ErrorUnauthorized.Format("token is required"))
+			return
+		}
+		ctx := req.Request.Context()
+		for _, authenticator := range authenticators {
+			g, ok, err := authenticator.Authenticate(ctx, token)
+			if err != nil {
+				log.FromContext(ctx).Errorf("Failed to authenticate token: %v", err)
+				writeError(resp, errors.ErrorInternalUnknown.Format(err))
+				return
+			}
+			if ok {
+				req.SetAttribute(attributeGrants, g)
+				chain.ProcessFilter(req, resp)
+				return
+			}
+		}
+		unauthorized(resp, challenge, errors.ErrorUnauthorized.Format("token is invalid"))
+	}
+}
+
+// requestToken returns the bearer token of request, or the password of basic credentials
+func requestToken(req *restful.Request) (string, bool) {
+	header := req.HeaderParameter("Authorization")
+	const prefix = "Bearer "
+	if strings.HasPrefix(header, prefix) {
+		return strings.TrimSpace(strings.TrimPrefix(header, prefix)), true
+	}
+	if _, password, ok := req.Request.BasicAuth(); ok {
+		return password, true
+	}
+	return "", false
+}
+
+// unauthorized responds with err and asks for a token by challenge
+func unauthorized(resp *restful.Response, challenge string, err *errors.Error) {
+	resp.Header().Set("WWW-Authenticate", challenge)
+	writeError(resp, err)
+}
+
+// writeError responds with err
+func writeError(resp *restful.Response, err *errors.Error) {
+	resp.WriteHeaderAndEntity(err.Code, map[string]string{
+		"message": err.Message,
+		"reason":  err.Reason,
+	})
+}
+
+// Authorize returns ErrorForbidden if the token of request has no permission on space,
+// or ErrorUnauthorized if the request is anonymous. It always returns nil if authorization
+// is disabled
+func Authorize(req *restful.Request, space string, permission Permission) error {
+	if !Enabled() {
+		return nil
+	}
+	g, _ := req.Attribute(attributeGrants).(Grants)
+	if g.Allows(space, permission) {
+		return nil
+	}
+	if Anonymous(req) {
+		return errors.ErrorUnauthorized.Format("token is required")
+	}
+	return errors.ErrorForbidden.Format(permission, space)
+}
+
+// Anonymous returns whether request is passed by filters without credentials
+func Anonymous(req *restful.Request) bool {
+	return len(Challenge(req)) > 0
+}
+
+// Challenge returns the challenge which asks an anonymous request for credentials. It
+// should be sent in the WWW-Authenticate header with ErrorUnauthorized. It returns an
+// empty string if the request is not anonymous
+func Challenge(req *restful.Request) string {
+	challenge, _ := req.Attribute(attributeChallenge).(string)
+	return challenge
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/emicklei/go-restful"
+)
+
+func TestFilterAnonymous(t *testing.T) {
+	if err := Initialize(Config{Tokens: []Token{{Token: "secret", Roles: map[string]Role{"team": RoleRead}}}}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { authenticators = nil }()
+
+	serve := func(method, token string) (*restful.Request, int) {
+		r := httptest.NewRequest(method, "/", nil)
+		if len(token) > 0 {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		req := restful.NewRequest(r)
+		recorder := httptest.NewRecorder()
+		var passed *restful.Request
+		chain := &restful.FilterChain{
+			Filters: []restful.FilterFunction{Filter()},
+			Target: func(req *restful.Request, resp *restful.Response) {
+				passed = req
+			},
+		}
+		resp := restful.NewResponse(recorder)
+		resp.SetRequestAccepts(restful.MIME_JSON)
+		chain.ProcessFilter(req, resp)
+		return passed, recorder.Code
+	}
+
+	req, _ := serve(http.MethodGet, "")
+	if req == nil || !Anonymous(req) || Challenge(req) != "Bearer" {
+		t.Fatalf("GET request without token should be passed as an anonymous request")
+	}
+	if err := Authorize(req, "team", PermissionRead); !errors.ErrorUnauthorized.Equal(err) {
+		t.Errorf("anonymous request should be unauthorized, but got %v", err)
+	}
+	if req, code := serve(http.MethodPost, ""); req != nil || code != http.StatusUnauthorized {
+		t.Errorf("POST request without token should be rejected, but got %d", code)
+	}
+	if req, code := serve(http.MethodGet, "invalid"); req != nil || code != http.StatusUnauthorized {
+		t.Errorf("request with an invalid token should be rejected, but got %d", code)
+	}
+	req, _ = serve(http.MethodGet, "secret")
+	if req == nil || Anonymous(req) {
+		t.Fatalf("request with a valid token should not be anonymous")
+	}
+	if err := Authorize(req, "team", PermissionRead); err != nil {
+		t.Errorf("token should read team, but got %v", err)
+	}
+	if err := Authorize(req, "other", PermissionRead); !errors.ErrorForbidden.Equal(err) {
+		t.Errorf("token should be forbidden to read other, but got %v", err)
+	}
+}
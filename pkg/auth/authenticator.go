@@ -0,0 +1,57 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Authenticator authenticates bearer tokens. Authenticators other than static tokens,
+// like OIDC, are registered by Register and enabled in config
+type Authenticator interface {
+	// Authenticate returns grants of token. It returns false if the token is not
+	// known by the authenticator, and the next authenticator will be tried
+	Authenticate(ctx context.Context, token string) (Grants, bool, error)
+}
+
+// AuthenticatorFactory is a factory for creating Authenticator
+type AuthenticatorFactory interface {
+	// Create creates a new Authenticator
+	Create(map[string]interface{}) (Authenticator, error)
+}
+
+var (
+	// factoriesMu is used for protecting factories
+	factoriesMu sync.RWMutex
+	// factories stores all registered AuthenticatorFactory
+	factories = make(map[string]AuthenticatorFactory)
+)
+
+// Register registers an AuthenticatorFactory
+func Register(name string, factory AuthenticatorFactory) {
+	if factory == nil {
+		panic("Must not provide nil AuthenticatorFactory")
+	}
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	_, registered := factories[name]
+	if registered {
+		panic(fmt.Sprintf("AuthenticatorFactory named %s already registered", name))
+	}
+	factories[name] = factory
+}
+
+// Create creates a new Authenticator with the given name and parameters.
+func Create(name string, parameters map[string]interface{}) (Authenticator, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("AuthenticatorFactory not registered: %s", name)
+	}
+	return factory.Create(parameters)
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package auth
+
+import (
+	"fmt"
+
+	"github.com/emicklei/go-restful"
+)
+
+// Certificate maps a client certificate to grants. A request without token is
+// authenticated by its client certificate if the certificate is verified by the client
+// CAs of the server and its subject common name is CommonName
+type Certificate struct {
+	// CommonName is the subject common name of client certificates
+	CommonName string `yaml:"commonName"`
+	// Roles maps space names to roles. Space `*` matches all spaces
+	Roles map[string]Role `yaml:"roles"`
+	// Spaces maps space names to permissions. It's merged with Roles
+	Spaces map[string][]Permission `yaml:"spaces"`
+}
+
+// certificates maps common names of client certificates to their grants
+var certificates map[string]Grants
+
+// newCertificates creates grants of certificates by common names
+func newCertificates(certs []Certificate) (map[string]Grants, error) {
+	result := make(map[string]Grants, len(certs))
+	for i, cert := range certs {
+		if len(cert.CommonName) <= 0 {
+			return nil, fmt.Errorf("common name of certificate %d is empty", i)
+		}
+		if _, ok := result[cert.CommonName]; ok {
+			return nil, fmt.Errorf("certificate %d is duplicated", i)
+		}
+		g, err := newGrants(cert.Roles, cert.Spaces)
+		if err != nil {
+			return nil, fmt.Errorf("%v in certificate %d", err, i)
+		}
+		result[cert.CommonName] = g
+	}
+	return result, nil
+}
+
+// certificateGrants returns grants of the verified client certificate of request
+func certificateGrants(req *restful.Request) (Grants, bool) {
+	state := req.Request.TLS
+	if state == nil || len(state.VerifiedChains) <= 0 || len(state.VerifiedChains[0]) <= 0 {
+		return nil, false
+	}
+	g, ok := certificates[state.VerifiedChains[0][0].Subject.CommonName]
+	return g, ok
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emicklei/go-restful"
+)
+
+func TestCertificateGrants(t *testing.T) {
+	certs, err := newCertificates([]Certificate{
+		{CommonName: "ci", Roles: map[string]Role{"team": RolePush}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificates = certs
+	defer func() { certificates = nil }()
+
+	request := func(cn string, verified bool) *restful.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		if verified {
+			r.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+		}
+		return restful.NewRequest(r)
+	}
+	g, ok := certificateGrants(request("ci", true))
+	if !ok {
+		t.Fatalf("verified certificate should be authenticated")
+	}
+	if !g.Allows("team", PermissionWrite) || g.Allows("team", PermissionDelete) {
+		t.Errorf("unexpected grants of certificate: %v", g)
+	}
+	if _, ok = certificateGrants(request("ci", false)); ok {
+		t.Errorf("unverified certificate should not be authenticated")
+	}
+	if _, ok = certificateGrants(request("other", true)); ok {
+		t.Errorf("unknown certificate should not be authenticated")
+	}
+	if _, err = newCertificates([]Certificate{{CommonName: "ci"}, {CommonName: "ci"}}); err == nil {
+		t.Errorf("duplicated certificate should be rejected")
+	}
+}
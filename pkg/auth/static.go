@@ -0,0 +1,89 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+)
+
+// Token is a static bearer token and its grants
+type Token struct {
+	// Token is the bearer token in header `Authorization: Bearer <token>`
+	Token string `yaml:"token"`
+	// Roles maps space names to roles. Space `*` matches all spaces
+	Roles map[string]Role `yaml:"roles"`
+	// Spaces maps space names to permissions. It's merged with Roles
+	Spaces map[string][]Permission `yaml:"spaces"`
+}
+
+// staticToken is a static token and its grants
+type staticToken struct {
+	token  []byte
+	grants Grants
+}
+
+// staticAuthenticator authenticates static tokens in config
+type staticAuthenticator struct {
+	tokens []staticToken
+}
+
+// newStaticAuthenticator creates an authenticator of tokens
+func newStaticAuthenticator(tokens []Token) (*staticAuthenticator, error) {
+	result := make([]staticToken, 0, len(tokens))
+	seen := make(map[string]bool, len(tokens))
+	for i, token := range tokens {
+		if len(token.Token) <= 0 {
+			return nil, fmt.Errorf("token %d is empty", i)
+		}
+		if seen[token.Token] {
+			return nil, fmt.Errorf("token %d is duplicated", i)
+		}
+		seen[token.Token] = true
+		g, err := newGrants(token.Roles, token.Spaces)
+		if err != nil {
+			return nil, fmt.Errorf("%v in token %d", err, i)
+		}
+		result = append(result, staticToken{[]byte(token.Token), g})
+	}
+	return &staticAuthenticator{result}, nil
+}
+
+// Authenticate returns grants of a static token. Tokens are compared in constant time,
+// and all of them are compared, so the time doesn't tell which token is close to match
+func (a *staticAuthenticator) Authenticate(ctx context.Context, token string) (Grants, bool, error) {
+	var g Grants
+	ok := false
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare(t.token, []byte(token)) == 1 {
+			g, ok = t.grants, true
+		}
+	}
+	return g, ok, nil
+}
+
+// newGrants creates grants of roles and permissions on spaces
+func newGrants(roles map[string]Role, spaces map[string][]Permission) (Grants, error) {
+	g := make(Grants, len(roles)+len(spaces))
+	for space, role := range roles {
+		permissions := role.Permissions()
+		if permissions == nil {
+			return nil, fmt.Errorf("unknown role %s of space %s", role, space)
+		}
+		g.Grant(space, permissions...)
+	}
+	for space, permissions := range spaces {
+		for _, permission := range permissions {
+			switch permission {
+			case PermissionRead, PermissionWrite, PermissionDelete:
+				g.Grant(space, permission)
+			default:
+				return nil, fmt.Errorf("unknown permission %s of space %s", permission, space)
+			}
+		}
+	}
+	return g, nil
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package auth
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStaticAuthenticator(t *testing.T) {
+	authenticator, err := newStaticAuthenticator([]Token{
+		{Token: "admin", Roles: map[string]Role{WildcardSpace: RoleAdmin}},
+		{Token: "team", Roles: map[string]Role{"team": RolePush, "library": RoleRead}, Spaces: map[string][]Permission{"team": {PermissionDelete}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		token      string
+		space      string
+		permission Permission
+		allowed    bool
+	}{
+		{"admin", "any", PermissionDelete, true},
+		{"team", "team", PermissionWrite, true},
+		{"team", "team", PermissionDelete, true},
+		{"team", "library", PermissionRead, true},
+		{"team", "library", PermissionWrite, false},
+		{"team", "other", PermissionRead, false},
+	}
+	for _, c := range cases {
+		g, ok, err := authenticator.Authenticate(context.Background(), c.token)
+		if err != nil || !ok {
+			t.Fatalf("token %s should be authenticated: %v", c.token, err)
+		}
+		if g.Allows(c.space, c.permission) != c.allowed {
+			t.Errorf("expected %s on %s by %s to be %v", c.permission, c.space, c.token, c.allowed)
+		}
+	}
+	for _, token := range []string{"unknown", "", "admi", "admin2"} {
+		if _, ok, _ := authenticator.Authenticate(context.Background(), token); ok {
+			t.Errorf("unknown token %q should not be authenticated", token)
+		}
+	}
+	if _, err = newStaticAuthenticator([]Token{{Token: "t", Roles: map[string]Role{"team": "owner"}}}); err == nil {
+		t.Errorf("unknown role should be rejected")
+	}
+}
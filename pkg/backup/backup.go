@@ -0,0 +1,541 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// FormatVersion is the version of backup format
+const FormatVersion = 1
+
+// manifestName is the name of the manifest in a backup. It's the first file of backup
+const manifestName = "manifest.json"
+
+// names of version files in a backup
+const (
+	archiveName     = "chart.tgz"
+	provenanceName  = "chart.tgz.prov"
+	ociManifestName = "oci.manifest"
+	ociConfigName   = "oci.config"
+)
+
+// Manifest describes all data in a backup
+type Manifest struct {
+	// Version is the version of backup format
+	Version int `json:"version"`
+	// CreatedAt is the time when the backup is created
+	CreatedAt time.Time `json:"createdAt"`
+	// Spaces are all spaces in the backup
+	Spaces []Space `json:"spaces"`
+}
+
+// Space describes a space in a backup
+type Space struct {
+	// Name is the name of space
+	Name string `json:"name"`
+	// Info is the description, owner and visibility of space
+	Info *storage.SpaceInfo `json:"info,omitempty"`
+	// Retention is the retention policy of space
+	Retention *storage.RetentionPolicy `json:"retention,omitempty"`
+	// Webhooks are the webhooks of space
+	Webhooks []storage.Webhook `json:"webhooks,omitempty"`
+	// Charts are all charts in space
+	Charts []Chart `json:"charts"`
+}
+
+// Chart describes a chart in a backup
+type Chart struct {
+	// Name is the name of chart
+	Name string `json:"name"`
+	// Retention is the retention policy of chart
+	Retention *storage.RetentionPolicy `json:"retention,omitempty"`
+	// Attributes are labels and annotations of chart
+	Attributes *storage.Attributes `json:"attributes,omitempty"`
+	// Versions are all versions in chart
+	Versions []Version `json:"versions"`
+}
+
+// Version describes a version in a backup
+type Version struct {
+	// Version is the version number
+	Version string `json:"version"`
+	// Files are sha256 digests in hex of version files by their names. A version has
+	// chart.tgz, and optional chart.tgz.prov, oci.manifest and oci.config
+	Files map[string]string `json:"files"`
+	// Verified is whether the provenance has been verified by a keyring
+	Verified bool `json:"verified,omitempty"`
+	// Downloads is the number of times the version is downloaded
+	Downloads int64 `json:"downloads,omitempty"`
+	// Attributes are labels and annotations of version
+	Attributes *storage.Attributes `json:"attributes,omitempty"`
+}
+
+// filePath returns the path of a version file in a backup
+func filePath(space, chart, version, name string) string {
+	return path.Join("spaces", space, chart, version, name)
+}
+
+// digest returns the sha256 digest of data in hex
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Export writes all spaces, charts and versions in sm to w as a gzipped tar. The manifest
+// is written first with checksums of all files, so a restore can verify files while reading
+func Export(ctx context.Context, sm storage.SpaceManager, w io.Writer) (*Manifest, error) {
+	manifest, err := newManifest(ctx, sm)
+	if err != nil {
+		return nil, err
+	}
+	zipper := gzip.NewWriter(w)
+	twriter := tar.NewWriter(zipper)
+	err = writeBackup(ctx, sm, manifest, twriter)
+	if err == nil {
+		err = twriter.Close()
+	}
+	if err == nil {
+		err = zipper.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// newManifest creates a manifest of all data in sm. Digests of archives are read from
+// storage, so archives are not read until they are written
+func newManifest(ctx context.Context, sm storage.SpaceManager) (*Manifest, error) {
+	manifest := &Manifest{Version: FormatVersion, CreatedAt: time.Now().UTC(), Spaces: []Space{}}
+	spaces, err := sm.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, spaceName := range spaces {
+		space, err := sm.Space(ctx, spaceName)
+		if err != nil {
+			return nil, err
+		}
+		entry := Space{Name: spaceName, Charts: []Chart{}}
+		if entry.Info, err = space.Info(ctx); err != nil {
+			return nil, err
+		}
+		if entry.Retention, err = space.RetentionPolicy(ctx); err != nil {
+			return nil, err
+		}
+		if entry.Webhooks, err = space.Webhooks(ctx); err != nil {
+			return nil, err
+		}
+		charts, err := space.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, chartName := range charts {
+			chart, err := space.Chart(ctx, chartName)
+			if err != nil {
+				return nil, err
+			}
+			chartEntry, err := newChartEntry(ctx, chart)
+			if err != nil {
+				return nil, err
+			}
+			entry.Charts = append(entry.Charts, *chartEntry)
+		}
+		manifest.Spaces = append(manifest.Spaces, entry)
+	}
+	return manifest, nil
+}
+
+// newChartEntry creates the manifest entry of chart
+func newChartEntry(ctx context.Context, chart storage.Chart) (*Chart, error) {
+	retention, err := chart.RetentionPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attributes, err := chart.Attributes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entry := &Chart{Name: chart.Name(), Retention: retention, Attributes: attributesEntry(attributes), Versions: []Version{}}
+	versions, err := chart.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, number := range versions {
+		version, err := chart.Version(ctx, number)
+		if err != nil {
+			return nil, err
+		}
+		versionEntry, err := newVersionEntry(ctx, version)
+		if err != nil {
+			return nil, err
+		}
+		entry.Versions = append(entry.Versions, *versionEntry)
+	}
+	return entry, nil
+}
+
+// newVersionEntry creates the manifest entry of version
+func newVersionEntry(ctx context.Context, version storage.Version) (*Version, error) {
+	files, err := readSmallFiles(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+	entry := &Version{Version: version.Number(), Files: make(map[string]string, len(files)+1)}
+	if entry.Files[archiveName], err = version.Digest(ctx); err != nil {
+		return nil, err
+	}
+	for name, data := range files {
+		entry.Files[name] = digest(data)
+	}
+	status, err := version.ProvenanceStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entry.Verified = status == storage.ProvenanceVerified
+	if entry.Downloads, err = version.Downloads(ctx); err != nil {
+		return nil, err
+	}
+	attributes, err := version.Attributes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entry.Attributes = attributesEntry(attributes)
+	return entry, nil
+}
+
+// attributesEntry returns attributes in a manifest entry. It returns nil if there is no attribute
+func attributesEntry(attributes *storage.Attributes) *storage.Attributes {
+	if len(attributes.Labels) <= 0 && len(attributes.Annotations) <= 0 {
+		return nil
+	}
+	return attributes
+}
+
+// replaceAttributes replaces attributes of a chart or a version by update, which is
+// UpdateAttributes of the chart or the version
+func replaceAttributes(ctx context.Context, update func(context.Context, func(*storage.Attributes) error) (*storage.Attributes, error),
+	attributes *storage.Attributes) error {
+	_, err := update(ctx, func(current *storage.Attributes) error {
+		*current = *attributes
+		return nil
+	})
+	return err
+}
+
+// readSmallFiles reads the provenance and the OCI manifest of version by their names
+// in a backup. Files which don't exist are omitted
+func readSmallFiles(ctx context.Context, version storage.Version) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	status, err := version.ProvenanceStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if status != storage.ProvenanceNone {
+		if files[provenanceName], err = version.GetProvenance(ctx); err != nil {
+			return nil, err
+		}
+	}
+	manifest, config, err := version.Manifest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if manifest != nil {
+		files[ociManifestName] = manifest
+		files[ociConfigName] = config
+	}
+	return files, nil
+}
+
+// writeBackup writes the manifest and all version files to twriter
+func writeBackup(ctx context.Context, sm storage.SpaceManager, manifest *Manifest, twriter *tar.Writer) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = writeFile(twriter, manifestName, bytes.NewReader(data), int64(len(data))); err != nil {
+		return err
+	}
+	for _, space := range manifest.Spaces {
+		for _, chart := range space.Charts {
+			for _, entry := range chart.Versions {
+				version, err := getVersion(ctx, sm, space.Name, chart.Name, entry.Version)
+				if err != nil {
+					return err
+				}
+				if err = writeVersion(ctx, version, space.Name, chart.Name, entry, twriter); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writeVersion writes files of a version. The archive is streamed and checked by its
+// digest in manifest, so a version changed after the manifest is created fails the backup
+func writeVersion(ctx context.Context, version storage.Version, space, chart string, entry Version, twriter *tar.Writer) error {
+	reader, size, err := version.StreamContent(ctx)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	err = writeFile(twriter, filePath(space, chart, entry.Version, archiveName), io.TeeReader(reader, hash), size)
+	reader.Close()
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != entry.Files[archiveName] {
+		return fmt.Errorf("%s/%s/%s is modified during backup", space, chart, entry.Version)
+	}
+	files, err := readSmallFiles(ctx, version)
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{provenanceName, ociManifestName, ociConfigName} {
+		data, ok := files[name]
+		if !ok {
+			continue
+		}
+		if digest(data) != entry.Files[name] {
+			return fmt.Errorf("%s of %s/%s/%s is modified during backup", name, space, chart, entry.Version)
+		}
+		if err = writeFile(twriter, filePath(space, chart, entry.Version, name), bytes.NewReader(data), int64(len(data))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile writes a regular file to twriter
+func writeFile(twriter *tar.Writer, name string, reader io.Reader, size int64) error {
+	err := twriter.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(twriter, reader, size)
+	return err
+}
+
+// getVersion gets a version in sm
+func getVersion(ctx context.Context, sm storage.SpaceManager, space, chart, version string) (storage.Version, error) {
+	s, err := sm.Space(ctx, space)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.Chart(ctx, chart)
+	if err != nil {
+		return nil, err
+	}
+	return c.Version(ctx, version)
+}
+
+// Import restores a backup written by Export from r into sm. sm must have no space. Every
+// file is checked by its checksum in manifest before it's stored, and a backup with missing
+// files fails. A failed restore may leave restored data in sm
+func Import(ctx context.Context, sm storage.SpaceManager, r io.Reader) (*Manifest, error) {
+	spaces, err := sm.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(spaces) > 0 {
+		return nil, errors.ErrorResourceExist.Format(fmt.Sprintf("%d spaces", len(spaces)))
+	}
+	unzipped, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errors.ErrorParamTypeError.Format("backup", "gzip", "unknown")
+	}
+	defer unzipped.Close()
+	treader := tar.NewReader(unzipped)
+	manifest, err := readManifest(treader)
+	if err != nil {
+		return nil, err
+	}
+	// expected maps paths of files to their manifest entries
+	expected := make(map[string]*Version)
+	// manifests keeps OCI manifests until their configs are read
+	manifests := make(map[string][]byte)
+	for _, space := range manifest.Spaces {
+		if _, err = sm.Create(ctx, space.Name); err != nil {
+			return nil, err
+		}
+		for _, chart := range space.Charts {
+			for i := range chart.Versions {
+				entry := &chart.Versions[i]
+				for name := range entry.Files {
+					expected[filePath(space.Name, chart.Name, entry.Version, name)] = entry
+				}
+			}
+		}
+	}
+	for {
+		hd, err := treader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.ErrorParamTypeError.Format("backup", "tar", "unknown")
+		}
+		entry, ok := expected[hd.Name]
+		if !ok {
+			return nil, errors.ErrorInvalidParam.Format("backup", "unknown file "+hd.Name)
+		}
+		delete(expected, hd.Name)
+		data, err := ioutil.ReadAll(treader)
+		if err != nil {
+			return nil, err
+		}
+		name := path.Base(hd.Name)
+		if digest(data) != entry.Files[name] {
+			return nil, errors.ErrorInvalidParam.Format("backup", "checksum mismatch of "+hd.Name)
+		}
+		if err = restoreFile(ctx, sm, hd.Name, name, data, entry, manifests); err != nil {
+			return nil, err
+		}
+	}
+	for name := range expected {
+		return nil, errors.ErrorInvalidParam.Format("backup", "missing file "+name)
+	}
+	if err = restorePolicies(ctx, sm, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// readManifest reads the manifest which is the first file of a backup
+func readManifest(treader *tar.Reader) (*Manifest, error) {
+	hd, err := treader.Next()
+	if err != nil || hd.Name != manifestName {
+		return nil, errors.ErrorInvalidParam.Format("backup", manifestName+" should be the first file")
+	}
+	data, err := ioutil.ReadAll(treader)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{}
+	if err = json.Unmarshal(data, manifest); err != nil {
+		return nil, errors.ErrorParamTypeError.Format(manifestName, "json", "unknown")
+	}
+	if manifest.Version != FormatVersion {
+		return nil, errors.ErrorInvalidParam.Format("backup", fmt.Sprintf("unsupported version %d", manifest.Version))
+	}
+	return manifest, nil
+}
+
+// restoreFile stores a version file. Export writes an archive before other files of its
+// version, and an OCI manifest before its config
+func restoreFile(ctx context.Context, sm storage.SpaceManager, filePath, name string, data []byte, entry *Version, manifests map[string][]byte) error {
+	// the path is spaces/{space}/{chart}/{version}/{name}
+	dir := path.Dir(filePath)
+	chart := path.Dir(dir)
+	version, err := getVersion(ctx, sm, path.Base(path.Dir(chart)), path.Base(chart), entry.Version)
+	if err != nil {
+		return err
+	}
+	switch name {
+	case archiveName:
+		if err = version.PutContent(ctx, data); err != nil {
+			return err
+		}
+		if entry.Attributes != nil {
+			if err = replaceAttributes(ctx, version.UpdateAttributes, entry.Attributes); err != nil {
+				return err
+			}
+		}
+		if entry.Downloads > 0 {
+			return version.AddDownloads(ctx, entry.Downloads)
+		}
+		return nil
+	case provenanceName:
+		return version.PutProvenance(ctx, data, entry.Verified)
+	case ociManifestName:
+		manifests[dir] = data
+		return nil
+	case ociConfigName:
+		manifest, ok := manifests[dir]
+		if !ok {
+			return errors.ErrorInvalidParam.Format("backup", ociManifestName+" should be before "+filePath)
+		}
+		delete(manifests, dir)
+		return version.PutManifest(ctx, manifest, data)
+	}
+	return errors.ErrorInvalidParam.Format("backup", "unknown file "+filePath)
+}
+
+// restorePolicies restores space info, retention policies, webhooks and chart attributes after all versions are restored
+func restorePolicies(ctx context.Context, sm storage.SpaceManager, manifest *Manifest) error {
+	for _, entry := range manifest.Spaces {
+		if err := restoreSpacePolicies(ctx, sm, entry); err != nil {
+			return err
+		}
+	}
+	log.Infof("Restored %d spaces", len(manifest.Spaces))
+	return nil
+}
+
+// restoreSpacePolicies restores info, retention policies and webhooks of a space, and retention
+// policies and attributes of its charts
+func restoreSpacePolicies(ctx context.Context, sm storage.SpaceManager, entry Space) error {
+	space, err := sm.Space(ctx, entry.Name)
+	if err != nil {
+		return err
+	}
+	if entry.Info != nil {
+		if err = space.SetInfo(ctx, entry.Info); err != nil {
+			return err
+		}
+	}
+	if entry.Retention != nil {
+		if err = space.SetRetentionPolicy(ctx, entry.Retention); err != nil {
+			return err
+		}
+	}
+	if len(entry.Webhooks) > 0 {
+		if err = space.SetWebhooks(ctx, entry.Webhooks); err != nil {
+			return err
+		}
+	}
+	for _, chartEntry := range entry.Charts {
+		if chartEntry.Retention == nil && chartEntry.Attributes == nil {
+			continue
+		}
+		chart, err := space.Chart(ctx, chartEntry.Name)
+		if err != nil {
+			return err
+		}
+		if chartEntry.Retention != nil {
+			if err = chart.SetRetentionPolicy(ctx, chartEntry.Retention); err != nil {
+				return err
+			}
+		}
+		if chartEntry.Attributes != nil {
+			if err = replaceAttributes(ctx, chart.UpdateAttributes, chartEntry.Attributes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
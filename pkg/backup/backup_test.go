@@ -0,0 +1,151 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+
+	_ "github.com/caicloud/helm-registry/pkg/storage/simple"
+)
+
+// putTestVersion stores a chart archive to space/chart/version and returns the version
+func putTestVersion(t *testing.T, sm storage.SpaceManager, space, chart, version string) storage.Version {
+	ctx := context.Background()
+	s, err := sm.Space(ctx, space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.Chart(ctx, chart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := c.Version(ctx, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = v.PutContent(ctx, storagetest.NewArchive(t, chart, version)); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	source, cleanup := storagetest.NewSpaceManager(t)
+	defer cleanup()
+	for _, space := range []string{"a", "b"} {
+		if _, err := source.Create(ctx, space); err != nil {
+			t.Fatal(err)
+		}
+	}
+	putTestVersion(t, source, "a", "chart", "1.0.0")
+	v := putTestVersion(t, source, "a", "chart", "1.1.0")
+	if err := v.PutProvenance(ctx, []byte("prov"), true); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.PutManifest(ctx, []byte("manifest"), []byte("config")); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.AddDownloads(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	putTestVersion(t, source, "b", "other", "0.1.0")
+	s, _ := source.Space(ctx, "a")
+	if err := s.SetRetentionPolicy(ctx, &storage.RetentionPolicy{KeepLast: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetWebhooks(ctx, []storage.Webhook{{URL: "http://example.com"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetInfo(ctx, &storage.SpaceInfo{Owner: "payments", Visibility: storage.VisibilityPublic}); err != nil {
+		t.Fatal(err)
+	}
+	c, _ := s.Chart(ctx, "chart")
+	if err := c.SetRetentionPolicy(ctx, &storage.RetentionPolicy{KeepLast: 5, Protect: ">=1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	setLabels := func(a *storage.Attributes) error {
+		a.Labels = map[string]string{"team": "payments"}
+		return nil
+	}
+	if _, err := c.UpdateAttributes(ctx, setLabels); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.UpdateAttributes(ctx, setLabels); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	exported, err := Export(ctx, source, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	target, cleanup := storagetest.NewSpaceManager(t)
+	defer cleanup()
+	if _, err = Import(ctx, target, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := newManifest(ctx, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := json.Marshal(exported.Spaces)
+	got, _ := json.Marshal(restored.Spaces)
+	if !bytes.Equal(expected, got) {
+		t.Errorf("expected restored spaces %s, but got %s", expected, got)
+	}
+
+	// a backup can only be restored into an empty registry
+	if _, err = Import(ctx, target, bytes.NewReader(data)); err == nil {
+		t.Error("expected an error when restoring into a registry with spaces")
+	}
+}
+
+func TestImportCorrupted(t *testing.T) {
+	ctx := context.Background()
+	source, cleanup := storagetest.NewSpaceManager(t)
+	defer cleanup()
+	if _, err := source.Create(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	putTestVersion(t, source, "a", "chart", "1.0.0")
+	manifest, err := newManifest(ctx, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the checksum of archive doesn't match data
+	manifest.Spaces[0].Charts[0].Versions[0].Files[archiveName] = digest([]byte("other"))
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	if err = writeBackup(ctx, source, manifest, tw); err == nil {
+		t.Fatal("expected an error when a version is modified during backup")
+	}
+
+	// a backup without version files
+	buf.Reset()
+	gw = gzip.NewWriter(buf)
+	tw = tar.NewWriter(gw)
+	data := []byte(`{"version":1,"spaces":[{"name":"a","charts":[{"name":"chart","versions":[{"version":"1.0.0","files":{"chart.tgz":"00"}}]}]}]}`)
+	if err = writeFile(tw, manifestName, bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	gw.Close()
+	target, cleanup := storagetest.NewSpaceManager(t)
+	defer cleanup()
+	if _, err = Import(ctx, target, bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("expected an error when restoring a backup with missing files")
+	}
+}
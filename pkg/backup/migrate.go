@@ -0,0 +1,178 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package backup
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"reflect"
+
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// MigrationResult is the result of a migration
+type MigrationResult struct {
+	// Copied are versions copied to target, like "space/chart/version"
+	Copied []string `json:"copied"`
+	// Skipped are versions which have been in target with identical data
+	Skipped []string `json:"skipped"`
+}
+
+// Migrate copies all spaces, charts and versions in source to target. Versions which have
+// been in target with identical data are skipped, so an interrupted migration can be run
+// again to resume. Every copied version is read back from target and verified by checksums.
+// Data which only exists in target is kept, and trashed versions are not copied
+func Migrate(ctx context.Context, source, target storage.SpaceManager) (*MigrationResult, error) {
+	result := &MigrationResult{Copied: []string{}, Skipped: []string{}}
+	manifest, err := newManifest(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	for _, space := range manifest.Spaces {
+		s, err := target.Space(ctx, space.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !s.Exists(ctx) {
+			if _, err = target.Create(ctx, space.Name); err != nil {
+				return nil, err
+			}
+		}
+		for _, chart := range space.Charts {
+			for _, entry := range chart.Versions {
+				name := path.Join(space.Name, chart.Name, entry.Version)
+				copied, err := migrateVersion(ctx, source, target, space.Name, chart.Name, entry)
+				if err != nil {
+					return nil, fmt.Errorf("failed to migrate %s: %v", name, err)
+				}
+				if copied {
+					log.Infof("Migrated %s", name)
+					result.Copied = append(result.Copied, name)
+				} else {
+					result.Skipped = append(result.Skipped, name)
+				}
+			}
+		}
+		if err = restoreSpacePolicies(ctx, target, space); err != nil {
+			return nil, err
+		}
+	}
+	log.Infof("Migrated %d spaces, %d versions are copied and %d versions are skipped",
+		len(manifest.Spaces), len(result.Copied), len(result.Skipped))
+	return result, nil
+}
+
+// migrateVersion copies a version if it doesn't exist in target, it's incomplete in target or
+// its data is different. Download counts in target are raised to counts in source, and attributes
+// in source replace attributes in target. It returns whether the version is copied
+func migrateVersion(ctx context.Context, source, target storage.SpaceManager, space, chart string, entry Version) (bool, error) {
+	from, err := getVersion(ctx, source, space, chart, entry.Version)
+	if err != nil {
+		return false, err
+	}
+	to, err := getVersion(ctx, target, space, chart, entry.Version)
+	if err != nil {
+		return false, err
+	}
+	var downloads int64
+	copied := true
+	if to.Exists(ctx) && to.Validate(ctx) != nil {
+		// the version is left incomplete by an interrupted migration
+		log.Warnf("Removing incomplete version %s/%s/%s in target", space, chart, entry.Version)
+		if err = deleteVersion(ctx, target, space, chart, entry.Version); err != nil {
+			return false, err
+		}
+	} else if to.Exists(ctx) {
+		existing, err := newVersionEntry(ctx, to)
+		if err != nil {
+			return false, err
+		}
+		downloads = existing.Downloads
+		copied = existing.Verified != entry.Verified || !reflect.DeepEqual(existing.Files, entry.Files)
+	}
+	if copied {
+		if err = copyVersion(ctx, from, to, entry); err != nil {
+			return false, err
+		}
+	}
+	if downloads < entry.Downloads {
+		if err = to.AddDownloads(ctx, entry.Downloads-downloads); err != nil {
+			return false, err
+		}
+	}
+	if entry.Attributes != nil {
+		if err = replaceAttributes(ctx, to.UpdateAttributes, entry.Attributes); err != nil {
+			return false, err
+		}
+	}
+	return copied, nil
+}
+
+// deleteVersion deletes a version in sm
+func deleteVersion(ctx context.Context, sm storage.SpaceManager, space, chart, version string) error {
+	s, err := sm.Space(ctx, space)
+	if err != nil {
+		return err
+	}
+	c, err := s.Chart(ctx, chart)
+	if err != nil {
+		return err
+	}
+	return c.Delete(ctx, version)
+}
+
+// copyVersion copies files of a version and verifies them by checksums in entry
+func copyVersion(ctx context.Context, from, to storage.Version, entry Version) error {
+	data, err := from.GetContent(ctx)
+	if err != nil {
+		return err
+	}
+	files, err := readSmallFiles(ctx, from)
+	if err != nil {
+		return err
+	}
+	if err = verifyFiles(data, files, entry); err != nil {
+		return err
+	}
+	if err = to.PutContent(ctx, data); err != nil {
+		return err
+	}
+	if provenance, ok := files[provenanceName]; ok {
+		if err = to.PutProvenance(ctx, provenance, entry.Verified); err != nil {
+			return err
+		}
+	}
+	if manifest, ok := files[ociManifestName]; ok {
+		if err = to.PutManifest(ctx, manifest, files[ociConfigName]); err != nil {
+			return err
+		}
+	}
+	// files are read back, so data which is not stored correctly by target is found
+	if data, err = to.GetContent(ctx); err != nil {
+		return err
+	}
+	if files, err = readSmallFiles(ctx, to); err != nil {
+		return err
+	}
+	return verifyFiles(data, files, entry)
+}
+
+// verifyFiles checks the archive and other files of a version by checksums in entry
+func verifyFiles(data []byte, files map[string][]byte, entry Version) error {
+	if len(files)+1 != len(entry.Files) {
+		return fmt.Errorf("expected %d files, but got %d", len(entry.Files), len(files)+1)
+	}
+	if digest(data) != entry.Files[archiveName] {
+		return fmt.Errorf("checksum mismatch of %s", archiveName)
+	}
+	for name, content := range files {
+		if digest(content) != entry.Files[name] {
+			return fmt.Errorf("checksum mismatch of %s", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package backup
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/storage/simple"
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	source, cleanup := storagetest.NewSpaceManager(t)
+	defer cleanup()
+	if _, err := source.Create(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	putTestVersion(t, source, "a", "chart", "1.0.0")
+	v := putTestVersion(t, source, "a", "chart", "1.1.0")
+	if err := v.PutProvenance(ctx, []byte("prov"), false); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.AddDownloads(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	target, cleanup := storagetest.NewSpaceManager(t)
+	defer cleanup()
+	result, err := Migrate(ctx, source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"a/chart/1.0.0", "a/chart/1.1.0"}
+	if !reflect.DeepEqual(result.Copied, expected) || len(result.Skipped) != 0 {
+		t.Errorf("expected %v to be copied, but got %+v", expected, result)
+	}
+
+	// a migration runs again only copies versions which are changed
+	if err = v.PutProvenance(ctx, []byte("prov"), true); err != nil {
+		t.Fatal(err)
+	}
+	if err = v.AddDownloads(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if result, err = Migrate(ctx, source, target); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result.Copied, expected[1:]) || !reflect.DeepEqual(result.Skipped, expected[:1]) {
+		t.Errorf("expected %v to be copied, but got %+v", expected[1:], result)
+	}
+	sourceManifest, err := newManifest(ctx, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targetManifest, err := newManifest(ctx, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sourceManifest.Spaces, targetManifest.Spaces) {
+		t.Errorf("expected spaces %+v, but got %+v", sourceManifest.Spaces, targetManifest.Spaces)
+	}
+}
+
+func TestMigrateIncompleteVersion(t *testing.T) {
+	ctx := context.Background()
+	source, cleanup := storagetest.NewSpaceManager(t)
+	defer cleanup()
+	if _, err := source.Create(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	putTestVersion(t, source, "a", "chart", "1.0.0")
+	target, cleanup := storagetest.NewSpaceManager(t)
+	defer cleanup()
+	if _, err := target.Create(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	// an interrupted migration leaves a version which is being written
+	v := putTestVersion(t, target, "a", "chart", "1.0.0")
+	backend := target.(*simple.SpaceManager).Backend
+	if err := backend.PutContent(ctx, "/a/chart/1.0.0/.status", []byte("LOCKING")); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Migrate(ctx, source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"a/chart/1.0.0"}; !reflect.DeepEqual(result.Copied, expected) {
+		t.Errorf("expected %v to be copied, but got %+v", expected, result)
+	}
+	if _, err = v.Metadata(ctx); err != nil {
+		t.Errorf("expected version to be complete, but got %v", err)
+	}
+}
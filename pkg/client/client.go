@@ -0,0 +1,257 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package client
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/api/v1/types"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/rest"
+	"github.com/caicloud/helm-registry/pkg/rest/v1"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// defaults of Options
+const (
+	// DefaultRetries is the default number of retries of a request
+	DefaultRetries = 3
+	// DefaultBackoff is the default delay before the first retry
+	DefaultBackoff = 200 * time.Millisecond
+	// DefaultPageSize is the default number of items requested per page
+	DefaultPageSize = 100
+)
+
+// Options are options of a Client
+type Options struct {
+	// Token is sent in header Authorization as a bearer token if it's not empty
+	Token string
+	// Retries is the number of times a request is retried after a transient error. It's
+	// DefaultRetries if it's 0, and requests are not retried if it's negative
+	Retries int
+	// Backoff is the delay before the first retry. It's doubled for every retry. It's
+	// DefaultBackoff if it's 0
+	Backoff time.Duration
+	// PageSize is the number of items requested per page when listing. It's DefaultPageSize
+	// if it's 0
+	PageSize int
+	// Transport is the transport of requests. http.DefaultTransport is used if it's nil
+	Transport http.RoundTripper
+}
+
+// Client is a high-level client of registry. Listings return all items by requesting
+// pages, and errors of server are *errors.Error with the status code of response
+type Client struct {
+	api      *v1.Client
+	pageSize int
+}
+
+// New creates a Client. endpoint is the address of server, like http://host:port
+func New(endpoint string, options Options) (*Client, error) {
+	transport := options.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	retries := options.Retries
+	if retries == 0 {
+		retries = DefaultRetries
+	}
+	if retries > 0 {
+		backoff := options.Backoff
+		if backoff <= 0 {
+			backoff = DefaultBackoff
+		}
+		transport = &retryTransport{base: transport, retries: retries, backoff: backoff}
+	}
+	if len(options.Token) > 0 {
+		transport = &tokenTransport{base: transport, token: options.Token}
+	}
+	api, err := v1.NewTransportClient(endpoint, transport)
+	if err != nil {
+		return nil, err
+	}
+	pageSize := options.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	return &Client{api: api, pageSize: pageSize}, nil
+}
+
+// API returns the low-level client for apis which are not wrapped
+func (c *Client) API() *v1.Client {
+	return c.api
+}
+
+// ListSpaces lists names of all spaces
+func (c *Client) ListSpaces() ([]string, error) {
+	return c.listStrings(c.api.ListSpaces)
+}
+
+// CreateSpace creates a space
+func (c *Client) CreateSpace(space string) error {
+	_, err := c.api.CreateSpace(space)
+	return err
+}
+
+// ListCharts lists names of all charts in a space
+func (c *Client) ListCharts(space string) ([]string, error) {
+	return c.listStrings(func(start, limit int) (*v1.StringCollectionResult, error) {
+		return c.api.ListCharts(space, start, limit)
+	})
+}
+
+// ListVersions lists all version numbers of a chart in semver order
+func (c *Client) ListVersions(space, chart string) ([]string, error) {
+	return c.listStrings(func(start, limit int) (*v1.StringCollectionResult, error) {
+		return c.api.ListVersions(space, chart, start, limit)
+	})
+}
+
+// ListMetadata lists metadata of all versions of a chart
+func (c *Client) ListMetadata(space, chart string) ([]*storage.Metadata, error) {
+	return c.listMetadata(func(start, limit int) (*v1.MetadataCollectionResult, error) {
+		return c.api.FetchChartMetadata(space, chart, start, limit)
+	})
+}
+
+// PushChart uploads a chart archive to a space. The name and version of chart are read
+// from the archive
+func (c *Client) PushChart(space string, archive []byte) (*models.ChartLink, error) {
+	return c.api.UploadChart(space, archive)
+}
+
+// PullChart downloads the archive of a version
+func (c *Client) PullChart(space, chart, version string) ([]byte, error) {
+	return c.api.DownloadVersion(space, chart, version)
+}
+
+// DeleteVersion deletes a version
+func (c *Client) DeleteVersion(space, chart, version string) error {
+	return c.api.DeleteVersion(space, chart, version)
+}
+
+// DeleteChart deletes a chart and all of its versions
+func (c *Client) DeleteChart(space, chart string) error {
+	return c.api.DeleteChart(space, chart)
+}
+
+// FetchMetadata fetches metadata of a version
+func (c *Client) FetchMetadata(space, chart, version string) (*storage.Metadata, error) {
+	return c.api.FetchVersionMetadata(space, chart, version)
+}
+
+// FetchValues fetches values.yaml of a version
+func (c *Client) FetchValues(space, chart, version string) ([]byte, error) {
+	return c.api.FetchVersionValues(space, chart, version)
+}
+
+// Promote copies a version to target. If overwrite is true, an existing target version
+// is replaced
+func (c *Client) Promote(space, chart, version string, target types.VersionTarget, overwrite bool) (*models.ChartLink, error) {
+	return c.api.PromoteVersion(space, chart, version, &target, overwrite)
+}
+
+// SearchQuery is a query of Search
+type SearchQuery struct {
+	// Text is matched against names, descriptions and keywords of charts
+	Text string
+	// Keyword is a keyword which charts must have
+	Keyword string
+	// Maintainer is the name or email of a maintainer which charts must have
+	Maintainer string
+	// Space is the space to search in. All readable spaces are searched if it's empty
+	Space string
+	// Latest only returns the latest version of every chart
+	Latest bool
+	// Prerelease makes pre-release versions the latest ones if they are the highest
+	Prerelease bool
+}
+
+// Search returns metadata of all versions matching query, in the order of relevance
+func (c *Client) Search(query SearchQuery) ([]*storage.Metadata, error) {
+	return c.listMetadata(func(start, limit int) (*v1.MetadataCollectionResult, error) {
+		return c.api.Search(query.Text, query.Keyword, query.Maintainer, query.Space, query.Latest, query.Prerelease, start, limit)
+	})
+}
+
+// CollectGarbage purges trash and removes incomplete versions, unfinished uploads and
+// unreferenced blobs in storage
+func (c *Client) CollectGarbage() (*storage.GCResult, error) {
+	return c.api.CollectGarbage()
+}
+
+// listStrings requests all pages of a string listing
+func (c *Client) listStrings(list func(start, limit int) (*v1.StringCollectionResult, error)) ([]string, error) {
+	items := []string{}
+	err := c.paginate(func(start, limit int) (int, int, error) {
+		result, err := list(start, limit)
+		if err != nil {
+			return 0, 0, err
+		}
+		items = append(items, result.Items...)
+		return result.Metadata.Total, len(result.Items), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// listMetadata requests all pages of a metadata listing
+func (c *Client) listMetadata(list func(start, limit int) (*v1.MetadataCollectionResult, error)) ([]*storage.Metadata, error) {
+	items := []*storage.Metadata{}
+	err := c.paginate(func(start, limit int) (int, int, error) {
+		result, err := list(start, limit)
+		if err != nil {
+			return 0, 0, err
+		}
+		items = append(items, result.Items...)
+		return result.Metadata.Total, len(result.Items), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// paginate calls page from start 0 until all items are requested. page returns the total
+// number of items and the number of items in the page
+func (c *Client) paginate(page func(start, limit int) (int, int, error)) error {
+	start := 0
+	for {
+		total, n, err := page(start, c.pageSize)
+		if err != nil {
+			return err
+		}
+		start += n
+		if n <= 0 || start >= total {
+			return nil
+		}
+	}
+}
+
+// StatusCode returns the http status code of an error returned by Client. It returns 0
+// if err is not an error of server, like a network error
+func StatusCode(err error) int {
+	if rest.ErrorNoResponse.Equal(err) || rest.ErrorUnknownLocalError.Equal(err) {
+		return 0
+	}
+	if errx, ok := err.(*errors.Error); ok {
+		return errx.Code
+	}
+	return 0
+}
+
+// IsNotFound returns whether err is caused by a resource which doesn't exist
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// IsConflict returns whether err is caused by a resource which exists or has been modified
+func IsConflict(err error) bool {
+	return StatusCode(err) == http.StatusConflict
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+)
+
+func TestListVersions(t *testing.T) {
+	versions := []string{"0.1.0", "0.2.0", "1.0.0", "1.1.0", "2.0.0"}
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/v1/spaces/space/charts/chart/versions" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "chart not found", "reason": "ReasonInternal"}`))
+			return
+		}
+		// the first request fails with a transient error
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		end := start + limit
+		if end > len(versions) {
+			end = len(versions)
+		}
+		json.NewEncoder(w).Encode(models.NewListResponse(len(versions), versions[start:end]))
+	}))
+	defer server.Close()
+
+	client, err := New(server.URL, Options{Token: "token", PageSize: 2, Backoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := client.ListVersions("space", "chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, versions) {
+		t.Errorf("expected versions %v, but got %v", versions, result)
+	}
+	_, err = client.ListVersions("space", "other")
+	if !IsNotFound(err) {
+		t.Errorf("expected a not found error, but got %v", err)
+	}
+
+	client, err = New(server.URL, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.ListVersions("space", "chart"); StatusCode(err) != http.StatusUnauthorized {
+		t.Errorf("expected an unauthorized error, but got %v", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusLocked)
+		w.Write([]byte(`{"message": "version is locked", "reason": "ResourceLocking"}`))
+	}))
+	defer server.Close()
+
+	client, err := New(server.URL, Options{Retries: 2, Backoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = client.PushChart("space", []byte("chart")); StatusCode(err) != http.StatusLocked {
+		t.Errorf("expected a locking error, but got %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("expected 3 requests, but got %d", requests)
+	}
+
+	requests = 0
+	client, err = New(server.URL, Options{Retries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.PushChart("space", []byte("chart"))
+	if requests != 1 {
+		t.Errorf("expected 1 request without retries, but got %d", requests)
+	}
+}
+
+// roundTripperFunc is a http.RoundTripper which calls itself
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryNetworkError(t *testing.T) {
+	requests := 0
+	transport := &retryTransport{
+		base: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			requests++
+			return nil, errors.New("connection reset by peer")
+		}),
+		retries: 2,
+		backoff: time.Millisecond,
+	}
+	cases := []struct {
+		method   string
+		requests int
+	}{
+		{http.MethodGet, 3},
+		{http.MethodDelete, 3},
+		{http.MethodPost, 1},
+	}
+	for _, c := range cases {
+		requests = 0
+		req, err := http.NewRequest(c.method, "http://registry/api/v1/spaces", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := transport.RoundTrip(req); err == nil {
+			t.Errorf("%s: expected a network error", c.method)
+		}
+		if requests != c.requests {
+			t.Errorf("%s: expected %d requests, but got %d", c.method, c.requests, requests)
+		}
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	requests := 0
+	transport := &retryTransport{
+		base: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			requests++
+			return &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Header:     make(http.Header),
+				Body:       ioutil.NopCloser(strings.NewReader("")),
+			}, nil
+		}),
+		retries: 2,
+		backoff: time.Hour,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequest(http.MethodGet, "http://registry/api/v1/spaces", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(10*time.Millisecond, cancel)
+	done := make(chan error, 1)
+	go func() {
+		_, err := transport.RoundTrip(req.WithContext(ctx))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("expected %v, but got %v", context.Canceled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("backoff isn't interrupted by canceled context")
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request, but got %d", requests)
+	}
+}
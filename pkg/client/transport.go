@@ -0,0 +1,103 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// tokenTransport sets a bearer token to every request
+type tokenTransport struct {
+	base  http.RoundTripper
+	token string
+}
+
+// RoundTrip sends req with header Authorization
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the request
+	clone := *req
+	clone.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		clone.Header[k] = v
+	}
+	clone.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(&clone)
+}
+
+// retryTransport retries requests which fail with transient errors. A request is retried if
+// the version is locked by another request, or the server or a proxy is unavailable. A request
+// which fails without response is retried only if its method is idempotent, because it may
+// have been handled by the server. Header Retry-After of response is respected if it's longer
+// than the backoff. Requests with bodies which can't be read again are not retried
+type retryTransport struct {
+	base    http.RoundTripper
+	retries int
+	backoff time.Duration
+}
+
+// transient returns whether a response with code may succeed if it's requested again
+func transient(code int) bool {
+	switch code {
+	case http.StatusLocked, http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// idempotent returns whether a request with method can be sent more than once
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// RoundTrip sends req and retries it with backoff after transient errors
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	backoff := t.backoff
+	for i := 0; ; i++ {
+		resp, err := t.base.RoundTrip(req)
+		if i >= t.retries || (err == nil && !transient(resp.StatusCode)) ||
+			(err != nil && !idempotent(req.Method)) {
+			return resp, err
+		}
+		if req.Body != nil && req.GetBody == nil {
+			return resp, err
+		}
+		wait := backoff
+		if err == nil {
+			// the server may ask for a longer delay
+			if seconds, e := strconv.Atoi(resp.Header.Get("Retry-After")); e == nil && time.Duration(seconds)*time.Second > wait {
+				wait = time.Duration(seconds) * time.Second
+			}
+			// the response is dropped, so the connection can be reused
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			clone := *req
+			clone.Body = body
+			req = &clone
+		}
+	}
+}
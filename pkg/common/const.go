@@ -10,6 +10,9 @@ const (
 
 	// ContextNameSpaceParameters is the name of Space Parameters in Context
 	ContextNameSpaceParameters = "space.parameters"
+
+	// ContextNameTrashRetention is the name of Trash Retention in Context
+	ContextNameTrashRetention = "trash.retention"
 )
 
 const (
@@ -26,9 +29,15 @@ const (
 const (
 	// HTTPRequestUploadFileName is the name of file in HTTP Request
 	HTTPRequestUploadFileName = "chartfile"
+
+	// HTTPRequestUploadProvenanceName is the name of provenance file in HTTP Request
+	HTTPRequestUploadProvenanceName = "provfile"
 )
 
 const (
 	// DefaultPagingLimit is the default limit of paging.
 	DefaultPagingLimit = 10
+
+	// DefaultTrashRetention is the default retention period of trashed versions.
+	DefaultTrashRetention = "168h"
 )
@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sync"
 
 	"github.com/caicloud/helm-registry/pkg/errors"
 	"github.com/caicloud/helm-registry/pkg/log"
@@ -17,11 +18,16 @@ import (
 // a global SpaceManager
 var globalSpaceManager storage.SpaceManager
 
+// globalSpaceManagerLock guards globalSpaceManager, which is read by background goroutines
+var globalSpaceManagerLock sync.Mutex
+
 // GetSpaceManager gets a SpaceManager with configs from default Context.
 // kvStore should have two keys:
 //  ContextNameSpaceManager: specify the name of SpaceManager
 //  ContextNameSpaceParameters: specify the parameters of SpaceManager
 func GetSpaceManager() (storage.SpaceManager, error) {
+	globalSpaceManagerLock.Lock()
+	defer globalSpaceManagerLock.Unlock()
 	if globalSpaceManager != nil {
 		return globalSpaceManager, nil
 	}
@@ -45,6 +51,14 @@ func GetSpaceManager() (storage.SpaceManager, error) {
 	return manager, nil
 }
 
+// SetSpaceManager replaces the global SpaceManager. If manager is nil, GetSpaceManager
+// creates a SpaceManager with configs again
+func SetSpaceManager(manager storage.SpaceManager) {
+	globalSpaceManagerLock.Lock()
+	defer globalSpaceManagerLock.Unlock()
+	globalSpaceManager = manager
+}
+
 // MustGetSpaceManager must get a SpaceManager. If not, panic.
 func MustGetSpaceManager() storage.SpaceManager {
 	manager, err := GetSpaceManager()
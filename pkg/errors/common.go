@@ -22,26 +22,64 @@ const (
 
 var (
 	// ErrorParamTypeError defines param type error
-	ErrorParamTypeError = NewFormatError(http.StatusBadRequest, ReasonRequest, "%s should be %s, but got %s")
+	ErrorParamTypeError = NewFormatError(http.StatusBadRequest, ReasonRequest, "%s should be %s, but got %s").Named("ParamTypeError")
 	// ErrorParamValueError defines param value error
-	ErrorParamValueError = NewFormatError(http.StatusBadRequest, ReasonRequest, "value of %s should be %s, but got %s")
+	ErrorParamValueError = NewFormatError(http.StatusBadRequest, ReasonRequest, "value of %s should be %s, but got %s").Named("ParamValueError")
 	// ErrorParamNotFound defines request param error
-	ErrorParamNotFound = NewFormatError(http.StatusBadRequest, ReasonRequest, "can't find param %s in request")
+	ErrorParamNotFound = NewFormatError(http.StatusBadRequest, ReasonRequest, "can't find param %s in request").Named("ParamNotFound")
 	// ErrorContentNotFound defines not found error
-	ErrorContentNotFound = NewFormatError(http.StatusNotFound, ReasonInternal, "%s not found")
+	ErrorContentNotFound = NewFormatError(http.StatusNotFound, ReasonInternal, "%s not found").Named("ContentNotFound")
 	// ErrorInvalidParam defines invalid error
-	ErrorInvalidParam = NewFormatError(http.StatusBadRequest, ReasonRequest, "%s is invalid: %v")
+	ErrorInvalidParam = NewFormatError(http.StatusBadRequest, ReasonRequest, "%s is invalid: %v").Named("InvalidParam")
 	// ErrorResourceExist defines resource conflict error
-	ErrorResourceExist = NewFormatError(http.StatusConflict, ReasonInternal, "resource conflict because %s exist")
+	ErrorResourceExist = NewFormatError(http.StatusConflict, ReasonInternal, "resource conflict because %s exist").Named("ResourceExist")
 	// ErrorLocking defines locking error
-	ErrorLocking = NewFormatError(http.StatusLocked, ReasonLocking, "%s is locked and can't be handled: %v")
+	ErrorLocking = NewFormatError(http.StatusLocked, ReasonLocking, "%s is locked and can't be handled: %v").Named("Locking")
 	// ErrorInvalidStatus defines invalid status error
-	ErrorInvalidStatus = NewFormatError(http.StatusConflict, ReasonInternal, "%s status is invalid: %v")
+	ErrorInvalidStatus = NewFormatError(http.StatusConflict, ReasonInternal, "%s status is invalid: %v").Named("InvalidStatus")
+	// ErrorDependencyNotSatisfied defines dependency constraint error
+	ErrorDependencyNotSatisfied = NewFormatError(http.StatusConflict, ReasonRequest, "can't find a version of dependency %s satisfies constraint %s").Named("DependencyNotSatisfied")
+	// ErrorCircularDependency defines circular dependency error
+	ErrorCircularDependency = NewFormatError(http.StatusConflict, ReasonRequest, "circular dependency: %s").Named("CircularDependency")
+	// ErrorRedirect defines redirection to an external resource. Handlers should set header Location
+	ErrorRedirect = NewFormatError(http.StatusFound, ReasonRequest, "resource is at %s").Named("Redirect")
+	// ErrorNotModified defines not modified response for conditional requests
+	ErrorNotModified = NewStaticError(http.StatusNotModified, ReasonRequest, "not modified").Named("NotModified")
+	// ErrorPreconditionFailed defines precondition error for conditional requests
+	ErrorPreconditionFailed = NewFormatError(http.StatusPreconditionFailed, ReasonRequest, "%s has been modified, current etag is %s").Named("PreconditionFailed")
+	// ErrorVersionConflict defines conflict error for writes of a version which has been changed
+	ErrorVersionConflict = NewFormatError(http.StatusConflict, ReasonRequest, "%s has been modified, current digest is %s").Named("VersionConflict")
+	// ErrorUnauthorized defines authentication error
+	ErrorUnauthorized = NewFormatError(http.StatusUnauthorized, ReasonRequest, "unauthorized: %s").Named("Unauthorized")
+	// ErrorForbidden defines authorization error
+	ErrorForbidden = NewFormatError(http.StatusForbidden, ReasonRequest, "permission %s on space %s is required").Named("Forbidden")
+	// ErrorLintFailed defines chart lint error
+	ErrorLintFailed = NewFormatError(http.StatusBadRequest, ReasonRequest, "%s failed linting with %d blocking messages").Named("LintFailed")
+	// ErrorScanFailed defines chart scan error
+	ErrorScanFailed = NewFormatError(http.StatusBadRequest, ReasonRequest, "%s failed %d blocking scan checks").Named("ScanFailed")
+	// ErrorRenderFailed defines template rendering error
+	ErrorRenderFailed = NewFormatError(http.StatusBadRequest, ReasonRequest, "templates of %s can't be rendered: %v").Named("RenderFailed")
+	// ErrorUnverifiedProvenance defines provenance verification error
+	ErrorUnverifiedProvenance = NewFormatError(http.StatusBadRequest, ReasonRequest, "provenance of %s can't be verified: %v").Named("UnverifiedProvenance")
+	// ErrorArchiveTooLarge defines archive size limit error
+	ErrorArchiveTooLarge = NewFormatError(http.StatusRequestEntityTooLarge, ReasonRequest, "archive of %s is larger than the limit of %d bytes").Named("ArchiveTooLarge")
+	// ErrorQuotaExceeded defines quota error
+	ErrorQuotaExceeded = NewFormatError(http.StatusForbidden, ReasonRequest, "quota of %s is exceeded: %s").Named("QuotaExceeded")
+	// ErrorTooManyRequests defines rate limiting error
+	ErrorTooManyRequests = NewFormatError(http.StatusTooManyRequests, ReasonRequest, "too many requests: %s").Named("TooManyRequests")
+	// ErrorVersionImmutable defines error for overwriting an immutable version
+	ErrorVersionImmutable = NewFormatError(http.StatusConflict, ReasonRequest, "%s is immutable and can't be overwritten").Named("VersionImmutable")
+	// ErrorVersionUndeletable defines error for deleting an immutable version
+	ErrorVersionUndeletable = NewFormatError(http.StatusConflict, ReasonRequest, "%s is immutable and can't be deleted").Named("VersionUndeletable")
+	// ErrorResourceVersionExpired defines error for watching from an expired resource version
+	ErrorResourceVersionExpired = NewFormatError(http.StatusGone, ReasonRequest, "resource version %s is expired, list again and watch from now").Named("ResourceVersionExpired")
+	// ErrorUpstreamUnavailable defines error for pulling a version from an upstream which can't be reached
+	ErrorUpstreamUnavailable = NewFormatError(http.StatusBadGateway, ReasonInternal, "upstream of %s is unavailable: %v").Named("UpstreamUnavailable")
 
 	// ErrorInternalTypeError defines internal type error
-	ErrorInternalTypeError = NewFormatError(http.StatusInternalServerError, ReasonInternal, "type of %s should be %s, but got %s")
+	ErrorInternalTypeError = NewFormatError(http.StatusInternalServerError, ReasonInternal, "type of %s should be %s, but got %s").Named("InternalTypeError")
 	// ErrorUnknownNotFoundError defines not found error that we can't find a reason
-	ErrorUnknownNotFoundError = NewFormatError(http.StatusInternalServerError, ReasonInternal, "%s not found, may be it's a serious error")
+	ErrorUnknownNotFoundError = NewFormatError(http.StatusInternalServerError, ReasonInternal, "%s not found, may be it's a serious error").Named("UnknownNotFoundError")
 	// ErrorInternalUnknown defines internal unknown error that we can't find a reason
-	ErrorInternalUnknown = NewFormatError(http.StatusInternalServerError, ReasonInternal, "%v")
+	ErrorInternalUnknown = NewFormatError(http.StatusInternalServerError, ReasonInternal, "%v").Named("InternalUnknown")
 )
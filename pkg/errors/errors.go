@@ -11,11 +11,15 @@ import (
 
 // Error defines error with code
 type Error struct {
-	ID      int
+	ID int
+	// Name identifies the error in metrics, like `ContentNotFound`
+	Name    string
 	Code    int
 	Reason  string
 	Message string
 	Detail  string
+	// Details is a structured explanation of the error, like a list of problems
+	Details interface{}
 	format  string
 }
 
@@ -39,14 +43,30 @@ func (e *Error) Format(params ...interface{}) *Error {
 	}
 	return &Error{
 		e.ID,
+		e.Name,
 		e.Code,
 		e.Reason,
 		fmt.Sprintf(e.format, params...),
 		e.Detail,
+		e.Details,
 		e.format,
 	}
 }
 
+// WithDetails returns a copy of the error with structured details
+func (e *Error) WithDetails(details interface{}) *Error {
+	errx := *e
+	errx.Details = details
+	return &errx
+}
+
+// Named returns a copy of the error with name
+func (e *Error) Named(name string) *Error {
+	errx := *e
+	errx.Name = name
+	return &errx
+}
+
 // id counter
 var counter int32
 
@@ -57,10 +77,10 @@ func NewErrorID() int {
 
 // NewStaticError creates a static error
 func NewStaticError(code int, reason string, message string) *Error {
-	return &Error{NewErrorID(), code, reason, message, "", ""}
+	return &Error{NewErrorID(), "", code, reason, message, "", nil, ""}
 }
 
 // NewFormatError creates a format error
 func NewFormatError(code int, reason string, format string) *Error {
-	return &Error{NewErrorID(), code, reason, "", "", format}
+	return &Error{NewErrorID(), "", code, reason, "", "", nil, format}
 }
@@ -0,0 +1,111 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package gc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/quota"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// DefaultGracePeriod is the default period in which partial uploads are not collected
+const DefaultGracePeriod = "1h"
+
+// Config is a config of the garbage collector
+type Config struct {
+	// Interval is the period between two collections, like "24h". The collector isn't
+	// run in background if it's empty
+	Interval string `yaml:"interval"`
+	// GracePeriod is the minimum age of data to collect, like "1h". It should be longer
+	// than the longest upload
+	GracePeriod string `yaml:"gracePeriod"`
+}
+
+// gracePeriod is the configured grace period
+var gracePeriod time.Duration
+
+// Start starts the collector in background if an interval is set. It must be called
+// before Run
+func Start(config Config) error {
+	if len(config.GracePeriod) <= 0 {
+		config.GracePeriod = DefaultGracePeriod
+	}
+	grace, err := time.ParseDuration(config.GracePeriod)
+	if err != nil {
+		return err
+	}
+	if grace < 0 {
+		return fmt.Errorf("gc grace period should not be negative, but got %s", config.GracePeriod)
+	}
+	gracePeriod = grace
+	if _, err = TrashRetention(); err != nil {
+		return err
+	}
+	if len(config.Interval) <= 0 {
+		return nil
+	}
+	interval, err := time.ParseDuration(config.Interval)
+	if err != nil {
+		return err
+	}
+	if interval <= 0 {
+		return fmt.Errorf("gc interval should be positive, but got %s", config.Interval)
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := Run(context.Background()); err != nil {
+				log.Errorf("Failed to collect garbage: %v", err)
+			}
+		}
+	}()
+	log.Infof("Collecting garbage every %s", interval)
+	return nil
+}
+
+// TrashRetention returns the configured retention period of trashed versions
+func TrashRetention() (time.Duration, error) {
+	configured, ok := common.Get(common.ContextNameTrashRetention)
+	if !ok || len(fmt.Sprint(configured)) <= 0 {
+		configured = common.DefaultTrashRetention
+	}
+	retention, err := time.ParseDuration(fmt.Sprint(configured))
+	if err != nil {
+		return 0, err
+	}
+	if retention < 0 {
+		return 0, fmt.Errorf("trash retention should not be negative, but got %v", configured)
+	}
+	return retention, nil
+}
+
+// Run purges trashed versions which are older than the trash retention, and removes incomplete
+// versions, unfinished uploads and unreferenced blobs which are older than the grace period
+func Run(ctx context.Context) (*storage.GCResult, error) {
+	retention, err := TrashRetention()
+	if err != nil {
+		return nil, err
+	}
+	sm := common.MustGetSpaceManager()
+	// blobs of purged versions are collected in the same run
+	err = sm.PurgeTrash(ctx, time.Now().Add(-retention))
+	quota.InvalidateAll()
+	if err != nil {
+		return nil, err
+	}
+	result, err := sm.CollectGarbage(ctx, time.Now().Add(-gracePeriod))
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("Collected %d versions, %d uploads and %d blobs, reclaimed %d bytes",
+		len(result.Versions), len(result.Uploads), len(result.Blobs), result.ReclaimedBytes)
+	return result, nil
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package gc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/storage/simple"
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+)
+
+// trashTestVersion puts a version of chart in space and trashes it at deleted
+func trashTestVersion(t *testing.T, sm storage.SpaceManager, space, chart, version string, deleted time.Time) {
+	ctx := context.Background()
+	if s, err := sm.Space(ctx, space); err != nil || !s.Exists(ctx) {
+		if _, err = sm.Create(ctx, space); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v, err := common.GetVersion(ctx, space, chart, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = v.PutContent(ctx, storagetest.NewArchive(t, chart, version)); err != nil {
+		t.Fatal(err)
+	}
+	c, err := common.GetChart(ctx, space, chart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Trash(ctx, version); err != nil {
+		t.Fatal(err)
+	}
+	key := "/" + space + "/.trash/" + chart + "/" + version + "/.deleted"
+	err = sm.(*simple.SpaceManager).Backend.PutContent(ctx, key, []byte(deleted.UTC().Format(time.RFC3339)))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// trashedVersions returns versions in the trash of space
+func trashedVersions(t *testing.T, space string) []string {
+	s, err := common.GetSpace(context.Background(), space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := s.TrashedVersionMetadata(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := make([]string, 0, len(metadata))
+	for _, md := range metadata {
+		versions = append(versions, md.Name+"-"+md.Version)
+	}
+	return versions
+}
+
+func TestTrashRetention(t *testing.T) {
+	defer common.Set(common.ContextNameTrashRetention, "")
+	cases := []struct {
+		configured interface{}
+		expected   time.Duration
+		valid      bool
+	}{
+		{"", 168 * time.Hour, true},
+		{"24h", 24 * time.Hour, true},
+		{"0s", 0, true},
+		{"-1h", 0, false},
+		{"a week", 0, false},
+	}
+	for _, c := range cases {
+		common.Set(common.ContextNameTrashRetention, c.configured)
+		retention, err := TrashRetention()
+		if c.valid && (err != nil || retention != c.expected) {
+			t.Errorf("retention %q: expected %v, but got %v, %v", c.configured, c.expected, retention, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("retention %q: expected an error", c.configured)
+		}
+	}
+}
+
+func TestRunPurgesTrash(t *testing.T) {
+	sm, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	common.Set(common.ContextNameTrashRetention, "24h")
+	defer common.Set(common.ContextNameTrashRetention, "")
+
+	now := time.Now()
+	trashTestVersion(t, sm, "library", "old", "1.0.0", now.Add(-48*time.Hour))
+	trashTestVersion(t, sm, "library", "recent", "1.0.0", now.Add(-time.Hour))
+	trashTestVersion(t, sm, "library", "cutoff", "1.0.0", now.Add(-23*time.Hour))
+
+	if _, err := Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"cutoff-1.0.0", "recent-1.0.0"}
+	if versions := trashedVersions(t, "library"); !reflect.DeepEqual(versions, expected) {
+		t.Errorf("expected trashed versions %v after purging, but got %v", expected, versions)
+	}
+
+	common.Set(common.ContextNameTrashRetention, "0s")
+	if _, err := Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if versions := trashedVersions(t, "library"); len(versions) != 0 {
+		t.Errorf("expected an empty trash without retention, but got %v", versions)
+	}
+}
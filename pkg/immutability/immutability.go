@@ -0,0 +1,97 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package immutability
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// Config is a config of immutable versions. Once a version in an immutable space or
+// chart is stored, its archive, metadata and values can't be replaced, and it can't be
+// deleted, so a version number always refers to the same archive
+type Config struct {
+	// Spaces are names of spaces whose versions are immutable
+	Spaces []string `yaml:"spaces"`
+	// Charts are charts whose versions are immutable, like "library/nginx"
+	Charts []string `yaml:"charts"`
+}
+
+// immutable are names of immutable spaces and charts
+type immutable struct {
+	spaces map[string]bool
+	charts map[string]bool
+}
+
+// globalImmutable is the immutable spaces and charts of registry
+var globalImmutable = immutable{}
+
+// Initialize sets immutable spaces and charts by config
+func Initialize(config Config) error {
+	im := immutable{
+		spaces: make(map[string]bool, len(config.Spaces)),
+		charts: make(map[string]bool, len(config.Charts)),
+	}
+	for _, space := range config.Spaces {
+		if len(space) <= 0 || strings.Contains(space, "/") {
+			return fmt.Errorf("immutable space should be a space name, but got %q", space)
+		}
+		im.spaces[space] = true
+	}
+	for _, chart := range config.Charts {
+		parts := strings.Split(chart, "/")
+		if len(parts) != 2 || len(parts[0]) <= 0 || len(parts[1]) <= 0 {
+			return fmt.Errorf("immutable chart should be like space/chart, but got %q", chart)
+		}
+		im.charts[chart] = true
+	}
+	globalImmutable = im
+	if len(im.spaces) > 0 || len(im.charts) > 0 {
+		log.Infof("Versions of %d spaces and %d charts are immutable", len(im.spaces), len(im.charts))
+	}
+	return nil
+}
+
+// Immutable returns whether versions of a chart in a space are immutable
+func Immutable(space, chart string) bool {
+	return globalImmutable.spaces[space] || globalImmutable.charts[path.Join(space, chart)]
+}
+
+// HasImmutable returns whether a space is immutable or has immutable charts
+func HasImmutable(space string) bool {
+	if globalImmutable.spaces[space] {
+		return true
+	}
+	for chart := range globalImmutable.charts {
+		if strings.HasPrefix(chart, space+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckDelete checks whether a version of a chart in a space can be deleted. Versions of
+// immutable charts can't be deleted, or another archive could be pushed with the same number
+func CheckDelete(space, chart, version string) error {
+	if Immutable(space, chart) {
+		return errors.ErrorVersionUndeletable.Format(path.Join(space, chart, version))
+	}
+	return nil
+}
+
+// Check checks whether a version of a chart in a space can be overwritten. It returns
+// a conflict error if versions of the chart are immutable and the version exists
+func Check(ctx context.Context, space, chart string, version storage.Version) error {
+	if Immutable(space, chart) && version.Exists(ctx) {
+		return errors.ErrorVersionImmutable.Format(path.Join(space, chart, version.Number()))
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package immutability
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+
+	_ "github.com/caicloud/helm-registry/pkg/storage/simple"
+)
+
+func TestImmutable(t *testing.T) {
+	defer Initialize(Config{})
+	if err := Initialize(Config{Spaces: []string{"production"}, Charts: []string{"library/nginx"}}); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		space    string
+		chart    string
+		expected bool
+	}{
+		{"production", "redis", true},
+		{"library", "nginx", true},
+		{"library", "redis", false},
+		{"staging", "nginx", false},
+	}
+	for _, c := range cases {
+		if Immutable(c.space, c.chart) != c.expected {
+			t.Errorf("expected immutability of %s/%s to be %v", c.space, c.chart, c.expected)
+		}
+	}
+	for _, config := range []Config{{Spaces: []string{"a/b"}}, {Charts: []string{"nginx"}}, {Charts: []string{"a/b/c"}}} {
+		if err := Initialize(config); err == nil {
+			t.Errorf("expected an error of config %+v", config)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	defer Initialize(Config{})
+	if err := Initialize(Config{Spaces: []string{"production"}}); err != nil {
+		t.Fatal(err)
+	}
+	manager, cleanup := storagetest.NewSpaceManager(t)
+	defer cleanup()
+	ctx := context.Background()
+	space, err := manager.Create(ctx, "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chart, _ := space.Chart(ctx, "chart")
+	version, _ := chart.Version(ctx, "1.0.0")
+	if err = Check(ctx, "production", "chart", version); err != nil {
+		t.Errorf("expected a new version to be stored, but got %v", err)
+	}
+	if err = version.PutContent(ctx, storagetest.NewArchive(t, "chart", "1.0.0")); err != nil {
+		t.Fatal(err)
+	}
+	if err = Check(ctx, "production", "chart", version); !errors.ErrorVersionImmutable.Equal(err) {
+		t.Errorf("expected an immutable error, but got %v", err)
+	}
+	if err = Check(ctx, "staging", "chart", version); err != nil {
+		t.Errorf("expected versions of other spaces to be mutable, but got %v", err)
+	}
+}
+
+func TestCheckDelete(t *testing.T) {
+	defer Initialize(Config{})
+	if err := Initialize(Config{Spaces: []string{"production"}, Charts: []string{"library/nginx"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckDelete("production", "redis", "1.0.0"); !errors.ErrorVersionUndeletable.Equal(err) {
+		t.Errorf("expected a version in immutable space not to be deleted, but got %v", err)
+	}
+	if err := CheckDelete("library", "nginx", "1.0.0"); !errors.ErrorVersionUndeletable.Equal(err) {
+		t.Errorf("expected a version of immutable chart not to be deleted, but got %v", err)
+	}
+	if err := CheckDelete("library", "redis", "1.0.0"); err != nil {
+		t.Errorf("expected a mutable version to be deleted, but got %v", err)
+	}
+	for space, expected := range map[string]bool{"production": true, "library": true, "lib": false, "staging": false} {
+		if HasImmutable(space) != expected {
+			t.Errorf("expected space %s to have immutable versions %v", space, expected)
+		}
+	}
+}
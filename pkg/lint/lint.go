@@ -0,0 +1,181 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+// Package lint lints chart archives by helm lint rules before they are stored
+package lint
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/trace"
+	"k8s.io/helm/pkg/chartutil"
+	helmlint "k8s.io/helm/pkg/lint"
+	"k8s.io/helm/pkg/lint/support"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// severities maps lint severities to names
+var severities = map[int]string{
+	support.UnknownSev: "unknown",
+	support.InfoSev:    "info",
+	support.WarningSev: "warning",
+	support.ErrorSev:   "error",
+}
+
+// Check lints a chart archive by helm lint rules and returns the report. If any message
+// has error severity, or warning severity if strict is true, it returns ErrorLintFailed
+// with the full report as details.
+func Check(ctx context.Context, metadata *chart.Metadata, archive io.Reader, strict bool) ([]models.LintMessage, error) {
+	_, span := trace.StartSpan(ctx, "lint")
+	defer span.End()
+	linter, err := lintArchive(metadata.Name, archive)
+	if err != nil {
+		return nil, err
+	}
+	blocking := support.ErrorSev
+	if strict {
+		blocking = support.WarningSev
+	}
+	report := make([]models.LintMessage, 0, len(linter.Messages))
+	count := 0
+	for _, msg := range linter.Messages {
+		if msg.Severity >= blocking {
+			count++
+		}
+		report = append(report, models.LintMessage{
+			Severity: severities[msg.Severity],
+			Path:     msg.Path,
+			Message:  msg.Err.Error(),
+		})
+	}
+	if count > 0 {
+		name := fmt.Sprintf("%s/%s", metadata.Name, metadata.Version)
+		return nil, errors.ErrorLintFailed.Format(name, count).WithDetails(report)
+	}
+	return report, nil
+}
+
+// lintArchive expands a chart archive to a temporary directory and lints it. The chart
+// directory is renamed to the chart name because helm requires them to be the same
+func lintArchive(name string, archive io.Reader) (*support.Linter, error) {
+	dir, err := ioutil.TempDir("", "registry-lint")
+	if err != nil {
+		return nil, errors.ErrorInternalUnknown.Format(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Errorf("Failed to remove lint directory %s: %v", dir, err)
+		}
+	}()
+	expanded := filepath.Join(dir, "archive")
+	if err = expand(expanded, archive); err != nil {
+		return nil, err
+	}
+	root, err := findChartRoot(expanded)
+	if err != nil {
+		return nil, err
+	}
+	chartDir := filepath.Join(dir, filepath.Base(name))
+	if err = os.Rename(root, chartDir); err != nil {
+		return nil, errors.ErrorInternalUnknown.Format(err)
+	}
+	linter := helmlint.All(chartDir)
+	return &linter, nil
+}
+
+// expand extracts a chart archive to dir. Unlike chartutil.Expand, it only extracts regular
+// files and directories, and rejects entries with absolute paths or ".." segments, so files
+// of an archive are never written out of dir
+func expand(dir string, archive io.Reader) error {
+	reader, err := gzip.NewReader(archive)
+	if err != nil {
+		return errors.ErrorParamTypeError.Format("chart", "gzip", "unknown")
+	}
+	defer reader.Close()
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.ErrorParamTypeError.Format("chart", "gzip", "unknown")
+		}
+		if !validEntryName(header.Name) {
+			return errors.ErrorParamValueError.Format("chart", "an archive with relative paths in chart", header.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(target, 0755); err != nil {
+				return errors.ErrorInternalUnknown.Format(err)
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err = writeFile(target, tr); err != nil {
+				return err
+			}
+		default:
+			return errors.ErrorParamValueError.Format("chart", "an archive of regular files", header.Name)
+		}
+	}
+}
+
+// validEntryName returns whether name of an archive entry is a relative path in archive
+func validEntryName(name string) bool {
+	if len(name) <= 0 || path.IsAbs(name) || strings.Contains(name, "\\") {
+		return false
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// writeFile writes content of reader to a new file
+func writeFile(name string, reader io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return errors.ErrorInternalUnknown.Format(err)
+	}
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return errors.ErrorInternalUnknown.Format(err)
+	}
+	defer file.Close()
+	if _, err = io.Copy(file, reader); err != nil {
+		return errors.ErrorParamTypeError.Format("chart", "gzip", "unknown")
+	}
+	return nil
+}
+
+// findChartRoot returns the directory which contains Chart.yaml in an expanded archive.
+// Files of a chart are either in the root of archive or in its only top directory
+func findChartRoot(archive string) (string, error) {
+	if _, err := os.Stat(filepath.Join(archive, chartutil.ChartfileName)); err == nil {
+		return archive, nil
+	}
+	files, err := ioutil.ReadDir(archive)
+	if err != nil {
+		return "", errors.ErrorInternalUnknown.Format(err)
+	}
+	for _, file := range files {
+		if file.IsDir() && !strings.HasPrefix(file.Name(), ".") {
+			return filepath.Join(archive, file.Name()), nil
+		}
+	}
+	return "", errors.ErrorParamTypeError.Format("chart", "an archive of chart directory", "unknown")
+}
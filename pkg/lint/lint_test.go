@@ -0,0 +1,95 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package lint
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// testEntry is an entry of test archive
+type testEntry struct {
+	name     string
+	typeflag byte
+	content  string
+	linkname string
+}
+
+// newTestArchive creates a chart archive of entries
+func newTestArchive(t *testing.T, entries ...testEntry) []byte {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Typeflag: e.typeflag, Mode: 0644, Size: int64(len(e.content)), Linkname: e.linkname}
+		if e.typeflag != tar.TypeReg {
+			header.Size = 0
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCheck(t *testing.T) {
+	metadata := &chart.Metadata{Name: "demo", Version: "1.0.0"}
+	report, err := Check(context.Background(), metadata, bytes.NewReader(storagetest.NewArchive(t, "demo", "1.0.0")), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, msg := range report {
+		if msg.Severity == "error" {
+			t.Errorf("unexpected error message: %+v", msg)
+		}
+	}
+}
+
+func TestCheckRejectsUnsafeEntries(t *testing.T) {
+	chartYaml := "apiVersion: v1\nname: demo\nversion: 1.0.0\n"
+	escaped := fmt.Sprintf("registry-lint-escaped-%d", time.Now().UnixNano())
+	defer os.Remove(filepath.Join(os.TempDir(), escaped))
+
+	cases := []struct {
+		name  string
+		entry testEntry
+	}{
+		{"parent segments", testEntry{name: "demo/../../../" + escaped, typeflag: tar.TypeReg, content: "escaped"}},
+		{"absolute path", testEntry{name: filepath.Join(os.TempDir(), escaped), typeflag: tar.TypeReg, content: "escaped"}},
+		{"symbolic link", testEntry{name: "demo/templates/link.yaml", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"}},
+		{"hard link", testEntry{name: "demo/templates/link.yaml", typeflag: tar.TypeLink, linkname: "demo/Chart.yaml"}},
+	}
+	metadata := &chart.Metadata{Name: "demo", Version: "1.0.0"}
+	for _, c := range cases {
+		archive := newTestArchive(t, testEntry{name: "demo/Chart.yaml", typeflag: tar.TypeReg, content: chartYaml}, c.entry)
+		_, err := Check(context.Background(), metadata, bytes.NewReader(archive), false)
+		if !errors.ErrorParamValueError.Equal(err) {
+			t.Errorf("%s: expected a param value error, but got %v", c.name, err)
+		}
+		if _, err = os.Lstat(filepath.Join(os.TempDir(), escaped)); err == nil {
+			t.Fatalf("%s: a file is written out of the lint directory", c.name)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package log
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Config is a config of logging. Empty fields keep the levels and formatters set by
+// ENV_LOG_LEVEL and ENV_LOG_FORMATTER
+type Config struct {
+	// Level is one of debug, info, warning, error, fatal and panic
+	Level string `yaml:"level"`
+	// Format is json or text. Fields of entries, like request_id, are keys of json objects
+	Format string `yaml:"format"`
+}
+
+// Initialize sets the level and formatter of DefaultLogger by config
+func Initialize(config Config) error {
+	logger, ok := DefaultLogger.(*logrus.Logger)
+	if !ok {
+		return nil
+	}
+	if len(config.Level) > 0 {
+		level, err := logrus.ParseLevel(strings.ToLower(config.Level))
+		if err != nil {
+			return err
+		}
+		logger.Level = level
+	}
+	switch strings.ToLower(config.Format) {
+	case "":
+	case LogFormatterJson:
+		logger.Formatter = &logrus.JSONFormatter{}
+	case LogFormatterText:
+		logger.Formatter = &logrus.TextFormatter{}
+	default:
+		return fmt.Errorf("unknown log format %s", config.Format)
+	}
+	return nil
+}
+
+// Fields are structured fields of log entries
+type Fields map[string]interface{}
+
+// fieldsKey is the context key of fields
+type fieldsKey struct{}
+
+// NewContext returns a copy of ctx whose loggers log fields in addition to fields of ctx
+func NewContext(ctx context.Context, fields Fields) context.Context {
+	merged := Fields{}
+	if parent, ok := ctx.Value(fieldsKey{}).(Fields); ok {
+		for k, v := range parent {
+			merged[k] = v
+		}
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// FromContext returns a logger which logs fields of ctx, like request_id of requests.
+// It returns DefaultLogger if ctx has no fields
+func FromContext(ctx context.Context) Logger {
+	return WithFields(ctx, nil)
+}
+
+// WithFields returns a logger which logs fields of ctx and fields
+func WithFields(ctx context.Context, fields Fields) Logger {
+	merged, _ := ctx.Value(fieldsKey{}).(Fields)
+	if len(fields) > 0 {
+		merged, _ = NewContext(ctx, fields).Value(fieldsKey{}).(Fields)
+	}
+	logger, ok := DefaultLogger.(*logrus.Logger)
+	if !ok || len(merged) <= 0 {
+		return DefaultLogger
+	}
+	return logger.WithFields(logrus.Fields(merged))
+}
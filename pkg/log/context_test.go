@@ -0,0 +1,33 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Sirupsen/logrus"
+)
+
+func TestWithFields(t *testing.T) {
+	if FromContext(context.Background()) != DefaultLogger {
+		t.Errorf("logger of context without fields should be the default logger")
+	}
+	ctx := NewContext(context.Background(), Fields{"request_id": "a", "trace_id": "b"})
+	ctx = NewContext(ctx, Fields{"trace_id": "c"})
+	entry, ok := WithFields(ctx, Fields{"status": 200}).(*logrus.Entry)
+	if !ok {
+		t.Fatalf("logger with fields should be an entry")
+	}
+	expected := logrus.Fields{"request_id": "a", "trace_id": "c", "status": 200}
+	if len(entry.Data) != len(expected) {
+		t.Fatalf("expected fields %v, but got %v", expected, entry.Data)
+	}
+	for k, v := range expected {
+		if entry.Data[k] != v {
+			t.Errorf("expected field %s to be %v, but got %v", k, v, entry.Data[k])
+		}
+	}
+}
@@ -0,0 +1,460 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	entityPattern   = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+	autolinkPattern = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9+.-]{1,31}:[^\s<>]*)>`)
+	emailPattern    = regexp.MustCompile(`^<([A-Za-z0-9.+_-]+@[A-Za-z0-9](?:[A-Za-z0-9.-]*[A-Za-z0-9])?)>`)
+	tagPattern      = regexp.MustCompile(`<[^>]*>`)
+)
+
+// punctuations are ASCII punctuations which can be escaped by backslashes
+const punctuations = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+// inline is the state of rendering inlines of a text. It remembers delimiters which
+// are not closed, so later delimiters don't search for them again
+type inline struct {
+	*renderer
+	s string
+	// brackets maps positions of `[` to positions of matched `]`
+	brackets map[int]int
+	// unclosedCode has lengths of backtick runs which are not closed after a position
+	unclosedCode map[int]int
+	// unclosed has delimiter runs which are not closed after a position
+	unclosed map[string]int
+}
+
+// inline renders inlines of s to w
+func (r *renderer) inline(w *bytes.Buffer, s string) {
+	if r.depth > maxDepth {
+		w.WriteString(html.EscapeString(s))
+		return
+	}
+	r.depth++
+	defer func() { r.depth-- }()
+	in := &inline{renderer: r, s: s, unclosedCode: make(map[int]int), unclosed: make(map[string]int)}
+	in.brackets = in.matchBrackets()
+	in.render(w)
+}
+
+// plain renders inlines of s as text without tags
+func (r *renderer) plain(s string) string {
+	buf := &bytes.Buffer{}
+	r.inline(buf, s)
+	return tagPattern.ReplaceAllString(buf.String(), "")
+}
+
+// render renders inlines to w
+func (in *inline) render(w *bytes.Buffer) {
+	s := in.s
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch c {
+		case '\\':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				w.WriteString("<br />\n")
+				i += 2
+				continue
+			}
+			if i+1 < len(s) && strings.IndexByte(punctuations, s[i+1]) >= 0 {
+				w.WriteString(html.EscapeString(s[i+1 : i+2]))
+				i += 2
+				continue
+			}
+		case '`':
+			if end, code, ok := in.codeSpan(i); ok {
+				w.WriteString("<code>")
+				w.WriteString(html.EscapeString(code))
+				w.WriteString("</code>")
+				i = end
+				continue
+			}
+			n := runLength(s, i, c)
+			w.WriteString(s[i : i+n])
+			i += n
+			continue
+		case '!':
+			if end, text, l, ok := in.link(i + 1); ok {
+				in.image(w, text, l)
+				i = end
+				continue
+			}
+		case '[':
+			if end, text, l, ok := in.link(i); ok {
+				in.anchor(w, text, l)
+				i = end
+				continue
+			}
+		case '<':
+			if m := autolinkPattern.FindStringSubmatch(s[i:]); m != nil {
+				if url, ok := safeURL(m[1]); ok {
+					autolink(w, m[1], url)
+					i += len(m[0])
+					continue
+				}
+			}
+			if m := emailPattern.FindStringSubmatch(s[i:]); m != nil {
+				autolink(w, m[1], "mailto:"+m[1])
+				i += len(m[0])
+				continue
+			}
+		case '&':
+			if entity := entityPattern.FindString(s[i:]); len(entity) > 0 {
+				w.WriteString(entity)
+				i += len(entity)
+				continue
+			}
+		case '*', '_', '~':
+			n := runLength(s, i, c)
+			if end, ok := in.emphasis(w, i, n); ok {
+				i = end
+				continue
+			}
+			w.WriteString(s[i : i+n])
+			i += n
+			continue
+		case '\n':
+			w.WriteByte('\n')
+			i++
+			continue
+		}
+		w.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+}
+
+// codeSpan returns the end and content of a code span whose opening backticks start at i
+func (in *inline) codeSpan(i int) (int, string, bool) {
+	s := in.s
+	n := runLength(s, i, '`')
+	if after, ok := in.unclosedCode[n]; ok && i >= after {
+		return 0, "", false
+	}
+	for j := i + n; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		m := runLength(s, j, '`')
+		if m == n {
+			code := strings.Replace(s[i+n:j], "\n", " ", -1)
+			if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && len(strings.Trim(code, " ")) > 0 {
+				code = code[1 : len(code)-1]
+			}
+			return j + m, code, true
+		}
+		j += m
+	}
+	in.unclosedCode[n] = i
+	return 0, "", false
+}
+
+// matchBrackets matches brackets out of code spans
+func (in *inline) matchBrackets() map[int]int {
+	s := in.s
+	brackets := make(map[int]int)
+	opened := make([]int, 0, 1)
+	for j := 0; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '`':
+			if end, _, ok := in.codeSpan(j); ok {
+				j = end - 1
+			} else {
+				j += runLength(s, j, '`') - 1
+			}
+		case '[':
+			opened = append(opened, j)
+		case ']':
+			if len(opened) > 0 {
+				brackets[opened[len(opened)-1]] = j
+				opened = opened[:len(opened)-1]
+			}
+		}
+	}
+	return brackets
+}
+
+// link parses a link whose text starts at i, like `[text](url "title")`, `[text][label]`,
+// `[label][]` or `[label]`. It returns the end, text and destination of link
+func (in *inline) link(i int) (int, string, link, bool) {
+	s := in.s
+	if i >= len(s) || s[i] != '[' {
+		return 0, "", link{}, false
+	}
+	close, ok := in.brackets[i]
+	if !ok {
+		return 0, "", link{}, false
+	}
+	text := s[i+1 : close]
+	j := close + 1
+	if j < len(s) && s[j] == '(' {
+		if end, l, ok := inlineLink(s, j); ok {
+			return end, text, l, true
+		}
+	}
+	label, end := text, j
+	if j < len(s) && s[j] == '[' {
+		if k, ok := in.brackets[j]; ok {
+			if k > j+1 {
+				label = s[j+1 : k]
+			}
+			end = k + 1
+		}
+	}
+	l, ok := in.links[normalizeLabel(label)]
+	return end, text, l, ok
+}
+
+// inlineLink parses the destination and title of an inline link which start at j,
+// like `(url "title")`
+func inlineLink(s string, j int) (int, link, bool) {
+	k := skipSpaces(s, j+1)
+	var url string
+	if k < len(s) && s[k] == '<' {
+		e := strings.IndexAny(s[k+1:], ">\n")
+		if e < 0 || s[k+1+e] != '>' {
+			return 0, link{}, false
+		}
+		url = s[k+1 : k+1+e]
+		k += e + 2
+	} else {
+		start, depth := k, 0
+		for ; k < len(s); k++ {
+			c := s[k]
+			if c == '\\' && k+1 < len(s) {
+				k++
+				continue
+			}
+			if c == '(' {
+				depth++
+			} else if c == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			} else if c <= ' ' {
+				break
+			}
+		}
+		url = s[start:k]
+	}
+	k = skipSpaces(s, k)
+	var title string
+	if k < len(s) && (s[k] == '"' || s[k] == '\'' || s[k] == '(') {
+		closing := s[k]
+		if closing == '(' {
+			closing = ')'
+		}
+		e := k + 1
+		for ; e < len(s) && s[e] != closing; e++ {
+			if s[e] == '\\' {
+				e++
+			}
+		}
+		if e >= len(s) {
+			return 0, link{}, false
+		}
+		title = s[k+1 : e]
+		k = skipSpaces(s, e+1)
+	}
+	if k >= len(s) || s[k] != ')' {
+		return 0, link{}, false
+	}
+	return k + 1, link{url: unescape(url), title: unescape(title)}, true
+}
+
+// anchor renders a link. A link with an unsafe url is rendered as its text
+func (in *inline) anchor(w *bytes.Buffer, text string, l link) {
+	url, ok := safeURL(l.url)
+	if !ok {
+		in.renderer.inline(w, text)
+		return
+	}
+	w.WriteString(`<a href="`)
+	w.WriteString(html.EscapeString(url))
+	w.WriteString(`"`)
+	if len(l.title) > 0 {
+		w.WriteString(` title="`)
+		w.WriteString(html.EscapeString(l.title))
+		w.WriteString(`"`)
+	}
+	w.WriteString(">")
+	in.renderer.inline(w, text)
+	w.WriteString("</a>")
+}
+
+// autolink renders a link whose text is the url itself
+func autolink(w *bytes.Buffer, text, url string) {
+	w.WriteString(`<a href="`)
+	w.WriteString(html.EscapeString(url))
+	w.WriteString(`">`)
+	w.WriteString(html.EscapeString(text))
+	w.WriteString("</a>")
+}
+
+// image renders an image. An image with an unsafe url is rendered as its alt text
+func (in *inline) image(w *bytes.Buffer, text string, l link) {
+	alt := in.plain(text)
+	url, ok := safeURL(l.url)
+	if !ok {
+		w.WriteString(alt)
+		return
+	}
+	w.WriteString(`<img src="`)
+	w.WriteString(html.EscapeString(url))
+	w.WriteString(`" alt="`)
+	w.WriteString(alt)
+	w.WriteString(`"`)
+	if len(l.title) > 0 {
+		w.WriteString(` title="`)
+		w.WriteString(html.EscapeString(l.title))
+		w.WriteString(`"`)
+	}
+	w.WriteString(" />")
+}
+
+// emphasis renders emphasis, strong emphasis or strikethrough whose opening delimiter
+// run of n characters starts at i. It returns false if the run is not closed
+func (in *inline) emphasis(w *bytes.Buffer, i, n int) (int, bool) {
+	s := in.s
+	c := s[i]
+	if (c == '~' && n != 2) || n > 3 || !canOpen(s, i, n) {
+		return 0, false
+	}
+	j := in.closer(i+n, c, n)
+	if j < 0 {
+		return 0, false
+	}
+	open, close := "<em>", "</em>"
+	switch {
+	case c == '~':
+		open, close = "<del>", "</del>"
+	case n == 2:
+		open, close = "<strong>", "</strong>"
+	case n == 3:
+		open, close = "<em><strong>", "</strong></em>"
+	}
+	w.WriteString(open)
+	in.renderer.inline(w, s[i+n:j])
+	w.WriteString(close)
+	return j + n, true
+}
+
+// closer returns the position of a delimiter run of n c which closes the run opened
+// before from. It returns -1 if there is no such run
+func (in *inline) closer(from int, c byte, n int) int {
+	s := in.s
+	key := strings.Repeat(string(c), n)
+	if after, ok := in.unclosed[key]; ok && from >= after {
+		return -1
+	}
+	for j := from; j < len(s); {
+		switch s[j] {
+		case '\\':
+			j += 2
+			continue
+		case '`':
+			if end, _, ok := in.codeSpan(j); ok {
+				j = end
+			} else {
+				j += runLength(s, j, '`')
+			}
+			continue
+		case c:
+			m := runLength(s, j, c)
+			if m == n && j > from && canClose(s, j, m) {
+				return j
+			}
+			j += m
+			continue
+		}
+		j++
+	}
+	in.unclosed[key] = from
+	return -1
+}
+
+// canOpen returns whether the delimiter run of n characters at i can open emphasis.
+// Underscores in words don't open emphasis
+func canOpen(s string, i, n int) bool {
+	if i+n >= len(s) || isSpace(s[i+n]) {
+		return false
+	}
+	return s[i] != '_' || i == 0 || !isWord(s[i-1])
+}
+
+// canClose returns whether the delimiter run of n characters at j can close emphasis
+func canClose(s string, j, n int) bool {
+	if isSpace(s[j-1]) {
+		return false
+	}
+	return s[j] != '_' || j+n >= len(s) || !isWord(s[j+n])
+}
+
+// safeURL returns url if it's relative or an http, https or mailto url
+func safeURL(url string) (string, bool) {
+	url = strings.TrimSpace(url)
+	if k := strings.IndexAny(url, ":/?#"); k >= 0 && url[k] == ':' {
+		switch strings.ToLower(url[:k]) {
+		case "http", "https", "mailto":
+		default:
+			return "", false
+		}
+	}
+	return strings.Replace(url, " ", "%20", -1), true
+}
+
+// unescape removes backslashes which escape punctuations
+func unescape(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	buf := &bytes.Buffer{}
+	for k := 0; k < len(s); k++ {
+		if s[k] == '\\' && k+1 < len(s) && strings.IndexByte(punctuations, s[k+1]) >= 0 {
+			k++
+		}
+		buf.WriteByte(s[k])
+	}
+	return buf.String()
+}
+
+// runLength returns the number of c from i
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+// skipSpaces returns the position of the first character which is not a space or
+// a line break from i
+func skipSpaces(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+// isSpace returns whether c is a whitespace
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\t'
+}
+
+// isWord returns whether c is a letter, a digit or a byte of a non-ASCII character
+func isWord(c byte) bool {
+	return c >= 0x80 || c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
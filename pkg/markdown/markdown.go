@@ -0,0 +1,614 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+// Package markdown renders markdown documents like README.md of charts to html. It
+// supports CommonMark blocks and inlines which are common in readmes, and tables and
+// strikethrough of GitHub flavored markdown. Raw html is escaped, so rendered
+// documents can be embedded in pages.
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxDepth is the max nesting depth of blocks and inlines. Deeper content is rendered
+// as text, so a crafted document can't take quadratic time
+const maxDepth = 32
+
+var (
+	headingPattern    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+	setextPattern     = regexp.MustCompile(`^ {0,3}(=+|-+)\s*$`)
+	breakPattern      = regexp.MustCompile(`^ {0,3}((\*\s*){3,}|(-\s*){3,}|(_\s*){3,})$`)
+	fencePattern      = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})\\s*(.*)$")
+	bulletPattern     = regexp.MustCompile(`^( {0,3})([-+*])( +|$)`)
+	orderedPattern    = regexp.MustCompile(`^( {0,3})([0-9]{1,9})([.)])( +|$)`)
+	delimiterPattern  = regexp.MustCompile(`^:?-+:?$`)
+	definitionPattern = regexp.MustCompile(`^ {0,3}\[((?:[^\]\\]|\\.)+)\]:\s*(<[^>]*>|\S+)(?:\s+("(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|\((?:[^)\\]|\\.)*\)))?\s*$`)
+)
+
+// Render renders a markdown document to html
+func Render(source []byte) []byte {
+	lines := splitLines(string(source))
+	r := &renderer{links: collectLinks(lines), ids: make(map[string]int)}
+	r.blocks(lines, false)
+	return r.buf.Bytes()
+}
+
+// link is the destination and title of a link or an image
+type link struct {
+	url   string
+	title string
+}
+
+// renderer renders a document
+type renderer struct {
+	buf bytes.Buffer
+	// links are link reference definitions by their normalized labels
+	links map[string]link
+	// ids counts ids of headings, so duplicated ids get suffixes
+	ids map[string]int
+	// depth is the nesting depth of the block or inline being rendered
+	depth int
+	// tightEnd is the end of the last paragraph, so a tight list item which ends with
+	// a paragraph is closed right after its text
+	tightEnd int
+}
+
+// splitLines splits source to lines, and expands tabs in indentations to spaces
+func splitLines(source string) []string {
+	source = strings.Replace(source, "\r\n", "\n", -1)
+	source = strings.Replace(source, "\r", "\n", -1)
+	lines := strings.Split(source, "\n")
+	for i, line := range lines {
+		lines[i] = expandTabs(line)
+	}
+	return lines
+}
+
+// expandTabs expands tabs in the indentation of line to tab stops of 4 columns
+func expandTabs(line string) string {
+	column := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			column++
+		case '\t':
+			column += 4 - column%4
+		default:
+			if column == i {
+				return line
+			}
+			return strings.Repeat(" ", column) + line[i:]
+		}
+	}
+	return strings.Repeat(" ", column)
+}
+
+// collectLinks collects link reference definitions out of fenced code blocks
+func collectLinks(lines []string) map[string]link {
+	links := make(map[string]link)
+	fence := ""
+	for _, line := range lines {
+		if len(fence) > 0 {
+			if closesFence(line, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if _, f, _, ok := parseFence(line); ok {
+			fence = f
+			continue
+		}
+		m := definitionPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		label := normalizeLabel(m[1])
+		if _, ok := links[label]; ok {
+			continue
+		}
+		url := strings.TrimSuffix(strings.TrimPrefix(m[2], "<"), ">")
+		title := m[3]
+		if len(title) >= 2 {
+			title = title[1 : len(title)-1]
+		}
+		links[label] = link{url: unescape(url), title: unescape(title)}
+	}
+	return links
+}
+
+// normalizeLabel normalizes a link label, so labels are matched case-insensitively
+// and with collapsed whitespaces
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// blocks renders lines as blocks. Paragraphs in tight lists are not wrapped by <p>
+func (r *renderer) blocks(lines []string, tight bool) {
+	if r.depth > maxDepth {
+		r.text(lines)
+		return
+	}
+	r.depth++
+	defer func() { r.depth-- }()
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case isBlank(line):
+			i++
+		case indent(line) >= 4:
+			i = r.indentedCode(lines, i)
+		case isFence(line):
+			i = r.fencedCode(lines, i)
+		case headingPattern.MatchString(line):
+			m := headingPattern.FindStringSubmatch(line)
+			r.heading(len(m[1]), m[2])
+			i++
+		case breakPattern.MatchString(line):
+			r.buf.WriteString("<hr />\n")
+			i++
+		case isQuote(line):
+			i = r.quote(lines, i)
+		case isMarker(line):
+			i = r.list(lines, i)
+		case isTable(lines, i):
+			i = r.table(lines, i)
+		default:
+			i = r.paragraph(lines, i, tight)
+		}
+	}
+}
+
+// text renders lines as escaped text
+func (r *renderer) text(lines []string) {
+	r.buf.WriteString("<p>")
+	r.buf.WriteString(html.EscapeString(strings.TrimSpace(strings.Join(lines, "\n"))))
+	r.buf.WriteString("</p>\n")
+}
+
+// paragraph renders a paragraph or a setext heading which starts at lines[i]. Link
+// reference definitions at the start of paragraph are skipped
+func (r *renderer) paragraph(lines []string, i int, tight bool) int {
+	start := i
+	text := make([]string, 0, 1)
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if len(text) > 0 {
+			if m := setextPattern.FindStringSubmatch(line); m != nil {
+				level := 1
+				if m[1][0] == '-' {
+					level = 2
+				}
+				r.heading(level, strings.Join(text, "\n"))
+				return i + 1
+			}
+		}
+		if isBlank(line) || (i > start && interrupts(line)) {
+			break
+		}
+		if len(text) <= 0 && definitionPattern.MatchString(line) {
+			continue
+		}
+		text = append(text, line)
+	}
+	if len(text) <= 0 {
+		return i
+	}
+	for k, line := range text {
+		line = strings.TrimLeft(line, " ")
+		trimmed := strings.TrimRight(line, " ")
+		if k < len(text)-1 && len(line)-len(trimmed) >= 2 {
+			// two trailing spaces are a hard line break like a trailing backslash
+			trimmed += `\`
+		}
+		text[k] = trimmed
+	}
+	if !tight {
+		r.buf.WriteString("<p>")
+	}
+	r.inline(&r.buf, strings.Join(text, "\n"))
+	if !tight {
+		r.buf.WriteString("</p>")
+	}
+	r.tightEnd = r.buf.Len()
+	r.buf.WriteString("\n")
+	return i
+}
+
+// heading renders a heading with an id generated from its text like GitHub
+func (r *renderer) heading(level int, text string) {
+	text = strings.TrimSpace(text)
+	fmt.Fprintf(&r.buf, "<h%d", level)
+	if id := r.headingID(text); len(id) > 0 {
+		fmt.Fprintf(&r.buf, ` id="%s"`, id)
+	}
+	r.buf.WriteString(">")
+	r.inline(&r.buf, text)
+	fmt.Fprintf(&r.buf, "</h%d>\n", level)
+}
+
+// headingID returns an id of heading, which has lower case letters, digits, `-` and `_`
+func (r *renderer) headingID(text string) string {
+	id := &bytes.Buffer{}
+	for _, c := range strings.ToLower(html.UnescapeString(r.plain(text))) {
+		switch {
+		case unicode.IsLetter(c) || unicode.IsDigit(c) || c == '-' || c == '_':
+			id.WriteRune(c)
+		case c == ' ':
+			id.WriteByte('-')
+		}
+	}
+	if id.Len() <= 0 {
+		return ""
+	}
+	result := id.String()
+	n := r.ids[result]
+	r.ids[result] = n + 1
+	if n > 0 {
+		result = fmt.Sprintf("%s-%d", result, n)
+	}
+	return result
+}
+
+// indentedCode renders an indented code block which starts at lines[i]
+func (r *renderer) indentedCode(lines []string, i int) int {
+	code := make([]string, 0, 1)
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if isBlank(line) {
+			code = append(code, "")
+			continue
+		}
+		if indent(line) < 4 {
+			break
+		}
+		code = append(code, line[4:])
+	}
+	for len(code) > 0 && len(code[len(code)-1]) <= 0 {
+		code = code[:len(code)-1]
+	}
+	r.code("", code)
+	return i
+}
+
+// fencedCode renders a fenced code block which starts at lines[i]. The first word of
+// info string is the language of code
+func (r *renderer) fencedCode(lines []string, i int) int {
+	spaces, fence, info, _ := parseFence(lines[i])
+	code := make([]string, 0, 1)
+	for i++; i < len(lines); i++ {
+		if closesFence(lines[i], fence) {
+			i++
+			break
+		}
+		code = append(code, removeIndent(lines[i], spaces))
+	}
+	language := ""
+	if fields := strings.Fields(info); len(fields) > 0 {
+		language = unescape(fields[0])
+	}
+	r.code(language, code)
+	return i
+}
+
+// code renders a code block
+func (r *renderer) code(language string, lines []string) {
+	r.buf.WriteString("<pre><code")
+	if len(language) > 0 {
+		fmt.Fprintf(&r.buf, ` class="language-%s"`, html.EscapeString(language))
+	}
+	r.buf.WriteString(">")
+	for _, line := range lines {
+		r.buf.WriteString(html.EscapeString(line))
+		r.buf.WriteString("\n")
+	}
+	r.buf.WriteString("</code></pre>\n")
+}
+
+// quote renders a block quote which starts at lines[i]. Lines without `>` continue
+// a paragraph in quote
+func (r *renderer) quote(lines []string, i int) int {
+	inner := make([]string, 0, 1)
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if isQuote(line) {
+			line = strings.TrimLeft(line, " ")[1:]
+			inner = append(inner, strings.TrimPrefix(line, " "))
+			continue
+		}
+		if isBlank(line) || isBlank(inner[len(inner)-1]) || interrupts(line) {
+			break
+		}
+		inner = append(inner, line)
+	}
+	r.buf.WriteString("<blockquote>\n")
+	r.blocks(inner, false)
+	r.buf.WriteString("</blockquote>\n")
+	return i
+}
+
+// marker is the marker of a list item
+type marker struct {
+	ordered bool
+	// char is `-`, `+` or `*` of bullet lists, or `.` or `)` of ordered lists
+	char byte
+	// start is the number of an ordered list item
+	start int
+	// width is the indentation of content of list item
+	width int
+}
+
+// sameList returns whether items with markers m and other are in a list
+func (m marker) sameList(other marker) bool {
+	return m.ordered == other.ordered && m.char == other.char
+}
+
+// parseMarker parses the marker of a list item, and returns the content after marker
+func parseMarker(line string) (marker, string, bool) {
+	if breakPattern.MatchString(line) {
+		return marker{}, "", false
+	}
+	if m := bulletPattern.FindStringSubmatch(line); m != nil {
+		return newMarker(marker{char: m[2][0], start: 1, width: len(m[1]) + 1}, m[3], line[len(m[0]):])
+	}
+	if m := orderedPattern.FindStringSubmatch(line); m != nil {
+		start, _ := strconv.Atoi(m[2])
+		return newMarker(marker{ordered: true, char: m[3][0], start: start, width: len(m[1]) + len(m[2]) + 1}, m[4], line[len(m[0]):])
+	}
+	return marker{}, "", false
+}
+
+// newMarker adds spaces after marker to the width of m. Content after 5 or more spaces
+// is an indented code block, so only one space belongs to marker
+func newMarker(m marker, spaces, content string) (marker, string, bool) {
+	if len(content) <= 0 {
+		spaces = " "
+	} else if len(spaces) > 4 {
+		content = spaces[1:] + content
+		spaces = " "
+	}
+	m.width += len(spaces)
+	return m, content, true
+}
+
+// list renders a list which starts at lines[i]. A list is loose if its items are
+// separated by blank lines or contain blank lines between blocks
+func (r *renderer) list(lines []string, i int) int {
+	first, _, _ := parseMarker(lines[i])
+	items := make([][]string, 0, 1)
+	loose := false
+	for i < len(lines) {
+		m, content, ok := parseMarker(lines[i])
+		if !ok || !first.sameList(m) {
+			break
+		}
+		item := []string{content}
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if isBlank(line) {
+				next := nextNonBlank(lines, i)
+				if next >= len(lines) || indent(lines[next]) < m.width {
+					break
+				}
+				item = append(item, "")
+				continue
+			}
+			if indent(line) >= m.width {
+				item = append(item, line[m.width:])
+				continue
+			}
+			// a line which is not indented continues the paragraph in item
+			if isBlank(item[len(item)-1]) || interrupts(line) || isMarker(line) {
+				break
+			}
+			item = append(item, strings.TrimLeft(line, " "))
+		}
+		for k := 0; k < len(item)-1; k++ {
+			if isBlank(item[k]) && k > 0 {
+				loose = true
+			}
+		}
+		items = append(items, item)
+		if next := nextNonBlank(lines, i); next > i && next < len(lines) {
+			if m, _, ok := parseMarker(lines[next]); ok && first.sameList(m) {
+				loose = true
+				i = next
+			}
+		}
+	}
+	tag := "ul"
+	if first.ordered {
+		tag = "ol"
+	}
+	r.buf.WriteString("<" + tag)
+	if first.ordered && first.start != 1 {
+		fmt.Fprintf(&r.buf, ` start="%d"`, first.start)
+	}
+	r.buf.WriteString(">\n")
+	for _, item := range items {
+		r.buf.WriteString("<li>")
+		if loose {
+			r.buf.WriteString("\n")
+		}
+		r.blocks(item, !loose)
+		if !loose && r.buf.Len() == r.tightEnd+1 {
+			r.buf.Truncate(r.tightEnd)
+		}
+		r.buf.WriteString("</li>\n")
+	}
+	r.buf.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// table renders a table which starts at lines[i]. The header row is lines[i] and
+// lines[i+1] has alignments of columns
+func (r *renderer) table(lines []string, i int) int {
+	aligns, _ := parseDelimiterRow(lines[i+1])
+	r.buf.WriteString("<table>\n<thead>\n")
+	r.row("th", splitRow(lines[i]), aligns)
+	r.buf.WriteString("</thead>\n")
+	i += 2
+	if i < len(lines) && !isBlank(lines[i]) && !interrupts(lines[i]) {
+		r.buf.WriteString("<tbody>\n")
+		for ; i < len(lines) && !isBlank(lines[i]) && !interrupts(lines[i]); i++ {
+			r.row("td", splitRow(lines[i]), aligns)
+		}
+		r.buf.WriteString("</tbody>\n")
+	}
+	r.buf.WriteString("</table>\n")
+	return i
+}
+
+// row renders a row of table. Missing cells are empty and extra cells are ignored
+func (r *renderer) row(tag string, cells []string, aligns []string) {
+	r.buf.WriteString("<tr>\n")
+	for k, align := range aligns {
+		r.buf.WriteString("<" + tag)
+		if len(align) > 0 {
+			fmt.Fprintf(&r.buf, ` align="%s"`, align)
+		}
+		r.buf.WriteString(">")
+		if k < len(cells) {
+			r.inline(&r.buf, cells[k])
+		}
+		r.buf.WriteString("</" + tag + ">\n")
+	}
+	r.buf.WriteString("</tr>\n")
+}
+
+// isTable returns whether lines[i] is the header row of a table
+func isTable(lines []string, i int) bool {
+	if i+1 >= len(lines) || !strings.Contains(lines[i], "|") {
+		return false
+	}
+	aligns, ok := parseDelimiterRow(lines[i+1])
+	return ok && len(splitRow(lines[i])) == len(aligns)
+}
+
+// parseDelimiterRow parses a row like `| :--- | :---: | ---: |` to alignments of columns
+func parseDelimiterRow(line string) ([]string, bool) {
+	if !strings.Contains(line, "|") || indent(line) >= 4 {
+		return nil, false
+	}
+	cells := splitRow(line)
+	aligns := make([]string, len(cells))
+	for k, cell := range cells {
+		if !delimiterPattern.MatchString(cell) {
+			return nil, false
+		}
+		left, right := cell[0] == ':', cell[len(cell)-1] == ':'
+		switch {
+		case left && right:
+			aligns[k] = "center"
+		case left:
+			aligns[k] = "left"
+		case right:
+			aligns[k] = "right"
+		}
+	}
+	return aligns, true
+}
+
+// splitRow splits a row of table to cells. Escaped pipes `\|` are kept in cells
+func splitRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+	cells := make([]string, 0, 2)
+	cell := &bytes.Buffer{}
+	for k := 0; k < len(line); k++ {
+		switch {
+		case line[k] == '\\' && k+1 < len(line) && line[k+1] == '|':
+			cell.WriteByte('|')
+			k++
+		case line[k] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[k])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// parseFence parses the opening line of a fenced code block. It returns the indentation,
+// fence and info string of the block
+func parseFence(line string) (int, string, string, bool) {
+	m := fencePattern.FindStringSubmatch(line)
+	if m == nil || (m[2][0] == '`' && strings.Contains(m[3], "`")) {
+		return 0, "", "", false
+	}
+	return len(m[1]), m[2], m[3], true
+}
+
+// closesFence returns whether line closes a fenced code block opened by fence
+func closesFence(line, fence string) bool {
+	if indent(line) >= 4 {
+		return false
+	}
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, fence) && len(strings.Trim(line, fence[:1])) <= 0
+}
+
+// isFence returns whether line opens a fenced code block
+func isFence(line string) bool {
+	_, _, _, ok := parseFence(line)
+	return ok
+}
+
+// isQuote returns whether line is in a block quote
+func isQuote(line string) bool {
+	n := indent(line)
+	return n < 4 && n < len(line) && line[n] == '>'
+}
+
+// isMarker returns whether line starts a list item
+func isMarker(line string) bool {
+	_, _, ok := parseMarker(line)
+	return ok
+}
+
+// interrupts returns whether line starts a block which interrupts a paragraph. Only
+// list items which are not empty and ordered lists which start with 1 interrupt
+func interrupts(line string) bool {
+	if isFence(line) || headingPattern.MatchString(line) || breakPattern.MatchString(line) || isQuote(line) {
+		return true
+	}
+	m, content, ok := parseMarker(line)
+	return ok && !isBlank(content) && (!m.ordered || m.start == 1)
+}
+
+// isBlank returns whether line only has whitespaces
+func isBlank(line string) bool {
+	return len(strings.TrimSpace(line)) <= 0
+}
+
+// indent returns the number of spaces at the start of line
+func indent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// removeIndent removes at most n spaces at the start of line
+func removeIndent(line string, n int) string {
+	if spaces := indent(line); spaces < n {
+		n = spaces
+	}
+	return line[n:]
+}
+
+// nextNonBlank returns the index of the first line which is not blank from lines[i]
+func nextNonBlank(lines []string, i int) int {
+	for i < len(lines) && isBlank(lines[i]) {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package markdown
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	cases := []struct {
+		source   string
+		expected string
+	}{
+		{"# Title\n\ntext", "<h1 id=\"title\">Title</h1>\n<p>text</p>\n"},
+		{"## Usage ##\n## Usage", "<h2 id=\"usage\">Usage</h2>\n<h2 id=\"usage-1\">Usage</h2>\n"},
+		{"Setext\n======\nSub\n---", "<h1 id=\"setext\">Setext</h1>\n<h2 id=\"sub\">Sub</h2>\n"},
+		{"a *b* **c** ***d*** ~~e~~ snake_case_name", "<p>a <em>b</em> <strong>c</strong> <em><strong>d</strong></em> <del>e</del> snake_case_name</p>\n"},
+		{"line  \nbreak", "<p>line<br />\nbreak</p>\n"},
+		{"```yaml\na: <b>\n```", "<pre><code class=\"language-yaml\">a: &lt;b&gt;\n</code></pre>\n"},
+		{"    indented\n", "<pre><code>indented\n</code></pre>\n"},
+		{"`a <b>` \\*c\\*", "<p><code>a &lt;b&gt;</code> *c*</p>\n"},
+		{"***", "<hr />\n"},
+		{"> quote\nlazy", "<blockquote>\n<p>quote\nlazy</p>\n</blockquote>\n"},
+		{"- a\n- b\n  - c", "<ul>\n<li>a</li>\n<li>b\n<ul>\n<li>c</li>\n</ul>\n</li>\n</ul>\n"},
+		{"3. a\n\n4. b", "<ol start=\"3\">\n<li>\n<p>a</p>\n</li>\n<li>\n<p>b</p>\n</li>\n</ol>\n"},
+		{"| a | b |\n|:--|--:|\n| 1 | x \\| y |", "<table>\n<thead>\n<tr>\n<th align=\"left\">a</th>\n<th align=\"right\">b</th>\n</tr>\n</thead>\n" +
+			"<tbody>\n<tr>\n<td align=\"left\">1</td>\n<td align=\"right\">x | y</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"[link](https://a.io \"t\") <https://b.io> <me@c.io>", "<p><a href=\"https://a.io\" title=\"t\">link</a> <a href=\"https://b.io\">https://b.io</a> <a href=\"mailto:me@c.io\">me@c.io</a></p>\n"},
+		{"[ref][r] and [r]\n\n[r]: ./docs.md", "<p><a href=\"./docs.md\">ref</a> and <a href=\"./docs.md\">r</a></p>\n"},
+		{"[![badge](https://a.io/b.svg)](https://a.io)", "<p><a href=\"https://a.io\"><img src=\"https://a.io/b.svg\" alt=\"badge\" /></a></p>\n"},
+		{"&copy; &amp; & <", "<p>&copy; &amp; &amp; &lt;</p>\n"},
+		// raw html and unsafe urls are not rendered
+		{"<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"[x](javascript:alert(1))", "<p>x</p>\n"},
+		{"![x](data:image/svg+xml,1)", "<p>x</p>\n"},
+		{"[x](https://a.io\" onclick=\"alert(1))", "<p>[x](https://a.io&#34; onclick=&#34;alert(1))</p>\n"},
+	}
+	for _, c := range cases {
+		if result := string(Render([]byte(c.source))); result != c.expected {
+			t.Errorf("expected %q to be rendered as %q, but got %q", c.source, c.expected, result)
+		}
+	}
+}
+
+func TestRenderPathological(t *testing.T) {
+	sources := []string{
+		strings.Repeat("[", 20000) + strings.Repeat("a](b)", 20000),
+		strings.Repeat("*a ", 20000),
+		strings.Repeat("`", 20000) + "a",
+		strings.Repeat("> ", 10000) + "a",
+		strings.Repeat("- ", 10000) + "a",
+	}
+	for _, source := range sources {
+		start := time.Now()
+		Render([]byte(source))
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("rendering %q... takes %v", source[:16], elapsed)
+		}
+	}
+}
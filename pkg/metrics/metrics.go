@@ -0,0 +1,243 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace is the prefix of all metrics
+const namespace = "helm_registry"
+
+// storageInterval is the minimum interval between two counts of charts and versions in storage
+const storageInterval = time.Minute
+
+// Operation is a kind of operation on versions of chart
+type Operation string
+
+const (
+	// OperationUpload means a version is uploaded
+	OperationUpload Operation = "upload"
+	// OperationDownload means a version is downloaded
+	OperationDownload Operation = "download"
+	// OperationDelete means a version is deleted
+	OperationDelete Operation = "delete"
+	// OperationUpdateMetadata means the metadata of a version is updated
+	OperationUpdateMetadata Operation = "update_metadata"
+	// OperationUpdateValues means the values of a version are updated
+	OperationUpdateValues Operation = "update_values"
+	// OperationPrune means a version is deleted by its retention policy
+	OperationPrune Operation = "prune"
+	// OperationMirror means a version is synced from an upstream repository
+	OperationMirror Operation = "mirror"
+	// OperationProxy means a version is pulled from the upstream of a proxy when it's fetched
+	OperationProxy Operation = "proxy"
+)
+
+var (
+	// operations counts operations on versions by space
+	operations = map[Operation]*prometheus.CounterVec{
+		OperationUpload:         newOperationCounter("chart_uploads_total", "Total number of uploaded chart versions."),
+		OperationDownload:       newOperationCounter("chart_downloads_total", "Total number of downloaded chart versions."),
+		OperationDelete:         newOperationCounter("chart_deletes_total", "Total number of deleted chart versions."),
+		OperationUpdateMetadata: newOperationCounter("metadata_updates_total", "Total number of metadata updates."),
+		OperationUpdateValues:   newOperationCounter("values_updates_total", "Total number of values updates."),
+		OperationPrune:          newOperationCounter("chart_prunes_total", "Total number of chart versions pruned by retention policies."),
+		OperationMirror:         newOperationCounter("chart_mirrors_total", "Total number of chart versions synced from upstream repositories."),
+		OperationProxy:          newOperationCounter("chart_proxy_pulls_total", "Total number of chart versions pulled by proxies."),
+	}
+
+	// handlerDuration observes latencies of handlers
+	handlerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "handler_duration_seconds",
+		Help:      "Latencies of API handlers in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "path"})
+
+	// requests counts requests by route path and status code
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_total",
+		Help:      "Total number of API requests.",
+	}, []string{"method", "path", "code"})
+
+	// uploadsInFlight is the number of uploads which are being processed
+	uploadsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "uploads_in_flight",
+		Help:      "Number of chart uploads being processed.",
+	})
+
+	// storageDuration observes latencies of storage driver operations
+	storageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Latencies of storage driver operations in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"driver", "operation"})
+
+	// storageErrors counts failed storage driver operations
+	storageErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_errors_total",
+		Help:      "Total number of failed storage driver operations.",
+	}, []string{"driver", "operation"})
+
+	// errorResponses counts error responses by error code and name
+	errorResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "error_responses_total",
+		Help:      "Total number of error responses.",
+	}, []string{"code", "error"})
+)
+
+func init() {
+	for _, counter := range operations {
+		prometheus.MustRegister(counter)
+	}
+	prometheus.MustRegister(handlerDuration, requests, uploadsInFlight, storageDuration, storageErrors,
+		errorResponses, &storageCollector{})
+}
+
+// newOperationCounter creates a counter with label space
+func newOperationCounter(name, help string) *prometheus.CounterVec {
+	return prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, []string{"space"})
+}
+
+// Handler returns a http handler which exposes metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Count counts an operation in space
+func Count(operation Operation, space string) {
+	if counter, ok := operations[operation]; ok {
+		counter.WithLabelValues(space).Inc()
+	}
+}
+
+// ObserveHandler counts a request and observes the latency of its handler. path is
+// the route path of the handler and code is the status code of response
+func ObserveHandler(method, path string, code int, start time.Time) {
+	handlerDuration.WithLabelValues(method, path).Observe(time.Since(start).Seconds())
+	requests.WithLabelValues(method, path, strconv.Itoa(code)).Inc()
+}
+
+// TrackUpload marks an upload in flight. The returned function must be called when
+// the upload finishes
+func TrackUpload() func() {
+	uploadsInFlight.Inc()
+	return uploadsInFlight.Dec
+}
+
+// ObserveStorage observes the latency of a storage driver operation. A non-nil err
+// means the operation failed
+func ObserveStorage(driver, operation string, start time.Time, err error) {
+	storageDuration.WithLabelValues(driver, operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		storageErrors.WithLabelValues(driver, operation).Inc()
+	}
+}
+
+// CountError counts an error response by the name of err
+func CountError(err *errors.Error) {
+	errorResponses.WithLabelValues(strconv.Itoa(err.Code), err.Name).Inc()
+}
+
+var (
+	chartsDesc   = prometheus.NewDesc(namespace+"_charts", "Number of charts in space.", []string{"space"}, nil)
+	versionsDesc = prometheus.NewDesc(namespace+"_versions", "Number of chart versions in space.", []string{"space"}, nil)
+)
+
+// storageCount is the number of charts and versions in a space
+type storageCount struct {
+	space    string
+	charts   int
+	versions int
+}
+
+// storageCollector collects the number of charts and versions from space manager when scraping.
+// Counting walks the whole storage, so the counts are cached for storageInterval
+type storageCollector struct {
+	lock    sync.Mutex
+	counted time.Time
+	counts  []storageCount
+}
+
+// Describe implements prometheus.Collector
+func (sc *storageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- chartsDesc
+	ch <- versionsDesc
+}
+
+// Collect implements prometheus.Collector
+func (sc *storageCollector) Collect(ch chan<- prometheus.Metric) {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+	if sc.counted.IsZero() || time.Since(sc.counted) >= storageInterval {
+		counts, err := countStorage(context.Background())
+		if err == nil {
+			sc.counts = counts
+			sc.counted = time.Now()
+		}
+	}
+	for _, count := range sc.counts {
+		ch <- prometheus.MustNewConstMetric(chartsDesc, prometheus.GaugeValue, float64(count.charts), count.space)
+		ch <- prometheus.MustNewConstMetric(versionsDesc, prometheus.GaugeValue, float64(count.versions), count.space)
+	}
+}
+
+// countStorage counts charts and versions of every space in storage
+func countStorage(ctx context.Context) ([]storageCount, error) {
+	manager, err := common.GetSpaceManager()
+	if err != nil {
+		return nil, err
+	}
+	spaces, err := manager.List(ctx)
+	if err != nil {
+		log.Errorf("Failed to list spaces for metrics: %v", err)
+		return nil, err
+	}
+	counts := make([]storageCount, 0, len(spaces))
+	for _, spaceName := range spaces {
+		space, err := manager.Space(ctx, spaceName)
+		if err != nil {
+			continue
+		}
+		charts, err := space.List(ctx)
+		if err != nil {
+			continue
+		}
+		versions := 0
+		for _, chartName := range charts {
+			chart, err := space.Chart(ctx, chartName)
+			if err != nil {
+				continue
+			}
+			numbers, err := chart.List(ctx)
+			if err != nil {
+				continue
+			}
+			versions += len(numbers)
+		}
+		counts = append(counts, storageCount{spaceName, len(charts), versions})
+	}
+	return counts, nil
+}
@@ -0,0 +1,510 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/lint"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/metrics"
+	"github.com/caicloud/helm-registry/pkg/provenance"
+	"github.com/caicloud/helm-registry/pkg/quota"
+	"github.com/caicloud/helm-registry/pkg/scan"
+	"github.com/caicloud/helm-registry/pkg/search"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/webhook"
+	"github.com/ghodss/yaml"
+)
+
+// DefaultTimeout is the default timeout of fetching an index or archive from upstream
+const DefaultTimeout = "5m"
+
+// Config is a config of mirrors
+type Config struct {
+	// Interval is the period between two syncs of all mirrors, like "6h". Mirrors are
+	// synced when the registry starts and every interval. They are only synced on demand
+	// if it's empty
+	Interval string `yaml:"interval"`
+	// Timeout is the timeout of fetching an index or archive, like "5m"
+	Timeout string `yaml:"timeout"`
+	// Mirrors are upstream helm repositories to sync
+	Mirrors []Mirror `yaml:"mirrors"`
+	// Proxies are spaces which pull charts from upstream helm repositories when they
+	// are fetched
+	Proxies []Proxy `yaml:"proxies"`
+}
+
+// Mirror is a config of an upstream helm repository
+type Mirror struct {
+	// Name identifies the mirror
+	Name string `yaml:"name"`
+	// URL is the url of repository, where index.yaml is served
+	URL string `yaml:"url"`
+	// Space is the space which stores synced charts. It's created if it doesn't exist
+	Space string `yaml:"space"`
+	// Charts are names of charts to sync. All charts are synced if it's empty
+	Charts []string `yaml:"charts"`
+	// Versions is a semver range of versions to sync, like ">=1.0.0". All versions are
+	// synced if it's empty
+	Versions string `yaml:"versions"`
+	// Username and Password are basic credentials of repository. They are optional
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Status is the sync status of a mirror
+type Status struct {
+	// Name is the name of mirror
+	Name string `json:"name"`
+	// URL is the url of upstream repository
+	URL string `json:"url"`
+	// Space is the space which stores synced charts
+	Space string `json:"space"`
+	// Syncing shows whether the mirror is being synced
+	Syncing bool `json:"syncing"`
+	// LastSync is the time when the last sync finished
+	LastSync *time.Time `json:"lastSync,omitempty"`
+	// LastSuccess is the time when the last sync without errors finished
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	// Synced are versions stored by the last sync, like "chart/1.0.0"
+	Synced []string `json:"synced"`
+	// Failed maps versions which can't be synced by the last sync to reasons
+	Failed map[string]string `json:"failed,omitempty"`
+	// Error is the error which stops the last sync
+	Error string `json:"error,omitempty"`
+}
+
+// mirror is a configured mirror with its status
+type mirror struct {
+	Mirror
+	constraint *semver.Constraints
+	charts     map[string]bool
+
+	lock   sync.Mutex
+	status Status
+}
+
+var (
+	// mirrors are configured mirrors in config order
+	mirrors []*mirror
+	// client fetches data from upstream repositories
+	client = &http.Client{}
+	// nameFilter matches valid mirror names
+	nameFilter = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+)
+
+// Start validates mirrors in config and syncs them in background if an interval is set.
+// It must be called after the space manager is initialized
+func Start(config Config) error {
+	if len(config.Timeout) <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	timeout, err := time.ParseDuration(config.Timeout)
+	if err != nil {
+		return err
+	}
+	if timeout <= 0 {
+		return fmt.Errorf("mirror timeout should be positive, but got %s", config.Timeout)
+	}
+	client = &http.Client{Timeout: timeout}
+	result := make([]*mirror, 0, len(config.Mirrors))
+	names := make(map[string]bool)
+	for _, c := range config.Mirrors {
+		m, err := newMirror(c)
+		if err != nil {
+			return err
+		}
+		if names[m.Name] {
+			return fmt.Errorf("mirror %s is duplicated", m.Name)
+		}
+		names[m.Name] = true
+		result = append(result, m)
+	}
+	mirrors = result
+	if err = startProxies(config.Proxies); err != nil {
+		return err
+	}
+	if len(config.Interval) <= 0 || len(mirrors) <= 0 {
+		return nil
+	}
+	interval, err := time.ParseDuration(config.Interval)
+	if err != nil {
+		return err
+	}
+	if interval <= 0 {
+		return fmt.Errorf("mirror interval should be positive, but got %s", config.Interval)
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			for _, m := range mirrors {
+				m.sync(context.Background())
+			}
+			<-ticker.C
+		}
+	}()
+	log.Infof("Syncing %d mirrors every %s", len(mirrors), interval)
+	return nil
+}
+
+// newMirror validates config and creates a mirror
+func newMirror(config Mirror) (*mirror, error) {
+	if !nameFilter.MatchString(config.Name) {
+		return nil, fmt.Errorf("mirror name %q is invalid", config.Name)
+	}
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("url of mirror %s should be a http url, but got %q", config.Name, config.URL)
+	}
+	if !common.MustGetSpaceManager().Validate(context.Background(), storage.ValidationTypeSpaceName, config.Space) {
+		return nil, fmt.Errorf("space of mirror %s is invalid: %q", config.Name, config.Space)
+	}
+	m := &mirror{Mirror: config}
+	if len(config.Versions) > 0 {
+		if m.constraint, err = storage.ParseRange(config.Versions); err != nil {
+			return nil, fmt.Errorf("versions of mirror %s is invalid: %v", config.Name, err)
+		}
+	}
+	if len(config.Charts) > 0 {
+		m.charts = make(map[string]bool, len(config.Charts))
+		for _, chart := range config.Charts {
+			m.charts[chart] = true
+		}
+	}
+	m.status = Status{Name: config.Name, URL: config.URL, Space: config.Space, Synced: []string{}}
+	return m, nil
+}
+
+// List returns statuses of all mirrors
+func List() []Status {
+	result := make([]Status, 0, len(mirrors))
+	for _, m := range mirrors {
+		result = append(result, m.getStatus())
+	}
+	return result
+}
+
+// Get returns the status of a mirror. It returns false if the mirror doesn't exist
+func Get(name string) (*Status, bool) {
+	m := find(name)
+	if m == nil {
+		return nil, false
+	}
+	status := m.getStatus()
+	return &status, true
+}
+
+// Trigger starts a sync of a mirror in background unless it's being synced, and returns
+// its status. It returns false if the mirror doesn't exist
+func Trigger(name string) (*Status, bool) {
+	m := find(name)
+	if m == nil {
+		return nil, false
+	}
+	m.lock.Lock()
+	if !m.status.Syncing {
+		m.status.Syncing = true
+		go m.run(context.Background())
+	}
+	status := m.status
+	m.lock.Unlock()
+	return &status, true
+}
+
+// find returns a mirror by name
+func find(name string) *mirror {
+	for _, m := range mirrors {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// getStatus returns a copy of the status
+func (m *mirror) getStatus() Status {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.status
+}
+
+// sync syncs the mirror unless it's being synced
+func (m *mirror) sync(ctx context.Context) {
+	m.lock.Lock()
+	if m.status.Syncing {
+		m.lock.Unlock()
+		return
+	}
+	m.status.Syncing = true
+	m.lock.Unlock()
+	m.run(ctx)
+}
+
+// run syncs the mirror and records the result. The status must have been marked syncing
+func (m *mirror) run(ctx context.Context) {
+	synced, failed, err := m.syncVersions(ctx)
+	now := time.Now()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.status.Syncing = false
+	m.status.LastSync = &now
+	m.status.Synced = synced
+	m.status.Failed = failed
+	m.status.Error = ""
+	if err != nil {
+		m.status.Error = err.Error()
+		log.Errorf("Failed to sync mirror %s: %v", m.Name, err)
+		return
+	}
+	if len(failed) <= 0 {
+		m.status.LastSuccess = &now
+	}
+	log.Infof("Synced %d versions from mirror %s to space %s, %d failed", len(synced), m.Name, m.Space, len(failed))
+}
+
+// index is a helm repository index. Only fields used by mirrors are parsed
+type index struct {
+	Entries map[string][]*indexEntry `json:"entries"`
+}
+
+// indexEntry describes a version of chart in index
+type indexEntry struct {
+	Name    string   `json:"name"`
+	Version string   `json:"version"`
+	URLs    []string `json:"urls"`
+	Digest  string   `json:"digest"`
+}
+
+// syncVersions stores versions selected from upstream which don't exist in space. It
+// returns synced versions and failed versions with reasons
+func (m *mirror) syncVersions(ctx context.Context) ([]string, map[string]string, error) {
+	data, err := m.fetch(ctx, m.resolve("index.yaml"))
+	if err != nil {
+		return []string{}, nil, err
+	}
+	idx := &index{}
+	if err = yaml.Unmarshal(data, idx); err != nil {
+		return []string{}, nil, fmt.Errorf("can't parse index: %v", err)
+	}
+	manager := common.MustGetSpaceManager()
+	space, err := manager.Space(ctx, m.Space)
+	if err != nil {
+		return []string{}, nil, err
+	}
+	if !space.Exists(ctx) {
+		if space, err = manager.Create(ctx, m.Space); err != nil {
+			return []string{}, nil, err
+		}
+	}
+	synced := []string{}
+	var failed map[string]string
+	for _, entry := range m.selectVersions(idx) {
+		key := entry.Name + "/" + entry.Version
+		stored, err := storeVersion(ctx, m, space, entry, metrics.OperationMirror)
+		if err != nil {
+			if failed == nil {
+				failed = make(map[string]string)
+			}
+			failed[key] = err.Error()
+			log.Errorf("Failed to sync %s from mirror %s: %v", key, m.Name, err)
+			continue
+		}
+		if stored {
+			synced = append(synced, key)
+		}
+	}
+	if len(synced) > 0 {
+		search.Invalidate(space.Name())
+	}
+	return synced, failed, nil
+}
+
+// selectVersions selects versions of charts in index by the config of mirror. They are
+// sorted by chart name and then by upstream order
+func (m *mirror) selectVersions(idx *index) []*indexEntry {
+	names := make([]string, 0, len(idx.Entries))
+	for name := range idx.Entries {
+		if m.charts == nil || m.charts[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	result := make([]*indexEntry, 0)
+	for _, name := range names {
+		for _, entry := range idx.Entries[name] {
+			if entry == nil || len(entry.URLs) <= 0 {
+				continue
+			}
+			if m.constraint != nil {
+				v, err := semver.NewVersion(entry.Version)
+				if err != nil || !m.constraint.Check(v) {
+					continue
+				}
+			}
+			entry.Name = name
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// repository is an upstream helm repository
+type repository interface {
+	// resolve resolves a url in index relative to the url of repository
+	resolve(ref string) string
+	// fetch fetches data from repository
+	fetch(ctx context.Context, target string) ([]byte, error)
+}
+
+// storeVersion stores a version from upstream if it doesn't exist in space. It returns
+// whether the version is stored
+func storeVersion(ctx context.Context, repo repository, space storage.Space, entry *indexEntry, operation metrics.Operation) (bool, error) {
+	chart, err := space.Chart(ctx, entry.Name)
+	if err != nil {
+		return false, err
+	}
+	version, err := chart.Version(ctx, entry.Version)
+	if err != nil {
+		return false, err
+	}
+	if version.Exists(ctx) {
+		return false, nil
+	}
+	archiveURL := repo.resolve(entry.URLs[0])
+	data, err := repo.fetch(ctx, archiveURL)
+	if err != nil {
+		return false, err
+	}
+	if len(entry.Digest) > 0 {
+		sum := sha256.Sum256(data)
+		if digest := hex.EncodeToString(sum[:]); digest != strings.TrimPrefix(entry.Digest, "sha256:") {
+			return false, fmt.Errorf("digest of archive is %s, but index has %s", digest, entry.Digest)
+		}
+	}
+	chrt, err := storage.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		return false, fmt.Errorf("can't load archive: %v", err)
+	}
+	if chrt.Metadata.Name != entry.Name || chrt.Metadata.Version != entry.Version {
+		return false, fmt.Errorf("archive contains %s/%s", chrt.Metadata.Name, chrt.Metadata.Version)
+	}
+	// synced versions pass the same checks as pushed versions
+	if _, err = lint.Check(ctx, chrt.Metadata, bytes.NewReader(data), false); err != nil {
+		return false, err
+	}
+	report, err := scan.Check(ctx, space.Name(), chrt)
+	if err != nil {
+		return false, err
+	}
+	// a provenance is required if the registry verifies uploads
+	var prov []byte
+	if provenance.Enabled() {
+		if prov, err = repo.fetch(ctx, archiveURL+".prov"); err != nil {
+			return false, err
+		}
+		filename := fmt.Sprintf("%s-%s.tgz", entry.Name, entry.Version)
+		if err = provenance.Verify(filename, data, prov); err != nil {
+			return false, fmt.Errorf("can't verify provenance: %v", err)
+		}
+	}
+	unlock := quota.Lock(space.Name())
+	defer unlock()
+	if err = quota.Check(ctx, space, chart, version, int64(len(data))); err != nil {
+		return false, err
+	}
+	if err = version.PutContent(ctx, data); err != nil {
+		return false, err
+	}
+	if len(prov) > 0 {
+		if err = version.PutProvenance(ctx, prov, true); err != nil {
+			return false, err
+		}
+	}
+	if err = scan.PutReport(ctx, version, report); err != nil {
+		return false, err
+	}
+	metrics.Count(operation, space.Name())
+	webhook.Notify(space.Name(), chart.Name(), version.Number(), webhook.ActionCreate)
+	return true, nil
+}
+
+// resolve resolves a url in index relative to the url of repository
+func (m *mirror) resolve(ref string) string {
+	return resolveURL(m.URL, ref)
+}
+
+// fetch fetches data from upstream with the credentials of mirror. The credentials are
+// only sent to the host of mirror
+func (m *mirror) fetch(ctx context.Context, target string) ([]byte, error) {
+	username, password := credentials(m.URL, target, m.Username, m.Password)
+	return fetchURL(ctx, target, username, password)
+}
+
+// resolveURL resolves ref relative to the url of a repository
+func resolveURL(repositoryURL, ref string) string {
+	base, err := url.Parse(strings.TrimSuffix(repositoryURL, "/") + "/")
+	if err != nil {
+		return ref
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+// credentials returns username and password if target has the same scheme and host as the
+// url of repository. Urls in an index may point to any host, and credentials of repository
+// must not be sent to them
+func credentials(repositoryURL, target, username, password string) (string, string) {
+	repository, err := url.Parse(repositoryURL)
+	if err != nil {
+		return "", ""
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", ""
+	}
+	if !strings.EqualFold(repository.Scheme, u.Scheme) || !strings.EqualFold(repository.Host, u.Host) {
+		return "", ""
+	}
+	return username, password
+}
+
+// fetchURL fetches data from target with optional basic credentials
+func fetchURL(ctx context.Context, target, username, password string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(username) > 0 || len(password) > 0 {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s responds with %s", target, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Masterminds/semver"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/metrics"
+	"github.com/caicloud/helm-registry/pkg/quota"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+
+	_ "github.com/caicloud/helm-registry/pkg/storage/simple"
+)
+
+func TestSelectVersions(t *testing.T) {
+	idx := &index{Entries: map[string][]*indexEntry{
+		"redis": {
+			{Version: "2.0.0", URLs: []string{"redis-2.0.0.tgz"}},
+			{Version: "1.1.0", URLs: []string{"redis-1.1.0.tgz"}},
+			{Version: "1.0.0-rc.1", URLs: []string{"redis-1.0.0-rc.1.tgz"}},
+			{Version: "0.9.0", URLs: []string{"redis-0.9.0.tgz"}},
+		},
+		"mysql": {
+			{Version: "1.0.0", URLs: []string{"mysql-1.0.0.tgz"}},
+			{Version: "1.0.1"},
+		},
+		"nginx": {
+			{Version: "1.2.0", URLs: []string{"nginx-1.2.0.tgz"}},
+		},
+	}}
+	constraint, err := storage.ParseRange(">=1.0.0 <2.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name     string
+		mirror   *mirror
+		expected []string
+	}{
+		{"all versions", &mirror{}, []string{"mysql/1.0.0", "nginx/1.2.0", "redis/2.0.0", "redis/1.1.0", "redis/1.0.0-rc.1", "redis/0.9.0"}},
+		{"selected charts", &mirror{charts: map[string]bool{"redis": true, "mysql": true}}, []string{"mysql/1.0.0", "redis/2.0.0", "redis/1.1.0", "redis/1.0.0-rc.1", "redis/0.9.0"}},
+		{"semver range", &mirror{constraint: constraint}, []string{"mysql/1.0.0", "nginx/1.2.0", "redis/1.1.0"}},
+	}
+	for _, c := range cases {
+		versions := []string{}
+		for _, entry := range c.mirror.selectVersions(idx) {
+			versions = append(versions, entry.Name+"/"+entry.Version)
+		}
+		if !reflect.DeepEqual(versions, c.expected) {
+			t.Errorf("%s: expected %v, but got %v", c.name, c.expected, versions)
+		}
+	}
+}
+
+func TestNewMirrorVersions(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	config := Mirror{Name: "stable", URL: "https://charts.example.com/stable", Space: "library"}
+	for versions, valid := range map[string]bool{
+		">=1.0.0 <2.0.0":  true,
+		">=1.0.0, <2.0.0": true,
+		"~1.2 || >=3.0.0": true,
+		">=1.0.0 <":       false,
+	} {
+		config.Versions = versions
+		m, err := newMirror(config)
+		if (err == nil) != valid {
+			t.Errorf("expected validity of versions %q to be %v, but got %v", versions, valid, err)
+		}
+		if err == nil && m.constraint.Check(semver.MustParse("2.0.0")) {
+			t.Errorf("expected 2.0.0 not to match versions %q", versions)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	cases := []struct {
+		repository string
+		ref        string
+		expected   string
+	}{
+		{"https://charts.example.com/stable", "index.yaml", "https://charts.example.com/stable/index.yaml"},
+		{"https://charts.example.com/stable/", "redis-1.0.0.tgz", "https://charts.example.com/stable/redis-1.0.0.tgz"},
+		{"https://charts.example.com/stable", "https://cdn.example.com/redis-1.0.0.tgz", "https://cdn.example.com/redis-1.0.0.tgz"},
+		{"https://charts.example.com/stable", "/archives/redis-1.0.0.tgz", "https://charts.example.com/archives/redis-1.0.0.tgz"},
+	}
+	for _, c := range cases {
+		m := &mirror{Mirror: Mirror{URL: c.repository}}
+		if resolved := m.resolve(c.ref); resolved != c.expected {
+			t.Errorf("expected %s, but got %s", c.expected, resolved)
+		}
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	cases := []struct {
+		target   string
+		expected bool
+	}{
+		{"https://charts.example.com/stable/redis-1.0.0.tgz", true},
+		{"https://CHARTS.example.com/archives/redis-1.0.0.tgz", true},
+		{"https://charts.example.com:8443/stable/redis-1.0.0.tgz", false},
+		{"http://charts.example.com/stable/redis-1.0.0.tgz", false},
+		{"https://cdn.example.com/redis-1.0.0.tgz", false},
+		{"https://charts.example.com.evil.com/redis-1.0.0.tgz", false},
+	}
+	for _, c := range cases {
+		username, password := credentials("https://charts.example.com/stable", c.target, "user", "secret")
+		if sent := username == "user" && password == "secret"; sent != c.expected {
+			t.Errorf("%s: expected credentials sent %v, but got %v", c.target, c.expected, sent)
+		}
+		if !c.expected && (len(username) > 0 || len(password) > 0) {
+			t.Errorf("%s: expected no credentials, but got %s:%s", c.target, username, password)
+		}
+	}
+}
+
+func TestMirrorFetchCredentials(t *testing.T) {
+	authorizations := map[string]string{}
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorizations[name] = r.Header.Get("Authorization")
+			w.Write([]byte("archive"))
+		})
+	}
+	upstream := httptest.NewServer(handler("upstream"))
+	defer upstream.Close()
+	other := httptest.NewServer(handler("other"))
+	defer other.Close()
+
+	m := &mirror{Mirror: Mirror{URL: upstream.URL + "/stable", Username: "user", Password: "secret"}}
+	for _, target := range []string{m.resolve("redis-1.0.0.tgz"), m.resolve(other.URL + "/redis-1.0.0.tgz")} {
+		if _, err := m.fetch(context.Background(), target); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(authorizations["upstream"]) <= 0 {
+		t.Errorf("expected credentials to be sent to upstream")
+	}
+	if authorization := authorizations["other"]; len(authorization) > 0 {
+		t.Errorf("expected no credentials to be sent to another host, but got %s", authorization)
+	}
+}
+
+// fakeRepository serves archives by their names
+type fakeRepository map[string][]byte
+
+func (r fakeRepository) resolve(ref string) string {
+	return ref
+}
+
+func (r fakeRepository) fetch(ctx context.Context, target string) ([]byte, error) {
+	data, ok := r[target]
+	if !ok {
+		return nil, fmt.Errorf("%s is not found", target)
+	}
+	return data, nil
+}
+
+func TestStoreVersion(t *testing.T) {
+	defer quota.Initialize(quota.Config{})
+	if err := quota.Initialize(quota.Config{Default: quota.Limits{MaxVersions: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	manager, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	ctx := context.Background()
+	space, err := manager.Create(ctx, "library")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := fakeRepository{
+		"redis-1.0.0.tgz": storagetest.NewArchive(t, "redis", "1.0.0"),
+		"redis-1.1.0.tgz": storagetest.NewArchive(t, "redis", "1.1.0"),
+		"mysql-1.0.0.tgz": storagetest.NewArchiveFiles(t, map[string]string{
+			"mysql/Chart.yaml":  "apiVersion: v1\nname: mysql\nversion: 1.0.0\n",
+			"mysql/values.yaml": "a: [\n",
+		}),
+	}
+	store := func(name, version string) (bool, error) {
+		entry := &indexEntry{Name: name, Version: version, URLs: []string{name + "-" + version + ".tgz"}}
+		return storeVersion(ctx, repo, space, entry, metrics.OperationMirror)
+	}
+	if stored, err := store("redis", "1.0.0"); err != nil || !stored {
+		t.Fatalf("expected redis 1.0.0 to be stored, but got %v, %v", stored, err)
+	}
+	if stored, err := store("redis", "1.0.0"); err != nil || stored {
+		t.Errorf("expected an existing version to be skipped, but got %v, %v", stored, err)
+	}
+	if _, err := store("redis", "1.1.0"); !errors.ErrorQuotaExceeded.Equal(err) {
+		t.Errorf("expected a quota error, but got %v", err)
+	}
+	if _, err := store("mysql", "1.0.0"); !errors.ErrorLintFailed.Equal(err) {
+		t.Errorf("expected a lint error, but got %v", err)
+	}
+	entry := &indexEntry{Name: "mysql", Version: "2.0.0", URLs: []string{"redis-1.1.0.tgz"}, Digest: "0000"}
+	if _, err := storeVersion(ctx, repo, space, entry, metrics.OperationMirror); err == nil {
+		t.Errorf("expected an error of digest mismatch")
+	}
+}
@@ -0,0 +1,346 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/metrics"
+	"github.com/caicloud/helm-registry/pkg/search"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/ghodss/yaml"
+)
+
+// DefaultTTL is the default time for which a proxy uses the upstream index without
+// fetching it again
+const DefaultTTL = "5m"
+
+// Proxy is a config of a space which proxies an upstream helm repository. Versions
+// which don't exist in the space are pulled from upstream and cached in the space when
+// they are fetched
+type Proxy struct {
+	// Name identifies the proxy
+	Name string `yaml:"name"`
+	// URL is the url of repository, where index.yaml is served. A space of another
+	// registry is a repository like "https://registry.example.com/api/v1/spaces/library"
+	URL string `yaml:"url"`
+	// Space is the space which caches pulled charts. It's created if it doesn't exist
+	Space string `yaml:"space"`
+	// TTL is the time for which the upstream index is used without fetching it again,
+	// like "5m". The cached index is used longer if upstream can't be reached
+	TTL string `yaml:"ttl"`
+	// Username and Password are basic credentials of repository. They are optional
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// ProxyStatus is the status of a proxy
+type ProxyStatus struct {
+	// Name is the name of proxy
+	Name string `json:"name"`
+	// URL is the url of upstream repository
+	URL string `json:"url"`
+	// Space is the space which caches pulled charts
+	Space string `json:"space"`
+	// Refreshed is the time when the upstream index is fetched last
+	Refreshed *time.Time `json:"refreshed,omitempty"`
+	// Offline shows whether upstream can't be reached by the last fetch. Cached index
+	// and versions are served when it's offline
+	Offline bool `json:"offline"`
+	// Pulled is the number of versions pulled from upstream since the registry starts
+	Pulled int64 `json:"pulled"`
+	// Error is the error of the last fetch from upstream
+	Error string `json:"error,omitempty"`
+}
+
+// proxy is a configured proxy with its cached index and status
+type proxy struct {
+	Proxy
+	ttl time.Duration
+
+	// refresh serializes fetches of index
+	refresh sync.Mutex
+
+	lock    sync.Mutex
+	index   *models.Index
+	fetched time.Time
+	pulls   map[string]*pull
+	status  ProxyStatus
+}
+
+// pull is a version being pulled. Fetches of the version wait for it
+type pull struct {
+	done chan struct{}
+	err  error
+}
+
+var (
+	// proxies are configured proxies in config order
+	proxies []*proxy
+	// proxySpaces maps spaces to their proxies
+	proxySpaces map[string]*proxy
+)
+
+// startProxies validates proxies in config
+func startProxies(configs []Proxy) error {
+	result := make([]*proxy, 0, len(configs))
+	names := make(map[string]bool)
+	spaces := make(map[string]*proxy)
+	for _, c := range configs {
+		p, err := newProxy(c)
+		if err != nil {
+			return err
+		}
+		if names[p.Name] {
+			return fmt.Errorf("proxy %s is duplicated", p.Name)
+		}
+		if spaces[p.Space] != nil {
+			return fmt.Errorf("space %s is proxied by both %s and %s", p.Space, spaces[p.Space].Name, p.Name)
+		}
+		names[p.Name] = true
+		spaces[p.Space] = p
+		result = append(result, p)
+	}
+	proxies = result
+	proxySpaces = spaces
+	if len(proxies) > 0 {
+		log.Infof("Proxying %d spaces to upstream repositories", len(proxies))
+	}
+	return nil
+}
+
+// newProxy validates config and creates a proxy
+func newProxy(config Proxy) (*proxy, error) {
+	if !nameFilter.MatchString(config.Name) {
+		return nil, fmt.Errorf("proxy name %q is invalid", config.Name)
+	}
+	u, err := url.Parse(config.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("url of proxy %s should be a http url, but got %q", config.Name, config.URL)
+	}
+	if !common.MustGetSpaceManager().Validate(context.Background(), storage.ValidationTypeSpaceName, config.Space) {
+		return nil, fmt.Errorf("space of proxy %s is invalid: %q", config.Name, config.Space)
+	}
+	if len(config.TTL) <= 0 {
+		config.TTL = DefaultTTL
+	}
+	ttl, err := time.ParseDuration(config.TTL)
+	if err != nil {
+		return nil, fmt.Errorf("ttl of proxy %s is invalid: %v", config.Name, err)
+	}
+	if ttl < 0 {
+		return nil, fmt.Errorf("ttl of proxy %s should not be negative, but got %s", config.Name, config.TTL)
+	}
+	return &proxy{
+		Proxy:  config,
+		ttl:    ttl,
+		pulls:  make(map[string]*pull),
+		status: ProxyStatus{Name: config.Name, URL: config.URL, Space: config.Space},
+	}, nil
+}
+
+// ListProxies returns statuses of all proxies
+func ListProxies() []ProxyStatus {
+	result := make([]ProxyStatus, 0, len(proxies))
+	for _, p := range proxies {
+		p.lock.Lock()
+		result = append(result, p.status)
+		p.lock.Unlock()
+	}
+	return result
+}
+
+// IsProxy returns whether space is the space of a proxy
+func IsProxy(space string) bool {
+	return proxySpaces[space] != nil
+}
+
+// Entries returns versions in the upstream index of the proxy of space, or nil if space
+// isn't proxied. Versions are sorted by upstream order, which is highest version first.
+// The space of proxy is created if it doesn't exist. If upstream can't be reached, the
+// cached index is used and it's nil if there is no cached index
+func Entries(ctx context.Context, space string) map[string][]*models.IndexEntry {
+	p := proxySpaces[space]
+	if p == nil {
+		return nil
+	}
+	if _, err := p.getSpace(ctx); err != nil {
+		log.FromContext(ctx).Errorf("Failed to create space of proxy %s: %v", p.Name, err)
+		return nil
+	}
+	idx, err := p.getIndex(ctx)
+	if err != nil {
+		return nil
+	}
+	return idx.Entries
+}
+
+// Pull stores a version from upstream if space is the space of a proxy and the version
+// doesn't exist in it. Cached versions are not checked against upstream, because versions
+// are immutable. It does nothing if the version isn't in upstream index
+func Pull(ctx context.Context, space, chart, version string) error {
+	p := proxySpaces[space]
+	if p == nil {
+		return nil
+	}
+	return p.pull(ctx, chart, version)
+}
+
+// resolve resolves a url in index relative to the url of repository
+func (p *proxy) resolve(ref string) string {
+	return resolveURL(p.URL, ref)
+}
+
+// fetch fetches data from upstream with the credentials of proxy. The credentials are
+// only sent to the host of proxy
+func (p *proxy) fetch(ctx context.Context, target string) ([]byte, error) {
+	username, password := credentials(p.URL, target, p.Username, p.Password)
+	return fetchURL(ctx, target, username, password)
+}
+
+// getSpace returns the space of proxy. It's created if it doesn't exist
+func (p *proxy) getSpace(ctx context.Context) (storage.Space, error) {
+	manager := common.MustGetSpaceManager()
+	space, err := manager.Space(ctx, p.Space)
+	if err != nil {
+		return nil, err
+	}
+	if space.Exists(ctx) {
+		return space, nil
+	}
+	return manager.Create(ctx, p.Space)
+}
+
+// getIndex returns the upstream index. It's fetched again if it's older than ttl. If
+// upstream can't be reached, the cached index is used for another ttl
+func (p *proxy) getIndex(ctx context.Context) (*models.Index, error) {
+	p.refresh.Lock()
+	defer p.refresh.Unlock()
+	p.lock.Lock()
+	idx, fetched := p.index, p.fetched
+	p.lock.Unlock()
+	if idx != nil && time.Since(fetched) < p.ttl {
+		return idx, nil
+	}
+	fresh, err := p.fetchIndex(ctx)
+	now := time.Now()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if err != nil {
+		p.status.Offline = true
+		p.status.Error = err.Error()
+		log.FromContext(ctx).Errorf("Failed to fetch index of proxy %s: %v", p.Name, err)
+		if idx == nil {
+			return nil, err
+		}
+		p.fetched = now
+		return idx, nil
+	}
+	p.index = fresh
+	p.fetched = now
+	p.status.Refreshed = &now
+	p.status.Offline = false
+	p.status.Error = ""
+	return fresh, nil
+}
+
+// fetchIndex fetches and parses the upstream index
+func (p *proxy) fetchIndex(ctx context.Context) (*models.Index, error) {
+	data, err := p.fetch(ctx, p.resolve("index.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	idx := &models.Index{}
+	if err = yaml.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("can't parse index: %v", err)
+	}
+	for name, entries := range idx.Entries {
+		valid := make([]*models.IndexEntry, 0, len(entries))
+		for _, entry := range entries {
+			if entry != nil && entry.Metadata != nil && len(entry.URLs) > 0 {
+				entry.Name = name
+				valid = append(valid, entry)
+			}
+		}
+		idx.Entries[name] = valid
+	}
+	return idx, nil
+}
+
+// pull stores a version from upstream unless it exists. Concurrent pulls of a version
+// fetch it once
+func (p *proxy) pull(ctx context.Context, chartName, number string) error {
+	space, err := p.getSpace(ctx)
+	if err != nil {
+		return err
+	}
+	chart, err := space.Chart(ctx, chartName)
+	if err != nil {
+		return err
+	}
+	version, err := chart.Version(ctx, number)
+	if err != nil {
+		return err
+	}
+	if version.Exists(ctx) {
+		return nil
+	}
+	key := chartName + "/" + number
+	p.lock.Lock()
+	if current, ok := p.pulls[key]; ok {
+		p.lock.Unlock()
+		<-current.done
+		return current.err
+	}
+	current := &pull{done: make(chan struct{})}
+	p.pulls[key] = current
+	p.lock.Unlock()
+
+	current.err = p.pullVersion(ctx, space, chartName, number)
+	p.lock.Lock()
+	delete(p.pulls, key)
+	p.lock.Unlock()
+	close(current.done)
+	return current.err
+}
+
+// pullVersion finds a version in upstream index and stores it in space
+func (p *proxy) pullVersion(ctx context.Context, space storage.Space, chartName, number string) error {
+	idx, err := p.getIndex(ctx)
+	if err != nil {
+		return err
+	}
+	var entry *indexEntry
+	for _, e := range idx.Entries[chartName] {
+		if e.Version == number {
+			entry = &indexEntry{Name: chartName, Version: number, URLs: e.URLs, Digest: e.Digest}
+			break
+		}
+	}
+	if entry == nil {
+		return nil
+	}
+	stored, err := storeVersion(ctx, p, space, entry, metrics.OperationProxy)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if err != nil {
+		p.status.Error = err.Error()
+		log.FromContext(ctx).Errorf("Failed to pull %s/%s from proxy %s: %v", chartName, number, p.Name, err)
+		return err
+	}
+	if stored {
+		p.status.Pulled++
+		search.Invalidate(space.Name())
+		log.FromContext(ctx).Infof("Pulled %s/%s from proxy %s to space %s", chartName, number, p.Name, p.Space)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package mirror
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+
+	_ "github.com/caicloud/helm-registry/pkg/storage/simple"
+)
+
+func TestProxy(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	archive := storagetest.NewArchive(t, "redis", "1.0.0")
+	sum := sha256.Sum256(archive)
+	var lock sync.Mutex
+	requests := map[string]int{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requests[r.URL.Path]++
+		lock.Unlock()
+		switch r.URL.Path {
+		case "/charts/index.yaml":
+			fmt.Fprintf(w, "apiVersion: v1\nentries:\n  redis:\n  - name: redis\n    version: 2.0.0\n    urls: [redis-2.0.0.tgz]\n"+
+				"  - name: redis\n    version: 1.0.0\n    urls: [redis-1.0.0.tgz]\n    digest: %s\n", hex.EncodeToString(sum[:]))
+		case "/charts/redis-1.0.0.tgz":
+			w.Write(archive)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+	defer startProxies(nil)
+	err := startProxies([]Proxy{{Name: "central", URL: upstream.URL + "/charts", Space: "cache", TTL: "1h"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if IsProxy("other") || Entries(context.Background(), "other") != nil || Pull(context.Background(), "other", "redis", "1.0.0") != nil {
+		t.Fatalf("space which is not proxied should be ignored")
+	}
+
+	ctx := context.Background()
+	entries := Entries(ctx, "cache")
+	if len(entries["redis"]) != 2 || entries["redis"][0].Version != "2.0.0" {
+		t.Fatalf("expected 2 upstream versions of redis, but got %v", entries)
+	}
+	space, err := common.GetSpace(ctx, "cache")
+	if err != nil || !space.Exists(ctx) {
+		t.Fatalf("expected space of proxy to be created, but got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err = Pull(ctx, "cache", "redis", "1.0.0"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	version, err := common.GetVersion(ctx, "cache", "redis", "1.0.0")
+	if err != nil || !version.Exists(ctx) {
+		t.Fatalf("expected redis 1.0.0 to be pulled, but got %v", err)
+	}
+	if err = Pull(ctx, "cache", "mysql", "1.0.0"); err != nil {
+		t.Errorf("expected no error when pulling a version which is not in upstream, but got %v", err)
+	}
+	if err = Pull(ctx, "cache", "redis", "2.0.0"); err == nil {
+		t.Errorf("expected an error when upstream can't serve an archive in index")
+	}
+	lock.Lock()
+	if requests["/charts/index.yaml"] != 1 || requests["/charts/redis-1.0.0.tgz"] != 1 {
+		t.Errorf("expected index and archive to be fetched once, but got %v", requests)
+	}
+	lock.Unlock()
+
+	// cached index and versions are served when upstream is offline
+	upstream.Close()
+	p := proxySpaces["cache"]
+	p.lock.Lock()
+	p.fetched = time.Now().Add(-2 * time.Hour)
+	p.lock.Unlock()
+	if entries = Entries(ctx, "cache"); len(entries["redis"]) != 2 {
+		t.Errorf("expected cached index when upstream is offline, but got %v", entries)
+	}
+	if err = Pull(ctx, "cache", "redis", "1.0.0"); err != nil {
+		t.Errorf("expected cached version when upstream is offline, but got %v", err)
+	}
+	statuses := ListProxies()
+	if len(statuses) != 1 || !statuses[0].Offline || statuses[0].Pulled != 1 || len(statuses[0].Error) <= 0 {
+		t.Errorf("unexpected status of proxy: %+v", statuses)
+	}
+}
+
+func TestNewProxy(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	cases := []Proxy{
+		{Name: "", URL: "https://charts.example.com", Space: "cache"},
+		{Name: "central", URL: "ftp://charts.example.com", Space: "cache"},
+		{Name: "central", URL: "https://charts.example.com", Space: "cache", TTL: "1x"},
+		{Name: "central", URL: "https://charts.example.com", Space: "cache", TTL: "-1m"},
+	}
+	for _, c := range cases {
+		if _, err := newProxy(c); err == nil {
+			t.Errorf("expected an error of proxy %+v", c)
+		}
+	}
+	p, err := newProxy(Proxy{Name: "central", URL: "https://charts.example.com", Space: "cache"})
+	if err != nil || p.ttl != 5*time.Minute {
+		t.Errorf("expected a proxy with default ttl, but got %v, %v", p, err)
+	}
+}
+
+func TestProxyFetchCredentials(t *testing.T) {
+	var lock sync.Mutex
+	authorizations := map[string]string{}
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			authorizations[name] = r.Header.Get("Authorization")
+			lock.Unlock()
+			w.Write([]byte("archive"))
+		})
+	}
+	upstream := httptest.NewServer(handler("upstream"))
+	defer upstream.Close()
+	other := httptest.NewServer(handler("other"))
+	defer other.Close()
+
+	p := &proxy{Proxy: Proxy{URL: upstream.URL + "/charts", Username: "user", Password: "secret"}}
+	for _, target := range []string{p.resolve("redis-1.0.0.tgz"), p.resolve(other.URL + "/redis-1.0.0.tgz.prov")} {
+		if _, err := p.fetch(context.Background(), target); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if len(authorizations["upstream"]) <= 0 {
+		t.Errorf("expected credentials to be sent to upstream")
+	}
+	if authorization := authorizations["other"]; len(authorization) > 0 {
+		t.Errorf("expected no credentials to be sent to another host, but got %s", authorization)
+	}
+}
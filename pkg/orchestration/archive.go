@@ -11,7 +11,7 @@ import (
 	"path/filepath"
 
 	"github.com/caicloud/helm-registry/pkg/errors"
-	"github.com/ghodss/yaml"
+	"github.com/caicloud/helm-registry/pkg/storage"
 	"k8s.io/helm/pkg/proto/hapi/chart"
 )
 
@@ -48,7 +48,7 @@ func writeTarContents(out *tar.Writer, c *chart.Chart, prefix string) error {
 	base := filepath.Join(prefix, c.Metadata.Name)
 
 	// Save Chart.yaml
-	cdata, err := yaml.Marshal(c.Metadata)
+	cdata, err := storage.MarshalChartfile(c)
 	if err != nil {
 		return err
 	}
@@ -71,8 +71,11 @@ func writeTarContents(out *tar.Writer, c *chart.Chart, prefix string) error {
 		}
 	}
 
-	// Save files
+	// Save files. The original Chart.yaml has been merged
 	for _, f := range c.Files {
+		if f.TypeUrl == storage.ChartfileName {
+			continue
+		}
 		n := filepath.Join(base, f.TypeUrl)
 		if err := writeToTar(out, n, f.Value); err != nil {
 			return err
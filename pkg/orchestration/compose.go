@@ -0,0 +1,82 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package orchestration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/trace"
+	"github.com/ghodss/yaml"
+	"github.com/golang/protobuf/ptypes/any"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// requirementsName is the name of requirements file in chart
+const requirementsName = "requirements.yaml"
+
+// Component is a chart in registry which is composed into an umbrella chart
+type Component struct {
+	// Name is the name of subchart in the umbrella chart
+	Name string
+	// Package is where the chart is stored. It must be independent
+	Package Package
+	// Values override values of the chart
+	Values map[string]interface{}
+}
+
+// Compose creates an umbrella chart whose subcharts are components. Dependencies of every
+// component are resolved from its space and packed with it. The umbrella chart requires all
+// components, and its values are a scaffold which contains values of every component under
+// its name, with the values of component overriding them
+func Compose(ctx context.Context, metadata *chart.Metadata, components []Component) (*chart.Chart, error) {
+	ctx, span := trace.StartSpan(ctx, "orchestration.compose")
+	defer span.End()
+	umbrella := &chart.Chart{
+		Metadata:     metadata,
+		Values:       &chart.Config{},
+		Dependencies: make([]*chart.Chart, 0, len(components)),
+	}
+	reqs := &chartutil.Requirements{}
+	values := make(map[string]interface{}, len(components))
+	for _, component := range components {
+		pkg := component.Package
+		subchart, err := getChart(ctx, pkg.Space, pkg.Chart, pkg.Version)
+		if err != nil {
+			return nil, err
+		}
+		if err = ResolveDependencies(ctx, pkg.Space, subchart); err != nil {
+			return nil, err
+		}
+		overrides, err := yaml.Marshal(component.Values)
+		if err != nil {
+			return nil, errors.ErrorInternalUnknown.Format(err.Error())
+		}
+		merged, err := chartutil.CoalesceValues(subchart, &chart.Config{Raw: string(overrides)})
+		if err != nil {
+			return nil, errors.ErrorInvalidParam.Format(fmt.Sprintf("values of %s/%s", pkg.Chart, pkg.Version), err)
+		}
+		values[component.Name] = merged.AsMap()
+		subchart.Metadata.Name = component.Name
+		umbrella.Dependencies = append(umbrella.Dependencies, subchart)
+		reqs.Dependencies = append(reqs.Dependencies, &chartutil.Dependency{
+			Name:    component.Name,
+			Version: subchart.Metadata.Version,
+		})
+	}
+	raw, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, errors.ErrorInternalUnknown.Format(err.Error())
+	}
+	umbrella.Values.Raw = string(raw)
+	requirements, err := yaml.Marshal(reqs)
+	if err != nil {
+		return nil, errors.ErrorInternalUnknown.Format(err.Error())
+	}
+	umbrella.Files = append(umbrella.Files, &any.Any{TypeUrl: requirementsName, Value: requirements})
+	return umbrella, nil
+}
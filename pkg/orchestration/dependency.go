@@ -0,0 +1,116 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package orchestration
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/trace"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// ResolveDependencies resolves dependencies in requirements.yaml, or Chart.yaml of an
+// apiVersion v2 chart, of chrt from the specified space and appends them to
+// chrt.Dependencies recursively. A dependency is resolved to the highest version which
+// satisfies its constraint, and an aliased dependency is bundled with its alias as name.
+// Dependencies which already exist in charts/ are kept.
+func ResolveDependencies(ctx context.Context, space string, chrt *chart.Chart) error {
+	ctx, span := trace.StartSpan(ctx, "orchestration.resolve_dependencies")
+	defer span.End()
+	span.SetAttribute("registry.space", space)
+	span.SetAttribute("registry.chart", chrt.Metadata.Name)
+	err := resolveDependencies(ctx, space, chrt, []string{chrt.Metadata.Name})
+	span.SetError(err)
+	return err
+}
+
+// resolveDependencies resolves dependencies of chrt. chain records names of charts
+// from root to chrt for detecting circular dependencies.
+func resolveDependencies(ctx context.Context, space string, chrt *chart.Chart, chain []string) error {
+	reqs, err := storage.LoadRequirements(chrt)
+	if err != nil {
+		if err == chartutil.ErrRequirementsNotFound {
+			return nil
+		}
+		return errors.ErrorInvalidParam.Format("dependencies of "+chrt.Metadata.Name, err)
+	}
+	for _, dep := range reqs.Dependencies {
+		name := dep.Name
+		if len(dep.Alias) > 0 {
+			name = dep.Alias
+		}
+		if hasDependency(chrt, name) {
+			continue
+		}
+		next := append(append([]string{}, chain...), dep.Name)
+		for _, name := range chain {
+			if name == dep.Name {
+				return errors.ErrorCircularDependency.Format(strings.Join(next, " -> "))
+			}
+		}
+		number, err := resolveVersion(ctx, space, dep)
+		if err != nil {
+			return err
+		}
+		depChart, err := getChart(ctx, space, dep.Name, number)
+		if err != nil {
+			return err
+		}
+		if err = resolveDependencies(ctx, space, depChart, next); err != nil {
+			return err
+		}
+		// helm renders an aliased dependency by its name in charts/
+		depChart.Metadata.Name = name
+		chrt.Dependencies = append(chrt.Dependencies, depChart)
+	}
+	return nil
+}
+
+// hasDependency returns whether chrt has a dependency with specified name in charts/
+func hasDependency(chrt *chart.Chart, name string) bool {
+	for _, dep := range chrt.Dependencies {
+		if dep.Metadata.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveVersion finds the highest version number of dependency which satisfies its constraint
+func resolveVersion(ctx context.Context, space string, dep *chartutil.Dependency) (string, error) {
+	constraintStr := dep.Version
+	if len(constraintStr) <= 0 {
+		constraintStr = "*"
+	}
+	constraint, err := storage.ParseRange(constraintStr)
+	if err != nil {
+		return "", errors.ErrorInvalidParam.Format("version of dependency "+dep.Name, dep.Version)
+	}
+	chrt, err := common.GetChart(ctx, space, dep.Name)
+	if err != nil {
+		return "", err
+	}
+	if !chrt.Exists(ctx) {
+		return "", errors.ErrorDependencyNotSatisfied.Format(dep.Name, constraintStr)
+	}
+	numbers, err := chrt.List(ctx)
+	if err != nil {
+		return "", err
+	}
+	// numbers are sorted in increasing order
+	for i := len(numbers) - 1; i >= 0; i-- {
+		version, err := semver.NewVersion(numbers[i])
+		if err == nil && constraint.Check(version) {
+			return numbers[i], nil
+		}
+	}
+	return "", errors.ErrorDependencyNotSatisfied.Format(dep.Name, constraintStr)
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package orchestration
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	_ "github.com/caicloud/helm-registry/pkg/storage/simple"
+)
+
+// putTestChart stores a chart with requirements to library/name/version and returns the chart
+func putTestChart(t *testing.T, name, version, requirements string) *chart.Chart {
+	ctx := context.Background()
+	files := map[string]string{
+		name + "/Chart.yaml": "apiVersion: v1\nname: " + name + "\nversion: " + version + "\n",
+	}
+	if len(requirements) > 0 {
+		files[name+"/requirements.yaml"] = requirements
+	}
+	data := storagetest.NewArchiveFiles(t, files)
+	if s, err := common.MustGetSpaceManager().Space(ctx, "library"); err != nil || !s.Exists(ctx) {
+		if _, err = common.MustGetSpaceManager().Create(ctx, "library"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v, err := common.GetVersion(ctx, "library", name, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = v.PutContent(ctx, data); err != nil {
+		t.Fatal(err)
+	}
+	chrt, err := storage.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chrt
+}
+
+// dependencyVersions returns names and versions of dependencies of chrt, like "redis/1.0.0"
+func dependencyVersions(chrt *chart.Chart) []string {
+	versions := []string{}
+	for _, dep := range chrt.Dependencies {
+		versions = append(versions, dep.Metadata.Name+"/"+dep.Metadata.Version)
+	}
+	return versions
+}
+
+// sortedStrings sorts s and returns it
+func sortedStrings(s []string) []string {
+	sort.Strings(s)
+	return s
+}
+
+func TestResolveDependencies(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	for _, version := range []string{"1.0.0", "1.5.0", "2.0.0"} {
+		putTestChart(t, "redis", version, "")
+	}
+	putTestChart(t, "mysql", "1.0.0", "dependencies:\n- name: redis\n  version: ~1.0.0\n")
+	app := putTestChart(t, "app", "1.0.0", `dependencies:
+- name: redis
+  version: ">=1.0.0 <2.0.0"
+- name: redis
+  version: "2.0.0"
+  alias: cache
+- name: mysql
+  version: "*"
+`)
+	ctx := context.Background()
+	if err := ResolveDependencies(ctx, "library", app); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"redis/1.5.0", "cache/2.0.0", "mysql/1.0.0"}
+	if versions := dependencyVersions(app); !reflect.DeepEqual(versions, expected) {
+		t.Fatalf("expected dependencies %v, but got %v", expected, versions)
+	}
+	if versions := dependencyVersions(app.Dependencies[2]); !reflect.DeepEqual(versions, []string{"redis/1.0.0"}) {
+		t.Errorf("expected dependencies of mysql to be resolved, but got %v", versions)
+	}
+
+	// resolved dependencies are bundled under charts/
+	data, err := Archive(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundled, err := storage.LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// charts/ of a loaded archive is not ordered
+	sort.Strings(expected)
+	if versions := dependencyVersions(bundled); !reflect.DeepEqual(sortedStrings(versions), expected) {
+		t.Errorf("expected bundled dependencies %v, but got %v", expected, versions)
+	}
+	// bundled dependencies are kept when resolving again
+	if err = ResolveDependencies(ctx, "library", bundled); err != nil {
+		t.Fatal(err)
+	}
+	if versions := dependencyVersions(bundled); !reflect.DeepEqual(sortedStrings(versions), expected) {
+		t.Errorf("expected dependencies %v to be kept, but got %v", expected, versions)
+	}
+}
+
+func TestResolveDependenciesErrors(t *testing.T) {
+	_, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	putTestChart(t, "redis", "1.0.0", "")
+	putTestChart(t, "a", "1.0.0", "dependencies:\n- name: b\n  version: 1.0.0\n")
+	putTestChart(t, "b", "1.0.0", "dependencies:\n- name: a\n  version: 1.0.0\n")
+	cases := []struct {
+		name         string
+		requirements string
+		expected     *errors.Error
+	}{
+		{"unsatisfied version", "dependencies:\n- name: redis\n  version: \">=2.0.0 <3.0.0\"\n", errors.ErrorDependencyNotSatisfied},
+		{"missing chart", "dependencies:\n- name: mysql\n  version: 1.0.0\n", errors.ErrorDependencyNotSatisfied},
+		{"invalid version", "dependencies:\n- name: redis\n  version: \">=1.0.0 <\"\n", errors.ErrorInvalidParam},
+		{"circular dependency", "dependencies:\n- name: a\n  version: 1.0.0\n", errors.ErrorCircularDependency},
+	}
+	for _, c := range cases {
+		app := putTestChart(t, "app", "1.0.0", c.requirements)
+		if err := ResolveDependencies(context.Background(), "library", app); !c.expected.Equal(err) {
+			t.Errorf("%s: expected %v, but got %v", c.name, c.expected, err)
+		}
+	}
+}
@@ -13,7 +13,7 @@ import (
 	"github.com/caicloud/helm-registry/pkg/common"
 	"github.com/caicloud/helm-registry/pkg/errors"
 	"github.com/caicloud/helm-registry/pkg/storage"
-	"k8s.io/helm/pkg/chartutil"
+	"github.com/caicloud/helm-registry/pkg/trace"
 	"k8s.io/helm/pkg/proto/hapi/chart"
 )
 
@@ -71,8 +71,12 @@ func convertInterface(name string, inf interface{}) (map[string]interface{}, err
 //         }
 //     }
 // }
-func Create(configs map[string]interface{}) (*chart.Chart, error) {
-	return create(nil, configs)
+func Create(ctx context.Context, configs map[string]interface{}) (*chart.Chart, error) {
+	ctx, span := trace.StartSpan(ctx, "orchestration.create")
+	defer span.End()
+	c, err := create(ctx, nil, configs)
+	span.SetError(err)
+	return c, err
 }
 
 // ClearValues removes all values in a chart
@@ -84,7 +88,7 @@ func ClearValues(chrt *chart.Chart) {
 }
 
 // create creates a new chart from configs.
-func create(parent *chart.Chart, configs map[string]interface{}) (*chart.Chart, error) {
+func create(ctx context.Context, parent *chart.Chart, configs map[string]interface{}) (*chart.Chart, error) {
 	// packageConfig is the config of current package
 	var packageConfig *Package
 
@@ -104,14 +108,14 @@ func create(parent *chart.Chart, configs map[string]interface{}) (*chart.Chart,
 			}
 		} else {
 			// filter invalid chart name
-			if !common.MustGetSpaceManager().Validate(context.Background(),
+			if !common.MustGetSpaceManager().Validate(ctx,
 				storage.ValidationTypeChartName, key) {
 				return nil, errors.ErrorInvalidParam.Format("chart name", key)
 			}
 			deps[key] = data
 		}
 	}
-	currentChart, err := getChartByPackage(parent, packageConfig)
+	currentChart, err := getChartByPackage(ctx, parent, packageConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -119,7 +123,7 @@ func create(parent *chart.Chart, configs map[string]interface{}) (*chart.Chart,
 	if len(deps) > 0 {
 		children := make([]*chart.Chart, 0, len(deps))
 		for name, cfg := range deps {
-			child, err := create(currentChart, cfg)
+			child, err := create(ctx, currentChart, cfg)
 			if err != nil {
 				return nil, err
 			}
@@ -134,10 +138,10 @@ func create(parent *chart.Chart, configs map[string]interface{}) (*chart.Chart,
 }
 
 // getChartByPackage returns a chart via package configs
-func getChartByPackage(parent *chart.Chart, pkg *Package) (*chart.Chart, error) {
+func getChartByPackage(ctx context.Context, parent *chart.Chart, pkg *Package) (*chart.Chart, error) {
 	chartName := fmt.Sprintf("%s/%s", pkg.Chart, pkg.Version)
 	if pkg.Independent {
-		return getChart(pkg.Space, pkg.Chart, pkg.Version)
+		return getChart(ctx, pkg.Space, pkg.Chart, pkg.Version)
 	}
 	if parent == nil {
 		return nil, errors.ErrorInvalidParam.Format("parent chart", chartName)
@@ -151,8 +155,7 @@ func getChartByPackage(parent *chart.Chart, pkg *Package) (*chart.Chart, error)
 }
 
 // getChart gets a chart
-func getChart(spaceName, chartName, versionNumber string) (*chart.Chart, error) {
-	ctx := context.Background()
+func getChart(ctx context.Context, spaceName, chartName, versionNumber string) (*chart.Chart, error) {
 	space, err := common.MustGetSpaceManager().Space(ctx, spaceName)
 	if err != nil {
 		return nil, err
@@ -169,7 +172,7 @@ func getChart(spaceName, chartName, versionNumber string) (*chart.Chart, error)
 	if err != nil {
 		return nil, err
 	}
-	c, err := chartutil.LoadArchive(bytes.NewReader(data))
+	c, err := storage.LoadArchive(bytes.NewReader(data))
 	if err != nil {
 		return nil, errors.ErrorInternalTypeError.Format(
 			fmt.Sprintf("%s/%s", chartName, versionNumber), "chart", "unknown")
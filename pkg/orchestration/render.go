@@ -0,0 +1,60 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package orchestration
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/engine"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// notesName is the name of the template which is shown to users after installing
+const notesName = "NOTES.txt"
+
+// Render renders templates of chrt with config like installing a release, and returns manifests
+// as a multi-document yaml. Subcharts disabled by conditions or tags are not rendered
+func Render(chrt *chart.Chart, config *chart.Config, options chartutil.ReleaseOptions) ([]byte, error) {
+	name := fmt.Sprintf("%s/%s", chrt.Metadata.Name, chrt.Metadata.Version)
+	if err := chartutil.ProcessRequirementsEnabled(chrt, config); err != nil {
+		return nil, errors.ErrorInvalidParam.Format("dependencies of "+name, err)
+	}
+	if err := chartutil.ProcessRequirementsImportValues(chrt); err != nil {
+		return nil, errors.ErrorInvalidParam.Format("dependencies of "+name, err)
+	}
+	values, err := chartutil.ToRenderValues(chrt, config, options)
+	if err != nil {
+		return nil, errors.ErrorInvalidParam.Format("values", err)
+	}
+	rendered, err := engine.New().Render(chrt, values)
+	if err != nil {
+		return nil, errors.ErrorRenderFailed.Format(name, err)
+	}
+	return joinManifests(rendered), nil
+}
+
+// joinManifests joins rendered templates as a multi-document yaml. Templates are sorted
+// by file name, and empty templates and notes are skipped
+func joinManifests(rendered map[string]string) []byte {
+	names := make([]string, 0, len(rendered))
+	for name, manifest := range rendered {
+		if path.Base(name) == notesName || len(strings.TrimSpace(manifest)) <= 0 {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf := &bytes.Buffer{}
+	for _, name := range names {
+		fmt.Fprintf(buf, "---\n# Source: %s\n%s\n", name, strings.TrimSpace(rendered[name]))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package provenance
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/caicloud/helm-registry/pkg/log"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/clearsign"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is a config of provenance verification
+type Config struct {
+	// Keyring is the path of a public keyring. If it's not empty, every uploaded
+	// version must have a provenance file signed by a key in the keyring
+	Keyring string `yaml:"keyring"`
+}
+
+// keyring is the loaded public keyring. It's nil if verification is disabled
+var keyring openpgp.EntityList
+
+// Initialize loads the public keyring in config. Verification is disabled if config
+// has no keyring
+func Initialize(config Config) error {
+	if len(config.Keyring) <= 0 {
+		keyring = nil
+		return nil
+	}
+	file, err := os.Open(config.Keyring)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	entities, err := openpgp.ReadKeyRing(file)
+	if err != nil {
+		return err
+	}
+	keyring = entities
+	log.Infof("Verifying provenance with %d keys from %s", len(keyring), config.Keyring)
+	return nil
+}
+
+// Enabled returns whether uploaded versions should be verified
+func Enabled() bool {
+	return keyring != nil
+}
+
+// files is the section of signed message which records digests of archives
+type files struct {
+	Files map[string]string `yaml:"files"`
+}
+
+// Verify verifies that prov is signed by a key in keyring and records the digest of
+// archive. filename is the archive name in prov, like "chart-1.0.0.tgz"
+func Verify(filename string, archive, prov []byte) error {
+	return VerifyDigest(filename, Digest(archive), prov)
+}
+
+// VerifyDigest verifies that prov is signed by a key in keyring and records digest,
+// like "sha256:<hex>". It verifies an archive which is not held in memory
+func VerifyDigest(filename, digest string, prov []byte) error {
+	block, _ := clearsign.Decode(prov)
+	if block == nil {
+		return fmt.Errorf("signature block not found")
+	}
+	if _, err := openpgp.CheckDetachedSignature(keyring, bytes.NewReader(block.Bytes), block.ArmoredSignature.Body); err != nil {
+		return err
+	}
+	// the signed message is Chart.yaml and files separated by a yaml document end marker
+	parts := strings.SplitN(string(block.Plaintext), "\n...\n", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("files section not found")
+	}
+	f := &files{}
+	if err := yaml.Unmarshal([]byte(parts[1]), f); err != nil {
+		return err
+	}
+	expected, ok := f.Files[filename]
+	if !ok {
+		return fmt.Errorf("digest of %s not found", filename)
+	}
+	if expected != digest {
+		return fmt.Errorf("digest of %s should be %s, but got %s", filename, expected, digest)
+	}
+	return nil
+}
+
+// Digest returns the digest of data in the form of provenance files, like "sha256:<hex>"
+func Digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package provenance
+
+import (
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/provenance/provenancetest"
+)
+
+const chartYaml = "name: chart\nversion: 1.0.0\n"
+
+func TestVerify(t *testing.T) {
+	key := provenancetest.NewKey(t)
+	path, cleanup := provenancetest.WriteKeyring(t, key)
+	defer cleanup()
+	if err := Initialize(Config{Keyring: path}); err != nil {
+		t.Fatal(err)
+	}
+	defer Initialize(Config{})
+	if !Enabled() {
+		t.Fatal("expected verification to be enabled")
+	}
+
+	archive := []byte("archive of chart")
+	digest := Digest(archive)
+	cases := []struct {
+		name     string
+		filename string
+		archive  []byte
+		prov     []byte
+		valid    bool
+	}{
+		{"good signature", "chart-1.0.0.tgz", archive,
+			provenancetest.Sign(t, key, chartYaml, map[string]string{"chart-1.0.0.tgz": digest}), true},
+		{"multiple files", "chart-1.0.0.tgz", archive,
+			provenancetest.Sign(t, key, chartYaml, map[string]string{"chart-0.9.0.tgz": Digest([]byte("old")), "chart-1.0.0.tgz": digest}), true},
+		{"tampered digest", "chart-1.0.0.tgz", archive,
+			provenancetest.Sign(t, key, chartYaml, map[string]string{"chart-1.0.0.tgz": Digest([]byte("other archive"))}), false},
+		{"tampered archive", "chart-1.0.0.tgz", []byte("tampered archive of chart"),
+			provenancetest.Sign(t, key, chartYaml, map[string]string{"chart-1.0.0.tgz": digest}), false},
+		{"wrong filename", "chart-1.0.0.tgz", archive,
+			provenancetest.Sign(t, key, chartYaml, map[string]string{"other-1.0.0.tgz": digest}), false},
+		{"missing files section", "chart-1.0.0.tgz", archive,
+			provenancetest.SignMessage(t, key, chartYaml), false},
+		{"unknown key", "chart-1.0.0.tgz", archive,
+			provenancetest.Sign(t, provenancetest.NewKey(t), chartYaml, map[string]string{"chart-1.0.0.tgz": digest}), false},
+		{"unsigned", "chart-1.0.0.tgz", archive, []byte(chartYaml), false},
+	}
+	for _, c := range cases {
+		err := Verify(c.filename, c.archive, c.prov)
+		if c.valid && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+	}
+}
+
+func TestVerifyDigest(t *testing.T) {
+	key := provenancetest.NewKey(t)
+	path, cleanup := provenancetest.WriteKeyring(t, key)
+	defer cleanup()
+	if err := Initialize(Config{Keyring: path}); err != nil {
+		t.Fatal(err)
+	}
+	defer Initialize(Config{})
+
+	digest := Digest([]byte("archive of chart"))
+	prov := provenancetest.Sign(t, key, chartYaml, map[string]string{"chart-1.0.0.tgz": digest})
+	if err := VerifyDigest("chart-1.0.0.tgz", digest, prov); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := VerifyDigest("chart-1.0.0.tgz", Digest([]byte("other archive")), prov); err == nil {
+		t.Errorf("expected an error of tampered digest")
+	}
+	if err := VerifyDigest("chart-2.0.0.tgz", digest, prov); err == nil {
+		t.Errorf("expected an error of wrong filename")
+	}
+}
+
+func TestInitialize(t *testing.T) {
+	if err := Initialize(Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if Enabled() {
+		t.Errorf("expected verification to be disabled without keyring")
+	}
+	if err := Initialize(Config{Keyring: "/path/to/nonexistent/pubring.gpg"}); err == nil {
+		t.Errorf("expected an error of nonexistent keyring")
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+// Package provenancetest provides keyrings and signed provenance files for tests
+package provenancetest
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/clearsign"
+)
+
+// NewKey creates a signing key
+func NewKey(t testing.TB) *openpgp.Entity {
+	entity, err := openpgp.NewEntity("registry-test", "", "test@helm-registry", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// identities of a new key are self-signed by serializing the private key
+	if err = entity.SerializePrivate(ioutil.Discard, nil); err != nil {
+		t.Fatal(err)
+	}
+	return entity
+}
+
+// WriteKeyring writes a public keyring of keys to a temporary file. The returned function
+// removes the file
+func WriteKeyring(t testing.TB, keys ...*openpgp.Entity) (string, func()) {
+	dir, err := ioutil.TempDir("", "registry-keyring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	for _, key := range keys {
+		if err = key.Serialize(buf); err != nil {
+			os.RemoveAll(dir)
+			t.Fatal(err)
+		}
+	}
+	keyring := filepath.Join(dir, "pubring.gpg")
+	if err = ioutil.WriteFile(keyring, buf.Bytes(), 0644); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return keyring, func() { os.RemoveAll(dir) }
+}
+
+// Sign creates a provenance file which is signed by key and records digests of archives.
+// Keys of files are archive names and values are digests, like "sha256:<hex>"
+func Sign(t testing.TB, key *openpgp.Entity, chartYaml string, files map[string]string) []byte {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	message := chartYaml + "\n...\nfiles:\n"
+	for _, name := range names {
+		message += "  " + name + ": " + files[name] + "\n"
+	}
+	return SignMessage(t, key, message)
+}
+
+// SignMessage creates a provenance file which is signed by key with any message
+func SignMessage(t testing.TB, key *openpgp.Entity, message string) []byte {
+	buf := &bytes.Buffer{}
+	writer, err := clearsign.Encode(buf, key.PrivateKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = writer.Write([]byte(message)); err != nil {
+		t.Fatal(err)
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,283 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package quota
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// Limits are storage limits of a space. A limit which is 0 means no limit
+type Limits struct {
+	// MaxArchiveSize is the max size in bytes of a pushed chart archive
+	MaxArchiveSize int64 `yaml:"maxArchiveSize" json:"maxArchiveSize,omitempty"`
+	// MaxVersions is the max number of versions in a chart
+	MaxVersions int `yaml:"maxVersions" json:"maxVersions,omitempty"`
+	// MaxSpaceBytes is the max total size in bytes of archives of all versions in a space
+	MaxSpaceBytes int64 `yaml:"maxSpaceBytes" json:"maxSpaceBytes,omitempty"`
+}
+
+// Config is a config of space quotas
+type Config struct {
+	// Default is the limits of all spaces
+	Default Limits `yaml:"default"`
+	// Spaces are limits of spaces by name. A limit which is 0 is the default limit
+	Spaces map[string]Limits `yaml:"spaces"`
+}
+
+// Usage is the storage usage of a space
+type Usage struct {
+	// Space is the name of space
+	Space string `json:"space"`
+	// Charts is the number of charts in space
+	Charts int `json:"charts"`
+	// Versions is the number of versions in space
+	Versions int `json:"versions"`
+	// Bytes is the total size of archives of all versions in space, including trashed versions
+	Bytes int64 `json:"bytes"`
+	// TrashedVersions is the number of trashed versions in space
+	TrashedVersions int `json:"trashedVersions"`
+	// TrashedBytes is the total size of archives of trashed versions in space
+	TrashedBytes int64 `json:"trashedBytes"`
+	// Limits are the limits of space
+	Limits Limits `json:"limits"`
+}
+
+// usageExpiration is how long a cached usage of a space is used by checks. A cached usage
+// is adjusted by every push which passes a check, and it's reloaded after expiration to
+// count other changes of the space
+const usageExpiration = 5 * time.Minute
+
+// spaceUsage is the cached usage of a space
+type spaceUsage struct {
+	// write is held from a check to the end of the write of a version
+	write sync.Mutex
+	// lock protects bytes and loaded
+	lock   sync.Mutex
+	bytes  int64
+	loaded time.Time
+}
+
+// globalConfig is the config used by registry
+var globalConfig Config
+
+var (
+	usagesLock sync.Mutex
+	// usages are cached usages of spaces by name
+	usages = map[string]*spaceUsage{}
+)
+
+// Initialize sets quotas of spaces by config
+func Initialize(config Config) error {
+	if err := validate("default", config.Default); err != nil {
+		return err
+	}
+	for space, limits := range config.Spaces {
+		if err := validate(space, limits); err != nil {
+			return err
+		}
+	}
+	globalConfig = config
+	InvalidateAll()
+	if config.Default != (Limits{}) || len(config.Spaces) > 0 {
+		log.Infof("Enforcing quotas %+v, %d spaces have their own quotas", config.Default, len(config.Spaces))
+	}
+	return nil
+}
+
+// validate checks whether limits are not negative
+func validate(name string, limits Limits) error {
+	if limits.MaxArchiveSize < 0 || limits.MaxVersions < 0 || limits.MaxSpaceBytes < 0 {
+		return fmt.Errorf("quota limits of %s should not be negative, but got %+v", name, limits)
+	}
+	return nil
+}
+
+// SpaceLimits returns the limits of a space
+func SpaceLimits(space string) Limits {
+	limits := globalConfig.Default
+	override, ok := globalConfig.Spaces[space]
+	if !ok {
+		return limits
+	}
+	if override.MaxArchiveSize > 0 {
+		limits.MaxArchiveSize = override.MaxArchiveSize
+	}
+	if override.MaxVersions > 0 {
+		limits.MaxVersions = override.MaxVersions
+	}
+	if override.MaxSpaceBytes > 0 {
+		limits.MaxSpaceBytes = override.MaxSpaceBytes
+	}
+	return limits
+}
+
+// CheckArchiveSize checks whether an archive of size can be pushed to a space. name is
+// the name of archive in errors
+func CheckArchiveSize(space, name string, size int64) error {
+	limits := SpaceLimits(space)
+	if limits.MaxArchiveSize > 0 && size > limits.MaxArchiveSize {
+		return errors.ErrorArchiveTooLarge.Format(name, limits.MaxArchiveSize)
+	}
+	return nil
+}
+
+// Lock serializes pushes to a space which has limits of versions or bytes. The lock is held
+// from Check to the end of the write of the version, so concurrent pushes can't exceed the
+// limits together. It returns a func to release the lock
+func Lock(space string) func() {
+	limits := SpaceLimits(space)
+	if limits.MaxVersions <= 0 && limits.MaxSpaceBytes <= 0 {
+		return func() {}
+	}
+	entry := getSpaceUsage(space)
+	entry.write.Lock()
+	return entry.write.Unlock
+}
+
+// Check checks whether an archive of size can be stored as version. A version which exists
+// is replaced, so the size of its archive is not counted. The caller should hold the lock
+// of space returned by Lock until the version is written
+func Check(ctx context.Context, space storage.Space, chart storage.Chart, version storage.Version, size int64) error {
+	name := path.Join(space.Name(), chart.Name(), version.Number())
+	if err := CheckArchiveSize(space.Name(), name, size); err != nil {
+		return err
+	}
+	limits := SpaceLimits(space.Name())
+	var previous int64
+	exists := version.Exists(ctx)
+	if exists {
+		var err error
+		previous, err = version.Size(ctx)
+		if err != nil && !errors.ErrorInvalidStatus.Equal(err) {
+			return err
+		}
+	}
+	if limits.MaxVersions > 0 && !exists && chart.Exists(ctx) {
+		versions, err := chart.List(ctx)
+		if err != nil {
+			return err
+		}
+		if len(versions) >= limits.MaxVersions {
+			return errors.ErrorQuotaExceeded.Format(path.Join(space.Name(), chart.Name()),
+				fmt.Sprintf("it has %d versions and the limit is %d", len(versions), limits.MaxVersions))
+		}
+	}
+	if limits.MaxSpaceBytes > 0 {
+		entry := getSpaceUsage(space.Name())
+		entry.lock.Lock()
+		defer entry.lock.Unlock()
+		if time.Since(entry.loaded) > usageExpiration {
+			usage, err := GetUsage(ctx, space)
+			if err != nil {
+				return err
+			}
+			entry.bytes = usage.Bytes
+			entry.loaded = time.Now()
+		}
+		if entry.bytes-previous+size > limits.MaxSpaceBytes {
+			return errors.ErrorQuotaExceeded.Format(space.Name(),
+				fmt.Sprintf("%d bytes are used, %d bytes are pushed and the limit is %d bytes", entry.bytes, size, limits.MaxSpaceBytes))
+		}
+		// the version is counted before it's written. If the write fails, the usage is
+		// corrected when it's reloaded
+		entry.bytes += size - previous
+	}
+	return nil
+}
+
+// Invalidate drops the cached usage of a space. It's called when versions of the space
+// are deleted permanently
+func Invalidate(space string) {
+	usagesLock.Lock()
+	defer usagesLock.Unlock()
+	if entry, ok := usages[space]; ok {
+		entry.lock.Lock()
+		entry.loaded = time.Time{}
+		entry.lock.Unlock()
+	}
+}
+
+// InvalidateAll drops cached usages of all spaces
+func InvalidateAll() {
+	usagesLock.Lock()
+	defer usagesLock.Unlock()
+	for _, entry := range usages {
+		entry.lock.Lock()
+		entry.loaded = time.Time{}
+		entry.lock.Unlock()
+	}
+}
+
+// getSpaceUsage returns the cached usage of a space
+func getSpaceUsage(space string) *spaceUsage {
+	usagesLock.Lock()
+	defer usagesLock.Unlock()
+	entry, ok := usages[space]
+	if !ok {
+		entry = &spaceUsage{}
+		usages[space] = entry
+	}
+	return entry
+}
+
+// GetUsage returns the storage usage of a space. Trashed versions are counted because
+// they're kept until they're purged, and versions which are being stored are not counted
+func GetUsage(ctx context.Context, space storage.Space) (*Usage, error) {
+	usage := &Usage{Space: space.Name(), Limits: SpaceLimits(space.Name())}
+	charts, err := space.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, chartName := range charts {
+		chart, err := space.Chart(ctx, chartName)
+		if err != nil {
+			return nil, err
+		}
+		versions, err := chart.List(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) > 0 {
+			usage.Charts++
+		}
+		for _, number := range versions {
+			version, err := chart.Version(ctx, number)
+			if err != nil {
+				return nil, err
+			}
+			size, err := version.Size(ctx)
+			if errors.ErrorInvalidStatus.Equal(err) || errors.ErrorContentNotFound.Equal(err) {
+				// the version is being stored or is left by a failed upload
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			usage.Versions++
+			usage.Bytes += size
+		}
+	}
+	trashed, err := space.TrashedVersionMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, metadata := range trashed {
+		if metadata.Size == nil {
+			continue
+		}
+		usage.TrashedVersions++
+		usage.TrashedBytes += *metadata.Size
+	}
+	usage.Bytes += usage.TrashedBytes
+	return usage, nil
+}
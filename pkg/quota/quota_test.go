@@ -0,0 +1,226 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package quota
+
+import (
+	"context"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+
+	_ "github.com/caicloud/helm-registry/pkg/storage/simple"
+)
+
+// newTestSpace creates a space in a temporary directory
+func newTestSpace(t *testing.T) (storage.Space, func()) {
+	manager, cleanup := storagetest.NewSpaceManager(t)
+	space, err := manager.Create(context.Background(), "space")
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+	return space, cleanup
+}
+
+func TestSpaceLimits(t *testing.T) {
+	defer Initialize(Config{})
+	err := Initialize(Config{
+		Default: Limits{MaxArchiveSize: 100, MaxVersions: 2},
+		Spaces:  map[string]Limits{"big": {MaxArchiveSize: 1000, MaxSpaceBytes: 5000}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits := SpaceLimits("space"); limits != (Limits{MaxArchiveSize: 100, MaxVersions: 2}) {
+		t.Errorf("expected default limits, but got %+v", limits)
+	}
+	if limits := SpaceLimits("big"); limits != (Limits{MaxArchiveSize: 1000, MaxVersions: 2, MaxSpaceBytes: 5000}) {
+		t.Errorf("expected overridden limits, but got %+v", limits)
+	}
+	if err = Initialize(Config{Default: Limits{MaxVersions: -1}}); err == nil {
+		t.Error("expected an error for negative limits")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	defer Initialize(Config{})
+	ctx := context.Background()
+	space, cleanup := newTestSpace(t)
+	defer cleanup()
+	chart, err := space.Chart(ctx, "chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	put := func(number string) (storage.Version, int64) {
+		version, err := chart.Version(ctx, number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := storagetest.NewArchive(t, "chart", number)
+		if err = Check(ctx, space, chart, version, int64(len(data))); err != nil {
+			t.Fatal(err)
+		}
+		if err = version.PutContent(ctx, data); err != nil {
+			t.Fatal(err)
+		}
+		return version, int64(len(data))
+	}
+	_, size := put("1.0.0")
+	version, _ := put("1.1.0")
+	usage, err := GetUsage(ctx, space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Charts != 1 || usage.Versions != 2 || usage.Bytes <= size {
+		t.Errorf("expected usage of 1 chart and 2 versions, but got %+v", usage)
+	}
+
+	next, err := chart.Version(ctx, "1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		limits   Limits
+		version  storage.Version
+		size     int64
+		expected *errors.Error
+	}{
+		{Limits{MaxArchiveSize: 10}, next, 11, errors.ErrorArchiveTooLarge},
+		{Limits{MaxArchiveSize: 10}, next, 10, nil},
+		{Limits{MaxVersions: 2}, next, size, errors.ErrorQuotaExceeded},
+		// an existing version is replaced
+		{Limits{MaxVersions: 2}, version, size, nil},
+		{Limits{MaxSpaceBytes: usage.Bytes}, next, 1, errors.ErrorQuotaExceeded},
+		{Limits{MaxSpaceBytes: usage.Bytes + 1}, next, 1, nil},
+		{Limits{MaxSpaceBytes: usage.Bytes}, version, size, nil},
+	} {
+		if err = Initialize(Config{Default: c.limits}); err != nil {
+			t.Fatal(err)
+		}
+		err = Check(ctx, space, chart, c.version, c.size)
+		if c.expected == nil && err != nil || c.expected != nil && !c.expected.Equal(err) {
+			t.Errorf("expected error %v for %+v and %s of %d bytes, but got %v", c.expected, c.limits, c.version.Number(), c.size, err)
+		}
+	}
+}
+
+func TestGetUsageCountsTrash(t *testing.T) {
+	ctx := context.Background()
+	space, cleanup := newTestSpace(t)
+	defer cleanup()
+	chart, err := space.Chart(ctx, "chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, number := range []string{"1.0.0", "1.1.0"} {
+		version, err := chart.Version(ctx, number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = version.PutContent(ctx, storagetest.NewArchive(t, "chart", number)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, err := GetUsage(ctx, space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = chart.Trash(ctx, "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	after, err := GetUsage(ctx, space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Versions != 1 || after.TrashedVersions != 1 || after.TrashedBytes <= 0 {
+		t.Errorf("expected usage of 1 version and 1 trashed version, but got %+v", after)
+	}
+	if after.Bytes != before.Bytes {
+		t.Errorf("expected %d bytes with the trashed version, but got %d", before.Bytes, after.Bytes)
+	}
+}
+
+func TestCheckCachesUsage(t *testing.T) {
+	defer Initialize(Config{})
+	ctx := context.Background()
+	space, cleanup := newTestSpace(t)
+	defer cleanup()
+	chart, err := space.Chart(ctx, "chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := int64(len(storagetest.NewArchive(t, "chart", "1.0.0")))
+	if err = Initialize(Config{Default: Limits{MaxSpaceBytes: size + size/2}}); err != nil {
+		t.Fatal(err)
+	}
+	check := func(number string) error {
+		version, err := chart.Version(ctx, number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Check(ctx, space, chart, version, size)
+	}
+	if err = check("1.0.0"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the checked version is counted before it's written
+	if err = check("1.1.0"); !errors.ErrorQuotaExceeded.Equal(err) {
+		t.Errorf("expected quota exceeded with a checked version, but got %v", err)
+	}
+	Invalidate(space.Name())
+	if err = check("1.1.0"); err != nil {
+		t.Errorf("expected the usage to be reloaded after invalidation, but got %v", err)
+	}
+}
+
+func TestLockSerializesPushes(t *testing.T) {
+	defer Initialize(Config{})
+	ctx := context.Background()
+	space, cleanup := newTestSpace(t)
+	defer cleanup()
+	chart, err := space.Chart(ctx, "chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := int64(len(storagetest.NewArchive(t, "chart", "1.0.0")))
+	if err = Initialize(Config{Default: Limits{MaxSpaceBytes: size + size/2}}); err != nil {
+		t.Fatal(err)
+	}
+	numbers := []string{"1.0.0", "1.1.0", "1.2.0", "1.3.0", "1.4.0"}
+	errs := make(chan error, len(numbers))
+	for _, number := range numbers {
+		data := storagetest.NewArchive(t, "chart", number)
+		go func(number string, data []byte) {
+			version, err := chart.Version(ctx, number)
+			if err != nil {
+				errs <- err
+				return
+			}
+			unlock := Lock(space.Name())
+			defer unlock()
+			if err = Check(ctx, space, chart, version, int64(len(data))); err != nil {
+				errs <- err
+				return
+			}
+			errs <- version.PutContent(ctx, data)
+		}(number, data)
+	}
+	stored := 0
+	for range numbers {
+		err := <-errs
+		if err == nil {
+			stored++
+			continue
+		}
+		if !errors.ErrorQuotaExceeded.Equal(err) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if stored != 1 {
+		t.Errorf("expected 1 version to be stored, but got %d", stored)
+	}
+}
@@ -0,0 +1,290 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/metrics"
+	"github.com/emicklei/go-restful"
+)
+
+// DefaultExpensiveRoutes are routes limited by Concurrency if Concurrency.Routes is empty
+var DefaultExpensiveRoutes = []string{
+	"GET /api/v1/search",
+	"GET /api/v1/spaces/{space}/metadata",
+	"GET /api/v1/spaces/{space}/metadata/latest",
+	"GET /api/v1/spaces/{space}/index.yaml",
+}
+
+// Rate is a rate of requests. A rate whose RequestsPerSecond is 0 means no limit
+type Rate struct {
+	// RequestsPerSecond is the number of requests allowed per second on average
+	RequestsPerSecond float64 `yaml:"requestsPerSecond"`
+	// Burst is the number of requests allowed at once. It's RequestsPerSecond rounded up if it's 0
+	Burst int `yaml:"burst"`
+}
+
+// Concurrency is a config of concurrency limit of expensive routes
+type Concurrency struct {
+	// Max is the max number of requests of expensive routes served at the same time. 0 means no limit
+	Max int `yaml:"max"`
+	// Routes are expensive routes like "GET /api/v1/spaces/{space}/metadata/latest".
+	// It's DefaultExpensiveRoutes if it's empty
+	Routes []string `yaml:"routes"`
+}
+
+// Config is a config of rate limiting
+type Config struct {
+	// Global limits all requests
+	Global Rate `yaml:"global"`
+	// PerToken limits requests of every token
+	PerToken Rate `yaml:"perToken"`
+	// PerIP limits requests from every client address
+	PerIP Rate `yaml:"perIP"`
+	// ForwardedFor uses the first address in header X-Forwarded-For as client address. It
+	// should only be enabled behind a proxy which sets the header
+	ForwardedFor bool `yaml:"forwardedFor"`
+	// Concurrency limits concurrent requests of expensive routes
+	Concurrency Concurrency `yaml:"concurrency"`
+}
+
+// limits are limiters created by config. It's nil if rate limiting is disabled
+type limits struct {
+	global       *limiter
+	perToken     *limiter
+	perIP        *limiter
+	forwardedFor bool
+	// slots are taken by requests of expensive routes
+	slots     chan struct{}
+	expensive map[string]bool
+}
+
+// globalLimits are limits used by Filter
+var globalLimits *limits
+
+// now returns the current time. Tests replace it
+var now = time.Now
+
+// Initialize creates limiters by config
+func Initialize(config Config) error {
+	for name, rate := range map[string]Rate{"global": config.Global, "perToken": config.PerToken, "perIP": config.PerIP} {
+		if rate.RequestsPerSecond < 0 || rate.Burst < 0 {
+			return fmt.Errorf("rate limit %s should not be negative, but got %+v", name, rate)
+		}
+	}
+	if config.Concurrency.Max < 0 {
+		return fmt.Errorf("concurrency limit should not be negative, but got %d", config.Concurrency.Max)
+	}
+	l := &limits{
+		global:       newLimiter(config.Global),
+		perToken:     newLimiter(config.PerToken),
+		perIP:        newLimiter(config.PerIP),
+		forwardedFor: config.ForwardedFor,
+	}
+	if config.Concurrency.Max > 0 {
+		routes := config.Concurrency.Routes
+		if len(routes) <= 0 {
+			routes = DefaultExpensiveRoutes
+		}
+		l.slots = make(chan struct{}, config.Concurrency.Max)
+		l.expensive = make(map[string]bool, len(routes))
+		for _, route := range routes {
+			l.expensive[route] = true
+		}
+	}
+	if l.global == nil && l.perToken == nil && l.perIP == nil && l.slots == nil {
+		globalLimits = nil
+		return nil
+	}
+	globalLimits = l
+	log.Infof("Limiting requests by global rate %+v, token rate %+v, address rate %+v and %d concurrent expensive requests",
+		config.Global, config.PerToken, config.PerIP, config.Concurrency.Max)
+	return nil
+}
+
+// Filter rejects requests over rate limits or concurrency limit with 429 and header
+// Retry-After
+func Filter() restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		l := globalLimits
+		if l == nil {
+			chain.ProcessFilter(req, resp)
+			return
+		}
+		if wait, ok := l.allow(req); !ok {
+			tooManyRequests(resp, wait, "rate limit is exceeded")
+			return
+		}
+		if l.expensive[req.Request.Method+" "+req.SelectedRoutePath()] {
+			select {
+			case l.slots <- struct{}{}:
+				defer func() { <-l.slots }()
+			default:
+				tooManyRequests(resp, time.Second, fmt.Sprintf("%d expensive requests are being served", cap(l.slots)))
+				return
+			}
+		}
+		chain.ProcessFilter(req, resp)
+	}
+}
+
+// allow takes a request from every limiter which applies to req. It returns how long
+// the client should wait if req is rejected. Per-IP and per-token limits are checked
+// before the global limit, so a flooding client can't drain the global bucket, and
+// tokens taken before a rejection are refunded
+func (l *limits) allow(req *restful.Request) (time.Duration, bool) {
+	t := now()
+	address := l.clientAddress(req)
+	if wait, ok := l.perIP.take(address, t); !ok {
+		return wait, false
+	}
+	token := requestToken(req)
+	if token != "" {
+		if wait, ok := l.perToken.take(token, t); !ok {
+			l.perIP.refund(address)
+			return wait, false
+		}
+	}
+	if wait, ok := l.global.take("", t); !ok {
+		l.perIP.refund(address)
+		if token != "" {
+			l.perToken.refund(token)
+		}
+		return wait, false
+	}
+	return 0, true
+}
+
+// clientAddress returns the address of client without port
+func (l *limits) clientAddress(req *restful.Request) string {
+	if l.forwardedFor {
+		if forwarded := req.HeaderParameter("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(req.Request.RemoteAddr)
+	if err != nil {
+		return req.Request.RemoteAddr
+	}
+	return host
+}
+
+// requestToken returns the bearer token of request, or the password of basic credentials.
+// Tokens are not authenticated, so an invalid token is limited like a valid one
+func requestToken(req *restful.Request) string {
+	header := req.HeaderParameter("Authorization")
+	const prefix = "Bearer "
+	if strings.HasPrefix(header, prefix) {
+		return strings.TrimSpace(strings.TrimPrefix(header, prefix))
+	}
+	if _, password, ok := req.Request.BasicAuth(); ok {
+		return password
+	}
+	return ""
+}
+
+// tooManyRequests responds with 429 and asks client to retry after wait
+func tooManyRequests(resp *restful.Response, wait time.Duration, reason string) {
+	err := errors.ErrorTooManyRequests.Format(reason)
+	metrics.CountError(err)
+	resp.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	resp.WriteHeaderAndEntity(err.Code, map[string]string{
+		"message": err.Message,
+		"reason":  err.Reason,
+	})
+}
+
+// bucket is a token bucket of a key
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter limits requests of keys by token buckets
+type limiter struct {
+	rate    float64
+	burst   float64
+	lock    sync.Mutex
+	buckets map[string]*bucket
+	// buckets are swept at most once in the time to fill a bucket
+	swept time.Time
+}
+
+// newLimiter creates a limiter by rate. It returns nil if rate means no limit
+func newLimiter(rate Rate) *limiter {
+	if rate.RequestsPerSecond <= 0 {
+		return nil
+	}
+	burst := float64(rate.Burst)
+	if burst <= 0 {
+		burst = math.Ceil(rate.RequestsPerSecond)
+	}
+	return &limiter{
+		rate:    rate.RequestsPerSecond,
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// take takes a token from the bucket of key at time t. It returns the time until the
+// next token if the bucket is empty. A nil limiter allows all requests
+func (l *limiter) take(key string, t time.Time) (time.Duration, bool) {
+	if l == nil {
+		return 0, true
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.sweep(t)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: t}
+		l.buckets[key] = b
+	}
+	if elapsed := t.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+		b.last = t
+	}
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// refund gives back a token taken from the bucket of key
+func (l *limiter) refund(key string) {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if b, ok := l.buckets[key]; ok {
+		b.tokens = math.Min(l.burst, b.tokens+1)
+	}
+}
+
+// sweep removes buckets which have been full since they were last used, so keys of
+// clients which are gone don't stay in memory
+func (l *limiter) sweep(t time.Time) {
+	fill := time.Duration(l.burst / l.rate * float64(time.Second))
+	if t.Sub(l.swept) < fill {
+		return
+	}
+	l.swept = t
+	for key, b := range l.buckets {
+		if t.Sub(b.last) >= fill {
+			delete(l.buckets, key)
+		}
+	}
+}
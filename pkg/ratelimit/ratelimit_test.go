@@ -0,0 +1,191 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/emicklei/go-restful"
+)
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(Rate{RequestsPerSecond: 2, Burst: 3})
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, ok := l.take("a", start); !ok {
+			t.Fatalf("expected request %d in burst to be allowed", i)
+		}
+	}
+	wait, ok := l.take("a", start)
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("expected to wait 500ms after burst, but got %v and %v", wait, ok)
+	}
+	if _, ok = l.take("b", start); !ok {
+		t.Error("expected another key to have its own bucket")
+	}
+	if _, ok = l.take("a", start.Add(500*time.Millisecond)); !ok {
+		t.Error("expected a token after 500ms")
+	}
+	if _, ok = l.take("a", start.Add(500*time.Millisecond)); ok {
+		t.Error("expected only one token after 500ms")
+	}
+
+	// idle buckets are removed after the time to fill them
+	l.take("c", start.Add(time.Second))
+	l.take("c", start.Add(2*time.Second))
+	if len(l.buckets) != 1 {
+		t.Errorf("expected idle buckets to be swept, but got %d buckets", len(l.buckets))
+	}
+	if newLimiter(Rate{}) != nil {
+		t.Error("expected no limiter for a zero rate")
+	}
+}
+
+// newTestServer creates a server whose expensive route waits for release if it's not nil
+func newTestServer(release chan struct{}) *httptest.Server {
+	container := restful.NewContainer()
+	service := (&restful.WebService{}).Path("/api/v1").Produces(restful.MIME_JSON).Filter(Filter())
+	service.Route(service.GET("/spaces/{space}/metadata/latest").To(func(req *restful.Request, resp *restful.Response) {
+		if release != nil {
+			<-release
+		}
+		resp.WriteHeader(http.StatusOK)
+	}))
+	service.Route(service.GET("/spaces").To(func(req *restful.Request, resp *restful.Response) {
+		resp.WriteHeader(http.StatusOK)
+	}))
+	container.Add(service)
+	return httptest.NewServer(container)
+}
+
+// get requests path with token and returns the status code and header Retry-After
+func get(t *testing.T, url, token string) (int, string) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode, resp.Header.Get("Retry-After")
+}
+
+func TestFilterRate(t *testing.T) {
+	defer Initialize(Config{})
+	start := time.Now()
+	now = func() time.Time { return start }
+	defer func() { now = time.Now }()
+	err := Initialize(Config{
+		PerToken: Rate{RequestsPerSecond: 0.5, Burst: 1},
+		PerIP:    Rate{RequestsPerSecond: 1, Burst: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(nil)
+	defer server.Close()
+	url := server.URL + "/api/v1/spaces"
+
+	if code, _ := get(t, url, "a"); code != http.StatusOK {
+		t.Errorf("expected the first request of token a to be allowed, but got %d", code)
+	}
+	if code, retry := get(t, url, "a"); code != http.StatusTooManyRequests || retry != "2" {
+		t.Errorf("expected the second request of token a to be limited with Retry-After 2, but got %d and %q", code, retry)
+	}
+	if code, _ := get(t, url, "b"); code != http.StatusOK {
+		t.Errorf("expected token b to be allowed, but got %d", code)
+	}
+	if code, _ := get(t, url, ""); code != http.StatusOK {
+		t.Errorf("expected the third request of the address to be allowed, but got %d", code)
+	}
+	if code, retry := get(t, url, ""); code != http.StatusTooManyRequests || retry != "1" {
+		t.Errorf("expected the address to be limited with Retry-After 1, but got %d and %q", code, retry)
+	}
+
+	if err = Initialize(Config{Global: Rate{RequestsPerSecond: -1}}); err == nil {
+		t.Error("expected an error for a negative rate")
+	}
+}
+
+func TestLimitsFloodingAddress(t *testing.T) {
+	defer Initialize(Config{})
+	start := time.Now()
+	now = func() time.Time { return start }
+	defer func() { now = time.Now }()
+	err := Initialize(Config{
+		Global: Rate{RequestsPerSecond: 1, Burst: 3},
+		PerIP:  Rate{RequestsPerSecond: 1, Burst: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := func(address string) *restful.Request {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/spaces", nil)
+		req.RemoteAddr = address + ":1234"
+		return restful.NewRequest(req)
+	}
+
+	for i := 0; i < 10; i++ {
+		_, ok := globalLimits.allow(request("10.0.0.1"))
+		if ok != (i < 2) {
+			t.Errorf("expected request %d of the flooding address to be allowed: %v, but got %v", i, i < 2, ok)
+		}
+	}
+	if _, ok := globalLimits.allow(request("10.0.0.2")); !ok {
+		t.Error("expected another address to be allowed while the first one floods")
+	}
+	if _, ok := globalLimits.allow(request("10.0.0.3")); ok {
+		t.Error("expected the global limit to be exceeded")
+	}
+	// the rejected request of the third address is refunded
+	if tokens := globalLimits.perIP.buckets["10.0.0.3"].tokens; tokens != 2 {
+		t.Errorf("expected the token of a globally rejected request to be refunded, but got %v tokens", tokens)
+	}
+}
+
+func TestFilterConcurrency(t *testing.T) {
+	defer Initialize(Config{})
+	if err := Initialize(Config{Concurrency: Concurrency{Max: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	server := newTestServer(release)
+	defer server.Close()
+
+	done := make(chan int)
+	go func() {
+		code, _ := get(t, server.URL+"/api/v1/spaces/a/metadata/latest", "")
+		done <- code
+	}()
+	// wait until the first request takes the slot
+	for i := 0; len(globalLimits.slots) == 0; i++ {
+		if i > 100 {
+			t.Fatal("expected the first request to take the slot")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if code, retry := get(t, server.URL+"/api/v1/spaces/b/metadata/latest", ""); code != http.StatusTooManyRequests || retry != "1" {
+		t.Errorf("expected a concurrent expensive request to be limited, but got %d and %q", code, retry)
+	}
+	// other routes are not limited
+	if code, _ := get(t, server.URL+"/api/v1/spaces", ""); code != http.StatusOK {
+		t.Errorf("expected a cheap request to be allowed, but got %d", code)
+	}
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("expected the first request to succeed, but got %d", code)
+	}
+	if code, _ := get(t, server.URL+"/api/v1/spaces/a/metadata/latest", ""); code != http.StatusOK {
+		t.Errorf("expected the slot to be released, but got %d", code)
+	}
+}
@@ -0,0 +1,34 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package v1
+
+import (
+	"net/http"
+
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// APICollectGarbage defines an api of collecting garbage in storage
+type APICollectGarbage struct {
+	baseAPI
+}
+
+// NewAPICollectGarbage creates an instance of APICollectGarbage
+func NewAPICollectGarbage() *APICollectGarbage {
+	api := &APICollectGarbage{}
+	api.object = api
+	api.method = http.MethodPost
+	api.url = URLGarbage
+	api.result = &storage.GCResult{}
+	return api
+}
+
+// Convert converts result to *storage.GCResult
+func (api *APICollectGarbage) Convert(result interface{}, err error) (*storage.GCResult, error) {
+	if err != nil {
+		return nil, err
+	}
+	return result.(*storage.GCResult), nil
+}
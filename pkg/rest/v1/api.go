@@ -249,7 +249,7 @@ func (ba *baseAPI) request(endpoint string) (*http.Request, error) {
 	path := URL(ba.Path()).Format(ba.paths)
 	contentType := ""
 	var body io.Reader
-	if ba.Method() == http.MethodGet || body != nil {
+	if ba.Method() == http.MethodGet || ba.body != nil {
 		// append values to url
 		if len(ba.values) > 0 {
 			path += "?" + ba.values.Encode()
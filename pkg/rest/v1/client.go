@@ -7,9 +7,11 @@ package v1
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/api/v1/types"
 	"github.com/caicloud/helm-registry/pkg/rest"
 	"github.com/caicloud/helm-registry/pkg/storage"
 	"k8s.io/helm/pkg/proto/hapi/chart"
@@ -47,6 +49,25 @@ func (c *Client) CreateSpace(spaceName string) (*models.Link, error) {
 	return api.Convert(c.Do(api))
 }
 
+// FetchSpace fetches info and usage of a space
+func (c *Client) FetchSpace(spaceName string) (*models.Space, error) {
+	api := NewAPIFetchSpace()
+	api.Space = spaceName
+	return api.Convert(c.Do(api))
+}
+
+// UpdateSpace replaces info of a space
+func (c *Client) UpdateSpace(spaceName string, info *storage.SpaceInfo) (*storage.SpaceInfo, error) {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, rest.ErrorUnknownLocalError.Format(err.Error())
+	}
+	api := NewAPIUpdateSpace()
+	api.Space = spaceName
+	api.Info = data
+	return api.Convert(c.Do(api))
+}
+
 // DeleteSpace deletes a space by space name
 func (c *Client) DeleteSpace(spaceName string) error {
 	api := NewAPIDeleteSpace()
@@ -177,3 +198,38 @@ func (c *Client) UpdateVersionValues(spaceName string, chartName string, version
 	api.Values = values
 	return api.Convert(c.Do(api))
 }
+
+// PromoteVersion copies a version to the target. If overwrite is true, an existing target version is replaced
+func (c *Client) PromoteVersion(spaceName string, chartName string, versionNumber string, target *types.VersionTarget, overwrite bool) (*models.ChartLink, error) {
+	data, err := json.Marshal(target)
+	if err != nil {
+		return nil, rest.ErrorUnknownLocalError.Format(err.Error())
+	}
+	api := NewAPIPromoteVersion()
+	api.Space = spaceName
+	api.Chart = chartName
+	api.Version = versionNumber
+	api.Overwrite = strconv.FormatBool(overwrite)
+	api.Target = data
+	return api.Convert(c.Do(api))
+}
+
+// Search searches metadata of versions. An empty space searches all readable spaces
+func (c *Client) Search(query, keyword, maintainer, spaceName string, latest, prerelease bool, start, limit int) (*MetadataCollectionResult, error) {
+	api := NewAPISearch()
+	api.Query = query
+	api.Keyword = keyword
+	api.Maintainer = maintainer
+	api.Space = spaceName
+	api.Latest = strconv.FormatBool(latest)
+	api.Prerelease = strconv.FormatBool(prerelease)
+	api.Start = start
+	api.Limit = limit
+	return api.Convert(c.Do(api))
+}
+
+// CollectGarbage purges trash and removes incomplete versions, unfinished uploads and unreferenced blobs
+func (c *Client) CollectGarbage() (*storage.GCResult, error) {
+	api := NewAPICollectGarbage()
+	return api.Convert(c.Do(api))
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package v1
+
+import (
+	"net/http"
+)
+
+// APISearch defines an api of searching metadata of versions
+type APISearch struct {
+	baseAPI
+	// Query is the text to search in names, descriptions and keywords
+	Query string `kind:"query" name:"q"`
+	// Keyword is a keyword which versions must have
+	Keyword string `kind:"query" name:"keyword"`
+	// Maintainer is the name or email of a maintainer which versions must have
+	Maintainer string `kind:"query" name:"maintainer"`
+	// Space is the space to search in. All readable spaces are searched if it's empty
+	Space string `kind:"query" name:"space"`
+	// Latest is "true" if only latest versions of charts should be returned
+	Latest string `kind:"query" name:"latest"`
+	// Prerelease is "true" if pre-release versions can be the latest ones
+	Prerelease string `kind:"query" name:"prerelease"`
+	// Start is the start index of list
+	Start int `kind:"query" name:"start"`
+	// Limit is the max length of list
+	Limit int `kind:"query" name:"limit"`
+}
+
+// NewAPISearch creates an instance of APISearch
+func NewAPISearch() *APISearch {
+	api := &APISearch{}
+	api.object = api
+	api.method = http.MethodGet
+	api.url = URLSearch
+	api.result = &MetadataCollectionResult{}
+	return api
+}
+
+// Convert converts result to *MetadataCollectionResult
+func (api *APISearch) Convert(result interface{}, err error) (*MetadataCollectionResult, error) {
+	if err != nil {
+		return nil, err
+	}
+	return result.(*MetadataCollectionResult), nil
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	"github.com/caicloud/helm-registry/pkg/api/models"
+	"github.com/caicloud/helm-registry/pkg/storage"
 )
 
 // APIListSpace defines an api of listing spaces
@@ -62,6 +63,58 @@ func (api *APICreateSpace) Convert(result interface{}, err error) (*models.Link,
 	return result.(*models.Link), nil
 }
 
+// APIFetchSpace defines an api of fetching info and usage of space
+type APIFetchSpace struct {
+	baseAPI
+	// Space is the name of space
+	Space string `kind:"path" name:"space"`
+}
+
+// NewAPIFetchSpace creates an instance of APIFetchSpace
+func NewAPIFetchSpace() *APIFetchSpace {
+	api := &APIFetchSpace{}
+	api.object = api
+	api.method = http.MethodGet
+	api.url = URLSpace
+	api.result = &models.Space{}
+	return api
+}
+
+// Convert converts result to *models.Space
+func (api *APIFetchSpace) Convert(result interface{}, err error) (*models.Space, error) {
+	if err != nil {
+		return nil, err
+	}
+	return result.(*models.Space), nil
+}
+
+// APIUpdateSpace defines an api of replacing info of space
+type APIUpdateSpace struct {
+	baseAPI
+	// Space is the name of space
+	Space string `kind:"path" name:"space"`
+	// Info is the info of space
+	Info []byte `kind:"body"`
+}
+
+// NewAPIUpdateSpace creates an instance of APIUpdateSpace
+func NewAPIUpdateSpace() *APIUpdateSpace {
+	api := &APIUpdateSpace{}
+	api.object = api
+	api.method = http.MethodPut
+	api.url = URLSpace
+	api.result = &storage.SpaceInfo{}
+	return api
+}
+
+// Convert converts result to *storage.SpaceInfo
+func (api *APIUpdateSpace) Convert(result interface{}, err error) (*storage.SpaceInfo, error) {
+	if err != nil {
+		return nil, err
+	}
+	return result.(*storage.SpaceInfo), nil
+}
+
 // APIDeleteSpace defines an api of deleting space
 type APIDeleteSpace struct {
 	baseAPI
@@ -19,6 +19,9 @@ const (
 	URLVersion         URL = "/spaces/{space}/charts/{chart}/versions/{version}"
 	URLVersionMetadata URL = "/spaces/{space}/charts/{chart}/versions/{version}/manifests/metadata"
 	URLVersionValues   URL = "/spaces/{space}/charts/{chart}/versions/{version}/manifests/values"
+	URLVersionPromote  URL = "/spaces/{space}/charts/{chart}/versions/{version}/promote"
+	URLSearch          URL = "/search"
+	URLGarbage         URL = "/admin/gc"
 )
 
 // Format generates url. values should contain all keys in url.
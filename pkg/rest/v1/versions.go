@@ -126,3 +126,36 @@ func NewAPIDeleteVersion() *APIDeleteVersion {
 func (api *APIDeleteVersion) Convert(result interface{}, err error) error {
 	return err
 }
+
+// APIPromoteVersion defines an api of promoting version to another space
+type APIPromoteVersion struct {
+	baseAPI
+	// Space is the name of space
+	Space string `kind:"path" name:"space"`
+	// Chart is the name of chart
+	Chart string `kind:"path" name:"chart"`
+	// Version is the name of Version
+	Version string `kind:"path" name:"version"`
+	// Overwrite is "true" if an existing target version should be replaced
+	Overwrite string `kind:"query" name:"overwrite"`
+	// Target is a json of types.VersionTarget
+	Target []byte `kind:"body"`
+}
+
+// NewAPIPromoteVersion creates an instance of APIPromoteVersion
+func NewAPIPromoteVersion() *APIPromoteVersion {
+	api := &APIPromoteVersion{}
+	api.object = api
+	api.method = http.MethodPost
+	api.url = URLVersionPromote
+	api.result = &models.ChartLink{}
+	return api
+}
+
+// Convert converts result to *models.ChartLink
+func (api *APIPromoteVersion) Convert(result interface{}, err error) (*models.ChartLink, error) {
+	if err != nil {
+		return nil, err
+	}
+	return result.(*models.ChartLink), nil
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package retention
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/immutability"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/metrics"
+	"github.com/caicloud/helm-registry/pkg/search"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/webhook"
+)
+
+// Config is a config of the retention reaper
+type Config struct {
+	// Interval is the period between two runs of the reaper, like "1h". The reaper
+	// is disabled if it's empty
+	Interval string `yaml:"interval"`
+	// DryRun makes the reaper only log versions which would be pruned
+	DryRun bool `yaml:"dryRun"`
+}
+
+// Start starts the reaper in background. It prunes charts by their retention policies
+// every interval
+func Start(config Config) error {
+	if len(config.Interval) <= 0 {
+		return nil
+	}
+	interval, err := time.ParseDuration(config.Interval)
+	if err != nil {
+		return err
+	}
+	if interval <= 0 {
+		return fmt.Errorf("retention interval should be positive, but got %s", config.Interval)
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			Run(context.Background(), config.DryRun)
+		}
+	}()
+	log.Infof("Pruning charts by retention policies every %s, dry run: %v", interval, config.DryRun)
+	return nil
+}
+
+// Run prunes all charts by their retention policies. Errors are logged and
+// don't stop pruning other charts. If dryRun is true, no version is pruned
+func Run(ctx context.Context, dryRun bool) {
+	manager := common.MustGetSpaceManager()
+	spaceNames, err := manager.List(ctx)
+	if err != nil {
+		log.Errorf("Failed to list spaces for pruning: %v", err)
+		return
+	}
+	for _, spaceName := range spaceNames {
+		space, err := manager.Space(ctx, spaceName)
+		if err != nil {
+			log.Errorf("Failed to get space %s for pruning: %v", spaceName, err)
+			continue
+		}
+		chartNames, err := space.List(ctx)
+		if err != nil {
+			log.Errorf("Failed to list charts in space %s for pruning: %v", spaceName, err)
+			continue
+		}
+		for _, chartName := range chartNames {
+			chart, err := space.Chart(ctx, chartName)
+			if err == nil {
+				_, err = Prune(ctx, space, chart, dryRun)
+			}
+			if err != nil {
+				log.Errorf("Failed to prune %s/%s: %v", spaceName, chartName, err)
+			}
+		}
+	}
+}
+
+// Prune moves versions which are not kept by the retention policy of chart to trash.
+// A chart without policy uses the policy of space. It returns numbers of pruned versions.
+// If dryRun is true, it returns numbers of versions which would be pruned without pruning.
+// Versions of immutable charts are never pruned
+func Prune(ctx context.Context, space storage.Space, chart storage.Chart, dryRun bool) ([]string, error) {
+	if immutability.Immutable(space.Name(), chart.Name()) {
+		return nil, nil
+	}
+	policy, err := Policy(ctx, space, chart)
+	if err != nil || policy == nil {
+		return nil, err
+	}
+	numbers, err := chart.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]version, 0, len(numbers))
+	for _, number := range numbers {
+		semverVersion, err := semver.NewVersion(number)
+		if err != nil {
+			// versions which are not semver can't be ordered, so they are never pruned
+			log.Warnf("Skip version %s of chart %s/%s: %v", number, space.Name(), chart.Name(), err)
+			continue
+		}
+		v, err := chart.Version(ctx, number)
+		if err != nil {
+			return nil, err
+		}
+		modTime, err := v.ModTime(ctx)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version{number, semverVersion, modTime})
+	}
+	pruned, err := selectPruned(policy, versions, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		for _, number := range pruned {
+			log.Infof("Would prune %s/%s/%s by retention policy", space.Name(), chart.Name(), number)
+		}
+		return pruned, nil
+	}
+	result := make([]string, 0, len(pruned))
+	for _, number := range pruned {
+		if err = chart.Trash(ctx, number); err != nil {
+			return result, err
+		}
+		log.Infof("Pruned %s/%s/%s by retention policy", space.Name(), chart.Name(), number)
+		metrics.Count(metrics.OperationPrune, space.Name())
+		search.InvalidateChart(space.Name(), chart.Name())
+		webhook.Notify(space.Name(), chart.Name(), number, webhook.ActionDelete)
+		result = append(result, number)
+	}
+	return result, nil
+}
+
+// Policy returns the retention policy which applies to chart. It's the policy of chart
+// or the policy of space if the chart has no policy. It returns nil if neither has a policy
+func Policy(ctx context.Context, space storage.Space, chart storage.Chart) (*storage.RetentionPolicy, error) {
+	policy, err := chart.RetentionPolicy(ctx)
+	if err != nil || policy != nil {
+		return policy, err
+	}
+	return space.RetentionPolicy(ctx)
+}
+
+// version is a version of chart for selecting
+type version struct {
+	number  string
+	semver  *semver.Version
+	modTime time.Time
+}
+
+// selectPruned returns numbers of versions which are not kept by policy at now.
+// The highest version is always kept
+func selectPruned(policy *storage.RetentionPolicy, versions []version, now time.Time) ([]string, error) {
+	var within time.Duration
+	if len(policy.KeepWithin) > 0 {
+		d, err := time.ParseDuration(policy.KeepWithin)
+		if err != nil {
+			return nil, err
+		}
+		within = d
+	}
+	var protect *semver.Constraints
+	if len(policy.Protect) > 0 {
+		c, err := storage.ParseRange(policy.Protect)
+		if err != nil {
+			return nil, err
+		}
+		protect = c
+	}
+	sorted := make([]version, len(versions))
+	copy(sorted, versions)
+	// from the highest version to the lowest one
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[j].semver.LessThan(sorted[i].semver)
+	})
+	pruned := make([]string, 0)
+	for i, v := range sorted {
+		if i == 0 {
+			continue
+		}
+		if policy.KeepLast > 0 && i < policy.KeepLast {
+			continue
+		}
+		if within > 0 && now.Sub(v.modTime) < within {
+			continue
+		}
+		if protect != nil && protect.Check(v.semver) {
+			continue
+		}
+		pruned = append(pruned, v.number)
+	}
+	return pruned, nil
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package retention
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// newVersions creates versions in upload order. A version is uploaded one hour after the previous one
+func newVersions(t *testing.T, start time.Time, numbers ...string) []version {
+	versions := make([]version, 0, len(numbers))
+	for i, number := range numbers {
+		v, err := semver.NewVersion(number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, version{number, v, start.Add(time.Duration(i) * time.Hour)})
+	}
+	return versions
+}
+
+func TestSelectPruned(t *testing.T) {
+	start := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	// 1.10.0 is uploaded before 1.2.0 but it's higher
+	versions := newVersions(t, start, "1.0.0", "1.1.0", "1.10.0", "1.2.0", "2.0.0")
+	now := start.Add(10 * time.Hour)
+	cases := []struct {
+		name     string
+		policy   storage.RetentionPolicy
+		versions []version
+		expected []string
+	}{
+		{"keep last by semver", storage.RetentionPolicy{KeepLast: 2}, versions, []string{"1.2.0", "1.1.0", "1.0.0"}},
+		{"keep within", storage.RetentionPolicy{KeepWithin: "8h30m"}, versions, []string{"1.1.0", "1.0.0"}},
+		{"keep last or within", storage.RetentionPolicy{KeepLast: 2, KeepWithin: "7h30m"}, versions, []string{"1.1.0", "1.0.0"}},
+		{"keep more than existing", storage.RetentionPolicy{KeepLast: 10}, versions, []string{}},
+		{"always keep highest", storage.RetentionPolicy{KeepWithin: "1h"}, newVersions(t, start, "3.0.0", "1.0.0"), []string{"1.0.0"}},
+		{"protect range", storage.RetentionPolicy{KeepLast: 1, Protect: "~1.1.0 || ^1.10.0"}, versions, []string{"1.2.0", "1.0.0"}},
+		{"protect space separated range", storage.RetentionPolicy{KeepLast: 1, Protect: ">=1.1.0 <1.3.0"}, versions, []string{"1.10.0", "1.0.0"}},
+	}
+	for _, c := range cases {
+		pruned, err := selectPruned(&c.policy, c.versions, now)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(pruned, c.expected) {
+			t.Errorf("%s: expected %v, but got %v", c.name, c.expected, pruned)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package scan
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+func init() {
+	Register("deprecatedAPIs", CheckerFactoryFunc(newDeprecatedAPIs))
+}
+
+// deprecatedAPIVersions maps deprecated api versions to kinds and their replacements.
+// A kind without replacement has been removed from kubernetes
+var deprecatedAPIVersions = map[string]map[string]string{
+	"extensions/v1beta1": {
+		"Deployment":        "apps/v1",
+		"DaemonSet":         "apps/v1",
+		"ReplicaSet":        "apps/v1",
+		"Ingress":           "networking.k8s.io/v1",
+		"NetworkPolicy":     "networking.k8s.io/v1",
+		"PodSecurityPolicy": "",
+	},
+	"apps/v1beta1": {
+		"Deployment":  "apps/v1",
+		"StatefulSet": "apps/v1",
+	},
+	"apps/v1beta2": {
+		"Deployment":  "apps/v1",
+		"StatefulSet": "apps/v1",
+		"DaemonSet":   "apps/v1",
+		"ReplicaSet":  "apps/v1",
+	},
+	"networking.k8s.io/v1beta1": {
+		"Ingress":      "networking.k8s.io/v1",
+		"IngressClass": "networking.k8s.io/v1",
+	},
+	"batch/v1beta1": {
+		"CronJob": "batch/v1",
+	},
+	"policy/v1beta1": {
+		"PodDisruptionBudget": "policy/v1",
+		"PodSecurityPolicy":   "",
+	},
+	"rbac.authorization.k8s.io/v1beta1": {
+		"Role":               "rbac.authorization.k8s.io/v1",
+		"ClusterRole":        "rbac.authorization.k8s.io/v1",
+		"RoleBinding":        "rbac.authorization.k8s.io/v1",
+		"ClusterRoleBinding": "rbac.authorization.k8s.io/v1",
+	},
+	"apiextensions.k8s.io/v1beta1": {
+		"CustomResourceDefinition": "apiextensions.k8s.io/v1",
+	},
+	"admissionregistration.k8s.io/v1beta1": {
+		"MutatingWebhookConfiguration":   "admissionregistration.k8s.io/v1",
+		"ValidatingWebhookConfiguration": "admissionregistration.k8s.io/v1",
+	},
+	"autoscaling/v2beta1": {
+		"HorizontalPodAutoscaler": "autoscaling/v2",
+	},
+	"autoscaling/v2beta2": {
+		"HorizontalPodAutoscaler": "autoscaling/v2",
+	},
+	"scheduling.k8s.io/v1beta1": {
+		"PriorityClass": "scheduling.k8s.io/v1",
+	},
+	"storage.k8s.io/v1beta1": {
+		"StorageClass":     "storage.k8s.io/v1",
+		"VolumeAttachment": "storage.k8s.io/v1",
+		"CSIDriver":        "storage.k8s.io/v1",
+		"CSINode":          "storage.k8s.io/v1",
+	},
+}
+
+var (
+	// documentSeparator separates yaml documents in a template
+	documentSeparator = regexp.MustCompile(`(?m)^---`)
+	// apiVersionPattern and kindPattern match top level fields of a document
+	apiVersionPattern = regexp.MustCompile(`(?m)^apiVersion:\s*["']?([^"'\s]+)["']?\s*$`)
+	kindPattern       = regexp.MustCompile(`(?m)^kind:\s*["']?([^"'\s]+)["']?\s*$`)
+)
+
+// deprecatedAPIs fails charts whose templates use deprecated kubernetes api versions
+type deprecatedAPIs struct{}
+
+// newDeprecatedAPIs creates a checker of deprecated api versions. It has no parameter
+func newDeprecatedAPIs(parameters map[string]interface{}) (Checker, error) {
+	return &deprecatedAPIs{}, nil
+}
+
+// Check checks literal api versions and kinds of documents in templates of chart and
+// its subcharts. Templated api versions are not checked
+func (d *deprecatedAPIs) Check(ctx context.Context, c *chart.Chart) ([]Finding, error) {
+	var findings []Finding
+	err := walkCharts(c, "", func(c *chart.Chart, prefix string) error {
+		for _, template := range c.Templates {
+			for _, document := range documentSeparator.Split(string(template.Data), -1) {
+				apiVersion := apiVersionPattern.FindStringSubmatch(document)
+				kind := kindPattern.FindStringSubmatch(document)
+				if apiVersion == nil || kind == nil {
+					continue
+				}
+				replacement, ok := deprecatedAPIVersions[apiVersion[1]][kind[1]]
+				if !ok {
+					continue
+				}
+				message := fmt.Sprintf("%s %s is removed from kubernetes", apiVersion[1], kind[1])
+				if len(replacement) > 0 {
+					message = fmt.Sprintf("%s %s is deprecated, use %s instead", apiVersion[1], kind[1], replacement)
+				}
+				findings = append(findings, Finding{Path: prefix + template.Name, Message: message})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return findings, nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package scan
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// Finding is a problem found by a check
+type Finding struct {
+	// Path is the file which the finding relates to, like "templates/deployment.yaml"
+	Path string `json:"path"`
+	// Message is the description of the problem
+	Message string `json:"message"`
+}
+
+// Checker checks a chart. Checkers other than built-in ones, like invoking an external
+// vulnerability scanner, are registered by Register and enabled in config
+type Checker interface {
+	// Check returns problems found in chart. An error means the chart can't be checked
+	Check(ctx context.Context, chart *chart.Chart) ([]Finding, error)
+}
+
+// CheckerFactory is a factory for creating Checker
+type CheckerFactory interface {
+	// Create creates a new Checker
+	Create(map[string]interface{}) (Checker, error)
+}
+
+// CheckerFactoryFunc creates a Checker by a function
+type CheckerFactoryFunc func(map[string]interface{}) (Checker, error)
+
+// Create creates a new Checker
+func (f CheckerFactoryFunc) Create(parameters map[string]interface{}) (Checker, error) {
+	return f(parameters)
+}
+
+var (
+	// factoriesMu is used for protecting factories
+	factoriesMu sync.RWMutex
+	// factories stores all registered CheckerFactory
+	factories = make(map[string]CheckerFactory)
+)
+
+// Register registers a CheckerFactory
+func Register(name string, factory CheckerFactory) {
+	if factory == nil {
+		panic("Must not provide nil CheckerFactory")
+	}
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	_, registered := factories[name]
+	if registered {
+		panic(fmt.Sprintf("CheckerFactory named %s already registered", name))
+	}
+	factories[name] = factory
+}
+
+// Create creates a new Checker with the given name and parameters.
+func Create(name string, parameters map[string]interface{}) (Checker, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("CheckerFactory not registered: %s", name)
+	}
+	return factory.Create(parameters)
+}
+
+// stringsParameter gets a list of strings from parameters. It returns nil if the
+// parameter doesn't exist
+func stringsParameter(parameters map[string]interface{}, name string) ([]string, error) {
+	value, ok := parameters[name]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("parameter %s should be a list of strings, but got %v", name, value)
+	}
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("parameter %s should be a list of strings, but got %v", name, value)
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// walkCharts calls f with chart and all its subcharts. prefix is the path of a chart
+// in the archive of the top chart, like "" or "charts/redis/"
+func walkCharts(c *chart.Chart, prefix string, f func(c *chart.Chart, prefix string) error) error {
+	if err := f(c, prefix); err != nil {
+		return err
+	}
+	for _, dependency := range c.Dependencies {
+		if dependency.Metadata == nil {
+			continue
+		}
+		if err := walkCharts(dependency, prefix+"charts/"+dependency.Metadata.Name+"/", f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
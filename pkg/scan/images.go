@@ -0,0 +1,141 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package scan
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+func init() {
+	Register("imageAllowlist", CheckerFactoryFunc(newImageAllowlist))
+}
+
+// templateImagePattern matches literal images in templates, like `image: nginx:1.13`
+var templateImagePattern = regexp.MustCompile(`(?m)^[\s-]*image:\s*["']?([^"'\s]+)["']?\s*$`)
+
+// imageAllowlist fails charts which reference images out of allowed prefixes
+type imageAllowlist struct {
+	allowed []string
+}
+
+// newImageAllowlist creates an image allowlist by parameter allowed, which is a list of
+// prefixes of image references with registry, like "docker.io/library/" or "gcr.io/project/"
+func newImageAllowlist(parameters map[string]interface{}) (Checker, error) {
+	allowed, err := stringsParameter(parameters, "allowed")
+	if err != nil {
+		return nil, err
+	}
+	if len(allowed) <= 0 {
+		return nil, fmt.Errorf("parameter allowed is required")
+	}
+	return &imageAllowlist{allowed}, nil
+}
+
+// Check checks images in values and templates of chart and its subcharts. Images in
+// values are values of key image, or repositories of maps under key image
+func (a *imageAllowlist) Check(ctx context.Context, c *chart.Chart) ([]Finding, error) {
+	var findings []Finding
+	check := func(path, image string) {
+		if !a.allows(normalizeImage(image)) {
+			findings = append(findings, Finding{
+				Path:    path,
+				Message: fmt.Sprintf("image %s is not in the allowlist", image),
+			})
+		}
+	}
+	err := walkCharts(c, "", func(c *chart.Chart, prefix string) error {
+		if c.Values != nil && len(c.Values.Raw) > 0 {
+			values, err := chartutil.ReadValues([]byte(c.Values.Raw))
+			if err != nil {
+				return fmt.Errorf("can't parse %svalues.yaml: %v", prefix, err)
+			}
+			for _, image := range valuesImages(map[string]interface{}(values), "") {
+				check(prefix+chartutil.ValuesfileName, image)
+			}
+		}
+		for _, template := range c.Templates {
+			for _, match := range templateImagePattern.FindAllSubmatch(template.Data, -1) {
+				image := string(match[1])
+				// images rendered from values are checked in values
+				if strings.Contains(image, "{{") {
+					continue
+				}
+				check(prefix+template.Name, image)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return findings, nil
+}
+
+// allows returns whether a normalized image reference has an allowed prefix
+func (a *imageAllowlist) allows(image string) bool {
+	for _, prefix := range a.allowed {
+		if strings.HasPrefix(image, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// valuesImages returns images in value whose key is key, in the order of keys
+func valuesImages(value interface{}, key string) []string {
+	var images []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if key == "image" {
+			if repository, ok := v["repository"].(string); ok && len(repository) > 0 {
+				image := repository
+				if registry, ok := v["registry"].(string); ok && len(registry) > 0 {
+					image = registry + "/" + repository
+				}
+				if tag, ok := v["tag"]; ok && tag != nil && fmt.Sprint(tag) != "" {
+					image += ":" + fmt.Sprint(tag)
+				}
+				images = append(images, image)
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			images = append(images, valuesImages(v[k], k)...)
+		}
+	case []interface{}:
+		for _, item := range v {
+			images = append(images, valuesImages(item, key)...)
+		}
+	case string:
+		if key == "image" && len(v) > 0 {
+			images = append(images, v)
+		}
+	}
+	return images
+}
+
+// normalizeImage adds the default registry and repository of docker hub to image, so
+// "nginx" becomes "docker.io/library/nginx"
+func normalizeImage(image string) string {
+	i := strings.Index(image, "/")
+	if i < 0 {
+		return "docker.io/library/" + image
+	}
+	if domain := image[:i]; !strings.ContainsAny(domain, ".:") && domain != "localhost" {
+		return "docker.io/" + image
+	}
+	return image
+}
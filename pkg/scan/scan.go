@@ -0,0 +1,177 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package scan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/trace"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// Config is a config of scanning pushed charts. Scanning is disabled if there is no check
+type Config struct {
+	// Checks run on every pushed version in order
+	Checks []CheckConfig `yaml:"checks"`
+}
+
+// CheckConfig is a config of a registered check
+type CheckConfig struct {
+	// Name is the registered name of checker
+	Name string `yaml:"name"`
+	// Blocking rejects pushes which fail the check. Results of other checks are only reported
+	Blocking bool `yaml:"blocking"`
+	// Spaces are the spaces whose pushes are checked. Pushes to all spaces are checked if
+	// it's empty
+	Spaces []string `yaml:"spaces"`
+	// Parameters are passed to the factory of checker
+	Parameters map[string]interface{} `yaml:"parameters"`
+}
+
+// Result is the result of a check on a version
+type Result struct {
+	// Check is the name of check
+	Check string `json:"check"`
+	// Blocking is whether the push is rejected if the check fails
+	Blocking bool `json:"blocking"`
+	// Passed is whether the check finds no problem
+	Passed bool `json:"passed"`
+	// Findings are problems found by the check
+	Findings []Finding `json:"findings,omitempty"`
+	// Error is the reason why the check can't run. A check with an error fails
+	Error string `json:"error,omitempty"`
+}
+
+// Report is the result of scanning a version
+type Report struct {
+	// Passed is whether all checks passed
+	Passed bool `json:"passed"`
+	// Timestamp is the time when the version was scanned
+	Timestamp time.Time `json:"timestamp"`
+	// Results are results of checks in config order
+	Results []Result `json:"results"`
+}
+
+// Blocked returns the number of failed blocking checks
+func (r *Report) Blocked() int {
+	count := 0
+	for _, result := range r.Results {
+		if result.Blocking && !result.Passed {
+			count++
+		}
+	}
+	return count
+}
+
+// check is a configured checker
+type check struct {
+	config  CheckConfig
+	checker Checker
+}
+
+// runsIn returns whether the check runs on pushes to space
+func (c *check) runsIn(space string) bool {
+	if len(c.config.Spaces) <= 0 {
+		return true
+	}
+	for _, s := range c.config.Spaces {
+		if s == space {
+			return true
+		}
+	}
+	return false
+}
+
+// checks are created by Initialize
+var checks []*check
+
+// Initialize creates checkers in config
+func Initialize(config Config) error {
+	result := make([]*check, 0, len(config.Checks))
+	for _, c := range config.Checks {
+		checker, err := Create(c.Name, c.Parameters)
+		if err != nil {
+			return fmt.Errorf("can't create check %s: %v", c.Name, err)
+		}
+		result = append(result, &check{c, checker})
+	}
+	checks = result
+	if len(checks) > 0 {
+		log.Infof("Scanning pushed charts with %d checks", len(checks))
+	}
+	return nil
+}
+
+// Enabled returns whether any check is configured
+func Enabled() bool {
+	return len(checks) > 0
+}
+
+// Run runs checks of space on chart and returns the report. It returns nil if no
+// check runs on pushes to space
+func Run(ctx context.Context, space string, c *chart.Chart) *Report {
+	var report *Report
+	var span *trace.Span
+	defer func() { span.End() }()
+	for _, check := range checks {
+		if !check.runsIn(space) {
+			continue
+		}
+		if report == nil {
+			report = &Report{Passed: true, Timestamp: time.Now().UTC()}
+			ctx, span = trace.StartSpan(ctx, "scan")
+			span.SetAttribute("registry.space", space)
+		}
+		result := Result{Check: check.config.Name, Blocking: check.config.Blocking}
+		findings, err := check.checker.Check(ctx, c)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Findings = findings
+			result.Passed = len(findings) <= 0
+		}
+		if !result.Passed {
+			report.Passed = false
+		}
+		report.Results = append(report.Results, result)
+	}
+	if report != nil {
+		span.SetAttribute("scan.passed", report.Passed)
+	}
+	return report
+}
+
+// Check runs checks of space on a chart and returns the report. If any blocking check
+// fails, it returns ErrorScanFailed with the full report as details. The report is nil
+// if no check runs in the space
+func Check(ctx context.Context, space string, c *chart.Chart) (*Report, error) {
+	report := Run(ctx, space, c)
+	if report == nil {
+		return nil, nil
+	}
+	if count := report.Blocked(); count > 0 {
+		name := fmt.Sprintf("%s/%s", c.Metadata.Name, c.Metadata.Version)
+		return nil, errors.ErrorScanFailed.Format(name, count).WithDetails(report)
+	}
+	return report, nil
+}
+
+// PutReport stores the scan report of a version. report can be nil
+func PutReport(ctx context.Context, version storage.Version, report *Report) error {
+	if report == nil {
+		return nil
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return errors.ErrorInternalUnknown.Format(err)
+	}
+	return version.PutScanReport(ctx, data)
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package scan
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// newTestChart creates a chart with values and templates, and a subchart redis
+func newTestChart() *chart.Chart {
+	return &chart.Chart{
+		Metadata: &chart.Metadata{Name: "app", Version: "1.0.0"},
+		Values: &chart.Config{Raw: `
+image:
+  repository: gcr.io/project/app
+  tag: 1.0
+sidecars:
+- name: proxy
+  image: envoyproxy/envoy:v1.5
+`},
+		Templates: []*chart.Template{
+			{Name: "templates/deployment.yaml", Data: []byte(`apiVersion: extensions/v1beta1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: "{{ .Values.image.repository }}:{{ .Values.image.tag }}"
+      - name: busybox
+        image: busybox
+---
+apiVersion: {{ template "ingress.apiVersion" . }}
+kind: Ingress
+---
+apiVersion: v1
+kind: Service
+`)},
+		},
+		Dependencies: []*chart.Chart{
+			{
+				Metadata: &chart.Metadata{Name: "redis", Version: "1.0.0"},
+				Values:   &chart.Config{Raw: "image: quay.io/bitnami/redis:4.0\n"},
+				Templates: []*chart.Template{
+					{Name: "templates/pdb.yaml", Data: []byte("apiVersion: policy/v1beta1\nkind: PodSecurityPolicy\n")},
+				},
+			},
+		},
+	}
+}
+
+func TestImageAllowlist(t *testing.T) {
+	if _, err := Create("imageAllowlist", nil); err == nil {
+		t.Error("expected an error without allowed prefixes")
+	}
+	checker, err := Create("imageAllowlist", map[string]interface{}{
+		"allowed": []interface{}{"gcr.io/project/", "docker.io/library/"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := checker.Check(context.Background(), newTestChart())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Finding{
+		{Path: "values.yaml", Message: "image envoyproxy/envoy:v1.5 is not in the allowlist"},
+		{Path: "charts/redis/values.yaml", Message: "image quay.io/bitnami/redis:4.0 is not in the allowlist"},
+	}
+	if !reflect.DeepEqual(findings, expected) {
+		t.Errorf("expected findings %v, but got %v", expected, findings)
+	}
+}
+
+func TestDeprecatedAPIs(t *testing.T) {
+	checker, err := Create("deprecatedAPIs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings, err := checker.Check(context.Background(), newTestChart())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Finding{
+		{Path: "templates/deployment.yaml", Message: "extensions/v1beta1 Deployment is deprecated, use apps/v1 instead"},
+		{Path: "charts/redis/templates/pdb.yaml", Message: "policy/v1beta1 PodSecurityPolicy is removed from kubernetes"},
+	}
+	if !reflect.DeepEqual(findings, expected) {
+		t.Errorf("expected findings %v, but got %v", expected, findings)
+	}
+}
+
+func TestRun(t *testing.T) {
+	defer Initialize(Config{})
+	if err := Initialize(Config{Checks: []CheckConfig{{Name: "unknown"}}}); err == nil {
+		t.Error("expected an error for an unknown check")
+	}
+	err := Initialize(Config{Checks: []CheckConfig{
+		{Name: "deprecatedAPIs"},
+		{Name: "imageAllowlist", Blocking: true, Spaces: []string{"production"},
+			Parameters: map[string]interface{}{"allowed": []interface{}{"gcr.io/"}}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := Run(context.Background(), "library", newTestChart())
+	if report == nil || report.Passed || len(report.Results) != 1 || report.Blocked() != 0 {
+		t.Fatalf("expected only the non-blocking check to fail in library, but got %+v", report)
+	}
+	report = Run(context.Background(), "production", newTestChart())
+	if report == nil || len(report.Results) != 2 || report.Blocked() != 1 {
+		t.Fatalf("expected the blocking check to fail in production, but got %+v", report)
+	}
+
+	if err = Initialize(Config{Checks: []CheckConfig{{Name: "deprecatedAPIs", Spaces: []string{"production"}}}}); err != nil {
+		t.Fatal(err)
+	}
+	if report = Run(context.Background(), "library", newTestChart()); report != nil {
+		t.Errorf("expected no report if no check runs in the space, but got %+v", report)
+	}
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package search
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// Config is a config of the search index
+type Config struct {
+	// Expiration is the period after which metadata of a space is reloaded from storage,
+	// like "5m". Metadata never expires if it's empty. It should be set if several
+	// registries share a storage backend, because a registry only knows changes made by itself
+	Expiration string `yaml:"expiration"`
+}
+
+// spaceIndex is the cached metadata of a space
+type spaceIndex struct {
+	// loading is held while metadata of the space is loaded, so concurrent requests
+	// don't load the same metadata
+	loading sync.Mutex
+	// generation increases when the space is changed
+	generation int
+	// charts are metadata of versions by chart name. It's nil if the space is not loaded
+	charts map[string][]*storage.Metadata
+	// stale are charts which are changed after they are loaded or while loading. Values
+	// increase every time the chart is changed
+	stale map[string]int
+	// metadata are metadata of all charts in the order of chart names. It's nil if
+	// any chart is stale
+	metadata []*storage.Metadata
+	loaded   time.Time
+}
+
+// Index caches version metadata of spaces, so searching and listing don't read every
+// version from storage. Metadata of a space is loaded when it's first requested. A changed
+// chart is reloaded at the next request, and a changed space is reloaded entirely
+type Index struct {
+	lock       sync.Mutex
+	expiration time.Duration
+	spaces     map[string]*spaceIndex
+}
+
+// NewIndex creates an empty index. Metadata expires after expiration if it's positive
+func NewIndex(expiration time.Duration) *Index {
+	return &Index{
+		expiration: expiration,
+		spaces:     make(map[string]*spaceIndex),
+	}
+}
+
+// fresh returns metadata of index if it can be used without loading. It should be
+// called with i.lock held
+func (i *Index) fresh(index *spaceIndex) ([]*storage.Metadata, bool) {
+	if index.metadata == nil || (i.expiration > 0 && time.Since(index.loaded) >= i.expiration) {
+		return nil, false
+	}
+	return index.metadata, true
+}
+
+// Metadata returns version metadata of a space. Versions of a chart are sorted
+// in ascending order as storage.Space.VersionMetadata. The returned metadata are
+// shared by all callers and must not be modified
+func (i *Index) Metadata(ctx context.Context, spaceName string) ([]*storage.Metadata, error) {
+	i.lock.Lock()
+	index, ok := i.spaces[spaceName]
+	if !ok {
+		index = &spaceIndex{}
+		i.spaces[spaceName] = index
+	}
+	if metadata, ok := i.fresh(index); ok {
+		i.lock.Unlock()
+		return metadata, nil
+	}
+	i.lock.Unlock()
+
+	index.loading.Lock()
+	defer index.loading.Unlock()
+	i.lock.Lock()
+	// another request may have loaded the space
+	if metadata, ok := i.fresh(index); ok {
+		i.lock.Unlock()
+		return metadata, nil
+	}
+	generation := index.generation
+	// charts in stale are reloaded, and charts changed while loading stay stale
+	stale := make(map[string]int, len(index.stale))
+	for name, count := range index.stale {
+		stale[name] = count
+	}
+	loaded := index.charts
+	if i.expiration > 0 && time.Since(index.loaded) >= i.expiration {
+		loaded = nil
+	}
+	i.lock.Unlock()
+
+	names, charts, err := i.load(ctx, spaceName, loaded, stale)
+	if err != nil {
+		return nil, err
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	// the space may be changed while loading, and the loaded metadata may be stale
+	if index.generation != generation {
+		return flatten(names, charts), nil
+	}
+	index.charts = charts
+	if loaded == nil {
+		index.loaded = time.Now()
+	}
+	for name, count := range stale {
+		if index.stale[name] == count {
+			delete(index.stale, name)
+		}
+	}
+	metadata := flatten(names, charts)
+	if len(index.stale) <= 0 {
+		index.metadata = metadata
+	}
+	return metadata, nil
+}
+
+// load reads metadata of charts in a space. Metadata of charts in loaded are reused unless
+// the charts are stale, and other charts are read from storage. It returns names of all
+// charts and their metadata
+func (i *Index) load(ctx context.Context, spaceName string, loaded map[string][]*storage.Metadata,
+	stale map[string]int) ([]string, map[string][]*storage.Metadata, error) {
+	space, err := common.GetSpace(ctx, spaceName)
+	if err != nil {
+		return nil, nil, err
+	}
+	names, err := space.List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	charts := make(map[string][]*storage.Metadata, len(names))
+	for _, name := range names {
+		if metadata, ok := loaded[name]; ok {
+			if _, ok := stale[name]; !ok {
+				charts[name] = metadata
+				continue
+			}
+		}
+		chart, err := space.Chart(ctx, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		metadata, err := chart.VersionMetadata(ctx)
+		if err == nil {
+			err = setLabels(ctx, chart, metadata)
+		}
+		if errors.ErrorContentNotFound.Equal(err) {
+			// the chart is deleted while loading
+			metadata = nil
+		} else if err != nil {
+			return nil, nil, err
+		}
+		charts[name] = metadata
+	}
+	return names, charts, nil
+}
+
+// setLabels sets labels of chart and versions in metadata of the chart
+func setLabels(ctx context.Context, chart storage.Chart, metadata []*storage.Metadata) error {
+	attributes, err := chart.Attributes(ctx)
+	if err != nil {
+		return err
+	}
+	for _, md := range metadata {
+		version, err := chart.Version(ctx, md.Version)
+		if err != nil {
+			return err
+		}
+		versionAttributes, err := version.Attributes(ctx)
+		if err != nil {
+			return err
+		}
+		md.Labels = storage.MergeLabels(attributes.Labels, versionAttributes.Labels)
+	}
+	return nil
+}
+
+// flatten joins metadata of charts in the order of names
+func flatten(names []string, charts map[string][]*storage.Metadata) []*storage.Metadata {
+	count := 0
+	for _, name := range names {
+		count += len(charts[name])
+	}
+	metadata := make([]*storage.Metadata, 0, count)
+	for _, name := range names {
+		metadata = append(metadata, charts[name]...)
+	}
+	return metadata
+}
+
+// ChartMetadata returns version metadata of a chart in ascending order. It returns nil
+// if the chart has no version. The returned metadata must not be modified
+func (i *Index) ChartMetadata(ctx context.Context, spaceName, chartName string) ([]*storage.Metadata, error) {
+	metadata, err := i.Metadata(ctx, spaceName)
+	if err != nil {
+		return nil, err
+	}
+	var result []*storage.Metadata
+	for _, md := range metadata {
+		if md.Name == chartName {
+			result = append(result, md)
+		}
+	}
+	return result, nil
+}
+
+// Invalidate drops cached metadata of a space. It should be called after versions of
+// several charts in the space are created, updated or deleted
+func (i *Index) Invalidate(spaceName string) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if index, ok := i.spaces[spaceName]; ok {
+		index.generation++
+		index.charts = nil
+		index.stale = nil
+		index.metadata = nil
+	}
+}
+
+// InvalidateChart marks cached metadata of a chart stale, so only the chart is reloaded.
+// It should be called after any version of the chart is created, updated or deleted
+func (i *Index) InvalidateChart(spaceName, chartName string) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if index, ok := i.spaces[spaceName]; ok {
+		if index.stale == nil {
+			index.stale = make(map[string]int)
+		}
+		index.stale[chartName]++
+		index.metadata = nil
+	}
+}
+
+// globalIndex is the index used by registry
+var globalIndex = NewIndex(0)
+
+// Initialize initializes the global index by config
+func Initialize(config Config) error {
+	var expiration time.Duration
+	if len(config.Expiration) > 0 {
+		d, err := time.ParseDuration(config.Expiration)
+		if err != nil {
+			return err
+		}
+		if d <= 0 {
+			return fmt.Errorf("search expiration should be positive, but got %s", config.Expiration)
+		}
+		expiration = d
+	}
+	globalIndex = NewIndex(expiration)
+	return nil
+}
+
+// Metadata returns version metadata of a space in the global index. The returned
+// metadata must not be modified
+func Metadata(ctx context.Context, spaceName string) ([]*storage.Metadata, error) {
+	return globalIndex.Metadata(ctx, spaceName)
+}
+
+// ChartMetadata returns version metadata of a chart in the global index. The returned
+// metadata must not be modified
+func ChartMetadata(ctx context.Context, spaceName, chartName string) ([]*storage.Metadata, error) {
+	return globalIndex.ChartMetadata(ctx, spaceName, chartName)
+}
+
+// Invalidate drops cached metadata of a space in the global index
+func Invalidate(spaceName string) {
+	globalIndex.Invalidate(spaceName)
+}
+
+// InvalidateChart marks cached metadata of a chart stale in the global index
+func InvalidateChart(spaceName, chartName string) {
+	globalIndex.InvalidateChart(spaceName, chartName)
+}
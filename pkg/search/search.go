@@ -0,0 +1,191 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// Query describes conditions of searching. Empty fields match any metadata
+type Query struct {
+	// Text is a list of terms separated by spaces. Every term should be a substring
+	// of chart name, description or maintainer, or one of the chart keywords
+	Text string
+	// Keyword should be exactly one of the chart keywords. It's case sensitive
+	Keyword string
+	// Maintainer is a substring of name or email of a chart maintainer
+	Maintainer string
+	// Selector should match labels of versions. An empty selector matches any metadata
+	Selector storage.Selector
+	// Latest only keeps the latest matched version of every chart. It's resolved like
+	// storage.LatestVersion, so pre-release versions are skipped unless Prerelease is true
+	Latest bool
+	// Prerelease makes pre-release versions the latest ones if they are the highest
+	Prerelease bool
+}
+
+// candidate is a matched version and its relevance
+type candidate struct {
+	metadata *storage.Metadata
+	score    int
+}
+
+// weights of matched fields for relevance ordering
+const (
+	weightName        = 8
+	weightNamePrefix  = 4
+	weightNameContent = 2
+	weightKeyword     = 2
+	weightDescription = 1
+	weightMaintainer  = 1
+)
+
+// Search searches metadata in spaces by the global index. Results are ordered by
+// relevance, and results with the same relevance keep the order of spaces and versions
+func Search(ctx context.Context, spaces []string, query Query) ([]*storage.Metadata, error) {
+	return globalIndex.Search(ctx, spaces, query)
+}
+
+// Search searches metadata in spaces
+func (i *Index) Search(ctx context.Context, spaces []string, query Query) ([]*storage.Metadata, error) {
+	terms := strings.Fields(strings.ToLower(query.Text))
+	maintainer := strings.ToLower(query.Maintainer)
+	result := make([]*storage.Metadata, 0)
+	scores := make([]int, 0)
+	for _, spaceName := range spaces {
+		metadata, err := i.Metadata(ctx, spaceName)
+		if err != nil {
+			return nil, err
+		}
+		// index of the latest matched version of every chart in result, and matched versions
+		// of every chart in ascending order
+		latestIndex := make(map[string]int)
+		candidates := make(map[string][]candidate)
+		for _, md := range metadata {
+			s, ok := score(md, terms)
+			if !ok || !matchKeyword(md, query.Keyword) || !matchMaintainer(md, maintainer) ||
+				!query.Selector.Matches(md.Labels) {
+				continue
+			}
+			if query.Latest {
+				candidates[md.Name] = append(candidates[md.Name], candidate{md, s})
+				if _, ok := latestIndex[md.Name]; ok {
+					continue
+				}
+				latestIndex[md.Name] = len(result)
+			}
+			result = append(result, md)
+			scores = append(scores, s)
+		}
+		for name, index := range latestIndex {
+			versions := make([]string, 0, len(candidates[name]))
+			for _, c := range candidates[name] {
+				versions = append(versions, c.metadata.Version)
+			}
+			latest, _ := storage.LatestVersion(versions, query.Prerelease)
+			for _, c := range candidates[name] {
+				if c.metadata.Version == latest {
+					result[index] = c.metadata
+					scores[index] = c.score
+					break
+				}
+			}
+		}
+	}
+	sort.Stable(&byScore{result, scores})
+	return result, nil
+}
+
+// score returns the relevance of metadata to lowercase terms, which are not empty. It returns false if
+// any term is not matched
+func score(md *storage.Metadata, terms []string) (int, bool) {
+	name := strings.ToLower(md.Name)
+	description := strings.ToLower(md.Description)
+	total := 0
+	for _, term := range terms {
+		s := 0
+		switch {
+		case name == term:
+			s += weightName
+		case strings.HasPrefix(name, term):
+			s += weightNamePrefix
+		case strings.Contains(name, term):
+			s += weightNameContent
+		}
+		if matchKeywordTerm(md, term) {
+			s += weightKeyword
+		}
+		if strings.Contains(description, term) {
+			s += weightDescription
+		}
+		if matchMaintainer(md, term) {
+			s += weightMaintainer
+		}
+		if s <= 0 {
+			return 0, false
+		}
+		total += s
+	}
+	return total, true
+}
+
+// matchKeyword checks whether keyword is exactly one of the chart keywords. An empty keyword matches
+// any metadata
+func matchKeyword(md *storage.Metadata, keyword string) bool {
+	if len(keyword) <= 0 {
+		return true
+	}
+	for _, kw := range md.Keywords {
+		if kw == keyword {
+			return true
+		}
+	}
+	return false
+}
+
+// matchKeywordTerm checks whether lowercase term is one of the chart keywords regardless of case
+func matchKeywordTerm(md *storage.Metadata, term string) bool {
+	for _, kw := range md.Keywords {
+		if strings.ToLower(kw) == term {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMaintainer checks whether lowercase maintainer is a substring of name or email of a
+// chart maintainer. An empty maintainer matches any metadata
+func matchMaintainer(md *storage.Metadata, maintainer string) bool {
+	if len(maintainer) <= 0 {
+		return true
+	}
+	for _, m := range md.Maintainers {
+		if m == nil {
+			continue
+		}
+		if strings.Contains(strings.ToLower(m.Name), maintainer) ||
+			strings.Contains(strings.ToLower(m.Email), maintainer) {
+			return true
+		}
+	}
+	return false
+}
+
+// byScore sorts metadata by scores in descending order
+type byScore struct {
+	metadata []*storage.Metadata
+	scores   []int
+}
+
+func (s *byScore) Len() int           { return len(s.metadata) }
+func (s *byScore) Less(i, j int) bool { return s.scores[i] > s.scores[j] }
+func (s *byScore) Swap(i, j int) {
+	s.metadata[i], s.metadata[j] = s.metadata[j], s.metadata[i]
+	s.scores[i], s.scores[j] = s.scores[j], s.scores[i]
+}
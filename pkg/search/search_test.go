@@ -0,0 +1,219 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+
+	_ "github.com/caicloud/helm-registry/pkg/storage/simple"
+)
+
+// newMetadata creates metadata of a version
+func newMetadata(name, version, description string, keywords []string, maintainers ...string) *storage.Metadata {
+	md := &storage.Metadata{
+		Metadata: chart.Metadata{
+			Name:        name,
+			Version:     version,
+			Description: description,
+			Keywords:    keywords,
+		},
+	}
+	for _, m := range maintainers {
+		md.Maintainers = append(md.Maintainers, &chart.Maintainer{Name: m, Email: m + "@example.com"})
+	}
+	return md
+}
+
+// newLoadedIndex creates an index with loaded metadata of a space named "library"
+func newLoadedIndex(metadata ...*storage.Metadata) *Index {
+	index := NewIndex(0)
+	index.spaces["library"] = &spaceIndex{charts: map[string][]*storage.Metadata{}, metadata: metadata, loaded: time.Now()}
+	return index
+}
+
+func TestSearch(t *testing.T) {
+	redis := newMetadata("redis", "1.0.0", "Key value store", []string{"cache"}, "bob")
+	redis.Labels = map[string]string{"team": "payments"}
+	index := newLoadedIndex(
+		newMetadata("mysql-exporter", "1.0.0", "Exports metrics of mysql", nil, "bob"),
+		newMetadata("mariadb", "1.0.0", "A mysql compatible database", []string{"database"}, "alice"),
+		newMetadata("mysql", "1.0.0", "Fast database", []string{"database"}, "alice"),
+		newMetadata("mysql", "1.1.0", "Fast database", []string{"database"}, "alice"),
+		redis,
+	)
+	payments, _ := storage.ParseSelector("team=payments")
+	cases := []struct {
+		name     string
+		query    Query
+		expected []string
+	}{
+		{"empty query", Query{}, []string{"mysql-exporter-1.0.0", "mariadb-1.0.0", "mysql-1.0.0", "mysql-1.1.0", "redis-1.0.0"}},
+		{"by relevance", Query{Text: "MySQL"}, []string{"mysql-1.0.0", "mysql-1.1.0", "mysql-exporter-1.0.0", "mariadb-1.0.0"}},
+		{"latest", Query{Text: "mysql", Latest: true}, []string{"mysql-1.1.0", "mysql-exporter-1.0.0", "mariadb-1.0.0"}},
+		{"all terms", Query{Text: "mysql database"}, []string{"mysql-1.0.0", "mysql-1.1.0", "mariadb-1.0.0"}},
+		{"keyword", Query{Keyword: "cache"}, []string{"redis-1.0.0"}},
+		{"case sensitive keyword", Query{Keyword: "Cache"}, []string{}},
+		{"keyword in text", Query{Text: "CACHE"}, []string{"redis-1.0.0"}},
+		{"maintainer", Query{Text: "mysql", Maintainer: "BOB@"}, []string{"mysql-exporter-1.0.0"}},
+		{"no match", Query{Text: "postgres"}, []string{}},
+		{"label", Query{Selector: payments}, []string{"redis-1.0.0"}},
+	}
+	for _, c := range cases {
+		metadata, err := index.Search(context.Background(), []string{"library"}, c.query)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		result := make([]string, 0, len(metadata))
+		for _, md := range metadata {
+			result = append(result, md.Name+"-"+md.Version)
+		}
+		if !reflect.DeepEqual(result, c.expected) {
+			t.Errorf("%s: expected %v, but got %v", c.name, c.expected, result)
+		}
+	}
+}
+
+func TestSearchLatestPrerelease(t *testing.T) {
+	index := newLoadedIndex(
+		newMetadata("mysql", "1.0.0", "Fast database", nil),
+		newMetadata("mysql", "1.1.0", "Fast database", nil),
+		newMetadata("mysql", "2.0.0-rc.1", "Fast database", nil),
+		newMetadata("redis", "1.0.0-beta.1", "Key value store", nil),
+	)
+	cases := []struct {
+		name     string
+		query    Query
+		expected []string
+	}{
+		{"skip pre-release", Query{Latest: true}, []string{"mysql-1.1.0", "redis-1.0.0-beta.1"}},
+		{"include pre-release", Query{Latest: true, Prerelease: true}, []string{"mysql-2.0.0-rc.1", "redis-1.0.0-beta.1"}},
+	}
+	for _, c := range cases {
+		metadata, err := index.Search(context.Background(), []string{"library"}, c.query)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		result := make([]string, 0, len(metadata))
+		for _, md := range metadata {
+			result = append(result, md.Name+"-"+md.Version)
+		}
+		if !reflect.DeepEqual(result, c.expected) {
+			t.Errorf("%s: expected %v, but got %v", c.name, c.expected, result)
+		}
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	index := newLoadedIndex(newMetadata("mysql", "1.0.0", "", nil))
+	index.Invalidate("library")
+	index.Invalidate("unknown")
+	if index.spaces["library"].metadata != nil || index.spaces["library"].generation != 1 {
+		t.Errorf("expected metadata of library to be dropped")
+	}
+	if _, ok := index.spaces["unknown"]; ok {
+		t.Errorf("expected no index of unknown space")
+	}
+}
+
+func TestInvalidateChart(t *testing.T) {
+	manager, cleanup := storagetest.UseSpaceManager(t)
+	defer cleanup()
+	ctx := context.Background()
+	space, err := manager.Create(ctx, "library")
+	if err != nil {
+		t.Fatal(err)
+	}
+	put := func(name, number string) {
+		chart, err := space.Chart(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		version, err := chart.Version(ctx, number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = version.PutContent(ctx, storagetest.NewArchive(t, name, number)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index := NewIndex(0)
+	check := func(expected ...string) {
+		metadata, err := index.Metadata(ctx, "library")
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := make([]string, 0, len(metadata))
+		for _, md := range metadata {
+			result = append(result, md.Name+"-"+md.Version)
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("expected %v, but got %v", expected, result)
+		}
+	}
+
+	put("mysql", "1.0.0")
+	put("redis", "1.0.0")
+	check("mysql-1.0.0", "redis-1.0.0")
+	// changes are not seen until they are invalidated
+	put("mysql", "1.1.0")
+	put("redis", "2.0.0")
+	check("mysql-1.0.0", "redis-1.0.0")
+	// only the invalidated chart is reloaded
+	index.InvalidateChart("library", "mysql")
+	check("mysql-1.0.0", "mysql-1.1.0", "redis-1.0.0")
+	put("mariadb", "1.0.0")
+	index.InvalidateChart("library", "mariadb")
+	check("mariadb-1.0.0", "mysql-1.0.0", "mysql-1.1.0", "redis-1.0.0")
+	index.Invalidate("library")
+	check("mariadb-1.0.0", "mysql-1.0.0", "mysql-1.1.0", "redis-1.0.0", "redis-2.0.0")
+
+	if err = space.Delete(ctx, "mariadb"); err != nil {
+		t.Fatal(err)
+	}
+	index.InvalidateChart("library", "mariadb")
+	check("mysql-1.0.0", "mysql-1.1.0", "redis-1.0.0", "redis-2.0.0")
+
+	// labels of a version override labels of its chart
+	redis, _ := space.Chart(ctx, "redis")
+	_, err = redis.UpdateAttributes(ctx, func(a *storage.Attributes) error {
+		a.Labels = map[string]string{"team": "cache", "stage": "production"}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, _ := redis.Version(ctx, "2.0.0")
+	_, err = version.UpdateAttributes(ctx, func(a *storage.Attributes) error {
+		a.Labels = map[string]string{"stage": "testing"}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.InvalidateChart("library", "redis")
+	metadata, err := index.ChartMetadata(ctx, "library", "redis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metadata) != 2 || metadata[1].Version != "2.0.0" {
+		t.Fatalf("expected 2 versions of redis, but got %v", metadata)
+	}
+	expected := []map[string]string{
+		{"team": "cache", "stage": "production"},
+		{"team": "cache", "stage": "testing"},
+	}
+	for i, md := range metadata {
+		if !reflect.DeepEqual(md.Labels, expected[i]) {
+			t.Errorf("expected labels of redis %s to be %v, but got %v", md.Version, expected[i], md.Labels)
+		}
+	}
+}
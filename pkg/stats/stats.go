@@ -0,0 +1,109 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package stats
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/errors"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// DefaultFlushInterval is the default period between two flushes of download counts
+const DefaultFlushInterval = "10s"
+
+// Config is a config of download statistics
+type Config struct {
+	// FlushInterval is the period between two flushes of download counts to storage, like "10s"
+	FlushInterval string `yaml:"flushInterval"`
+}
+
+// key identifies a version
+type key struct {
+	space, chart, version string
+}
+
+var (
+	// pendingMu is used for protecting pending
+	pendingMu sync.Mutex
+	// pending stores download counts which have not been flushed to storage
+	pending = make(map[key]int64)
+)
+
+// Start flushes download counts to storage every interval in background
+func Start(config Config) error {
+	if len(config.FlushInterval) <= 0 {
+		config.FlushInterval = DefaultFlushInterval
+	}
+	interval, err := time.ParseDuration(config.FlushInterval)
+	if err != nil {
+		return err
+	}
+	if interval <= 0 {
+		return fmt.Errorf("stats flush interval should be positive, but got %s", config.FlushInterval)
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			Flush(context.Background())
+		}
+	}()
+	log.Infof("Flushing download statistics every %s", interval)
+	return nil
+}
+
+// CountDownload counts a download of a version. Counts are kept in memory and flushed
+// to storage later, so downloads are never blocked by locking versions
+func CountDownload(space, chart, version string) {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	pending[key{space, chart, version}]++
+}
+
+// Flush adds pending download counts to storage. Counts which can't be flushed are
+// kept for the next flush, except counts of versions which don't exist anymore
+func Flush(ctx context.Context) {
+	pendingMu.Lock()
+	counts := pending
+	pending = make(map[key]int64)
+	pendingMu.Unlock()
+	for k, n := range counts {
+		err := addDownloads(ctx, k, n)
+		if err == nil || errors.ErrorContentNotFound.Equal(err) {
+			continue
+		}
+		log.Warnf("Failed to flush downloads of %s/%s/%s: %v", k.space, k.chart, k.version, err)
+		pendingMu.Lock()
+		pending[k] += n
+		pendingMu.Unlock()
+	}
+}
+
+// addDownloads adds n downloads to a version in storage
+func addDownloads(ctx context.Context, k key, n int64) error {
+	version, err := common.GetVersion(ctx, k.space, k.chart, k.version)
+	if err != nil {
+		return err
+	}
+	return version.AddDownloads(ctx, n)
+}
+
+// Downloads returns the number of downloads of a version, including counts which
+// have not been flushed
+func Downloads(ctx context.Context, space, chart string, version storage.Version) (int64, error) {
+	downloads, err := version.Downloads(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+	return downloads + pending[key{space, chart, version.Number()}], nil
+}
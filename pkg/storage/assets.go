@@ -0,0 +1,74 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package storage
+
+import (
+	"net/url"
+	"strings"
+
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// ReadmeName is the name of the readme file in a chart. It's matched case-insensitively
+const ReadmeName = "README.md"
+
+// ExtractReadme returns the README.md in the top directory of chart. It returns nil if
+// the chart has no readme
+func ExtractReadme(chrt *chart.Chart) []byte {
+	return chartFile(chrt, ReadmeName)
+}
+
+// ValuesSchemaName is the name of the values schema file in a chart
+const ValuesSchemaName = "values.schema.json"
+
+// ExtractValuesSchema returns the values.schema.json in the top directory of chart. It
+// returns nil if the chart has no values schema
+func ExtractValuesSchema(chrt *chart.Chart) []byte {
+	for _, file := range chrt.Files {
+		if file.TypeUrl == ValuesSchemaName {
+			return file.Value
+		}
+	}
+	return nil
+}
+
+// ExtractIcon returns the icon file in chart which is referred by the icon in metadata.
+// It returns nil if the chart has no icon file, or the icon is an external url
+func ExtractIcon(chrt *chart.Chart) []byte {
+	if chrt.Metadata == nil {
+		return nil
+	}
+	filename, ok := IconFile(chrt.Metadata.Icon)
+	if !ok {
+		return nil
+	}
+	return chartFile(chrt, filename)
+}
+
+// IconFile returns the name of the file in chart which is referred by icon, like
+// "icon.png" of "file://./icon.png". It returns false if icon is empty or an external url
+func IconFile(icon string) (string, bool) {
+	if len(icon) <= 0 || ExternalIcon(icon) {
+		return "", false
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(icon, "file://"), "./"), true
+}
+
+// ExternalIcon returns whether icon is an http or https url
+func ExternalIcon(icon string) bool {
+	u, err := url.Parse(icon)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// chartFile finds a file in the top directory of chrt by case-insensitive name.
+// It returns nil if the file does not exist
+func chartFile(chrt *chart.Chart, name string) []byte {
+	for _, file := range chrt.Files {
+		if strings.EqualFold(file.TypeUrl, name) {
+			return file.Value
+		}
+	}
+	return nil
+}
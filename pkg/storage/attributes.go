@@ -0,0 +1,167 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Attributes are key/value pairs attached to a chart or a version. They're stored
+// outside of chart archives
+type Attributes struct {
+	// Labels can be matched by label selectors in listings and searching
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are arbitrary data which can't be matched
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+var (
+	// attributeKeyPattern matches keys of labels and annotations, like "team" or "example.com/stage"
+	attributeKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9]([-._/a-zA-Z0-9]*[a-zA-Z0-9])?$`)
+	// labelValuePattern matches values of labels, which may be empty
+	labelValuePattern = regexp.MustCompile(`^([a-zA-Z0-9]([-._a-zA-Z0-9]*[a-zA-Z0-9])?)?$`)
+)
+
+// limits of attributes
+const (
+	maxAttributeKeyLength = 128
+	maxLabelValueLength   = 63
+	maxAnnotationsSize    = 64 * 1024
+)
+
+// Validate checks whether keys and values of attributes are valid
+func (a *Attributes) Validate() error {
+	for key, value := range a.Labels {
+		if err := validateAttributeKey(key); err != nil {
+			return err
+		}
+		if len(value) > maxLabelValueLength || !labelValuePattern.MatchString(value) {
+			return fmt.Errorf("value of label %s should be at most %d alphanumeric characters, '-', '_' or '.', but got %q",
+				key, maxLabelValueLength, value)
+		}
+	}
+	size := 0
+	for key, value := range a.Annotations {
+		if err := validateAttributeKey(key); err != nil {
+			return err
+		}
+		size += len(key) + len(value)
+	}
+	if size > maxAnnotationsSize {
+		return fmt.Errorf("annotations should be at most %d bytes, but got %d bytes", maxAnnotationsSize, size)
+	}
+	return nil
+}
+
+// validateAttributeKey checks whether key is a valid key of labels and annotations
+func validateAttributeKey(key string) error {
+	if len(key) > maxAttributeKeyLength || !attributeKeyPattern.MatchString(key) {
+		return fmt.Errorf("key should be at most %d alphanumeric characters, '-', '_', '.' or '/', but got %q",
+			maxAttributeKeyLength, key)
+	}
+	return nil
+}
+
+// MergeLabels returns labels of a chart overridden by labels of a version. It returns
+// nil if both are empty
+func MergeLabels(chart, version map[string]string) map[string]string {
+	if len(chart) <= 0 && len(version) <= 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(chart)+len(version))
+	for key, value := range chart {
+		labels[key] = value
+	}
+	for key, value := range version {
+		labels[key] = value
+	}
+	return labels
+}
+
+// selector operators
+const (
+	selectorEquals    = "="
+	selectorNotEquals = "!="
+	selectorExists    = "exists"
+	selectorNotExists = "!"
+)
+
+// requirement is a condition on a label
+type requirement struct {
+	key      string
+	operator string
+	value    string
+}
+
+// Selector selects labels by requirements which should all be satisfied. An empty
+// selector matches any labels
+type Selector []requirement
+
+// ParseSelector parses requirements separated by commas. A requirement is one of
+// `key=value`, `key==value`, `key!=value`, `key` (the label exists) and `!key` (the
+// label doesn't exist)
+func ParseSelector(selector string) (Selector, error) {
+	result := Selector{}
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) <= 0 {
+			continue
+		}
+		r := requirement{}
+		switch {
+		case strings.Contains(part, "!="):
+			kv := strings.SplitN(part, "!=", 2)
+			r = requirement{strings.TrimSpace(kv[0]), selectorNotEquals, strings.TrimSpace(kv[1])}
+		case strings.Contains(part, "="):
+			kv := strings.SplitN(part, "=", 2)
+			r = requirement{strings.TrimSpace(kv[0]), selectorEquals, strings.TrimSpace(strings.TrimPrefix(kv[1], "="))}
+		case strings.HasPrefix(part, "!"):
+			r = requirement{strings.TrimSpace(part[1:]), selectorNotExists, ""}
+		default:
+			r = requirement{part, selectorExists, ""}
+		}
+		if err := validateAttributeKey(r.key); err != nil {
+			return nil, fmt.Errorf("invalid requirement %q: %v", part, err)
+		}
+		if !labelValuePattern.MatchString(r.value) {
+			return nil, fmt.Errorf("invalid requirement %q: invalid value %q", part, r.value)
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+// Empty returns whether the selector matches any labels
+func (s Selector) Empty() bool {
+	return len(s) <= 0
+}
+
+// Matches returns whether labels satisfy all requirements
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		value, ok := labels[r.key]
+		switch r.operator {
+		case selectorEquals:
+			if !ok || value != r.value {
+				return false
+			}
+		case selectorNotEquals:
+			if ok && value == r.value {
+				return false
+			}
+		case selectorExists:
+			if !ok {
+				return false
+			}
+		case selectorNotExists:
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
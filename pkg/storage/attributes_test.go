@@ -0,0 +1,61 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package storage
+
+import "testing"
+
+func TestSelector(t *testing.T) {
+	labels := map[string]string{"team": "payments", "stage": "production", "example.com/tier": ""}
+	cases := []struct {
+		selector string
+		matched  bool
+	}{
+		{"", true},
+		{"team=payments", true},
+		{"team==payments, stage=production", true},
+		{"team=search", false},
+		{"team!=search", true},
+		{"stage!=production", false},
+		{"owner!=someone", true},
+		{"example.com/tier", true},
+		{"example.com/tier=", true},
+		{"owner", false},
+		{"!owner", true},
+		{"!team", false},
+	}
+	for _, c := range cases {
+		selector, err := ParseSelector(c.selector)
+		if err != nil {
+			t.Fatalf("unexpected error of selector %q: %v", c.selector, err)
+		}
+		if selector.Matches(labels) != c.matched {
+			t.Errorf("expected selector %q to match %v: %v", c.selector, labels, c.matched)
+		}
+	}
+	for _, selector := range []string{"=payments", "team=pay ments", "-team", "!"} {
+		if _, err := ParseSelector(selector); err == nil {
+			t.Errorf("expected an error of selector %q", selector)
+		}
+	}
+}
+
+func TestValidateAttributes(t *testing.T) {
+	valid := &Attributes{
+		Labels:      map[string]string{"team": "payments", "example.com/stage": ""},
+		Annotations: map[string]string{"description": "any text: like this"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, attributes := range []*Attributes{
+		{Labels: map[string]string{"team": "pay ments"}},
+		{Labels: map[string]string{"": "payments"}},
+		{Annotations: map[string]string{"team/": "payments"}},
+	} {
+		if err := attributes.Validate(); err == nil {
+			t.Errorf("expected an error of attributes %+v", attributes)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package storage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/protobuf/ptypes/any"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// APIVersionV2 is the apiVersion of Chart.yaml of helm 3 charts
+const APIVersionV2 = "v2"
+
+// ChartfileName is the name of the chart file which keeps the original Chart.yaml of
+// an apiVersion v2 chart
+const ChartfileName = "Chart.yaml"
+
+// chartfileV2Fields are fields of apiVersion v2 Chart.yaml which helm 2 doesn't know
+var chartfileV2Fields = []string{"dependencies", "type"}
+
+// LoadArchive loads a chart archive like chartutil.LoadArchive. Helm 2 drops fields of
+// Chart.yaml which it doesn't know, like dependencies and type of apiVersion v2 charts,
+// so the original Chart.yaml of such a chart is kept in its files. MarshalChartfile
+// merges it back when the chart is archived again
+func LoadArchive(in io.Reader) (*chart.Chart, error) {
+	data, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	chrt, err := chartutil.LoadArchive(bytes.NewReader(data))
+	if err != nil || chrt.Metadata == nil || chrt.Metadata.ApiVersion != APIVersionV2 {
+		return chrt, err
+	}
+	raw, err := readChartfile(data)
+	if err != nil {
+		return nil, err
+	}
+	chrt.Files = append(chrt.Files, &any.Any{TypeUrl: ChartfileName, Value: raw})
+	return chrt, nil
+}
+
+// readChartfile reads Chart.yaml in the base directory of a chart archive
+func readChartfile(data []byte) ([]byte, error) {
+	unzipped, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer unzipped.Close()
+	tr := tar.NewReader(unzipped)
+	for {
+		hd, err := tr.Next()
+		if err != nil {
+			return nil, err
+		}
+		parts := strings.Split(strings.Replace(hd.Name, "\\", "/", -1), "/")
+		if len(parts) == 2 && parts[1] == ChartfileName {
+			return ioutil.ReadAll(tr)
+		}
+	}
+}
+
+// Chartfile returns the original Chart.yaml kept by LoadArchive. It returns nil if
+// the chart isn't an apiVersion v2 chart
+func Chartfile(chrt *chart.Chart) []byte {
+	for _, f := range chrt.Files {
+		if f.TypeUrl == ChartfileName {
+			return f.Value
+		}
+	}
+	return nil
+}
+
+// MarshalChartfile marshals the metadata of chart to Chart.yaml. Fields of apiVersion
+// v2 which helm 2 doesn't know are copied from the original Chart.yaml
+func MarshalChartfile(chrt *chart.Chart) ([]byte, error) {
+	data, err := yaml.Marshal(chrt.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	raw := Chartfile(chrt)
+	if chrt.Metadata.ApiVersion != APIVersionV2 || raw == nil {
+		return data, nil
+	}
+	original := map[string]interface{}{}
+	if err = yaml.Unmarshal(raw, &original); err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err = yaml.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for _, name := range chartfileV2Fields {
+		if value, ok := original[name]; ok {
+			fields[name] = value
+		}
+	}
+	return yaml.Marshal(fields)
+}
+
+// LoadRequirements loads dependencies of chart. They are declared in Chart.yaml by
+// apiVersion v2 charts, and in requirements.yaml by apiVersion v1 charts. It returns
+// chartutil.ErrRequirementsNotFound if the chart declares no dependency
+func LoadRequirements(chrt *chart.Chart) (*chartutil.Requirements, error) {
+	raw := Chartfile(chrt)
+	if chrt.Metadata.ApiVersion != APIVersionV2 || raw == nil {
+		return chartutil.LoadRequirements(chrt)
+	}
+	reqs := &chartutil.Requirements{}
+	if err := yaml.Unmarshal(raw, reqs); err != nil {
+		return nil, err
+	}
+	if len(reqs.Dependencies) <= 0 {
+		return nil, chartutil.ErrRequirementsNotFound
+	}
+	return reqs, nil
+}
+
+// chartType returns the type of an apiVersion v2 chart, like "application" or "library"
+func chartType(chrt *chart.Chart) (string, error) {
+	raw := Chartfile(chrt)
+	if chrt.Metadata.ApiVersion != APIVersionV2 || raw == nil {
+		return "", nil
+	}
+	fields := struct {
+		Type string `json:"type"`
+	}{}
+	if err := yaml.Unmarshal(raw, &fields); err != nil {
+		return "", err
+	}
+	return fields.Type, nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package storage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+)
+
+const chartfileV2 = `apiVersion: v2
+name: app
+version: 1.0.0
+type: application
+dependencies:
+- name: redis
+  version: ^3.0.0
+  repository: https://charts.example.com
+  condition: redis.enabled
+`
+
+// newArchive creates a chart archive with files
+func newArchive(t *testing.T, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestChartfileV2(t *testing.T) {
+	data := newArchive(t, map[string]string{
+		"app/Chart.yaml":  chartfileV2,
+		"app/values.yaml": "redis:\n  enabled: true\n",
+	})
+	chrt, err := LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := CoalesceMetadata(chrt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Type != "application" {
+		t.Errorf("expected type application, but got %q", metadata.Type)
+	}
+	if len(metadata.Requirements) != 1 || metadata.Requirements[0].Name != "redis" ||
+		metadata.Requirements[0].Version != "^3.0.0" || metadata.Requirements[0].Condition != "redis.enabled" {
+		t.Errorf("expected dependency redis, but got %+v", metadata.Requirements)
+	}
+
+	// metadata updated by helm 2 types keeps fields of apiVersion v2
+	chrt.Metadata.Description = "updated"
+	chartfile, err := MarshalChartfile(chrt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := map[string]interface{}{}
+	if err = yaml.Unmarshal(chartfile, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["description"] != "updated" || fields["type"] != "application" || fields["apiVersion"] != "v2" {
+		t.Errorf("expected updated v2 Chart.yaml, but got:\n%s", chartfile)
+	}
+	if !strings.Contains(string(chartfile), "repository: https://charts.example.com") {
+		t.Errorf("expected dependencies in Chart.yaml, but got:\n%s", chartfile)
+	}
+}
+
+func TestChartfileV1(t *testing.T) {
+	const chartfileV1 = "apiVersion: v1\nname: app\nversion: 1.0.0\n"
+	data := newArchive(t, map[string]string{"app/Chart.yaml": chartfileV1})
+	chrt, err := LoadArchive(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Chartfile(chrt) != nil {
+		t.Error("expected Chart.yaml of apiVersion v1 not to be kept")
+	}
+	if _, err = LoadRequirements(chrt); err == nil {
+		t.Error("expected no requirements")
+	}
+	metadata, err := CoalesceMetadata(chrt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Type != "" || metadata.Requirements != nil {
+		t.Errorf("expected no v2 fields, but got %q, %v", metadata.Type, metadata.Requirements)
+	}
+}
@@ -6,7 +6,11 @@ package driver
 
 import "github.com/docker/distribution/registry/storage/driver/factory"
 
-// Create creates a specific StorageDriver
+// Create creates a specific StorageDriver. Operations of the driver are observed by metrics
 func Create(name string, parameters map[string]interface{}) (StorageDriver, error) {
-	return factory.Create(name, parameters)
+	d, err := factory.Create(name, parameters)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedDriver{d}, nil
 }
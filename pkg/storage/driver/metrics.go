@@ -0,0 +1,114 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package driver
+
+import (
+	"io"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/metrics"
+	"github.com/caicloud/helm-registry/pkg/trace"
+	"github.com/docker/distribution/context"
+	storageDriver "github.com/docker/distribution/registry/storage/driver"
+)
+
+// instrumentedDriver observes latencies and errors of operations of a StorageDriver
+type instrumentedDriver struct {
+	StorageDriver
+}
+
+// observe starts a span of an operation on path, and returns a function which ends the
+// span and observes the operation when it returns err. A missing path is an expected
+// result of operations, so it's not counted as an error
+func (d *instrumentedDriver) observe(ctx context.Context, operation string, path string) (context.Context, func(err error)) {
+	start := time.Now()
+	spanCtx, span := trace.StartSpan(ctx, "storage."+operation)
+	span.SetAttribute("storage.driver", d.Name())
+	span.SetAttribute("storage.path", path)
+	return spanCtx, func(err error) {
+		if _, ok := err.(storageDriver.PathNotFoundError); ok {
+			err = nil
+		}
+		span.SetError(err)
+		span.End()
+		metrics.ObserveStorage(d.Name(), operation, start, err)
+	}
+}
+
+// GetContent implements StorageDriver
+func (d *instrumentedDriver) GetContent(ctx context.Context, path string) (data []byte, err error) {
+	ctx, done := d.observe(ctx, "get_content", path)
+	defer func() { done(err) }()
+	return d.StorageDriver.GetContent(ctx, path)
+}
+
+// PutContent implements StorageDriver
+func (d *instrumentedDriver) PutContent(ctx context.Context, path string, content []byte) (err error) {
+	ctx, done := d.observe(ctx, "put_content", path)
+	defer func() { done(err) }()
+	return d.StorageDriver.PutContent(ctx, path, content)
+}
+
+// Reader implements StorageDriver
+func (d *instrumentedDriver) Reader(ctx context.Context, path string, offset int64) (reader io.ReadCloser, err error) {
+	ctx, done := d.observe(ctx, "reader", path)
+	defer func() { done(err) }()
+	return d.StorageDriver.Reader(ctx, path, offset)
+}
+
+// Writer implements StorageDriver
+func (d *instrumentedDriver) Writer(ctx context.Context, path string, append bool) (writer storageDriver.FileWriter, err error) {
+	spanCtx, done := d.observe(ctx, "writer", path)
+	defer func() { done(err) }()
+	writer, err = d.StorageDriver.Writer(spanCtx, path, append)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedWriter{writer, d, ctx, path}, nil
+}
+
+// Stat implements StorageDriver
+func (d *instrumentedDriver) Stat(ctx context.Context, path string) (info storageDriver.FileInfo, err error) {
+	ctx, done := d.observe(ctx, "stat", path)
+	defer func() { done(err) }()
+	return d.StorageDriver.Stat(ctx, path)
+}
+
+// List implements StorageDriver
+func (d *instrumentedDriver) List(ctx context.Context, path string) (keys []string, err error) {
+	ctx, done := d.observe(ctx, "list", path)
+	defer func() { done(err) }()
+	return d.StorageDriver.List(ctx, path)
+}
+
+// Move implements StorageDriver
+func (d *instrumentedDriver) Move(ctx context.Context, sourcePath string, destPath string) (err error) {
+	ctx, done := d.observe(ctx, "move", sourcePath)
+	defer func() { done(err) }()
+	return d.StorageDriver.Move(ctx, sourcePath, destPath)
+}
+
+// Delete implements StorageDriver
+func (d *instrumentedDriver) Delete(ctx context.Context, path string) (err error) {
+	ctx, done := d.observe(ctx, "delete", path)
+	defer func() { done(err) }()
+	return d.StorageDriver.Delete(ctx, path)
+}
+
+// instrumentedWriter observes the latency and error of committing a FileWriter. The commit
+// is traced in the context where the writer is created
+type instrumentedWriter struct {
+	storageDriver.FileWriter
+	driver *instrumentedDriver
+	ctx    context.Context
+	path   string
+}
+
+// Commit implements FileWriter
+func (w *instrumentedWriter) Commit() (err error) {
+	_, done := w.driver.observe(w.ctx, "commit", w.path)
+	defer func() { done(err) }()
+	return w.FileWriter.Commit()
+}
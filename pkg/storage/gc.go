@@ -0,0 +1,17 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package storage
+
+// GCResult describes what is removed by a garbage collection
+type GCResult struct {
+	// Versions are paths of removed incomplete versions, like "space/chart/1.0.0"
+	Versions []string `json:"versions"`
+	// Blobs are digests of removed blobs which were not referenced by any version
+	Blobs []string `json:"blobs"`
+	// Uploads are removed uploads which were not finished, like "space/id"
+	Uploads []string `json:"uploads"`
+	// ReclaimedBytes is the total size of removed files
+	ReclaimedBytes int64 `json:"reclaimedBytes"`
+}
@@ -4,7 +4,11 @@ Copyright 2017 caicloud authors. All rights reserved.
 
 package storage
 
-import "context"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 // ValidationType defines a type for Validating in SpaceManager
 type ValidationType string
@@ -44,6 +48,14 @@ type SpaceManager interface {
 	// Validate validates whether the value of vType is valid.
 	// An instance of SpaceManager should validate Basic ValidationType at least
 	Validate(ctx context.Context, vType ValidationType, value interface{}) bool
+
+	// PurgeTrash permanently deletes trashed versions which are deleted before specific time
+	PurgeTrash(ctx context.Context, before time.Time) error
+
+	// CollectGarbage removes incomplete versions left by failed uploads and blobs which
+	// are not referenced by any version. Only data which is not modified since before is
+	// removed, so uploads in progress are not affected
+	CollectGarbage(ctx context.Context, before time.Time) (*GCResult, error)
 }
 
 // Space defines methods for managing specific chart space
@@ -65,6 +77,44 @@ type Space interface {
 	// VersionMetadata returns all version metadata in current space
 	VersionMetadata(ctx context.Context) ([]*Metadata, error)
 
+	// TrashedVersionMetadata returns all metadata of trashed versions in current space
+	TrashedVersionMetadata(ctx context.Context) ([]*Metadata, error)
+
+	// RetentionPolicy returns the retention policy of current space. It's used by charts
+	// which have no policy. It returns nil if the space has no policy
+	RetentionPolicy(ctx context.Context) (*RetentionPolicy, error)
+
+	// SetRetentionPolicy sets the retention policy of current space. A nil policy
+	// removes the policy
+	SetRetentionPolicy(ctx context.Context, policy *RetentionPolicy) error
+
+	// Info returns the description, owner and visibility of current space. It returns
+	// nil if the space has no info
+	Info(ctx context.Context) (*SpaceInfo, error)
+
+	// SetInfo sets the info of current space. A nil info removes the info
+	SetInfo(ctx context.Context, info *SpaceInfo) error
+
+	// Webhooks returns the webhooks of current space
+	Webhooks(ctx context.Context) ([]Webhook, error)
+
+	// SetWebhooks replaces the webhooks of current space. An empty list removes all webhooks
+	SetWebhooks(ctx context.Context, webhooks []Webhook) error
+
+	// CreateUpload starts a resumable upload of a chart archive in current space
+	CreateUpload(ctx context.Context) (Upload, error)
+
+	// Upload returns an upload in progress by id
+	Upload(ctx context.Context, id string) (Upload, error)
+
+	// PutBlob stores the data received by upload as a blob of current space if its sha256
+	// digest in hex is digest. The upload is consumed. Blobs are pushed by OCI clients
+	// before the manifest which references them
+	PutBlob(ctx context.Context, upload Upload, digest string) error
+
+	// Blob returns a reader of a blob put by PutBlob and the size of blob
+	Blob(ctx context.Context, digest string) (io.ReadCloser, int64, error)
+
 	// Chart returns a Chart for managing specific chart
 	Chart(ctx context.Context, chart string) (Chart, error)
 }
@@ -76,10 +126,16 @@ type Chart interface {
 	// Name returns name of instance
 	Name() string
 
-	// Delete deletes specific chart
+	// Delete deletes specific version permanently
 	Delete(ctx context.Context, version string) error
 
-	// List lists all version numbers in current chart
+	// Trash moves specific version to trash. A trashed version can be restored
+	Trash(ctx context.Context, version string) error
+
+	// Restore moves specific version from trash back to current chart
+	Restore(ctx context.Context, version string) error
+
+	// List lists all version numbers in current chart in ascending semver order
 	List(ctx context.Context) ([]string, error)
 
 	// Exists returns whether the chart exists
@@ -88,6 +144,24 @@ type Chart interface {
 	// VersionMetadata returns all version metadata in current chart
 	VersionMetadata(ctx context.Context) ([]*Metadata, error)
 
+	// TrashedVersionMetadata returns all metadata of trashed versions in current chart
+	TrashedVersionMetadata(ctx context.Context) ([]*Metadata, error)
+
+	// RetentionPolicy returns the retention policy of current chart. It returns nil if
+	// the chart has no policy
+	RetentionPolicy(ctx context.Context) (*RetentionPolicy, error)
+
+	// SetRetentionPolicy sets the retention policy of current chart. A nil policy
+	// removes the policy
+	SetRetentionPolicy(ctx context.Context, policy *RetentionPolicy) error
+
+	// Attributes returns labels and annotations of current chart
+	Attributes(ctx context.Context) (*Attributes, error)
+
+	// UpdateAttributes stores the attributes modified by update and returns them. Other
+	// updates of the attributes are blocked until update returns
+	UpdateAttributes(ctx context.Context, update func(*Attributes) error) (*Attributes, error)
+
 	// Version returns a Version for managing specific version
 	Version(ctx context.Context, version string) (Version, error)
 }
@@ -102,9 +176,64 @@ type Version interface {
 	// PutContent stores chart data
 	PutContent(ctx context.Context, data []byte) error
 
+	// Update reads chart data and stores the data returned by update. Other writes of the
+	// version are blocked until update returns, so update must not call methods of the
+	// version. If digest is not empty and chart data has been changed from it, update is
+	// not called and a conflict error is returned
+	Update(ctx context.Context, digest string, update func(data []byte) ([]byte, error)) error
+
+	// PutUpload stores the data received by upload as chart data. The upload must be
+	// created by the space of current version, and it's consumed after storing
+	PutUpload(ctx context.Context, upload Upload) error
+
+	// PutBlob stores a blob put by PutBlob of the space as chart data
+	PutBlob(ctx context.Context, digest string) error
+
 	// GetContent gets chart data
 	GetContent(ctx context.Context) ([]byte, error)
 
+	// StreamContent returns a reader of chart data and the size of data. Caller must
+	// close the reader. It should be used for sending large charts
+	StreamContent(ctx context.Context) (io.ReadCloser, int64, error)
+
+	// PutProvenance stores provenance data of chart. verified is whether data has been
+	// verified by a keyring. PutContent removes the provenance of previous chart data,
+	// so it should be called after PutContent
+	PutProvenance(ctx context.Context, data []byte, verified bool) error
+
+	// GetProvenance gets provenance data of chart
+	GetProvenance(ctx context.Context) ([]byte, error)
+
+	// PutManifest stores the OCI manifest and config pushed with chart data. PutContent
+	// removes the manifest of previous chart data, so it should be called after PutContent
+	PutManifest(ctx context.Context, manifest, config []byte) error
+
+	// Manifest returns the OCI manifest and config of chart. They are nil if the version
+	// is not pushed by an OCI client
+	Manifest(ctx context.Context) ([]byte, []byte, error)
+
+	// PutScanReport stores the report of scanning chart data. PutContent removes the report
+	// of previous chart data, so it should be called after PutContent
+	PutScanReport(ctx context.Context, report []byte) error
+
+	// ScanReport returns the report of scanning chart data. It's nil if the chart is not scanned
+	ScanReport(ctx context.Context) ([]byte, error)
+
+	// Readme returns the README.md of chart, which is extracted when chart data is stored.
+	// It's nil if the chart has no readme
+	Readme(ctx context.Context) ([]byte, error)
+
+	// Icon returns the icon file of chart, which is extracted when chart data is stored.
+	// It's nil if the chart has no icon file or its icon is an external url
+	Icon(ctx context.Context) ([]byte, error)
+
+	// ValuesSchema returns the values.schema.json of chart, which is extracted when chart
+	// data is stored. It's nil if the chart has no values schema
+	ValuesSchema(ctx context.Context) ([]byte, error)
+
+	// ProvenanceStatus returns the verification status of the provenance of chart
+	ProvenanceStatus(ctx context.Context) (ProvenanceStatus, error)
+
 	// Exists returns whether the version exists
 	Exists(ctx context.Context) bool
 
@@ -116,4 +245,27 @@ type Version interface {
 
 	// Values gets data from values.yaml file which in current chart data
 	Values(ctx context.Context) ([]byte, error)
+
+	// ModTime returns the time when chart data was stored last time
+	ModTime(ctx context.Context) (time.Time, error)
+
+	// Digest returns the sha256 digest of chart data in hex
+	Digest(ctx context.Context) (string, error)
+
+	// Size returns the size of chart data in bytes
+	Size(ctx context.Context) (int64, error)
+
+	// Downloads returns the number of times the version is downloaded
+	Downloads(ctx context.Context) (int64, error)
+
+	// AddDownloads adds n to the number of times the version is downloaded
+	AddDownloads(ctx context.Context, n int64) error
+
+	// Attributes returns labels and annotations of current version. They are kept when
+	// chart data is replaced
+	Attributes(ctx context.Context) (*Attributes, error)
+
+	// UpdateAttributes stores the attributes modified by update and returns them. Other
+	// writes of the version are blocked until update returns
+	UpdateAttributes(ctx context.Context, update func(*Attributes) error) (*Attributes, error)
 }
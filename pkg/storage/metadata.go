@@ -5,6 +5,10 @@ Copyright 2017 caicloud authors. All rights reserved.
 package storage
 
 import (
+	"encoding/json"
+	"time"
+
+	"k8s.io/helm/pkg/chartutil"
 	"k8s.io/helm/pkg/proto/hapi/chart"
 )
 
@@ -12,12 +16,42 @@ import (
 type Metadata struct {
 	chart.Metadata
 	Dependencies []*Metadata `json:"dependencies,omitempty"`
+	// Type is the type of an apiVersion v2 chart, like "application" or "library"
+	Type string `json:"type,omitempty"`
+	// Requirements are dependencies declared in Chart.yaml of an apiVersion v2 chart
+	Requirements []*chartutil.Dependency `json:"requirements,omitempty"`
+	// DeletedAt is the time when the version is moved to trash. It's nil if the version is not trashed
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	// Downloads is the number of times the version is downloaded. It's only set in metadata listings
+	Downloads *int64 `json:"downloads,omitempty"`
+	// Size is the size of chart data in bytes. It's only set in metadata of trashed versions
+	Size *int64 `json:"size,omitempty"`
+	// Provenance is the verification status of the provenance of the version. It's only
+	// set in metadata listings and fetched metadata
+	Provenance ProvenanceStatus `json:"provenance,omitempty"`
+	// Labels are labels of the chart overridden by labels of the version. They're only
+	// set in metadata listings and search results
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // CoalesceMetadata coalesces all metadata in chart
 func CoalesceMetadata(chart *chart.Chart) (*Metadata, error) {
 	metadata := &Metadata{}
 	metadata.Metadata = *chart.Metadata
+	if chart.Metadata.ApiVersion == APIVersionV2 {
+		chartType, err := chartType(chart)
+		if err != nil {
+			return nil, err
+		}
+		metadata.Type = chartType
+		reqs, err := LoadRequirements(chart)
+		if err != nil && err != chartutil.ErrRequirementsNotFound {
+			return nil, err
+		}
+		if reqs != nil {
+			metadata.Requirements = reqs.Dependencies
+		}
+	}
 	for _, dep := range chart.Dependencies {
 		m, err := CoalesceMetadata(dep)
 		if err != nil {
@@ -27,3 +61,12 @@ func CoalesceMetadata(chart *chart.Chart) (*Metadata, error) {
 	}
 	return metadata, nil
 }
+
+// CoalesceValues coalesces values of chart and its dependencies to json
+func CoalesceValues(chart *chart.Chart) ([]byte, error) {
+	values, err := chartutil.CoalesceValues(chart, chart.Values)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(values)
+}
@@ -0,0 +1,17 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package storage
+
+// ProvenanceStatus is the verification status of the provenance of a version
+type ProvenanceStatus string
+
+const (
+	// ProvenanceNone means the version has no provenance
+	ProvenanceNone ProvenanceStatus = "none"
+	// ProvenanceUnverified means the provenance was not verified when it's stored
+	ProvenanceUnverified ProvenanceStatus = "unverified"
+	// ProvenanceVerified means the provenance was verified by a keyring when it's stored
+	ProvenanceVerified ProvenanceStatus = "verified"
+)
@@ -0,0 +1,47 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// RetentionPolicy describes which versions of a chart are kept when pruning. A version
+// is kept if it's one of the KeepLast highest versions, it's stored within KeepWithin
+// or it matches Protect. The highest version is always kept.
+type RetentionPolicy struct {
+	// KeepLast is the number of highest versions to keep. 0 means no limit by number
+	KeepLast int `json:"keepLast,omitempty"`
+	// KeepWithin is a duration like "720h". Versions stored within it are kept
+	KeepWithin string `json:"keepWithin,omitempty"`
+	// Protect is a semver range like ">=1.0.0". Versions in the range are never pruned
+	Protect string `json:"protect,omitempty"`
+}
+
+// Validate validates whether the policy is valid
+func (p *RetentionPolicy) Validate() error {
+	if p.KeepLast < 0 {
+		return fmt.Errorf("keepLast should not be negative")
+	}
+	if len(p.KeepWithin) > 0 {
+		duration, err := time.ParseDuration(p.KeepWithin)
+		if err != nil {
+			return err
+		}
+		if duration <= 0 {
+			return fmt.Errorf("keepWithin should be positive")
+		}
+	}
+	if len(p.Protect) > 0 {
+		if _, err := ParseRange(p.Protect); err != nil {
+			return fmt.Errorf("protect should be a semver range: %v", err)
+		}
+	}
+	if p.KeepLast == 0 && len(p.KeepWithin) <= 0 {
+		return fmt.Errorf("keepLast or keepWithin should be specified")
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"bytes"
+	"context"
+	"path"
+
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// names of the files which store assets extracted from chart data. An empty file means
+// the chart has no such asset
+const (
+	readmeName = "readme.dat"
+	iconName   = "icon.dat"
+	schemaName = "schema.dat"
+)
+
+// putAssets stores the readme, icon and values schema of chart, so they can be served
+// without loading chart data. The version must be locked by caller
+func (v *Version) putAssets(ctx context.Context, chrt *chart.Chart) error {
+	assets := map[string][]byte{
+		readmeName: storage.ExtractReadme(chrt),
+		iconName:   storage.ExtractIcon(chrt),
+		schemaName: storage.ExtractValuesSchema(chrt),
+	}
+	for name, data := range assets {
+		if err := v.Backend.PutContent(ctx, path.Join(v.Prefix, name), data); err != nil {
+			return ErrorInternalUnknown.Format(err)
+		}
+	}
+	return nil
+}
+
+// Readme returns the README.md of chart. It's nil if the chart has no readme
+func (v *Version) Readme(ctx context.Context) ([]byte, error) {
+	return v.asset(ctx, readmeName, storage.ExtractReadme)
+}
+
+// Icon returns the icon file of chart. It's nil if the chart has no icon file or its
+// icon is an external url
+func (v *Version) Icon(ctx context.Context) ([]byte, error) {
+	return v.asset(ctx, iconName, storage.ExtractIcon)
+}
+
+// ValuesSchema returns the values.schema.json of chart. It's nil if the chart has no
+// values schema
+func (v *Version) ValuesSchema(ctx context.Context) ([]byte, error) {
+	return v.asset(ctx, schemaName, storage.ExtractValuesSchema)
+}
+
+// asset returns an asset stored in name. Versions stored before assets are extracted
+// have no asset files, so the asset is extracted from chart data by extract
+func (v *Version) asset(ctx context.Context, name string, extract func(*chart.Chart) []byte) ([]byte, error) {
+	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
+	if !lock.RLock(v.Chart.Space.SpaceManager.LockTimeout) {
+		return nil, ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
+	}
+	defer lock.RUnlock()
+	if err := v.Validate(ctx); err != nil {
+		return nil, err
+	}
+	key := path.Join(v.Prefix, name)
+	if !keyExists(ctx, v.Backend, key) {
+		content, err := v.getContent(ctx)
+		if err != nil {
+			return nil, err
+		}
+		chrt, err := storage.LoadArchive(bytes.NewReader(content))
+		if err != nil {
+			return nil, ErrorInternalUnknown.Format(err)
+		}
+		return extract(chrt), nil
+	}
+	data, err := v.Backend.GetContent(ctx, key)
+	if err != nil {
+		return nil, ErrorInternalUnknown.Format(err)
+	}
+	if len(data) <= 0 {
+		return nil, nil
+	}
+	return data, nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"context"
+	"path"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+)
+
+func TestAssets(t *testing.T) {
+	sm, cleanup := newTestSpaceManager(t)
+	defer cleanup()
+	ctx := context.Background()
+	putTestVersion(t, sm, "plain", "plain", "1.0.0", newTestArchive(t, "plain", "1.0.0", "a: 1\n"))
+	putTestVersion(t, sm, "space", "app", "1.0.0", storagetest.NewArchiveFiles(t, map[string]string{
+		"app/Chart.yaml":         "apiVersion: v1\nname: app\nversion: 1.0.0\nicon: file://./icon.svg\n",
+		"app/Readme.md":          "# App\n",
+		"app/icon.svg":           "<svg></svg>",
+		"app/values.schema.json": `{"type": "object"}`,
+	}))
+	s, _ := sm.Space(ctx, "plain")
+	c, _ := s.Chart(ctx, "plain")
+	v, _ := c.Version(ctx, "1.0.0")
+	if readme, err := v.Readme(ctx); err != nil || readme != nil {
+		t.Errorf("expected no readme, but got %q, %v", readme, err)
+	}
+	if icon, err := v.Icon(ctx); err != nil || icon != nil {
+		t.Errorf("expected no icon, but got %q, %v", icon, err)
+	}
+	if schema, err := v.ValuesSchema(ctx); err != nil || schema != nil {
+		t.Errorf("expected no values schema, but got %q, %v", schema, err)
+	}
+
+	s, _ = sm.Space(ctx, "space")
+	c, _ = s.Chart(ctx, "app")
+	v, _ = c.Version(ctx, "1.0.0")
+	if readme, err := v.Readme(ctx); err != nil || string(readme) != "# App\n" {
+		t.Errorf("expected readme of app, but got %q, %v", readme, err)
+	}
+	if icon, err := v.Icon(ctx); err != nil || string(icon) != "<svg></svg>" {
+		t.Errorf("expected icon of app, but got %q, %v", icon, err)
+	}
+	if schema, err := v.ValuesSchema(ctx); err != nil || string(schema) != `{"type": "object"}` {
+		t.Errorf("expected values schema of app, but got %q, %v", schema, err)
+	}
+	// versions stored without asset files are extracted from chart data
+	version := v.(*Version)
+	for _, name := range []string{readmeName, iconName, schemaName} {
+		if err := sm.Backend.Delete(ctx, path.Join(version.Prefix, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if readme, err := v.Readme(ctx); err != nil || string(readme) != "# App\n" {
+		t.Errorf("expected readme extracted from chart data, but got %q, %v", readme, err)
+	}
+	if icon, err := v.Icon(ctx); err != nil || string(icon) != "<svg></svg>" {
+		t.Errorf("expected icon extracted from chart data, but got %q, %v", icon, err)
+	}
+	if schema, err := v.ValuesSchema(ctx); err != nil || string(schema) != `{"type": "object"}` {
+		t.Errorf("expected values schema extracted from chart data, but got %q, %v", schema, err)
+	}
+}
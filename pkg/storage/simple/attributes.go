@@ -0,0 +1,113 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/storage/driver"
+)
+
+// attributesName is the name of the file which stores labels and annotations of a chart
+// or a version
+const attributesName = reservedPrefix + "attributes"
+
+// Attributes returns labels and annotations of current chart
+func (c *Chart) Attributes(ctx context.Context) (*storage.Attributes, error) {
+	lock := c.Space.SpaceManager.Lock.Get(c.Space.Name(), c.Name())
+	if !lock.RLock(c.Space.SpaceManager.LockTimeout) {
+		return nil, ErrorLocking.Format("chart", c.Space.Name()+"/"+c.Name())
+	}
+	defer lock.RUnlock()
+	return readAttributes(ctx, c.Space.SpaceManager.Backend, path.Join(c.Prefix, attributesName))
+}
+
+// UpdateAttributes stores the attributes modified by update. The chart must exist
+func (c *Chart) UpdateAttributes(ctx context.Context, update func(*storage.Attributes) error) (*storage.Attributes, error) {
+	if !c.Exists(ctx) {
+		return nil, ErrorContentNotFound.Format(c.Space.Name() + "/" + c.Name())
+	}
+	lock := c.Space.SpaceManager.Lock.Get(c.Space.Name(), c.Name())
+	if !lock.Lock(c.Space.SpaceManager.LockTimeout) {
+		return nil, ErrorLocking.Format("chart", c.Space.Name()+"/"+c.Name())
+	}
+	defer lock.Unlock()
+	return updateAttributes(ctx, c.Space.SpaceManager.Backend, path.Join(c.Prefix, attributesName), update)
+}
+
+// Attributes returns labels and annotations of current version
+func (v *Version) Attributes(ctx context.Context) (*storage.Attributes, error) {
+	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
+	if !lock.RLock(v.Chart.Space.SpaceManager.LockTimeout) {
+		return nil, ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
+	}
+	defer lock.RUnlock()
+	return readAttributes(ctx, v.Backend, path.Join(v.Prefix, attributesName))
+}
+
+// UpdateAttributes stores the attributes modified by update. The version must be stored
+// successfully
+func (v *Version) UpdateAttributes(ctx context.Context, update func(*storage.Attributes) error) (*storage.Attributes, error) {
+	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
+	if !lock.Lock(v.Chart.Space.SpaceManager.LockTimeout) {
+		return nil, ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
+	}
+	defer lock.Unlock()
+	if err := v.validate(ctx); err != nil {
+		return nil, err
+	}
+	return updateAttributes(ctx, v.Backend, path.Join(v.Prefix, attributesName), update)
+}
+
+// readAttributes reads the attributes in key. It returns empty attributes if key does not exist
+func readAttributes(ctx context.Context, backend driver.StorageDriver, key string) (*storage.Attributes, error) {
+	attributes := &storage.Attributes{}
+	if !keyExists(ctx, backend, key) {
+		return attributes, nil
+	}
+	data, err := backend.GetContent(ctx, key)
+	if err != nil {
+		return nil, ErrorInternalUnknown.Format(err)
+	}
+	if err = json.Unmarshal(data, attributes); err != nil {
+		return nil, ErrorInternalUnknown.Format(err)
+	}
+	return attributes, nil
+}
+
+// updateAttributes reads the attributes in key, modifies them by update and stores them.
+// key is removed if no attribute is left
+func updateAttributes(ctx context.Context, backend driver.StorageDriver, key string,
+	update func(*storage.Attributes) error) (*storage.Attributes, error) {
+	attributes, err := readAttributes(ctx, backend, key)
+	if err != nil {
+		return nil, err
+	}
+	if err = update(attributes); err != nil {
+		return nil, err
+	}
+	if err = attributes.Validate(); err != nil {
+		return nil, ErrorInvalidParam.Format("attributes", err)
+	}
+	if len(attributes.Labels) <= 0 && len(attributes.Annotations) <= 0 {
+		if keyExists(ctx, backend, key) {
+			if err = backend.Delete(ctx, key); err != nil {
+				return nil, ErrorInternalUnknown.Format(err)
+			}
+		}
+		return &storage.Attributes{}, nil
+	}
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, ErrorInternalUnknown.Format(err)
+	}
+	if err = backend.PutContent(ctx, key, data); err != nil {
+		return nil, ErrorInternalUnknown.Format(err)
+	}
+	return attributes, nil
+}
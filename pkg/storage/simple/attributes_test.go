@@ -0,0 +1,88 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+func TestUpdateAttributes(t *testing.T) {
+	sm, cleanup := newTestSpaceManager(t)
+	defer cleanup()
+	putTestVersion(t, sm, "space", "chart", "1.0.0", newTestArchive(t, "chart", "1.0.0", ""))
+	ctx := context.Background()
+	s, _ := sm.Space(ctx, "space")
+	c, _ := s.Chart(ctx, "chart")
+	other, _ := c.Version(ctx, "1.1.0")
+	if err := other.PutContent(ctx, newTestArchive(t, "chart", "1.1.0", "")); err != nil {
+		t.Fatal(err)
+	}
+	v, err := c.Version(ctx, "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := map[string]string{"stage": "production"}
+	_, err = v.UpdateAttributes(ctx, func(a *storage.Attributes) error {
+		a.Labels = labels
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectLabels := func(expected map[string]string) {
+		attributes, err := v.Attributes(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(attributes.Labels, expected) {
+			t.Errorf("expected labels %v, but got %v", expected, attributes.Labels)
+		}
+	}
+	expectLabels(labels)
+
+	// attributes are kept when chart data is replaced, and moved with trashed versions
+	if err = v.PutContent(ctx, newTestArchive(t, "chart", "1.0.0", "")); err != nil {
+		t.Fatal(err)
+	}
+	expectLabels(labels)
+	if err = c.Trash(ctx, "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	expectLabels(nil)
+	if err = c.Restore(ctx, "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	expectLabels(labels)
+
+	// invalid attributes are not stored
+	_, err = v.UpdateAttributes(ctx, func(a *storage.Attributes) error {
+		a.Labels["team"] = "pay ments"
+		return nil
+	})
+	if !ErrorInvalidParam.Equal(err) {
+		t.Errorf("expected an invalid param error, but got %v", err)
+	}
+	expectLabels(labels)
+
+	// chart attributes don't appear as versions
+	_, err = c.UpdateAttributes(ctx, func(a *storage.Attributes) error {
+		a.Annotations = map[string]string{"owner": "payments@example.com"}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err := c.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(versions, []string{"1.0.0", "1.1.0"}) {
+		t.Errorf("expected versions 1.0.0 and 1.1.0, but got %v", versions)
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"path"
+	"strconv"
+
+	"github.com/caicloud/helm-registry/pkg/storage/driver"
+)
+
+// blobsName is the name of the directory which stores chart archives by digest. Identical
+// archives in all spaces share a blob in `/.blobs/digest`
+const blobsName = reservedPrefix + "blobs"
+
+// blobDataName is the name of the file which stores archive data in a blob
+const blobDataName = "data"
+
+// refcountName is the name of the file which records the number of versions referencing a blob
+const refcountName = "refcount"
+
+// referenceName is the name of the file which records the blob digest of a version
+const referenceName = "chart.ref"
+
+// blobPrefix returns the prefix of the blob with specific digest
+func (sm *SpaceManager) blobPrefix(digest string) string {
+	return path.Join(sm.Prefix, blobsName, digest)
+}
+
+// putBlob stores data as a blob if no identical blob exists and increases its refcount.
+// It returns the digest of data
+func (sm *SpaceManager) putBlob(ctx context.Context, data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	lock := sm.Lock.Get(blobsName, digest)
+	if !lock.Lock(sm.LockTimeout) {
+		return "", ErrorLocking.Format("blob", digest)
+	}
+	defer lock.Unlock()
+	refcount, err := sm.refcount(ctx, digest)
+	if err != nil {
+		return "", err
+	}
+	prefix := sm.blobPrefix(digest)
+	if refcount <= 0 {
+		if err = writeKey(ctx, sm.Backend, path.Join(prefix, blobDataName), data); err != nil {
+			return "", ErrorInternalUnknown.Format(err)
+		}
+	}
+	err = sm.Backend.PutContent(ctx, path.Join(prefix, refcountName), []byte(strconv.Itoa(refcount+1)))
+	if err != nil {
+		return "", ErrorInternalUnknown.Format(err)
+	}
+	return digest, nil
+}
+
+// referenceBlob increases the refcount of an existing blob with specific digest
+func (sm *SpaceManager) referenceBlob(ctx context.Context, digest string) error {
+	lock := sm.Lock.Get(blobsName, digest)
+	if !lock.Lock(sm.LockTimeout) {
+		return ErrorLocking.Format("blob", digest)
+	}
+	defer lock.Unlock()
+	prefix := sm.blobPrefix(digest)
+	if !keyExists(ctx, sm.Backend, path.Join(prefix, blobDataName)) {
+		return ErrorContentNotFound.Format("blob " + digest)
+	}
+	refcount, err := sm.refcount(ctx, digest)
+	if err != nil {
+		return err
+	}
+	err = sm.Backend.PutContent(ctx, path.Join(prefix, refcountName), []byte(strconv.Itoa(refcount+1)))
+	if err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	return nil
+}
+
+// writeKey stores data by a writer of backend. Unlike PutContent, drivers like s3aws
+// upload large data in parts
+func writeKey(ctx context.Context, backend driver.StorageDriver, key string, data []byte) error {
+	writer, err := backend.Writer(ctx, key, false)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(writer, bytes.NewReader(data)); err == nil {
+		err = writer.Commit()
+	}
+	if err != nil {
+		writer.Cancel()
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
+// getBlob gets data of the blob with specific digest
+func (sm *SpaceManager) getBlob(ctx context.Context, digest string) ([]byte, error) {
+	lock := sm.Lock.Get(blobsName, digest)
+	if !lock.RLock(sm.LockTimeout) {
+		return nil, ErrorLocking.Format("blob", digest)
+	}
+	defer lock.RUnlock()
+	key := path.Join(sm.blobPrefix(digest), blobDataName)
+	data, err := sm.Backend.GetContent(ctx, key)
+	if err != nil {
+		return nil, ErrorContentNotFound.Format(key)
+	}
+	return data, nil
+}
+
+// openBlob returns a reader of data of the blob with specific digest and the size of data
+func (sm *SpaceManager) openBlob(ctx context.Context, digest string) (io.ReadCloser, int64, error) {
+	lock := sm.Lock.Get(blobsName, digest)
+	if !lock.RLock(sm.LockTimeout) {
+		return nil, 0, ErrorLocking.Format("blob", digest)
+	}
+	defer lock.RUnlock()
+	return openKey(ctx, sm.Backend, path.Join(sm.blobPrefix(digest), blobDataName))
+}
+
+// releaseBlob decreases the refcount of the blob with specific digest. The blob is not
+// deleted even if no version references it. Refcounts may be lost by registries which
+// share a backend, so unreferenced blobs are only removed by CollectGarbage, which counts
+// references of versions instead of trusting refcounts
+func (sm *SpaceManager) releaseBlob(ctx context.Context, digest string) error {
+	lock := sm.Lock.Get(blobsName, digest)
+	if !lock.Lock(sm.LockTimeout) {
+		return ErrorLocking.Format("blob", digest)
+	}
+	defer lock.Unlock()
+	refcount, err := sm.refcount(ctx, digest)
+	if err != nil || refcount <= 0 {
+		return err
+	}
+	err = sm.Backend.PutContent(ctx, path.Join(sm.blobPrefix(digest), refcountName), []byte(strconv.Itoa(refcount-1)))
+	if err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	return nil
+}
+
+// refcount returns the refcount of the blob with specific digest. It returns 0 if the
+// blob does not exist. Caller must hold the lock of the blob
+func (sm *SpaceManager) refcount(ctx context.Context, digest string) (int, error) {
+	key := path.Join(sm.blobPrefix(digest), refcountName)
+	if !keyExists(ctx, sm.Backend, key) {
+		return 0, nil
+	}
+	data, err := sm.Backend.GetContent(ctx, key)
+	if err != nil {
+		return 0, ErrorInternalUnknown.Format(err)
+	}
+	refcount, err := strconv.Atoi(string(data))
+	if err != nil {
+		return 0, ErrorInternalUnknown.Format(err)
+	}
+	return refcount, nil
+}
+
+// readReference returns the blob digest recorded in key
+func readReference(ctx context.Context, backend driver.StorageDriver, key string) (string, error) {
+	data, err := backend.GetContent(ctx, key)
+	if err != nil {
+		return "", ErrorContentNotFound.Format(key)
+	}
+	return string(data), nil
+}
+
+// releaseReferences releases blobs referenced by all versions under prefix. It
+// should be called before deleting prefix
+func (sm *SpaceManager) releaseReferences(ctx context.Context, prefix string) error {
+	keys, err := sm.Backend.List(ctx, prefix)
+	if err != nil {
+		// nothing to release
+		return nil
+	}
+	for _, key := range keys {
+		if lastElement(key) == referenceName {
+			digest, err := readReference(ctx, sm.Backend, key)
+			if err != nil {
+				return err
+			}
+			if err = sm.releaseBlob(ctx, digest); err != nil {
+				return err
+			}
+			continue
+		}
+		info, err := sm.Backend.Stat(ctx, key)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		if err = sm.releaseReferences(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/storage/storagetest"
+)
+
+// newTestSpaceManager creates a SpaceManager in a temporary directory
+func newTestSpaceManager(t testing.TB) (*SpaceManager, func()) {
+	sm, cleanup := storagetest.NewSpaceManager(t)
+	return sm.(*SpaceManager), cleanup
+}
+
+// newTestArchive creates a chart archive with values
+func newTestArchive(t testing.TB, name, version, values string) []byte {
+	return storagetest.NewArchiveFiles(t, map[string]string{
+		name + "/Chart.yaml":  "apiVersion: v1\nname: " + name + "\nversion: " + version + "\n",
+		name + "/values.yaml": values,
+	})
+}
+
+// putTestVersion stores data to space/chart/version
+func putTestVersion(t testing.TB, sm *SpaceManager, space, chart, version string, data []byte) {
+	ctx := context.Background()
+	if _, err := sm.Create(ctx, space); err != nil {
+		t.Fatal(err)
+	}
+	s, err := sm.Space(ctx, space)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.Chart(ctx, chart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := c.Version(ctx, version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = v.PutContent(ctx, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// blobDigests lists digests of all blobs
+func blobDigests(sm *SpaceManager) []string {
+	digests, err := list(context.Background(), sm.Backend, "/"+blobsName, func(string) bool { return true }, nil)
+	if err != nil {
+		return nil
+	}
+	return digests
+}
+
+func TestIdenticalArchivesShareBlob(t *testing.T) {
+	sm, cleanup := newTestSpaceManager(t)
+	defer cleanup()
+	ctx := context.Background()
+	data := newTestArchive(t, "chart", "1.0.0", "key: value\n")
+	putTestVersion(t, sm, "space1", "chart", "1.0.0", data)
+	putTestVersion(t, sm, "space2", "chart", "1.0.0", data)
+
+	digests := blobDigests(sm)
+	if len(digests) != 1 {
+		t.Fatalf("expected 1 blob, but got %v", digests)
+	}
+	refcount, err := sm.refcount(ctx, digests[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refcount != 2 {
+		t.Fatalf("expected refcount 2, but got %d", refcount)
+	}
+
+	// deleting a version must not break the other one
+	s1, _ := sm.Space(ctx, "space1")
+	c1, _ := s1.Chart(ctx, "chart")
+	if err = c1.Delete(ctx, "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if refcount, _ = sm.refcount(ctx, digests[0]); refcount != 1 {
+		t.Fatalf("expected refcount 1, but got %d", refcount)
+	}
+	s2, _ := sm.Space(ctx, "space2")
+	c2, _ := s2.Chart(ctx, "chart")
+	v2, _ := c2.Version(ctx, "1.0.0")
+	content, err := v2.GetContent(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, data) {
+		t.Fatal("content of space2/chart/1.0.0 is changed")
+	}
+
+	// the blob is kept after the last reference is released, until it's collected
+	if err = sm.Delete(ctx, "space2"); err != nil {
+		t.Fatal(err)
+	}
+	if refcount, _ = sm.refcount(ctx, digests[0]); refcount != 0 {
+		t.Fatalf("expected refcount 0, but got %d", refcount)
+	}
+	if _, err = sm.CollectGarbage(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if digests = blobDigests(sm); len(digests) != 0 {
+		t.Fatalf("expected no blob, but got %v", digests)
+	}
+}
+
+func TestLostRefcountKeepsBlob(t *testing.T) {
+	sm, cleanup := newTestSpaceManager(t)
+	defer cleanup()
+	ctx := context.Background()
+	data := newTestArchive(t, "chart", "1.0.0", "")
+	putTestVersion(t, sm, "space1", "chart", "1.0.0", data)
+	putTestVersion(t, sm, "space2", "chart", "1.0.0", data)
+	digest := blobDigests(sm)[0]
+	// another registry sharing the backend lost an update of the refcount
+	if err := sm.Backend.PutContent(ctx, path.Join(sm.blobPrefix(digest), refcountName), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	s1, _ := sm.Space(ctx, "space1")
+	c1, _ := s1.Chart(ctx, "chart")
+	if err := c1.Delete(ctx, "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	s2, _ := sm.Space(ctx, "space2")
+	c2, _ := s2.Chart(ctx, "chart")
+	v2, _ := c2.Version(ctx, "1.0.0")
+	if _, err := v2.GetContent(ctx); err != nil {
+		t.Fatalf("expected the blob to be kept, but got %v", err)
+	}
+	result, err := sm.CollectGarbage(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Blobs) != 0 {
+		t.Errorf("expected no blob to be collected, but got %v", result.Blobs)
+	}
+	if refcount, _ := sm.refcount(ctx, digest); refcount != 1 {
+		t.Errorf("expected refcount to be repaired to 1, but got %d", refcount)
+	}
+	if _, err = v2.GetContent(ctx); err != nil {
+		t.Errorf("expected the blob to be kept, but got %v", err)
+	}
+}
+
+func TestOverwriteReleasesPreviousBlob(t *testing.T) {
+	sm, cleanup := newTestSpaceManager(t)
+	defer cleanup()
+	ctx := context.Background()
+	putTestVersion(t, sm, "space", "chart", "1.0.0", newTestArchive(t, "chart", "1.0.0", "key: value\n"))
+	s, _ := sm.Space(ctx, "space")
+	c, _ := s.Chart(ctx, "chart")
+	v, _ := c.Version(ctx, "1.0.0")
+	data := newTestArchive(t, "chart", "1.0.0", "key: another value\n")
+	if err := v.PutContent(ctx, data); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sm.CollectGarbage(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	digests := blobDigests(sm)
+	if len(digests) != 1 {
+		t.Fatalf("expected 1 blob, but got %v", digests)
+	}
+	content, err := v.GetContent(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, data) {
+		t.Fatal("content is not updated")
+	}
+}
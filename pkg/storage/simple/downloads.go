@@ -0,0 +1,63 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"context"
+	"path"
+	"strconv"
+)
+
+// downloadsName is the name of the file which records the number of downloads of a version
+const downloadsName = "downloads.dat"
+
+// Downloads returns the number of times the version is downloaded
+func (v *Version) Downloads(ctx context.Context) (int64, error) {
+	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
+	if !lock.RLock(v.Chart.Space.SpaceManager.LockTimeout) {
+		return 0, ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
+	}
+	defer lock.RUnlock()
+	return v.downloads(ctx)
+}
+
+// AddDownloads adds n to the number of times the version is downloaded
+func (v *Version) AddDownloads(ctx context.Context, n int64) error {
+	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
+	if !lock.Lock(v.Chart.Space.SpaceManager.LockTimeout) {
+		return ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
+	}
+	defer lock.Unlock()
+	// a version without status has been deleted or moved to trash
+	if !keyExists(ctx, v.Backend, path.Join(v.Prefix, statusName)) {
+		return ErrorContentNotFound.Format(v.Prefix)
+	}
+	downloads, err := v.downloads(ctx)
+	if err != nil {
+		return err
+	}
+	err = v.Backend.PutContent(ctx, path.Join(v.Prefix, downloadsName), []byte(strconv.FormatInt(downloads+n, 10)))
+	if err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	return nil
+}
+
+// downloads reads the number of downloads. It's 0 if the version has never been downloaded
+func (v *Version) downloads(ctx context.Context) (int64, error) {
+	key := path.Join(v.Prefix, downloadsName)
+	if !keyExists(ctx, v.Backend, key) {
+		return 0, nil
+	}
+	data, err := v.Backend.GetContent(ctx, key)
+	if err != nil {
+		return 0, ErrorInternalUnknown.Format(err)
+	}
+	downloads, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return 0, ErrorInternalUnknown.Format(err)
+	}
+	return downloads, nil
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAddDownloads(t *testing.T) {
+	sm, cleanup := newTestSpaceManager(t)
+	defer cleanup()
+	putTestVersion(t, sm, "space", "chart", "1.0.0", newTestArchive(t, "chart", "1.0.0", ""))
+	ctx := context.Background()
+	s, _ := sm.Space(ctx, "space")
+	c, _ := s.Chart(ctx, "chart")
+	v, err := c.Version(ctx, "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int64{1, 2} {
+		if err = v.AddDownloads(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	downloads, err := v.Downloads(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 3 {
+		t.Errorf("expected 3 downloads, but got %d", downloads)
+	}
+
+	missing, err := c.Version(ctx, "2.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = missing.AddDownloads(ctx, 1); !ErrorContentNotFound.Equal(err) {
+		t.Errorf("expected content not found for a missing version, but got %v", err)
+	}
+}
@@ -12,13 +12,13 @@ import (
 
 var (
 	// ErrorContentMissing defines content must be specified
-	ErrorContentMissing = errors.NewFormatError(http.StatusInternalServerError, errors.ReasonInternal, "%s does not specify")
+	ErrorContentMissing = errors.NewFormatError(http.StatusInternalServerError, errors.ReasonInternal, "%s does not specify").Named("ContentMissing")
 	// ErrorNoParameter defines parameter can't be nil or empty
-	ErrorNoParameter = errors.NewFormatError(http.StatusInternalServerError, errors.ReasonInternal, "%s can't be nil or empty")
+	ErrorNoParameter = errors.NewFormatError(http.StatusInternalServerError, errors.ReasonInternal, "%s can't be nil or empty").Named("NoParameter")
 	// ErrorNoResource defines can't find resource from package
-	ErrorNoResource = errors.NewFormatError(http.StatusConflict, errors.ReasonInternal, "can't find %s from package: %v")
+	ErrorNoResource = errors.NewFormatError(http.StatusConflict, errors.ReasonInternal, "can't find %s from package: %v").Named("NoResource")
 	// ErrorNeedForcedDelete defines need to force to delete resource
-	ErrorNeedForcedDelete = errors.NewFormatError(http.StatusInternalServerError, errors.ReasonInternal, "need force to delete %s")
+	ErrorNeedForcedDelete = errors.NewFormatError(http.StatusInternalServerError, errors.ReasonInternal, "need force to delete %s").Named("NeedForcedDelete")
 	// ErrorInvalidParam defines invalid error
 	ErrorInvalidParam = errors.ErrorInvalidParam
 	// ErrorResourceExist defines resource conflict error
@@ -27,6 +27,8 @@ var (
 	ErrorInternalUnknown = errors.ErrorInternalUnknown
 	// ErrorLocking defines locking error
 	ErrorLocking = errors.ErrorLocking
+	// ErrorVersionConflict defines version conflict error
+	ErrorVersionConflict = errors.ErrorVersionConflict
 	// ErrorInvalidStatus defines invalid status error
 	ErrorInvalidStatus = errors.ErrorInvalidStatus
 	// ErrorParamTypeError defines param type error
@@ -0,0 +1,261 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"context"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// CollectGarbage removes incomplete versions, unfinished uploads and unreferenced blobs which
+// are not modified since before. Versions in all spaces and trashes are scanned for references
+// first, then blobs are removed if no version references them. Refcounts which don't match
+// references are repaired.
+func (sm *SpaceManager) CollectGarbage(ctx context.Context, before time.Time) (*storage.GCResult, error) {
+	result := &storage.GCResult{
+		Versions: []string{},
+		Blobs:    []string{},
+		Uploads:  []string{},
+	}
+	spaces, err := sm.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	references := make(map[string]int)
+	for _, spaceName := range spaces {
+		space, err := NewSpace(sm, spaceName)
+		if err != nil {
+			return nil, err
+		}
+		charts, err := space.chartsWithTrash(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, chartName := range charts {
+			chart, err := NewChart(space, chartName)
+			if err != nil {
+				return nil, err
+			}
+			if err = chart.collectGarbage(ctx, before, references, result); err != nil {
+				return nil, err
+			}
+		}
+		if err = space.collectUploads(ctx, before, result); err != nil {
+			return nil, err
+		}
+	}
+	if err = sm.collectBlobs(ctx, before, references, result); err != nil {
+		return nil, err
+	}
+	for _, spaceName := range spaces {
+		space, err := NewSpace(sm, spaceName)
+		if err != nil {
+			return nil, err
+		}
+		if err = space.collectBlobLinks(ctx, before); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// chartsWithTrash lists names of charts which have versions or trashed versions
+func (s *Space) chartsWithTrash(ctx context.Context) ([]string, error) {
+	charts, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	trashed, err := s.trashedCharts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mergeNames(charts, trashed), nil
+}
+
+// mergeNames returns names in a or b without duplicates
+func mergeNames(a, b []string) []string {
+	exists := make(map[string]bool, len(a))
+	result := make([]string, 0, len(a)+len(b))
+	for _, names := range [][]string{a, b} {
+		for _, name := range names {
+			if !exists[name] {
+				exists[name] = true
+				result = append(result, name)
+			}
+		}
+	}
+	return result
+}
+
+// collectGarbage removes incomplete versions and trashed versions of current chart and
+// records blob references of the others. A version and its trashed one are checked under
+// the same lock, so a version which is being trashed or restored is never missed
+func (c *Chart) collectGarbage(ctx context.Context, before time.Time, references map[string]int, result *storage.GCResult) error {
+	backend := c.Space.SpaceManager.Backend
+	versions, err := list(ctx, backend, c.Prefix, validateVersion, nil)
+	if err != nil {
+		// the chart only has trashed versions
+		versions = []string{}
+	}
+	trashed, err := c.trashedVersions(ctx)
+	if err != nil {
+		return err
+	}
+	for _, version := range mergeNames(versions, trashed) {
+		lock := c.Space.SpaceManager.Lock.Get(c.Space.Name(), c.Name(), version)
+		if !lock.Lock(c.Space.SpaceManager.LockTimeout) {
+			return ErrorLocking.Format("version", c.Space.Name()+"/"+c.Name()+"/"+version)
+		}
+		for _, prefix := range []string{path.Join(c.Prefix, version), path.Join(c.trashPrefix(), version)} {
+			if err = c.Space.SpaceManager.collectVersion(ctx, prefix, before, references, result); err != nil {
+				break
+			}
+		}
+		lock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return deleteEmptyTrash(ctx, c)
+}
+
+// collectVersion removes the version in prefix if it's incomplete and not modified since
+// before. Otherwise its blob reference is recorded. Caller must hold the lock of the version
+func (sm *SpaceManager) collectVersion(ctx context.Context, prefix string, before time.Time,
+	references map[string]int, result *storage.GCResult) error {
+	if !keyExists(ctx, sm.Backend, prefix) {
+		return nil
+	}
+	status, err := sm.Backend.GetContent(ctx, path.Join(prefix, statusName))
+	complete := err == nil && string(status) == statusSuccess
+	keys, err := sm.Backend.List(ctx, prefix)
+	if err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	var size int64
+	modTime := time.Time{}
+	for _, key := range keys {
+		info, err := sm.Backend.Stat(ctx, key)
+		if err != nil {
+			continue
+		}
+		size += info.Size()
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	if complete || modTime.After(before) {
+		// a version which is being uploaded may reference a blob
+		referenceKey := path.Join(prefix, referenceName)
+		if !keyExists(ctx, sm.Backend, referenceKey) {
+			return nil
+		}
+		digest, err := readReference(ctx, sm.Backend, referenceKey)
+		if err != nil {
+			return err
+		}
+		references[digest]++
+		return nil
+	}
+	// the refcount of the referenced blob is repaired when collecting blobs
+	if err = deleteKeys(ctx, sm.Backend, prefix, true); err != nil {
+		return err
+	}
+	log.FromContext(ctx).Infof("Removed incomplete version %s", prefix)
+	result.Versions = append(result.Versions, strings.TrimPrefix(prefix, sm.Prefix))
+	result.ReclaimedBytes += size
+	return nil
+}
+
+// collectUploads removes uploads of current space which receive no chunk since before
+func (s *Space) collectUploads(ctx context.Context, before time.Time, result *storage.GCResult) error {
+	ids, err := s.uploads(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		upload := s.newUpload(id)
+		// the data of a finished upload has been moved to a blob
+		modTime, _ := upload.ModTime(ctx)
+		if modTime.After(before) {
+			continue
+		}
+		size, _ := upload.Size(ctx)
+		if err = upload.Delete(ctx); err != nil {
+			return err
+		}
+		log.FromContext(ctx).Infof("Removed unfinished upload %s/%s", s.Name(), id)
+		result.Uploads = append(result.Uploads, s.Name()+"/"+id)
+		result.ReclaimedBytes += size
+	}
+	return nil
+}
+
+// collectBlobs removes blobs which are not referenced and not modified since before, and
+// repairs refcounts of the other blobs by references
+func (sm *SpaceManager) collectBlobs(ctx context.Context, before time.Time, references map[string]int, result *storage.GCResult) error {
+	blobs := path.Join(sm.Prefix, blobsName)
+	if !keyExists(ctx, sm.Backend, blobs) {
+		return nil
+	}
+	digests, err := list(ctx, sm.Backend, blobs, func(string) bool { return true }, nil)
+	if err != nil {
+		return err
+	}
+	for _, digest := range digests {
+		if err = sm.collectBlob(ctx, digest, before, references[digest], result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectBlob removes the blob with specific digest if it's not referenced, or sets its
+// refcount to the number of references. A blob modified since before is skipped because
+// an upload or a deletion may be changing it
+func (sm *SpaceManager) collectBlob(ctx context.Context, digest string, before time.Time, referenced int, result *storage.GCResult) error {
+	lock := sm.Lock.Get(blobsName, digest)
+	if !lock.Lock(sm.LockTimeout) {
+		return ErrorLocking.Format("blob", digest)
+	}
+	defer lock.Unlock()
+	prefix := sm.blobPrefix(digest)
+	var size int64
+	for _, name := range []string{blobDataName, refcountName} {
+		info, err := sm.Backend.Stat(ctx, path.Join(prefix, name))
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(before) {
+			return nil
+		}
+		size += info.Size()
+	}
+	if referenced <= 0 {
+		if err := deleteKeys(ctx, sm.Backend, prefix, true); err != nil {
+			return err
+		}
+		log.FromContext(ctx).Infof("Removed unreferenced blob %s", digest)
+		result.Blobs = append(result.Blobs, digest)
+		result.ReclaimedBytes += size
+		return nil
+	}
+	refcount, err := sm.refcount(ctx, digest)
+	if err != nil || refcount == referenced {
+		return err
+	}
+	err = sm.Backend.PutContent(ctx, path.Join(prefix, refcountName), []byte(strconv.Itoa(referenced)))
+	if err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	log.FromContext(ctx).Infof("Repaired refcount of blob %s from %d to %d", digest, refcount, referenced)
+	return nil
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"context"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCollectGarbage(t *testing.T) {
+	sm, cleanup := newTestSpaceManager(t)
+	defer cleanup()
+	ctx := context.Background()
+	data := newTestArchive(t, "chart", "1.0.0", "")
+	putTestVersion(t, sm, "space", "chart", "1.0.0", data)
+	digest := blobDigests(sm)[0]
+
+	// an upload which failed after referencing the blob
+	partial := path.Join(sm.Prefix, "space", "chart", "2.0.0")
+	if _, err := sm.putBlob(ctx, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.Backend.PutContent(ctx, path.Join(partial, statusName), []byte(statusLocking)); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.Backend.PutContent(ctx, path.Join(partial, referenceName), []byte(digest)); err != nil {
+		t.Fatal(err)
+	}
+	// an upload which failed before referencing the blob
+	orphan, err := sm.putBlob(ctx, newTestArchive(t, "orphan", "1.0.0", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// recent data is kept
+	result, err := sm.CollectGarbage(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Versions) != 0 || len(result.Blobs) != 0 {
+		t.Fatalf("expected nothing to be collected, but got %+v", result)
+	}
+
+	result, err = sm.CollectGarbage(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"space/chart/2.0.0"}; !reflect.DeepEqual(result.Versions, expected) {
+		t.Errorf("expected versions %v, but got %v", expected, result.Versions)
+	}
+	if expected := []string{orphan}; !reflect.DeepEqual(result.Blobs, expected) {
+		t.Errorf("expected blobs %v, but got %v", expected, result.Blobs)
+	}
+	if result.ReclaimedBytes <= 0 {
+		t.Errorf("expected reclaimed bytes, but got %d", result.ReclaimedBytes)
+	}
+	if refcount, _ := sm.refcount(ctx, digest); refcount != 1 {
+		t.Errorf("expected refcount 1, but got %d", refcount)
+	}
+	s, _ := sm.Space(ctx, "space")
+	c, _ := s.Chart(ctx, "chart")
+	v, _ := c.Version(ctx, "1.0.0")
+	if _, err = v.GetContent(ctx); err != nil {
+		t.Errorf("expected complete version to be kept, but got %v", err)
+	}
+}
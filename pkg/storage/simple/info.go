@@ -0,0 +1,76 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// infoName is the name of the file which stores the info of a space
+const infoName = reservedPrefix + "info"
+
+// Info returns the info of current space. It returns nil if the space has no info
+func (s *Space) Info(ctx context.Context) (*storage.SpaceInfo, error) {
+	lock := s.SpaceManager.Lock.Get(s.Name())
+	if !lock.RLock(s.SpaceManager.LockTimeout) {
+		return nil, ErrorLocking.Format("space", s.Name())
+	}
+	defer lock.RUnlock()
+	backend := s.SpaceManager.Backend
+	key := path.Join(s.Prefix, infoName)
+	if !keyExists(ctx, backend, key) {
+		return nil, nil
+	}
+	data, err := backend.GetContent(ctx, key)
+	if err != nil {
+		return nil, ErrorInternalUnknown.Format(err)
+	}
+	info := &storage.SpaceInfo{}
+	if err = json.Unmarshal(data, info); err != nil {
+		return nil, ErrorInternalUnknown.Format(err)
+	}
+	return info, nil
+}
+
+// SetInfo sets the info of current space. A nil info removes the info. The space
+// must exist
+func (s *Space) SetInfo(ctx context.Context, info *storage.SpaceInfo) error {
+	if !s.Exists(ctx) {
+		return ErrorContentNotFound.Format(s.Name())
+	}
+	if info != nil {
+		if err := info.Validate(); err != nil {
+			return ErrorInvalidParam.Format("space info", err)
+		}
+	}
+	lock := s.SpaceManager.Lock.Get(s.Name())
+	if !lock.Lock(s.SpaceManager.LockTimeout) {
+		return ErrorLocking.Format("space", s.Name())
+	}
+	defer lock.Unlock()
+	backend := s.SpaceManager.Backend
+	key := path.Join(s.Prefix, infoName)
+	if info == nil {
+		if !keyExists(ctx, backend, key) {
+			return nil
+		}
+		if err := backend.Delete(ctx, key); err != nil {
+			return ErrorInternalUnknown.Format(err)
+		}
+		return nil
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	if err = backend.PutContent(ctx, key, data); err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+func TestSpaceInfo(t *testing.T) {
+	sm, cleanup := newTestSpaceManager(t)
+	defer cleanup()
+	ctx := context.Background()
+	s, _ := sm.Space(ctx, "space")
+	if err := s.SetInfo(ctx, &storage.SpaceInfo{Owner: "team"}); err == nil {
+		t.Fatal("expected an error when setting info of a space which does not exist")
+	}
+	if _, err := sm.Create(ctx, "space"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := s.Info(ctx)
+	if err != nil || info != nil || info.Public() {
+		t.Fatalf("expected no info of a new private space, but got %v, %v", info, err)
+	}
+	if err = s.SetInfo(ctx, &storage.SpaceInfo{Visibility: "internal"}); err == nil {
+		t.Error("expected an error when setting an unknown visibility")
+	}
+	expected := &storage.SpaceInfo{Description: "charts of payments", Owner: "payments", Visibility: storage.VisibilityPublic}
+	if err = s.SetInfo(ctx, expected); err != nil {
+		t.Fatal(err)
+	}
+	if info, err = s.Info(ctx); err != nil || !reflect.DeepEqual(info, expected) || !info.Public() {
+		t.Errorf("expected info %v, but got %v, %v", expected, info, err)
+	}
+	// the info file is not listed as a chart
+	charts, err := s.List(ctx)
+	if err != nil || len(charts) != 0 {
+		t.Errorf("expected no charts, but got %v, %v", charts, err)
+	}
+	if err = s.SetInfo(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if info, err = s.Info(ctx); err != nil || info != nil {
+		t.Errorf("expected info to be removed, but got %v, %v", info, err)
+	}
+}
@@ -7,24 +7,30 @@ package simple
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"path"
 	"regexp"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Masterminds/semver"
 	"github.com/caicloud/helm-registry/pkg/lock"
 	"github.com/caicloud/helm-registry/pkg/log"
 	"github.com/caicloud/helm-registry/pkg/storage"
 	"github.com/caicloud/helm-registry/pkg/storage/driver"
-	"k8s.io/helm/pkg/chartutil"
+	"github.com/caicloud/helm-registry/pkg/trace"
 )
 
 const managerName = "simple"
 const chartPackageName = "chart.tgz"
+const provenanceName = "chart.tgz.prov"
+const provenanceVerifiedName = "chart.tgz.prov.verified"
 const metadataName = "metadata.dat"
 const valuesName = "values.dat"
 
@@ -44,7 +50,7 @@ func init() {
 
 // simpleSpaceManagerFactory implements storage.SpaceManagerFactory.
 // Parameters has a specified parameter named storagedriver. Its value can be:
-// inmemory, filesystem, s3, azure, swift, oss, gcs.
+// inmemory, filesystem, s3aws, azure, swift, oss, gcs. Only filesystem and s3aws are built in.
 // For more information, please refer to [link](https://docs.docker.com/registry/storage-drivers/)
 // All parameters will pass to docker registry StorageDriver for creating a storage backend.
 // If you want to specify a backend driver, please find other parameters from above-mentioned link.
@@ -133,7 +139,11 @@ func (sm *SpaceManager) Delete(ctx context.Context, space string) error {
 		return ErrorLocking.Format("space", space)
 	}
 	defer lock.Unlock()
-	return deleteKeys(ctx, sm.Backend, path.Join(sm.Prefix, space), true)
+	prefix := path.Join(sm.Prefix, space)
+	if err := sm.releaseReferences(ctx, prefix); err != nil {
+		return err
+	}
+	return deleteKeys(ctx, sm.Backend, prefix, true)
 }
 
 // List returns all space names
@@ -204,7 +214,11 @@ func (s *Space) Delete(ctx context.Context, chart string) error {
 		return ErrorLocking.Format("chart", s.Name()+"/"+chart)
 	}
 	defer lock.Unlock()
-	return deleteKeys(ctx, s.SpaceManager.Backend, path.Join(s.Prefix, chart), true)
+	prefix := path.Join(s.Prefix, chart)
+	if err := s.SpaceManager.releaseReferences(ctx, prefix); err != nil {
+		return err
+	}
+	return deleteKeys(ctx, s.SpaceManager.Backend, prefix, true)
 }
 
 // List returns all chart names
@@ -285,7 +299,7 @@ func (c *Chart) Delete(ctx context.Context, version string) error {
 	if !lock.Lock(c.Space.SpaceManager.LockTimeout) {
 		return ErrorLocking.Format("version", c.Space.Name()+"/"+c.Name()+"/"+version)
 	}
-	err := deleteKeys(ctx, c.Space.SpaceManager.Backend, path.Join(c.Prefix, version), true)
+	err := c.deleteVersion(ctx, version)
 	// unlock before return
 	lock.Unlock()
 	if err != nil {
@@ -299,6 +313,16 @@ func (c *Chart) Delete(ctx context.Context, version string) error {
 	return err
 }
 
+// deleteVersion deletes files of specific version and releases its blob. The version
+// must be locked by caller
+func (c *Chart) deleteVersion(ctx context.Context, version string) error {
+	prefix := path.Join(c.Prefix, version)
+	if err := c.Space.SpaceManager.releaseReferences(ctx, prefix); err != nil {
+		return err
+	}
+	return deleteKeys(ctx, c.Space.SpaceManager.Backend, prefix, true)
+}
+
 // List returns all version numbers
 func (c *Chart) List(ctx context.Context) ([]string, error) {
 	lock := c.Space.SpaceManager.Lock.Get(c.Space.Name(), c.Name())
@@ -374,37 +398,122 @@ func (v *Version) Number() string {
 
 // PutContent stores chart data
 func (v *Version) PutContent(ctx context.Context, data []byte) error {
+	if len(data) <= 0 {
+		return ErrorNoParameter.Format("data")
+	}
+	return v.putChart(ctx, func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}, func() (string, error) {
+		return v.Chart.Space.SpaceManager.putBlob(ctx, data)
+	})
+}
+
+// PutUpload stores the data received by upload as chart data. The data is moved to
+// a blob instead of being copied, and the upload is deleted after storing
+func (v *Version) PutUpload(ctx context.Context, upload storage.Upload) error {
+	u, ok := upload.(*Upload)
+	if !ok || u.Space.Name() != v.Chart.Space.Name() {
+		return ErrorInvalidParam.Format("upload", upload.ID())
+	}
+	if err := v.putChart(ctx, func() (io.ReadCloser, error) {
+		return u.Reader(ctx)
+	}, func() (string, error) {
+		return u.moveToBlob(ctx, "", true)
+	}); err != nil {
+		return err
+	}
+	return u.Delete(ctx)
+}
+
+// Update reads chart data and stores the data returned by update while the version is locked
+func (v *Version) Update(ctx context.Context, digest string, update func(data []byte) ([]byte, error)) (err error) {
+	ctx, span := v.startSpan(ctx, "version.update")
+	defer func() {
+		span.SetError(err)
+		span.End()
+	}()
 	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
 	if !lock.Lock(v.Chart.Space.SpaceManager.LockTimeout) {
 		return ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
 	}
 	defer lock.Unlock()
+	if err := v.validate(ctx); err != nil {
+		return err
+	}
+	if digest != "" {
+		current, err := v.digest(ctx)
+		if err != nil {
+			return err
+		}
+		if current != digest {
+			return ErrorVersionConflict.Format(v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number(), current)
+		}
+	}
+	data, err := v.getContent(ctx)
+	if err != nil {
+		return err
+	}
+	data, err = update(data)
+	if err != nil {
+		return err
+	}
 	if len(data) <= 0 {
 		return ErrorNoParameter.Format("data")
 	}
-	// Check whether process succeed
-	var success = false
+	return v.storeChart(ctx, func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}, func() (string, error) {
+		return v.Chart.Space.SpaceManager.putBlob(ctx, data)
+	})
+}
+
+// putChart stores a chart archive which is read by open. The archive is validated before
+// store is called to store it as a blob and return the digest of blob
+func (v *Version) putChart(ctx context.Context, open func() (io.ReadCloser, error), store func() (string, error)) (err error) {
+	ctx, span := v.startSpan(ctx, "version.put")
 	defer func() {
-		if !success {
-			// GC when it's failed
-			err := v.Chart.Delete(ctx, v.Version)
-			if err != nil {
-				log.Error(err)
+		span.SetError(err)
+		span.End()
+	}()
+	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
+	if !lock.Lock(v.Chart.Space.SpaceManager.LockTimeout) {
+		return ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
+	}
+	err = v.storeChart(ctx, open, store)
+	// unlock before deleting the chart which is left without version
+	lock.Unlock()
+	if err != nil {
+		if versions, listErr := v.Chart.List(ctx); listErr == nil && len(versions) <= 0 {
+			if deleteErr := v.Chart.Space.Delete(ctx, v.Chart.Name()); deleteErr != nil {
+				log.FromContext(ctx).Error(deleteErr)
 			}
 		}
-	}()
+	}
+	return err
+}
+
+// replacedFiles are files of a version which are replaced or removed when new chart data
+// is stored. They are restored if storing fails
+var replacedFiles = []string{referenceName, metadataName, valuesName, readmeName, iconName, schemaName,
+	provenanceName, provenanceVerifiedName, manifestName, configName, scanReportName}
+
+// storeChart stores a chart archive like putChart. The version must be locked by caller.
+// If storing fails, an existing version is restored to its previous data and a new version
+// is removed
+func (v *Version) storeChart(ctx context.Context, open func() (io.ReadCloser, error), store func() (string, error)) (err error) {
 	statusKey := path.Join(v.Prefix, statusName)
-	statusData, err := v.Backend.GetContent(ctx, statusKey)
+	statusData, _ := v.Backend.GetContent(ctx, statusKey)
 	if string(statusData) == statusLocking {
 		return ErrorLocking.Format("chart", v.Chart.Name()+"/"+v.Version)
 	}
-	// Create a `statusName` file with `statusLocking` to lock the place
-	err = v.Backend.PutContent(ctx, statusKey, []byte(statusLocking))
+	existing := string(statusData) == statusSuccess
+	// Validate chart
+	reader, err := open()
 	if err != nil {
-		return ErrorInternalUnknown.Format(err)
+		return err
 	}
-	// Validate chart
-	chart, err := chartutil.LoadArchive(bytes.NewReader(data))
+	chart, err := storage.LoadArchive(reader)
+	reader.Close()
 	if err != nil {
 		return ErrorParamTypeError.Format("chart", "gzip", "unknown")
 	}
@@ -414,44 +523,139 @@ func (v *Version) PutContent(ctx context.Context, data []byte) error {
 		return ErrorInvalidParam.Format("metadata", err.Error())
 	}
 	// Coalesce values
-	values, err := chartutil.CoalesceValues(chart, chart.Values)
+	values, err := storage.CoalesceValues(chart)
 	if err != nil {
 		return ErrorInvalidParam.Format("values", err.Error())
 	}
-
-	// Store chart
-	err = v.Backend.PutContent(ctx, path.Join(v.Prefix, chartPackageName), data)
+	metadataData, err := json.Marshal(metadata)
 	if err != nil {
 		return ErrorInternalUnknown.Format(err)
 	}
-	// Store metadata
-	data, err = json.Marshal(metadata)
+
+	// Keep files of the existing version for restoring
+	var previous map[string][]byte
+	if existing {
+		if previous, err = v.readFiles(ctx, replacedFiles); err != nil {
+			return err
+		}
+	}
+	digest := ""
+	defer func() {
+		if err == nil {
+			return
+		}
+		var cleanupErr error
+		if existing {
+			cleanupErr = v.restoreFiles(ctx, previous, digest)
+		} else {
+			cleanupErr = v.Chart.deleteVersion(ctx, v.Version)
+		}
+		if cleanupErr != nil {
+			log.FromContext(ctx).Error(cleanupErr)
+		}
+	}()
+	// Create a `statusName` file with `statusLocking` to lock the place
+	err = v.Backend.PutContent(ctx, statusKey, []byte(statusLocking))
 	if err != nil {
 		return ErrorInternalUnknown.Format(err)
 	}
-	err = v.Backend.PutContent(ctx, path.Join(v.Prefix, metadataName), data)
-	if err != nil {
+	// Store chart
+	if digest, err = store(); err != nil {
+		return err
+	}
+	if err = v.Backend.PutContent(ctx, path.Join(v.Prefix, referenceName), []byte(digest)); err != nil {
 		return ErrorInternalUnknown.Format(err)
 	}
-	// Store values
-	data, err = json.Marshal(values)
+	// Remove provenance, manifest and scan report of previous chart data
+	for _, name := range []string{provenanceName, provenanceVerifiedName, manifestName, configName, scanReportName} {
+		provenanceKey := path.Join(v.Prefix, name)
+		if keyExists(ctx, v.Backend, provenanceKey) {
+			if err = v.Backend.Delete(ctx, provenanceKey); err != nil {
+				return ErrorInternalUnknown.Format(err)
+			}
+		}
+	}
+	// Store metadata
+	err = v.Backend.PutContent(ctx, path.Join(v.Prefix, metadataName), metadataData)
 	if err != nil {
 		return ErrorInternalUnknown.Format(err)
 	}
-	err = v.Backend.PutContent(ctx, path.Join(v.Prefix, valuesName), data)
+	// Store values
+	err = v.Backend.PutContent(ctx, path.Join(v.Prefix, valuesName), values)
 	if err != nil {
 		return ErrorInternalUnknown.Format(err)
 	}
+	// Store readme and icon
+	if err = v.putAssets(ctx, chart); err != nil {
+		return err
+	}
 	// Write `statusSuccess` to `statusName` file
 	err = v.Backend.PutContent(ctx, statusKey, []byte(statusSuccess))
 	if err != nil {
 		return ErrorInternalUnknown.Format(err)
 	}
-	// Succeed in storing chart
-	success = true
+	// Succeed in storing chart, so previous data is not needed any more
+	if err := v.releasePrevious(ctx, previous[referenceName]); err != nil {
+		log.FromContext(ctx).Error(err)
+	}
+	return nil
+}
+
+// readFiles reads files with names in current version. Files which don't exist are
+// not included. The version must be locked by caller
+func (v *Version) readFiles(ctx context.Context, names []string) (map[string][]byte, error) {
+	files := make(map[string][]byte, len(names))
+	for _, name := range names {
+		key := path.Join(v.Prefix, name)
+		if !keyExists(ctx, v.Backend, key) {
+			continue
+		}
+		data, err := v.Backend.GetContent(ctx, key)
+		if err != nil {
+			return nil, ErrorInternalUnknown.Format(err)
+		}
+		files[name] = data
+	}
+	return files, nil
+}
+
+// restoreFiles restores files read by readFiles after storing new chart data failed, and
+// releases the blob with specific digest which was stored for new data. The version is
+// marked as complete again. The version must be locked by caller
+func (v *Version) restoreFiles(ctx context.Context, files map[string][]byte, digest string) error {
+	for _, name := range replacedFiles {
+		key := path.Join(v.Prefix, name)
+		data, ok := files[name]
+		if !ok {
+			if keyExists(ctx, v.Backend, key) {
+				if err := v.Backend.Delete(ctx, key); err != nil {
+					return ErrorInternalUnknown.Format(err)
+				}
+			}
+			continue
+		}
+		if err := v.Backend.PutContent(ctx, key, data); err != nil {
+			return ErrorInternalUnknown.Format(err)
+		}
+	}
+	if err := v.Backend.PutContent(ctx, path.Join(v.Prefix, statusName), []byte(statusSuccess)); err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	if len(digest) > 0 {
+		return v.Chart.Space.SpaceManager.releaseBlob(ctx, digest)
+	}
 	return nil
 }
 
+// startSpan starts a span of an operation on the version
+func (v *Version) startSpan(ctx context.Context, name string) (context.Context, *trace.Span) {
+	ctx, span := trace.StartSpan(ctx, name)
+	span.SetAttribute("registry.space", v.Chart.Space.Name())
+	span.SetAttribute("registry.chart", v.Chart.Name())
+	span.SetAttribute("registry.version", v.Number())
+	return ctx, span
+}
+
 // GetContent gets chart data
 func (v *Version) GetContent(ctx context.Context) ([]byte, error) {
 	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
@@ -462,14 +666,139 @@ func (v *Version) GetContent(ctx context.Context) ([]byte, error) {
 	if err := v.Validate(ctx); err != nil {
 		return nil, err
 	}
-	path := path.Join(v.Prefix, chartPackageName)
-	data, err := v.Chart.Space.SpaceManager.Backend.GetContent(ctx, path)
+	return v.getContent(ctx)
+}
+
+// getContent gets chart data. The version must be locked by caller
+func (v *Version) getContent(ctx context.Context) ([]byte, error) {
+	referenceKey := path.Join(v.Prefix, referenceName)
+	if keyExists(ctx, v.Backend, referenceKey) {
+		digest, err := readReference(ctx, v.Backend, referenceKey)
+		if err != nil {
+			return nil, err
+		}
+		return v.Chart.Space.SpaceManager.getBlob(ctx, digest)
+	}
+	// versions stored before deduplication have their own archives
+	data, err := v.Backend.GetContent(ctx, path.Join(v.Prefix, chartPackageName))
 	if err != nil {
 		return nil, ErrorContentNotFound.Format(v.Prefix)
 	}
 	return data, nil
 }
 
+// StreamContent returns a reader of chart data and the size of data. The lock of version
+// is released after opening, so the reader is not blocked by updates
+func (v *Version) StreamContent(ctx context.Context) (io.ReadCloser, int64, error) {
+	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
+	if !lock.RLock(v.Chart.Space.SpaceManager.LockTimeout) {
+		return nil, 0, ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
+	}
+	defer lock.RUnlock()
+	if err := v.Validate(ctx); err != nil {
+		return nil, 0, err
+	}
+	referenceKey := path.Join(v.Prefix, referenceName)
+	if keyExists(ctx, v.Backend, referenceKey) {
+		digest, err := readReference(ctx, v.Backend, referenceKey)
+		if err != nil {
+			return nil, 0, err
+		}
+		return v.Chart.Space.SpaceManager.openBlob(ctx, digest)
+	}
+	// versions stored before deduplication have their own archives
+	return openKey(ctx, v.Backend, path.Join(v.Prefix, chartPackageName))
+}
+
+// releasePrevious releases the blob referenced by previous data of current version. If
+// previous data has no reference, the archive stored before deduplication is removed.
+// The version must be locked by caller
+func (v *Version) releasePrevious(ctx context.Context, previous []byte) error {
+	if len(previous) > 0 {
+		return v.Chart.Space.SpaceManager.releaseBlob(ctx, string(previous))
+	}
+	packageKey := path.Join(v.Prefix, chartPackageName)
+	if keyExists(ctx, v.Backend, packageKey) {
+		if err := v.Backend.Delete(ctx, packageKey); err != nil {
+			return ErrorInternalUnknown.Format(err)
+		}
+	}
+	return nil
+}
+
+// PutProvenance stores provenance data of chart. A verified provenance is marked by
+// an empty file
+func (v *Version) PutProvenance(ctx context.Context, data []byte, verified bool) error {
+	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
+	if !lock.Lock(v.Chart.Space.SpaceManager.LockTimeout) {
+		return ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
+	}
+	defer lock.Unlock()
+	if len(data) <= 0 {
+		return ErrorNoParameter.Format("data")
+	}
+	// Provenance can only be stored with a stored chart. Validate() can't be called
+	// because it requires a read lock
+	statusData, err := v.Backend.GetContent(ctx, path.Join(v.Prefix, statusName))
+	if err != nil {
+		return ErrorContentNotFound.Format(v.Prefix)
+	}
+	if string(statusData) != statusSuccess {
+		return ErrorInvalidStatus.Format("version", string(statusData))
+	}
+	err = v.Backend.PutContent(ctx, path.Join(v.Prefix, provenanceName), data)
+	if err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	verifiedKey := path.Join(v.Prefix, provenanceVerifiedName)
+	if verified {
+		err = v.Backend.PutContent(ctx, verifiedKey, []byte{})
+	} else if keyExists(ctx, v.Backend, verifiedKey) {
+		err = v.Backend.Delete(ctx, verifiedKey)
+	}
+	if err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	return nil
+}
+
+// GetProvenance gets provenance data of chart
+func (v *Version) GetProvenance(ctx context.Context) ([]byte, error) {
+	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
+	if !lock.RLock(v.Chart.Space.SpaceManager.LockTimeout) {
+		return nil, ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
+	}
+	defer lock.RUnlock()
+	if err := v.Validate(ctx); err != nil {
+		return nil, err
+	}
+	path := path.Join(v.Prefix, provenanceName)
+	data, err := v.Backend.GetContent(ctx, path)
+	if err != nil {
+		return nil, ErrorContentNotFound.Format(path)
+	}
+	return data, nil
+}
+
+// ProvenanceStatus returns the verification status of the provenance of chart
+func (v *Version) ProvenanceStatus(ctx context.Context) (storage.ProvenanceStatus, error) {
+	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
+	if !lock.RLock(v.Chart.Space.SpaceManager.LockTimeout) {
+		return "", ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
+	}
+	defer lock.RUnlock()
+	if err := v.Validate(ctx); err != nil {
+		return "", err
+	}
+	if !keyExists(ctx, v.Backend, path.Join(v.Prefix, provenanceName)) {
+		return storage.ProvenanceNone, nil
+	}
+	if !keyExists(ctx, v.Backend, path.Join(v.Prefix, provenanceVerifiedName)) {
+		return storage.ProvenanceUnverified, nil
+	}
+	return storage.ProvenanceVerified, nil
+}
+
 // Validate validates whether the chart is valid
 func (v *Version) Validate(ctx context.Context) error {
 	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
@@ -477,6 +806,11 @@ func (v *Version) Validate(ctx context.Context) error {
 		return ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
 	}
 	defer lock.RUnlock()
+	return v.validate(ctx)
+}
+
+// validate validates the status of chart. The version must be locked by caller
+func (v *Version) validate(ctx context.Context) error {
 	data, err := v.Backend.GetContent(ctx, path.Join(v.Prefix, statusName))
 	if err != nil {
 		return ErrorContentNotFound.Format(v.Prefix)
@@ -534,16 +868,101 @@ func (v *Version) Values(ctx context.Context) ([]byte, error) {
 	return data, nil
 }
 
+// ModTime returns the time when chart data was stored last time
+func (v *Version) ModTime(ctx context.Context) (time.Time, error) {
+	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
+	if !lock.RLock(v.Chart.Space.SpaceManager.LockTimeout) {
+		return time.Time{}, ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
+	}
+	defer lock.RUnlock()
+	if err := v.Validate(ctx); err != nil {
+		return time.Time{}, err
+	}
+	// metadata is rewritten every time chart data is stored
+	info, err := v.Backend.Stat(ctx, path.Join(v.Prefix, metadataName))
+	if err != nil {
+		return time.Time{}, ErrorContentNotFound.Format(v.Prefix)
+	}
+	return info.ModTime(), nil
+}
+
+// Digest returns the sha256 digest of chart data in hex
+func (v *Version) Digest(ctx context.Context) (string, error) {
+	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
+	if !lock.RLock(v.Chart.Space.SpaceManager.LockTimeout) {
+		return "", ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
+	}
+	defer lock.RUnlock()
+	if err := v.Validate(ctx); err != nil {
+		return "", err
+	}
+	return v.digest(ctx)
+}
+
+// digest returns the digest of chart data. The version must be locked by caller
+func (v *Version) digest(ctx context.Context) (string, error) {
+	// a blob is named by the digest of its data
+	referenceKey := path.Join(v.Prefix, referenceName)
+	if keyExists(ctx, v.Backend, referenceKey) {
+		return readReference(ctx, v.Backend, referenceKey)
+	}
+	data, err := v.Backend.GetContent(ctx, path.Join(v.Prefix, chartPackageName))
+	if err != nil {
+		return "", ErrorContentNotFound.Format(v.Prefix)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Size returns the size of chart data in bytes
+func (v *Version) Size(ctx context.Context) (int64, error) {
+	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
+	if !lock.RLock(v.Chart.Space.SpaceManager.LockTimeout) {
+		return 0, ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
+	}
+	defer lock.RUnlock()
+	if err := v.Validate(ctx); err != nil {
+		return 0, err
+	}
+	return v.Chart.Space.SpaceManager.archiveSize(ctx, v.Prefix)
+}
+
+// archiveSize returns the size of the chart data of a version stored in prefix
+func (sm *SpaceManager) archiveSize(ctx context.Context, prefix string) (int64, error) {
+	key := path.Join(prefix, chartPackageName)
+	referenceKey := path.Join(prefix, referenceName)
+	if keyExists(ctx, sm.Backend, referenceKey) {
+		digest, err := readReference(ctx, sm.Backend, referenceKey)
+		if err != nil {
+			return 0, err
+		}
+		key = path.Join(sm.blobPrefix(digest), blobDataName)
+	}
+	info, err := sm.Backend.Stat(ctx, key)
+	if err != nil {
+		return 0, ErrorContentNotFound.Format(key)
+	}
+	return info.Size(), nil
+}
+
 var nameFilter = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
 
+// reservedPrefix is the prefix of the files and directories which are stored beside
+// spaces, charts and versions, like `.blobs` and `.info`. They can't conflict with names
+// and version numbers, because nameFilter and versionFilter require them to start with a
+// letter or digit. So they are skipped when spaces, charts and versions are listed
+const reservedPrefix = "."
+
 // validateName validates whether the name can be used
 func validateName(name string) bool {
 	return nameFilter.MatchString(name)
 }
 
-var versionFilter = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+$`)
+// versionFilter matches semver versions with optional pre-release tags. Build metadata
+// like `+build.1` is not allowed because `+` can't be used in keys of storage drivers
+var versionFilter = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+(-[0-9A-Za-z-]+(\.[0-9A-Za-z-]+)*)?$`)
 
-// validateVersion validates whether the name can be used
+// validateVersion validates whether the version can be used
 func validateVersion(version string) bool {
 	return versionFilter.MatchString(version)
 }
@@ -599,6 +1018,19 @@ func deleteKeys(ctx context.Context, backend driver.StorageDriver, prefix string
 	return nil
 }
 
+// openKey returns a reader of the content in key and the size of content
+func openKey(ctx context.Context, backend driver.StorageDriver, key string) (io.ReadCloser, int64, error) {
+	info, err := backend.Stat(ctx, key)
+	if err != nil || info.IsDir() {
+		return nil, 0, ErrorContentNotFound.Format(key)
+	}
+	reader, err := backend.Reader(ctx, key, 0)
+	if err != nil {
+		return nil, 0, ErrorContentNotFound.Format(key)
+	}
+	return reader, info.Size(), nil
+}
+
 // keyExists check whether the key exists
 func keyExists(ctx context.Context, backend driver.StorageDriver, key string) bool {
 	_, err := backend.Stat(ctx, key)
@@ -612,39 +1044,23 @@ func (p StringSlice) Len() int           { return len(p) }
 func (p StringSlice) Less(i, j int) bool { return p[i] < p[j] }
 func (p StringSlice) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
 
-// parseVersionNumber parses version string to int
-func parseVersionNumber(version string) []int {
-	elements := strings.Split(version, ".")
-	numbers := make([]int, 0, len(elements))
-	for _, ele := range elements {
-		num, err := strconv.Atoi(ele)
-		if err != nil {
-			// If came here, There is a bug in current manager.
-			log.Panicln(err)
-		}
-		numbers = append(numbers, num)
+// parseVersionNumber parses a version which is validated by validateVersion
+func parseVersionNumber(version string) *semver.Version {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		// If came here, There is a bug in current manager.
+		log.Panicln(err)
 	}
-	return numbers
+	return v
 }
 
-// VersionSlice attaches the methods of Interface to []string, sorting in increasing order.
+// VersionSlice attaches the methods of Interface to []string, sorting in increasing semver order.
+// A pre-release version is lower than its normal version, like 1.0.0-rc.1 < 1.0.0.
 type VersionSlice []string
 
 func (p VersionSlice) Len() int { return len(p) }
 func (p VersionSlice) Less(i, j int) bool {
-	vni := parseVersionNumber(p[i])
-	vnj := parseVersionNumber(p[j])
-	for pos := 0; pos < len(vni) && pos < len(vnj); pos++ {
-		switch {
-		case vni[pos] < vnj[pos]:
-			// less
-			return true
-		case vni[pos] > vnj[pos]:
-			return false
-		}
-	}
-	// equal
-	return false
+	return parseVersionNumber(p[i]).LessThan(parseVersionNumber(p[j]))
 }
 func (p VersionSlice) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
 
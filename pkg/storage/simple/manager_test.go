@@ -0,0 +1,283 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/storage/driver"
+	dcontext "github.com/docker/distribution/context"
+)
+
+func TestSortVersions(t *testing.T) {
+	versions := sortVersions([]string{"1.10.0", "1.9.0", "2.0.0-rc.1", "2.0.0", "2.0.0-beta.2", "2.0.0-beta.10", "0.1.0"})
+	expected := []string{"0.1.0", "1.9.0", "1.10.0", "2.0.0-beta.2", "2.0.0-beta.10", "2.0.0-rc.1", "2.0.0"}
+	if !reflect.DeepEqual(versions, expected) {
+		t.Errorf("expected %v, but got %v", expected, versions)
+	}
+}
+
+func TestValidateVersion(t *testing.T) {
+	for version, valid := range map[string]bool{
+		"1.0.0":         true,
+		"1.0.0-rc.1":    true,
+		"1.0.0-alpha-1": true,
+		"1.0":           false,
+		"v1.0.0":        false,
+		"1.0.0+build.1": false,
+		"1.0.0-":        false,
+		"1.0.0-rc..1":   false,
+	} {
+		if validateVersion(version) != valid {
+			t.Errorf("expected validity of %s to be %v", version, valid)
+		}
+	}
+}
+
+func TestProvenanceStatus(t *testing.T) {
+	sm, cleanup := newTestSpaceManager(t)
+	defer cleanup()
+	data := newTestArchive(t, "chart", "1.0.0", "")
+	putTestVersion(t, sm, "space", "chart", "1.0.0", data)
+	ctx := context.Background()
+	s, _ := sm.Space(ctx, "space")
+	c, _ := s.Chart(ctx, "chart")
+	v, err := c.Version(ctx, "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStatus := func(expected storage.ProvenanceStatus) {
+		status, err := v.ProvenanceStatus(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != expected {
+			t.Errorf("expected provenance status %s, but got %s", expected, status)
+		}
+	}
+	expectStatus(storage.ProvenanceNone)
+	if err = v.PutProvenance(ctx, []byte("prov"), true); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(storage.ProvenanceVerified)
+	if err = v.PutProvenance(ctx, []byte("prov"), false); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(storage.ProvenanceUnverified)
+	// new chart data removes the provenance of previous data
+	if err = v.PutProvenance(ctx, []byte("prov"), true); err != nil {
+		t.Fatal(err)
+	}
+	if err = v.PutContent(ctx, data); err != nil {
+		t.Fatal(err)
+	}
+	expectStatus(storage.ProvenanceNone)
+}
+
+func TestUpdate(t *testing.T) {
+	sm, cleanup := newTestSpaceManager(t)
+	defer cleanup()
+	data := newTestArchive(t, "chart", "1.0.0", "")
+	putTestVersion(t, sm, "space", "chart", "1.0.0", data)
+	ctx := context.Background()
+	s, _ := sm.Space(ctx, "space")
+	c, _ := s.Chart(ctx, "chart")
+	v, err := c.Version(ctx, "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, err := v.Digest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := newTestArchive(t, "chart", "1.0.0", "key: value\n")
+	if err = v.Update(ctx, digest, func(current []byte) ([]byte, error) {
+		if !reflect.DeepEqual(current, data) {
+			t.Errorf("expected current data to be the stored data")
+		}
+		return updated, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// the digest has been changed by the update
+	err = v.Update(ctx, digest, func(current []byte) ([]byte, error) {
+		t.Errorf("expected update not to be called")
+		return current, nil
+	})
+	if !ErrorVersionConflict.Equal(err) {
+		t.Errorf("expected a version conflict, but got %v", err)
+	}
+	values, err := v.Values(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(values) != `{"key":"value"}` {
+		t.Errorf("expected updated values, but got %s", values)
+	}
+	// an empty digest skips the check
+	if err = v.Update(ctx, "", func(current []byte) ([]byte, error) {
+		return data, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// failingDriver fails to put content to a key with specific suffix once
+type failingDriver struct {
+	driver.StorageDriver
+	suffix string
+	failed bool
+}
+
+// PutContent stores content to key unless it's the key to fail
+func (d *failingDriver) PutContent(ctx dcontext.Context, key string, content []byte) error {
+	if !d.failed && strings.HasSuffix(key, d.suffix) {
+		d.failed = true
+		return errors.New("injected failure")
+	}
+	return d.StorageDriver.PutContent(ctx, key, content)
+}
+
+func TestFailedUpdateKeepsVersion(t *testing.T) {
+	sm, cleanup := newTestSpaceManager(t)
+	defer cleanup()
+	data := newTestArchive(t, "chart", "1.0.0", "key: value\n")
+	putTestVersion(t, sm, "space", "chart", "1.0.0", data)
+	ctx := context.Background()
+	sm.Backend = &failingDriver{StorageDriver: sm.Backend, suffix: "/" + valuesName}
+	s, _ := sm.Space(ctx, "space")
+	c, _ := s.Chart(ctx, "chart")
+	v, err := c.Version(ctx, "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = v.Update(ctx, "", func(current []byte) ([]byte, error) {
+		return newTestArchive(t, "chart", "1.0.0", "key: another value\n"), nil
+	})
+	if err == nil {
+		t.Fatal("expected update to fail")
+	}
+	expectKept := func() {
+		content, err := v.GetContent(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(content, data) {
+			t.Fatal("expected previous content to be kept")
+		}
+		values, err := v.Values(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(values) != `{"key":"value"}` {
+			t.Errorf("expected previous values, but got %s", values)
+		}
+	}
+	expectKept()
+	if _, err = v.Metadata(ctx); err != nil {
+		t.Fatal(err)
+	}
+	result, err := sm.CollectGarbage(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Versions) != 0 {
+		t.Errorf("expected no version to be collected, but got %v", result.Versions)
+	}
+	expectKept()
+	if err = v.PutContent(ctx, data); err != nil {
+		t.Errorf("expected version to be writable, but got %v", err)
+	}
+}
+
+func TestFailedPutRemovesNewVersion(t *testing.T) {
+	sm, cleanup := newTestSpaceManager(t)
+	defer cleanup()
+	putTestVersion(t, sm, "space", "chart", "1.0.0", newTestArchive(t, "chart", "1.0.0", ""))
+	ctx := context.Background()
+	sm.Backend = &failingDriver{StorageDriver: sm.Backend, suffix: "/" + valuesName}
+	s, _ := sm.Space(ctx, "space")
+	c, _ := s.Chart(ctx, "chart")
+	v, err := c.Version(ctx, "2.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = v.PutContent(ctx, newTestArchive(t, "chart", "2.0.0", "")); err == nil {
+		t.Fatal("expected put to fail")
+	}
+	versions, err := c.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"1.0.0"}; !reflect.DeepEqual(versions, expected) {
+		t.Errorf("expected versions %v, but got %v", expected, versions)
+	}
+}
+
+// newLargeTestVersion stores a chart with 16MB of random values and returns the version
+func newLargeTestVersion(b *testing.B) (*Version, func()) {
+	sm, cleanup := newTestSpaceManager(b)
+	random := make([]byte, 8<<20)
+	if _, err := rand.Read(random); err != nil {
+		b.Fatal(err)
+	}
+	data := newTestArchive(b, "chart", "1.0.0", "data: "+hex.EncodeToString(random)+"\n")
+	putTestVersion(b, sm, "space", "chart", "1.0.0", data)
+	ctx := context.Background()
+	s, _ := sm.Space(ctx, "space")
+	c, _ := s.Chart(ctx, "chart")
+	v, err := c.Version(ctx, "1.0.0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	return v.(*Version), cleanup
+}
+
+func BenchmarkGetContent(b *testing.B) {
+	v, cleanup := newLargeTestVersion(b)
+	defer cleanup()
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, err := v.GetContent(ctx)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err = ioutil.Discard.Write(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkStreamContent(b *testing.B) {
+	v, cleanup := newLargeTestVersion(b)
+	defer cleanup()
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader, _, err := v.StreamContent(ctx)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err = io.Copy(ioutil.Discard, reader); err != nil {
+			b.Fatal(err)
+		}
+		reader.Close()
+	}
+}
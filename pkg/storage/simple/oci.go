@@ -0,0 +1,161 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"context"
+	"io"
+	"path"
+	"regexp"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// blobLinksName is the name of the directory which records blobs put in a space. A blob
+// can only be read by the spaces which have its link
+const blobLinksName = reservedPrefix + "links"
+
+// manifestName is the name of the file which stores the OCI manifest of a version
+const manifestName = "oci.manifest"
+
+// configName is the name of the file which stores the OCI config of a version
+const configName = "oci.config"
+
+// digestFilter matches sha256 digests in hex
+var digestFilter = regexp.MustCompile("^[0-9a-f]{64}$")
+
+// PutBlob stores the data received by upload as a blob of current space if its sha256
+// digest in hex is digest. The upload is consumed
+func (s *Space) PutBlob(ctx context.Context, upload storage.Upload, digest string) error {
+	u, ok := upload.(*Upload)
+	if !ok || u.Space.Name() != s.Name() {
+		return ErrorInvalidParam.Format("upload", upload.ID())
+	}
+	if !digestFilter.MatchString(digest) {
+		return ErrorInvalidParam.Format("digest", digest)
+	}
+	if _, err := u.moveToBlob(ctx, digest, false); err != nil {
+		return err
+	}
+	if err := s.SpaceManager.Backend.PutContent(ctx, s.blobLinkKey(digest), []byte{}); err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	return u.Delete(ctx)
+}
+
+// Blob returns a reader of a blob put by PutBlob and the size of blob
+func (s *Space) Blob(ctx context.Context, digest string) (io.ReadCloser, int64, error) {
+	if !s.hasBlob(ctx, digest) {
+		return nil, 0, ErrorContentNotFound.Format("blob " + digest)
+	}
+	return s.SpaceManager.openBlob(ctx, digest)
+}
+
+// hasBlob returns whether current space has the link of a blob
+func (s *Space) hasBlob(ctx context.Context, digest string) bool {
+	return digestFilter.MatchString(digest) && keyExists(ctx, s.SpaceManager.Backend, s.blobLinkKey(digest))
+}
+
+// blobLinkKey returns the key of the link of a blob
+func (s *Space) blobLinkKey(digest string) string {
+	return path.Join(s.Prefix, blobLinksName, digest)
+}
+
+// collectBlobLinks removes links of current space whose blobs have been removed and which
+// are not modified since before
+func (s *Space) collectBlobLinks(ctx context.Context, before time.Time) error {
+	backend := s.SpaceManager.Backend
+	prefix := path.Join(s.Prefix, blobLinksName)
+	if !keyExists(ctx, backend, prefix) {
+		return nil
+	}
+	digests, err := list(ctx, backend, prefix, digestFilter.MatchString, nil)
+	if err != nil {
+		return err
+	}
+	for _, digest := range digests {
+		if keyExists(ctx, backend, path.Join(s.SpaceManager.blobPrefix(digest), blobDataName)) {
+			continue
+		}
+		info, err := backend.Stat(ctx, s.blobLinkKey(digest))
+		if err != nil || info.ModTime().After(before) {
+			continue
+		}
+		if err = backend.Delete(ctx, s.blobLinkKey(digest)); err != nil {
+			return ErrorInternalUnknown.Format(err)
+		}
+		log.FromContext(ctx).Infof("Removed link of blob %s in space %s", digest, s.Name())
+	}
+	return nil
+}
+
+// PutBlob stores a blob put by PutBlob of the space as chart data
+func (v *Version) PutBlob(ctx context.Context, digest string) error {
+	sm := v.Chart.Space.SpaceManager
+	if !v.Chart.Space.hasBlob(ctx, digest) {
+		return ErrorContentNotFound.Format("blob " + digest)
+	}
+	return v.putChart(ctx, func() (io.ReadCloser, error) {
+		reader, _, err := sm.openBlob(ctx, digest)
+		return reader, err
+	}, func() (string, error) {
+		return digest, sm.referenceBlob(ctx, digest)
+	})
+}
+
+// PutManifest stores the OCI manifest and config pushed with chart data
+func (v *Version) PutManifest(ctx context.Context, manifest, config []byte) error {
+	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
+	if !lock.Lock(v.Chart.Space.SpaceManager.LockTimeout) {
+		return ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
+	}
+	defer lock.Unlock()
+	if len(manifest) <= 0 {
+		return ErrorNoParameter.Format("manifest")
+	}
+	// Manifest can only be stored with a stored chart like provenance
+	statusData, err := v.Backend.GetContent(ctx, path.Join(v.Prefix, statusName))
+	if err != nil {
+		return ErrorContentNotFound.Format(v.Prefix)
+	}
+	if string(statusData) != statusSuccess {
+		return ErrorInvalidStatus.Format("version", string(statusData))
+	}
+	if err = v.Backend.PutContent(ctx, path.Join(v.Prefix, configName), config); err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	if err = v.Backend.PutContent(ctx, path.Join(v.Prefix, manifestName), manifest); err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	return nil
+}
+
+// Manifest returns the OCI manifest and config of chart. They are nil if the version
+// is not pushed by an OCI client
+func (v *Version) Manifest(ctx context.Context) ([]byte, []byte, error) {
+	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
+	if !lock.RLock(v.Chart.Space.SpaceManager.LockTimeout) {
+		return nil, nil, ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
+	}
+	defer lock.RUnlock()
+	if err := v.Validate(ctx); err != nil {
+		return nil, nil, err
+	}
+	manifestKey := path.Join(v.Prefix, manifestName)
+	if !keyExists(ctx, v.Backend, manifestKey) {
+		return nil, nil, nil
+	}
+	manifest, err := v.Backend.GetContent(ctx, manifestKey)
+	if err != nil {
+		return nil, nil, ErrorInternalUnknown.Format(err)
+	}
+	config, err := v.Backend.GetContent(ctx, path.Join(v.Prefix, configName))
+	if err != nil {
+		return nil, nil, ErrorInternalUnknown.Format(err)
+	}
+	return manifest, config, nil
+}
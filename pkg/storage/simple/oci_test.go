@@ -0,0 +1,79 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"testing"
+)
+
+func TestPutBlob(t *testing.T) {
+	sm, cleanup := newTestSpaceManager(t)
+	defer cleanup()
+	ctx := context.Background()
+	data := newTestArchive(t, "chart", "1.0.0", "key: value\n")
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	s, err := sm.Create(ctx, "space")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := sm.Create(ctx, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload, err := s.CreateUpload(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = upload.Append(ctx, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.PutBlob(ctx, upload, digest[1:]+"0"); !ErrorInvalidParam.Equal(err) {
+		t.Fatalf("expected digest mismatch, but got %v", err)
+	}
+	if err = s.PutBlob(ctx, upload, digest); err != nil {
+		t.Fatal(err)
+	}
+	reader, size, err := s.Blob(ctx, digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := ioutil.ReadAll(reader)
+	reader.Close()
+	if size != int64(len(data)) || !bytes.Equal(content, data) {
+		t.Errorf("expected blob of %d bytes, but got %d bytes", len(data), len(content))
+	}
+	// blobs can only be read by the space in which they are put
+	if _, _, err = other.Blob(ctx, digest); !ErrorContentNotFound.Equal(err) {
+		t.Errorf("expected blob to be invisible in other space, but got %v", err)
+	}
+
+	c, _ := s.Chart(ctx, "chart")
+	v, _ := c.Version(ctx, "1.0.0")
+	if err = v.PutManifest(ctx, []byte("{}"), []byte("{}")); err == nil {
+		t.Fatal("expected manifest to be rejected without chart")
+	}
+	if err = v.PutBlob(ctx, digest); err != nil {
+		t.Fatal(err)
+	}
+	if refcount, _ := sm.refcount(ctx, digest); refcount != 1 {
+		t.Errorf("expected refcount 1, but got %d", refcount)
+	}
+	if manifest, config, err := v.Manifest(ctx); err != nil || manifest != nil || config != nil {
+		t.Errorf("expected no manifest, but got %q, %q: %v", manifest, config, err)
+	}
+	if err = v.PutManifest(ctx, []byte("manifest"), []byte("config")); err != nil {
+		t.Fatal(err)
+	}
+	manifest, config, err := v.Manifest(ctx)
+	if err != nil || string(manifest) != "manifest" || string(config) != "config" {
+		t.Errorf("expected stored manifest, but got %q, %q: %v", manifest, config, err)
+	}
+}
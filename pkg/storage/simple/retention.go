@@ -0,0 +1,108 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/storage/driver"
+)
+
+// retentionName is the name of the file which stores the retention policy of a chart
+// or a space
+const retentionName = reservedPrefix + "retention"
+
+// RetentionPolicy returns the retention policy of current chart. It returns nil if
+// the chart has no policy
+func (c *Chart) RetentionPolicy(ctx context.Context) (*storage.RetentionPolicy, error) {
+	lock := c.Space.SpaceManager.Lock.Get(c.Space.Name(), c.Name())
+	if !lock.RLock(c.Space.SpaceManager.LockTimeout) {
+		return nil, ErrorLocking.Format("chart", c.Space.Name()+"/"+c.Name())
+	}
+	defer lock.RUnlock()
+	return readRetentionPolicy(ctx, c.Space.SpaceManager.Backend, path.Join(c.Prefix, retentionName))
+}
+
+// SetRetentionPolicy sets the retention policy of current chart. A nil policy
+// removes the policy. The chart must exist
+func (c *Chart) SetRetentionPolicy(ctx context.Context, policy *storage.RetentionPolicy) error {
+	if !c.Exists(ctx) {
+		return ErrorContentNotFound.Format(c.Space.Name() + "/" + c.Name())
+	}
+	lock := c.Space.SpaceManager.Lock.Get(c.Space.Name(), c.Name())
+	if !lock.Lock(c.Space.SpaceManager.LockTimeout) {
+		return ErrorLocking.Format("chart", c.Space.Name()+"/"+c.Name())
+	}
+	defer lock.Unlock()
+	return writeRetentionPolicy(ctx, c.Space.SpaceManager.Backend, path.Join(c.Prefix, retentionName), policy)
+}
+
+// RetentionPolicy returns the retention policy of current space. It returns nil if
+// the space has no policy
+func (s *Space) RetentionPolicy(ctx context.Context) (*storage.RetentionPolicy, error) {
+	lock := s.SpaceManager.Lock.Get(s.Name())
+	if !lock.RLock(s.SpaceManager.LockTimeout) {
+		return nil, ErrorLocking.Format("space", s.Name())
+	}
+	defer lock.RUnlock()
+	return readRetentionPolicy(ctx, s.SpaceManager.Backend, path.Join(s.Prefix, retentionName))
+}
+
+// SetRetentionPolicy sets the retention policy of current space. A nil policy
+// removes the policy. The space must exist
+func (s *Space) SetRetentionPolicy(ctx context.Context, policy *storage.RetentionPolicy) error {
+	if !s.Exists(ctx) {
+		return ErrorContentNotFound.Format(s.Name())
+	}
+	lock := s.SpaceManager.Lock.Get(s.Name())
+	if !lock.Lock(s.SpaceManager.LockTimeout) {
+		return ErrorLocking.Format("space", s.Name())
+	}
+	defer lock.Unlock()
+	return writeRetentionPolicy(ctx, s.SpaceManager.Backend, path.Join(s.Prefix, retentionName), policy)
+}
+
+// readRetentionPolicy reads the retention policy in key. It returns nil if key does not exist
+func readRetentionPolicy(ctx context.Context, backend driver.StorageDriver, key string) (*storage.RetentionPolicy, error) {
+	if !keyExists(ctx, backend, key) {
+		return nil, nil
+	}
+	data, err := backend.GetContent(ctx, key)
+	if err != nil {
+		return nil, ErrorInternalUnknown.Format(err)
+	}
+	policy := &storage.RetentionPolicy{}
+	if err = json.Unmarshal(data, policy); err != nil {
+		return nil, ErrorInternalUnknown.Format(err)
+	}
+	return policy, nil
+}
+
+// writeRetentionPolicy stores policy in key. A nil policy removes key
+func writeRetentionPolicy(ctx context.Context, backend driver.StorageDriver, key string, policy *storage.RetentionPolicy) error {
+	if policy == nil {
+		if !keyExists(ctx, backend, key) {
+			return nil
+		}
+		if err := backend.Delete(ctx, key); err != nil {
+			return ErrorInternalUnknown.Format(err)
+		}
+		return nil
+	}
+	if err := policy.Validate(); err != nil {
+		return ErrorInvalidParam.Format("retention policy", err)
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	if err = backend.PutContent(ctx, key, data); err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	return nil
+}
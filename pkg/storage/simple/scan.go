@@ -0,0 +1,55 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"context"
+	"path"
+)
+
+// scanReportName is the name of the file which stores the scan report of a version
+const scanReportName = "scan.json"
+
+// PutScanReport stores the report of scanning chart data. PutContent removes the report
+// of previous chart data, so it should be called after PutContent
+func (v *Version) PutScanReport(ctx context.Context, report []byte) error {
+	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
+	if !lock.Lock(v.Chart.Space.SpaceManager.LockTimeout) {
+		return ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
+	}
+	defer lock.Unlock()
+	if len(report) <= 0 {
+		return ErrorNoParameter.Format("report")
+	}
+	// scan report can only be stored with a stored chart like provenance
+	if err := v.validate(ctx); err != nil {
+		return err
+	}
+	if err := v.Backend.PutContent(ctx, path.Join(v.Prefix, scanReportName), report); err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	return nil
+}
+
+// ScanReport returns the report of scanning chart data. It's nil if the chart is not scanned
+func (v *Version) ScanReport(ctx context.Context) ([]byte, error) {
+	lock := v.Chart.Space.SpaceManager.Lock.Get(v.Chart.Space.Name(), v.Chart.Name(), v.Number())
+	if !lock.RLock(v.Chart.Space.SpaceManager.LockTimeout) {
+		return nil, ErrorLocking.Format("version", v.Chart.Space.Name()+"/"+v.Chart.Name()+"/"+v.Number())
+	}
+	defer lock.RUnlock()
+	if err := v.validate(ctx); err != nil {
+		return nil, err
+	}
+	key := path.Join(v.Prefix, scanReportName)
+	if !keyExists(ctx, v.Backend, key) {
+		return nil, nil
+	}
+	report, err := v.Backend.GetContent(ctx, key)
+	if err != nil {
+		return nil, ErrorInternalUnknown.Format(err)
+	}
+	return report, nil
+}
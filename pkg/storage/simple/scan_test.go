@@ -0,0 +1,55 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPutScanReport(t *testing.T) {
+	sm, cleanup := newTestSpaceManager(t)
+	defer cleanup()
+	putTestVersion(t, sm, "space", "chart", "1.0.0", newTestArchive(t, "chart", "1.0.0", ""))
+	ctx := context.Background()
+	s, _ := sm.Space(ctx, "space")
+	c, _ := s.Chart(ctx, "chart")
+	v, err := c.Version(ctx, "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report, err := v.ScanReport(ctx); err != nil || report != nil {
+		t.Fatalf("expected no report of an unscanned version, but got %q and %v", report, err)
+	}
+	if err = v.PutScanReport(ctx, []byte(`{"passed":true}`)); err != nil {
+		t.Fatal(err)
+	}
+	if report, err := v.ScanReport(ctx); err != nil || string(report) != `{"passed":true}` {
+		t.Errorf("expected the stored report, but got %q and %v", report, err)
+	}
+
+	// the report is moved with a trashed version
+	if err = c.Trash(ctx, "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Restore(ctx, "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if report, err := v.ScanReport(ctx); err != nil || len(report) <= 0 {
+		t.Errorf("expected the report to be restored, but got %q and %v", report, err)
+	}
+	// the report of previous chart data is removed
+	if err = v.PutContent(ctx, newTestArchive(t, "chart", "1.0.0", "a: 1")); err != nil {
+		t.Fatal(err)
+	}
+	if report, err := v.ScanReport(ctx); err != nil || report != nil {
+		t.Errorf("expected the report to be removed with previous chart data, but got %q and %v", report, err)
+	}
+
+	other, _ := c.Version(ctx, "2.0.0")
+	if err = other.PutScanReport(ctx, []byte(`{}`)); err == nil {
+		t.Error("expected an error for a version which is not stored")
+	}
+}
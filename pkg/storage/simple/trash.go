@@ -0,0 +1,380 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/storage/driver"
+)
+
+// trashName is the name of the trash directory in every space. Trashed versions are
+// stored in `/space/.trash/chart/version`
+const trashName = reservedPrefix + "trash"
+
+// deletedName is the name of the file which records the deleted time of a trashed version
+const deletedName = ".deleted"
+
+// chartFiles are files of a chart beside its versions. They are moved to trash with the
+// last version of chart, so they are restored with it
+var chartFiles = []string{attributesName, retentionName}
+
+// versionFiles are all files of a version
+var versionFiles = []string{metadataName, valuesName, statusName}
+
+// optionalVersionFiles are files which may not exist in a version. A version has
+// either a reference or an archive stored before deduplication
+var optionalVersionFiles = []string{referenceName, chartPackageName, provenanceName, provenanceVerifiedName,
+	downloadsName, manifestName, configName, attributesName, scanReportName, readmeName, iconName, schemaName}
+
+// trashPrefix returns the trash prefix of current chart
+func (c *Chart) trashPrefix() string {
+	return path.Join(c.Space.Prefix, trashName, c.Chart)
+}
+
+// Trash moves specific version to trash. If the trash has a version with same
+// number, the old one will be replaced. The chart is deleted if it has no version,
+// and its files are moved to trash too.
+func (c *Chart) Trash(ctx context.Context, version string) error {
+	if !validateVersion(version) {
+		return ErrorInvalidParam.Format("version", version)
+	}
+	lock := c.Space.SpaceManager.Lock.Get(c.Space.Name(), c.Name(), version)
+	if !lock.Lock(c.Space.SpaceManager.LockTimeout) {
+		return ErrorLocking.Format("version", c.Space.Name()+"/"+c.Name()+"/"+version)
+	}
+	backend := c.Space.SpaceManager.Backend
+	source := path.Join(c.Prefix, version)
+	target := path.Join(c.trashPrefix(), version)
+	err := func() error {
+		if !keyExists(ctx, backend, source) {
+			return ErrorContentNotFound.Format(source)
+		}
+		if keyExists(ctx, backend, target) {
+			if err := c.Space.SpaceManager.releaseReferences(ctx, target); err != nil {
+				return err
+			}
+			if err := deleteKeys(ctx, backend, target, true); err != nil {
+				return err
+			}
+		}
+		if err := moveVersionFiles(ctx, backend, source, target); err != nil {
+			return err
+		}
+		deleted := []byte(time.Now().UTC().Format(time.RFC3339))
+		if err := backend.PutContent(ctx, path.Join(target, deletedName), deleted); err != nil {
+			return ErrorInternalUnknown.Format(err)
+		}
+		return deleteEmptyKey(ctx, backend, source)
+	}()
+	// unlock before return
+	lock.Unlock()
+	if err != nil {
+		return err
+	}
+	return deleteEmptyChart(ctx, c)
+}
+
+// Restore moves specific version from trash back to current chart. If current
+// chart has a version with same number, it returns an error. Files of chart in
+// trash are restored unless current chart has them.
+func (c *Chart) Restore(ctx context.Context, version string) error {
+	if !validateVersion(version) {
+		return ErrorInvalidParam.Format("version", version)
+	}
+	lock := c.Space.SpaceManager.Lock.Get(c.Space.Name(), c.Name(), version)
+	if !lock.Lock(c.Space.SpaceManager.LockTimeout) {
+		return ErrorLocking.Format("version", c.Space.Name()+"/"+c.Name()+"/"+version)
+	}
+	backend := c.Space.SpaceManager.Backend
+	source := path.Join(c.trashPrefix(), version)
+	target := path.Join(c.Prefix, version)
+	err := func() error {
+		if !keyExists(ctx, backend, source) {
+			return ErrorContentNotFound.Format(source)
+		}
+		if keyExists(ctx, backend, target) {
+			return ErrorResourceExist.Format(c.Space.Name() + "/" + c.Name() + "/" + version)
+		}
+		if err := moveVersionFiles(ctx, backend, source, target); err != nil {
+			return err
+		}
+		if err := backend.Delete(ctx, path.Join(source, deletedName)); err != nil {
+			return ErrorInternalUnknown.Format(err)
+		}
+		return deleteEmptyKey(ctx, backend, source)
+	}()
+	// unlock before return
+	lock.Unlock()
+	if err != nil {
+		return err
+	}
+	if err = restoreChartFiles(ctx, c); err != nil {
+		return err
+	}
+	return deleteEmptyTrash(ctx, c)
+}
+
+// TrashedVersionMetadata returns all metadata of trashed versions in current chart
+func (c *Chart) TrashedVersionMetadata(ctx context.Context) ([]*storage.Metadata, error) {
+	versions, err := c.trashedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mtList := make([]*storage.Metadata, 0, len(versions))
+	for _, version := range versions {
+		mt, err := c.trashedMetadata(ctx, version)
+		if err != nil {
+			return nil, err
+		}
+		mtList = append(mtList, mt)
+	}
+	return mtList, nil
+}
+
+// trashedVersions lists all trashed version numbers of current chart
+func (c *Chart) trashedVersions(ctx context.Context) ([]string, error) {
+	lock := c.Space.SpaceManager.Lock.Get(c.Space.Name(), c.Name())
+	if !lock.RLock(c.Space.SpaceManager.LockTimeout) {
+		return nil, ErrorLocking.Format("chart", c.Space.Name()+"/"+c.Name())
+	}
+	defer lock.RUnlock()
+	backend := c.Space.SpaceManager.Backend
+	if !keyExists(ctx, backend, c.trashPrefix()) {
+		return []string{}, nil
+	}
+	return list(ctx, backend, c.trashPrefix(), validateVersion, sortVersions)
+}
+
+// trashedMetadata returns the metadata of a trashed version
+func (c *Chart) trashedMetadata(ctx context.Context, version string) (*storage.Metadata, error) {
+	lock := c.Space.SpaceManager.Lock.Get(c.Space.Name(), c.Name(), version)
+	if !lock.RLock(c.Space.SpaceManager.LockTimeout) {
+		return nil, ErrorLocking.Format("version", c.Space.Name()+"/"+c.Name()+"/"+version)
+	}
+	defer lock.RUnlock()
+	backend := c.Space.SpaceManager.Backend
+	prefix := path.Join(c.trashPrefix(), version)
+	data, err := backend.GetContent(ctx, path.Join(prefix, metadataName))
+	if err != nil {
+		return nil, ErrorContentNotFound.Format(prefix)
+	}
+	meta := &storage.Metadata{}
+	if err = json.Unmarshal(data, meta); err != nil {
+		return nil, ErrorInternalUnknown.Format(err)
+	}
+	deletedAt, err := trashedTime(ctx, c, version)
+	if err != nil {
+		return nil, err
+	}
+	meta.DeletedAt = &deletedAt
+	size, err := c.Space.SpaceManager.archiveSize(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	meta.Size = &size
+	return meta, nil
+}
+
+// TrashedVersionMetadata returns all metadata of trashed versions in current space
+func (s *Space) TrashedVersionMetadata(ctx context.Context) ([]*storage.Metadata, error) {
+	charts, err := s.trashedCharts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mtAll := make([]*storage.Metadata, 0, len(charts))
+	for _, name := range charts {
+		chart, err := NewChart(s, name)
+		if err != nil {
+			return nil, err
+		}
+		mtList, err := chart.TrashedVersionMetadata(ctx)
+		if err != nil {
+			return nil, err
+		}
+		mtAll = append(mtAll, mtList...)
+	}
+	return mtAll, nil
+}
+
+// trashedCharts lists all chart names which have trashed versions in current space
+func (s *Space) trashedCharts(ctx context.Context) ([]string, error) {
+	lock := s.SpaceManager.Lock.Get(s.Name())
+	if !lock.RLock(s.SpaceManager.LockTimeout) {
+		return nil, ErrorLocking.Format("space", s.Name())
+	}
+	defer lock.RUnlock()
+	prefix := path.Join(s.Prefix, trashName)
+	if !keyExists(ctx, s.SpaceManager.Backend, prefix) {
+		return []string{}, nil
+	}
+	return list(ctx, s.SpaceManager.Backend, prefix, validateName, sortNames)
+}
+
+// PurgeTrash permanently deletes trashed versions which are deleted before specific time
+func (sm *SpaceManager) PurgeTrash(ctx context.Context, before time.Time) error {
+	spaces, err := sm.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, spaceName := range spaces {
+		space, err := NewSpace(sm, spaceName)
+		if err != nil {
+			return err
+		}
+		charts, err := space.trashedCharts(ctx)
+		if err != nil {
+			return err
+		}
+		for _, chartName := range charts {
+			chart, err := NewChart(space, chartName)
+			if err != nil {
+				return err
+			}
+			if err = chart.purgeTrash(ctx, before); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// purgeTrash permanently deletes trashed versions of current chart which are deleted before specific time
+func (c *Chart) purgeTrash(ctx context.Context, before time.Time) error {
+	versions, err := c.trashedVersions(ctx)
+	if err != nil {
+		return err
+	}
+	backend := c.Space.SpaceManager.Backend
+	for _, version := range versions {
+		lock := c.Space.SpaceManager.Lock.Get(c.Space.Name(), c.Name(), version)
+		if !lock.Lock(c.Space.SpaceManager.LockTimeout) {
+			return ErrorLocking.Format("version", c.Space.Name()+"/"+c.Name()+"/"+version)
+		}
+		deletedAt, err := trashedTime(ctx, c, version)
+		if err == nil && deletedAt.Before(before) {
+			prefix := path.Join(c.trashPrefix(), version)
+			if err = c.Space.SpaceManager.releaseReferences(ctx, prefix); err == nil {
+				err = deleteKeys(ctx, backend, prefix, true)
+			}
+		}
+		lock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	return deleteEmptyTrash(ctx, c)
+}
+
+// trashedTime returns the deleted time of a trashed version
+func trashedTime(ctx context.Context, c *Chart, version string) (time.Time, error) {
+	key := path.Join(c.trashPrefix(), version, deletedName)
+	data, err := c.Space.SpaceManager.Backend.GetContent(ctx, key)
+	if err != nil {
+		return time.Time{}, ErrorContentNotFound.Format(key)
+	}
+	deletedAt, err := time.Parse(time.RFC3339, string(data))
+	if err != nil {
+		return time.Time{}, ErrorInternalUnknown.Format(err)
+	}
+	return deletedAt, nil
+}
+
+// deleteEmptyChart deletes chart if it has no version. Its files are moved to trash
+func deleteEmptyChart(ctx context.Context, c *Chart) error {
+	lock := c.Space.SpaceManager.Lock.Get(c.Space.Name(), c.Name())
+	if !lock.Lock(c.Space.SpaceManager.LockTimeout) {
+		return ErrorLocking.Format("chart", c.Space.Name()+"/"+c.Name())
+	}
+	defer lock.Unlock()
+	backend := c.Space.SpaceManager.Backend
+	if !keyExists(ctx, backend, c.Prefix) {
+		return nil
+	}
+	versions, err := list(ctx, backend, c.Prefix, validateVersion, nil)
+	if err != nil || len(versions) > 0 {
+		return err
+	}
+	if err = moveChartFiles(ctx, backend, c.Prefix, c.trashPrefix(), true); err != nil {
+		return err
+	}
+	return deleteEmptyKey(ctx, backend, c.Prefix)
+}
+
+// restoreChartFiles moves files of chart from trash back to chart
+func restoreChartFiles(ctx context.Context, c *Chart) error {
+	lock := c.Space.SpaceManager.Lock.Get(c.Space.Name(), c.Name())
+	if !lock.Lock(c.Space.SpaceManager.LockTimeout) {
+		return ErrorLocking.Format("chart", c.Space.Name()+"/"+c.Name())
+	}
+	defer lock.Unlock()
+	return moveChartFiles(ctx, c.Space.SpaceManager.Backend, c.trashPrefix(), c.Prefix, false)
+}
+
+// moveChartFiles moves files of a chart from source to target. A file which exists in
+// target is replaced if replace is true, or it's kept
+func moveChartFiles(ctx context.Context, backend driver.StorageDriver, source, target string, replace bool) error {
+	for _, name := range chartFiles {
+		from, to := path.Join(source, name), path.Join(target, name)
+		if !keyExists(ctx, backend, from) || (!replace && keyExists(ctx, backend, to)) {
+			continue
+		}
+		if err := backend.Move(ctx, from, to); err != nil {
+			return ErrorInternalUnknown.Format(err)
+		}
+	}
+	return nil
+}
+
+// deleteEmptyTrash deletes the trash of chart if it has no version
+func deleteEmptyTrash(ctx context.Context, c *Chart) error {
+	lock := c.Space.SpaceManager.Lock.Get(c.Space.Name(), c.Name())
+	if !lock.Lock(c.Space.SpaceManager.LockTimeout) {
+		return ErrorLocking.Format("chart", c.Space.Name()+"/"+c.Name())
+	}
+	defer lock.Unlock()
+	backend := c.Space.SpaceManager.Backend
+	prefix := c.trashPrefix()
+	if !keyExists(ctx, backend, prefix) {
+		return nil
+	}
+	versions, err := list(ctx, backend, prefix, validateVersion, nil)
+	if err != nil || len(versions) > 0 {
+		return err
+	}
+	return deleteEmptyKey(ctx, backend, prefix)
+}
+
+// moveVersionFiles moves all files of a version from source to target. Storage drivers
+// like s3 can't move a directory, so files are moved one by one.
+func moveVersionFiles(ctx context.Context, backend driver.StorageDriver, source, target string) error {
+	for _, name := range versionFiles {
+		if err := backend.Move(ctx, path.Join(source, name), path.Join(target, name)); err != nil {
+			return ErrorInternalUnknown.Format(err)
+		}
+	}
+	for _, name := range optionalVersionFiles {
+		if !keyExists(ctx, backend, path.Join(source, name)) {
+			continue
+		}
+		if err := backend.Move(ctx, path.Join(source, name), path.Join(target, name)); err != nil {
+			return ErrorInternalUnknown.Format(err)
+		}
+	}
+	return nil
+}
+
+// deleteEmptyKey deletes key if it still exists. Storage drivers like filesystem
+// keep empty directories after moving files.
+func deleteEmptyKey(ctx context.Context, backend driver.StorageDriver, key string) error {
+	if !keyExists(ctx, backend, key) {
+		return nil
+	}
+	return deleteKeys(ctx, backend, key, true)
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+func TestTrashLastVersionKeepsChartFiles(t *testing.T) {
+	sm, cleanup := newTestSpaceManager(t)
+	defer cleanup()
+	putTestVersion(t, sm, "space", "chart", "1.0.0", newTestArchive(t, "chart", "1.0.0", ""))
+	ctx := context.Background()
+	s, _ := sm.Space(ctx, "space")
+	c, _ := s.Chart(ctx, "chart")
+	chart := c.(*Chart)
+	labels := map[string]string{"team": "payments"}
+	_, err := chart.UpdateAttributes(ctx, func(a *storage.Attributes) error {
+		a.Labels = labels
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &storage.RetentionPolicy{KeepLast: 3}
+	if err = chart.SetRetentionPolicy(ctx, policy); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = chart.Trash(ctx, "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if charts, _ := s.List(ctx); len(charts) != 0 {
+		t.Fatalf("expected chart without versions to be deleted, but got %v", charts)
+	}
+	if versions, _ := chart.trashedVersions(ctx); !reflect.DeepEqual(versions, []string{"1.0.0"}) {
+		t.Fatalf("expected trashed version 1.0.0, but got %v", versions)
+	}
+
+	if err = chart.Restore(ctx, "1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	attributes, err := chart.Attributes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(attributes.Labels, labels) {
+		t.Errorf("expected chart labels %v after restoring, but got %v", labels, attributes.Labels)
+	}
+	restored, err := chart.RetentionPolicy(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored == nil || *restored != *policy {
+		t.Errorf("expected retention policy %+v after restoring, but got %+v", policy, restored)
+	}
+	if keyExists(ctx, sm.Backend, chart.trashPrefix()) {
+		t.Errorf("expected empty trash of chart to be deleted")
+	}
+}
@@ -0,0 +1,211 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/lock"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// uploadsName is the name of the directory which stores uploads in progress of a space
+const uploadsName = reservedPrefix + "uploads"
+
+// uploadDataName is the name of the file which stores received data in an upload
+const uploadDataName = "data"
+
+// uploadFilter matches upload ids generated by CreateUpload
+var uploadFilter = regexp.MustCompile("^[0-9a-f]{32}$")
+
+// Upload is a resumable upload of a chart archive in a space
+type Upload struct {
+	Space  *Space
+	Prefix string
+	Upload string
+}
+
+// CreateUpload starts a resumable upload of a chart archive in current space
+func (s *Space) CreateUpload(ctx context.Context) (storage.Upload, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, ErrorInternalUnknown.Format(err)
+	}
+	upload := s.newUpload(hex.EncodeToString(id))
+	if err := writeKey(ctx, s.SpaceManager.Backend, upload.dataKey(), []byte{}); err != nil {
+		return nil, ErrorInternalUnknown.Format(err)
+	}
+	return upload, nil
+}
+
+// Upload returns an upload in progress by id
+func (s *Space) Upload(ctx context.Context, id string) (storage.Upload, error) {
+	if !uploadFilter.MatchString(id) {
+		return nil, ErrorInvalidParam.Format("upload", id)
+	}
+	upload := s.newUpload(id)
+	if !keyExists(ctx, s.SpaceManager.Backend, upload.dataKey()) {
+		return nil, ErrorContentNotFound.Format("upload " + id)
+	}
+	return upload, nil
+}
+
+// uploads lists ids of uploads in current space
+func (s *Space) uploads(ctx context.Context) ([]string, error) {
+	prefix := path.Join(s.Prefix, uploadsName)
+	if !keyExists(ctx, s.SpaceManager.Backend, prefix) {
+		return []string{}, nil
+	}
+	return list(ctx, s.SpaceManager.Backend, prefix, uploadFilter.MatchString, nil)
+}
+
+// newUpload creates an Upload with specific id
+func (s *Space) newUpload(id string) *Upload {
+	return &Upload{s, path.Join(s.Prefix, uploadsName, id), id}
+}
+
+// ID returns the id of upload
+func (u *Upload) ID() string {
+	return u.Upload
+}
+
+// Size returns the number of bytes received
+func (u *Upload) Size(ctx context.Context) (int64, error) {
+	info, err := u.Space.SpaceManager.Backend.Stat(ctx, u.dataKey())
+	if err != nil {
+		return 0, ErrorContentNotFound.Format("upload " + u.Upload)
+	}
+	return info.Size(), nil
+}
+
+// ModTime returns the time when a chunk is received last
+func (u *Upload) ModTime(ctx context.Context) (time.Time, error) {
+	info, err := u.Space.SpaceManager.Backend.Stat(ctx, u.dataKey())
+	if err != nil {
+		return time.Time{}, ErrorContentNotFound.Format("upload " + u.Upload)
+	}
+	return info.ModTime(), nil
+}
+
+// Append appends data read from reader and returns the size after appending. Data which
+// has been read is committed even if reading is broken, so the upload can be resumed
+func (u *Upload) Append(ctx context.Context, reader io.Reader) (int64, error) {
+	lock := u.locker()
+	if !lock.Lock(u.Space.SpaceManager.LockTimeout) {
+		return 0, ErrorLocking.Format("upload", u.Upload)
+	}
+	defer lock.Unlock()
+	writer, err := u.Space.SpaceManager.Backend.Writer(ctx, u.dataKey(), true)
+	if err != nil {
+		return 0, ErrorContentNotFound.Format("upload " + u.Upload)
+	}
+	_, copyErr := io.Copy(writer, reader)
+	if err = writer.Commit(); err != nil {
+		writer.Close()
+		return 0, ErrorInternalUnknown.Format(err)
+	}
+	size := writer.Size()
+	if err = writer.Close(); err != nil {
+		return 0, ErrorInternalUnknown.Format(err)
+	}
+	if copyErr != nil {
+		return size, ErrorInvalidParam.Format("chunk", copyErr)
+	}
+	return size, nil
+}
+
+// Reader returns a reader of received data
+func (u *Upload) Reader(ctx context.Context) (io.ReadCloser, error) {
+	reader, _, err := openKey(ctx, u.Space.SpaceManager.Backend, u.dataKey())
+	return reader, err
+}
+
+// Digest returns the hex encoded sha256 digest of received data
+func (u *Upload) Digest(ctx context.Context) (string, error) {
+	reader, err := u.Reader(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, reader); err != nil {
+		return "", ErrorInternalUnknown.Format(err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Delete cancels the upload and removes received data
+func (u *Upload) Delete(ctx context.Context) error {
+	lock := u.locker()
+	if !lock.Lock(u.Space.SpaceManager.LockTimeout) {
+		return ErrorLocking.Format("upload", u.Upload)
+	}
+	defer lock.Unlock()
+	if !keyExists(ctx, u.Space.SpaceManager.Backend, u.Prefix) {
+		return nil
+	}
+	return deleteKeys(ctx, u.Space.SpaceManager.Backend, u.Prefix, true)
+}
+
+// moveToBlob moves received data to the blob of its digest if no identical blob exists.
+// If expected is not empty, it must be the digest of data. The refcount of the blob is
+// increased if reference is true. It returns the digest of data
+func (u *Upload) moveToBlob(ctx context.Context, expected string, reference bool) (string, error) {
+	lock := u.locker()
+	if !lock.Lock(u.Space.SpaceManager.LockTimeout) {
+		return "", ErrorLocking.Format("upload", u.Upload)
+	}
+	defer lock.Unlock()
+	digest, err := u.Digest(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(expected) > 0 && expected != digest {
+		return "", ErrorInvalidParam.Format("digest", "data digest is "+digest)
+	}
+	sm := u.Space.SpaceManager
+	blobLock := sm.Lock.Get(blobsName, digest)
+	if !blobLock.Lock(sm.LockTimeout) {
+		return "", ErrorLocking.Format("blob", digest)
+	}
+	defer blobLock.Unlock()
+	refcount, err := sm.refcount(ctx, digest)
+	if err != nil {
+		return "", err
+	}
+	prefix := sm.blobPrefix(digest)
+	dataKey := path.Join(prefix, blobDataName)
+	if refcount <= 0 || !keyExists(ctx, sm.Backend, dataKey) {
+		if err = sm.Backend.Move(ctx, u.dataKey(), dataKey); err != nil {
+			return "", ErrorInternalUnknown.Format(err)
+		}
+	}
+	if !reference {
+		return digest, nil
+	}
+	err = sm.Backend.PutContent(ctx, path.Join(prefix, refcountName), []byte(strconv.Itoa(refcount+1)))
+	if err != nil {
+		return "", ErrorInternalUnknown.Format(err)
+	}
+	return digest, nil
+}
+
+// dataKey returns the key of received data
+func (u *Upload) dataKey() string {
+	return path.Join(u.Prefix, uploadDataName)
+}
+
+// locker returns the lock of upload
+func (u *Upload) locker() lock.Locker {
+	return u.Space.SpaceManager.Lock.Get(u.Space.Name(), uploadsName, u.Upload)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPutUpload(t *testing.T) {
+	sm, cleanup := newTestSpaceManager(t)
+	defer cleanup()
+	ctx := context.Background()
+	data := newTestArchive(t, "chart", "1.0.0", "key: value\n")
+	// an identical archive has been stored, so the upload shares its blob
+	putTestVersion(t, sm, "space", "chart", "0.1.0", data)
+	s, err := sm.Space(ctx, "space")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload, err := s.CreateUpload(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	half := len(data) / 2
+	for _, chunk := range [][]byte{data[:half], data[half:]} {
+		if _, err = upload.Append(ctx, bytes.NewReader(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// resume the upload by id
+	upload, err = s.Upload(ctx, upload.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size, err := upload.Size(ctx); err != nil || size != int64(len(data)) {
+		t.Fatalf("expected size %d, but got %d: %v", len(data), size, err)
+	}
+	sum := sha256.Sum256(data)
+	if digest, err := upload.Digest(ctx); err != nil || digest != hex.EncodeToString(sum[:]) {
+		t.Fatalf("expected digest %x, but got %s: %v", sum, digest, err)
+	}
+
+	c, _ := s.Chart(ctx, "chart")
+	v, _ := c.Version(ctx, "1.0.0")
+	if err = v.PutUpload(ctx, upload); err != nil {
+		t.Fatal(err)
+	}
+	content, err := v.GetContent(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(content, data) {
+		t.Errorf("expected content of upload, but got %d bytes", len(content))
+	}
+	if digests := blobDigests(sm); len(digests) != 1 {
+		t.Errorf("expected 1 blob, but got %v", digests)
+	} else if refcount, _ := sm.refcount(ctx, digests[0]); refcount != 2 {
+		t.Errorf("expected refcount 2, but got %d", refcount)
+	}
+	if _, err = s.Upload(ctx, upload.ID()); !ErrorContentNotFound.Equal(err) {
+		t.Errorf("expected upload to be consumed, but got %v", err)
+	}
+}
+
+func TestCollectUploads(t *testing.T) {
+	sm, cleanup := newTestSpaceManager(t)
+	defer cleanup()
+	ctx := context.Background()
+	s, err := sm.Create(ctx, "space")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload, err := s.CreateUpload(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = upload.Append(ctx, bytes.NewReader([]byte("partial"))); err != nil {
+		t.Fatal(err)
+	}
+	result, err := sm.CollectGarbage(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Uploads) != 0 {
+		t.Fatalf("expected recent upload to be kept, but got %v", result.Uploads)
+	}
+	result, err = sm.CollectGarbage(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"space/" + upload.ID()}; !reflect.DeepEqual(result.Uploads, expected) {
+		t.Errorf("expected uploads %v, but got %v", expected, result.Uploads)
+	}
+	if _, err = upload.Reader(ctx); err == nil {
+		t.Error("expected upload data to be removed")
+	}
+	if charts, err := s.List(ctx); err != nil || len(charts) != 0 {
+		t.Errorf("expected no charts, but got %v: %v", charts, err)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package simple
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+// webhooksName is the name of the file which stores webhooks of a space
+const webhooksName = reservedPrefix + "webhooks"
+
+// Webhooks returns the webhooks of current space
+func (s *Space) Webhooks(ctx context.Context) ([]storage.Webhook, error) {
+	lock := s.SpaceManager.Lock.Get(s.Name())
+	if !lock.RLock(s.SpaceManager.LockTimeout) {
+		return nil, ErrorLocking.Format("space", s.Name())
+	}
+	defer lock.RUnlock()
+	backend := s.SpaceManager.Backend
+	key := path.Join(s.Prefix, webhooksName)
+	webhooks := make([]storage.Webhook, 0)
+	if !keyExists(ctx, backend, key) {
+		return webhooks, nil
+	}
+	data, err := backend.GetContent(ctx, key)
+	if err != nil {
+		return nil, ErrorInternalUnknown.Format(err)
+	}
+	if err = json.Unmarshal(data, &webhooks); err != nil {
+		return nil, ErrorInternalUnknown.Format(err)
+	}
+	return webhooks, nil
+}
+
+// SetWebhooks replaces the webhooks of current space. An empty list removes all
+// webhooks. The space must exist
+func (s *Space) SetWebhooks(ctx context.Context, webhooks []storage.Webhook) error {
+	if !s.Exists(ctx) {
+		return ErrorContentNotFound.Format(s.Name())
+	}
+	for i := range webhooks {
+		if err := webhooks[i].Validate(); err != nil {
+			return ErrorInvalidParam.Format("webhook", err)
+		}
+	}
+	lock := s.SpaceManager.Lock.Get(s.Name())
+	if !lock.Lock(s.SpaceManager.LockTimeout) {
+		return ErrorLocking.Format("space", s.Name())
+	}
+	defer lock.Unlock()
+	backend := s.SpaceManager.Backend
+	key := path.Join(s.Prefix, webhooksName)
+	if len(webhooks) <= 0 {
+		if !keyExists(ctx, backend, key) {
+			return nil
+		}
+		if err := backend.Delete(ctx, key); err != nil {
+			return ErrorInternalUnknown.Format(err)
+		}
+		return nil
+	}
+	data, err := json.Marshal(webhooks)
+	if err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	if err = backend.PutContent(ctx, key, data); err != nil {
+		return ErrorInternalUnknown.Format(err)
+	}
+	return nil
+}
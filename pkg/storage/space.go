@@ -0,0 +1,50 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Visibility decides who can read charts in a space
+type Visibility string
+
+const (
+	// VisibilityPrivate spaces can only be read by tokens which have read permission on them
+	VisibilityPrivate Visibility = "private"
+	// VisibilityPublic spaces can be read by everyone, including requests without token
+	VisibilityPublic Visibility = "public"
+)
+
+// maxDescriptionLength is the max number of characters in the description of a space
+const maxDescriptionLength = 1024
+
+// SpaceInfo describes a space. A space without info is private
+type SpaceInfo struct {
+	// Description is a human readable description of space
+	Description string `json:"description,omitempty"`
+	// Owner is the person or team who is responsible for space
+	Owner string `json:"owner,omitempty"`
+	// Visibility is the visibility of space. Default to private
+	Visibility Visibility `json:"visibility,omitempty"`
+}
+
+// Validate validates whether the info is valid
+func (i *SpaceInfo) Validate() error {
+	if utf8.RuneCountInString(i.Description) > maxDescriptionLength {
+		return fmt.Errorf("description should not be longer than %d characters", maxDescriptionLength)
+	}
+	switch i.Visibility {
+	case "", VisibilityPrivate, VisibilityPublic:
+		return nil
+	}
+	return fmt.Errorf("visibility should be %s or %s, but got %q", VisibilityPrivate, VisibilityPublic, i.Visibility)
+}
+
+// Public returns whether the space can be read by everyone. A nil info is private
+func (i *SpaceInfo) Public() bool {
+	return i != nil && i.Visibility == VisibilityPublic
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+// Package storagetest provides chart archives and space managers for tests
+package storagetest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/storage"
+
+	_ "github.com/docker/distribution/registry/storage/driver/filesystem"
+)
+
+// NewArchive creates a chart archive which only has Chart.yaml
+func NewArchive(t testing.TB, name, version string) []byte {
+	return NewArchiveFiles(t, map[string]string{
+		name + "/Chart.yaml": "apiVersion: v1\nname: " + name + "\nversion: " + version + "\n",
+	})
+}
+
+// NewArchiveFiles creates a chart archive of files. Keys are paths in the archive, like
+// "chart/Chart.yaml"
+func NewArchiveFiles(t testing.TB, files map[string]string) []byte {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for _, name := range names {
+		content := files[name]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// NewSpaceManager creates a simple SpaceManager with a memory locker in a temporary
+// directory. The returned function removes the directory. The simple storage must be
+// registered by importing pkg/storage/simple
+func NewSpaceManager(t testing.TB) (storage.SpaceManager, func()) {
+	dir, err := ioutil.TempDir("", "registry-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager, err := storage.Create("simple", map[string]interface{}{
+		common.ParameterNameStorageDriver: "filesystem",
+		common.ParameterNameRootDirectory: dir,
+		common.ParameterResourceLocker:    "memory",
+	})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return manager, func() { os.RemoveAll(dir) }
+}
+
+// UseSpaceManager creates a SpaceManager by NewSpaceManager and makes it the global one.
+// The returned function resets the global SpaceManager and removes the directory
+func UseSpaceManager(t testing.TB) (storage.SpaceManager, func()) {
+	manager, cleanup := NewSpaceManager(t)
+	common.SetSpaceManager(manager)
+	return manager, func() {
+		common.SetSpaceManager(nil)
+		cleanup()
+	}
+}
@@ -0,0 +1,37 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Upload is a resumable upload of a chart archive. Chunks of the archive are appended
+// to the backend one by one, so the registry never holds the whole archive in memory
+type Upload interface {
+	// ID returns the id of upload
+	ID() string
+
+	// Size returns the number of bytes received
+	Size(ctx context.Context) (int64, error)
+
+	// ModTime returns the time when a chunk is received last
+	ModTime(ctx context.Context) (time.Time, error)
+
+	// Append appends data read from reader and returns the size after appending. If reading
+	// is broken, data which has been read is kept and the upload can be resumed from Size
+	Append(ctx context.Context, reader io.Reader) (int64, error)
+
+	// Reader returns a reader of received data
+	Reader(ctx context.Context) (io.ReadCloser, error)
+
+	// Digest returns the hex encoded sha256 digest of received data
+	Digest(ctx context.Context) (string, error)
+
+	// Delete cancels the upload and removes received data
+	Delete(ctx context.Context) error
+}
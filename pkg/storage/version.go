@@ -0,0 +1,40 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package storage
+
+import (
+	"regexp"
+
+	"github.com/Masterminds/semver"
+)
+
+// LatestVersion returns the latest version in versions which are sorted in ascending
+// semver order. Pre-release versions are skipped unless prerelease is true or there is
+// no other version. It returns false if versions is empty.
+func LatestVersion(versions []string, prerelease bool) (string, bool) {
+	if len(versions) <= 0 {
+		return "", false
+	}
+	latest := versions[len(versions)-1]
+	if prerelease {
+		return latest, true
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		v, err := semver.NewVersion(versions[i])
+		if err == nil && len(v.Prerelease()) <= 0 {
+			return versions[i], true
+		}
+	}
+	return latest, true
+}
+
+// andRangeSeparator matches spaces between two comparisons like `>=2.0.0 <3.0.0`
+var andRangeSeparator = regexp.MustCompile(`([0-9xX*])\s+([<>=!~^])`)
+
+// ParseRange parses a semver range. Besides ranges accepted by the semver library, like
+// `>=2.0.0, <3.0.0`, comparisons separated by spaces like `>=2.0.0 <3.0.0` are an AND range
+func ParseRange(r string) (*semver.Constraints, error) {
+	return semver.NewConstraint(andRangeSeparator.ReplaceAllString(r, "$1, $2"))
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package storage
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver"
+)
+
+func TestLatestVersion(t *testing.T) {
+	cases := []struct {
+		versions   []string
+		prerelease bool
+		expected   string
+	}{
+		{[]string{"1.9.0", "1.10.0", "2.0.0-rc.1"}, false, "1.10.0"},
+		{[]string{"1.9.0", "1.10.0", "2.0.0-rc.1"}, true, "2.0.0-rc.1"},
+		{[]string{"1.0.0-alpha", "1.0.0-beta"}, false, "1.0.0-beta"},
+	}
+	for _, c := range cases {
+		latest, ok := LatestVersion(c.versions, c.prerelease)
+		if !ok || latest != c.expected {
+			t.Errorf("expected latest of %v to be %s, but got %s", c.versions, c.expected, latest)
+		}
+	}
+	if _, ok := LatestVersion(nil, false); ok {
+		t.Errorf("expected no latest version of empty versions")
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	cases := []struct {
+		r       string
+		version string
+		matched bool
+	}{
+		{">=1.0.0 <2.0.0", "1.5.0", true},
+		{">=1.0.0 <2.0.0", "2.0.0", false},
+		{">=1.0.0, <2.0.0", "1.0.0", true},
+		{"^1.2.x || >=3.0.0  <4.0.0", "3.1.0", true},
+		{"^1.2.x || >=3.0.0  <4.0.0", "4.0.0", false},
+	}
+	for _, c := range cases {
+		constraint, err := ParseRange(c.r)
+		if err != nil {
+			t.Errorf("failed to parse %q: %v", c.r, err)
+			continue
+		}
+		if matched := constraint.Check(semver.MustParse(c.version)); matched != c.matched {
+			t.Errorf("expected %s in %q to be %v", c.version, c.r, c.matched)
+		}
+	}
+	if _, err := ParseRange(">=1.0.0 <"); err == nil {
+		t.Errorf("expected an invalid range to fail")
+	}
+}
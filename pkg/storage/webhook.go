@@ -0,0 +1,32 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package storage
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Webhook is an http callback of a space. It receives events of versions in the space
+type Webhook struct {
+	// URL is the endpoint which receives events
+	URL string `json:"url"`
+	// Secret is a shared secret for signing the body of events. Events are not signed if it's empty
+	Secret string `json:"secret,omitempty"`
+	// Actions are the actions of events which are sent to URL. All events are sent if it's empty
+	Actions []string `json:"actions,omitempty"`
+}
+
+// Validate validates whether the webhook is valid
+func (w *Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) <= 0 {
+		return fmt.Errorf("url should be an absolute http or https url, but got %q", w.URL)
+	}
+	return nil
+}
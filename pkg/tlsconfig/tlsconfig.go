@@ -0,0 +1,174 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/log"
+)
+
+// DefaultReloadInterval is the default period between two checks of certificate files
+const DefaultReloadInterval = "1m"
+
+const (
+	// ClientAuthOptional verifies client certificates if clients send them
+	ClientAuthOptional = "optional"
+	// ClientAuthRequired rejects connections without a verified client certificate
+	ClientAuthRequired = "required"
+)
+
+// Config is a config of serving TLS. TLS is disabled if CertFile is empty
+type Config struct {
+	// CertFile is the path of PEM encoded certificate chain of server
+	CertFile string `yaml:"certFile"`
+	// KeyFile is the path of PEM encoded private key of server
+	KeyFile string `yaml:"keyFile"`
+	// ClientCAFile is the path of PEM encoded CAs which verify client certificates. Client
+	// certificates are not requested if it's empty
+	ClientCAFile string `yaml:"clientCAFile"`
+	// ClientAuth is "optional" or "required". It's "optional" by default, so clients
+	// without certificate can still be authenticated by tokens
+	ClientAuth string `yaml:"clientAuth"`
+	// ReloadInterval is the period between two checks of certificate files, like "1m".
+	// Changed files are reloaded without restarting the server
+	ReloadInterval string `yaml:"reloadInterval"`
+}
+
+// reloader keeps the last valid certificate and client CAs loaded from files
+type reloader struct {
+	config     Config
+	clientAuth tls.ClientAuthType
+	lock       sync.RWMutex
+	// modTimes are modification times of files when they were last loaded. It's only
+	// accessed by reload
+	modTimes    map[string]time.Time
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+}
+
+// Start loads certificate files in config and reloads them in background when they
+// change. It returns nil if TLS is disabled. If reloading fails, the last valid files
+// are kept
+func Start(config Config) (*tls.Config, error) {
+	if len(config.CertFile) <= 0 {
+		return nil, nil
+	}
+	if len(config.KeyFile) <= 0 {
+		return nil, fmt.Errorf("tls key file is required")
+	}
+	r := &reloader{config: config, modTimes: map[string]time.Time{}}
+	if len(config.ClientCAFile) > 0 {
+		switch config.ClientAuth {
+		case "", ClientAuthOptional:
+			r.clientAuth = tls.VerifyClientCertIfGiven
+		case ClientAuthRequired:
+			r.clientAuth = tls.RequireAndVerifyClientCert
+		default:
+			return nil, fmt.Errorf("unknown tls client auth %s", config.ClientAuth)
+		}
+	} else if len(config.ClientAuth) > 0 {
+		return nil, fmt.Errorf("tls client auth requires client CA file")
+	}
+	if len(config.ReloadInterval) <= 0 {
+		config.ReloadInterval = DefaultReloadInterval
+	}
+	interval, err := time.ParseDuration(config.ReloadInterval)
+	if err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("tls reload interval should be positive, but got %s", config.ReloadInterval)
+	}
+	if _, err = r.reload(); err != nil {
+		return nil, err
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			reloaded, err := r.reload()
+			if err != nil {
+				log.Errorf("Failed to reload tls certificates, keep the last ones: %v", err)
+			} else if reloaded {
+				log.Infof("Reloaded tls certificates")
+			}
+		}
+	}()
+	log.Infof("Serving TLS with certificate %s, checking changes every %s", config.CertFile, interval)
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: r.configForClient,
+	}, nil
+}
+
+// files returns paths of certificate files in config
+func (r *reloader) files() []string {
+	files := []string{r.config.CertFile, r.config.KeyFile}
+	if len(r.config.ClientCAFile) > 0 {
+		files = append(files, r.config.ClientCAFile)
+	}
+	return files
+}
+
+// reload loads certificate files if any of them changes since the last load, and
+// returns whether they are reloaded. Files failing to load are retried when they change
+func (r *reloader) reload() (bool, error) {
+	modTimes := make(map[string]time.Time, 3)
+	changed := false
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, err
+		}
+		modTimes[file] = info.ModTime()
+		if last, ok := r.modTimes[file]; !ok || !last.Equal(info.ModTime()) {
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+	r.modTimes = modTimes
+	certificate, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return false, err
+	}
+	var clientCAs *x509.CertPool
+	if len(r.config.ClientCAFile) > 0 {
+		data, err := ioutil.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return false, err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(data) {
+			return false, fmt.Errorf("no certificate in client CA file %s", r.config.ClientCAFile)
+		}
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.certificate = &certificate
+	r.clientCAs = clientCAs
+	return true, nil
+}
+
+// configForClient returns a config with the last loaded certificate and client CAs
+// for a handshake
+func (r *reloader) configForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*r.certificate},
+		ClientCAs:    r.clientCAs,
+		ClientAuth:   r.clientAuth,
+	}, nil
+}
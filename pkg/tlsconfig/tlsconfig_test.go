@@ -0,0 +1,150 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed certificate of cn and its key to dir
+func writeCertificate(t *testing.T, dir, cn string, modTime time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]*pem.Block{
+		"tls.crt": {Type: "CERTIFICATE", Bytes: der},
+		"tls.key": {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	}
+	for name, block := range files {
+		path := filepath.Join(dir, name)
+		if err = ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// servedName returns the common name of certificate served by r
+func servedName(t *testing.T, r *reloader) string {
+	config, err := r.configForClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert.Subject.CommonName
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	now := time.Now()
+	writeCertificate(t, dir, "first", now.Add(-time.Minute))
+
+	r := &reloader{
+		config: Config{
+			CertFile: filepath.Join(dir, "tls.crt"),
+			KeyFile:  filepath.Join(dir, "tls.key"),
+		},
+		modTimes: map[string]time.Time{},
+	}
+	if reloaded, err := r.reload(); err != nil || !reloaded {
+		t.Fatalf("certificate should be loaded: %v", err)
+	}
+	if reloaded, err := r.reload(); err != nil || reloaded {
+		t.Fatalf("unchanged certificate should not be reloaded: %v", err)
+	}
+
+	writeCertificate(t, dir, "second", now)
+	if reloaded, err := r.reload(); err != nil || !reloaded {
+		t.Fatalf("changed certificate should be reloaded: %v", err)
+	}
+	if name := servedName(t, r); name != "second" {
+		t.Errorf("expected reloaded certificate second, but got %s", name)
+	}
+
+	// a broken certificate doesn't replace the last valid one
+	if err = ioutil.WriteFile(r.config.CertFile, []byte("broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chtimes(r.config.CertFile, now.Add(time.Minute), now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.reload(); err == nil {
+		t.Errorf("broken certificate should fail to reload")
+	}
+	if _, err = r.reload(); err != nil {
+		t.Errorf("unchanged broken certificate should not be reloaded again: %v", err)
+	}
+	if name := servedName(t, r); name != "second" {
+		t.Errorf("expected last valid certificate second, but got %s", name)
+	}
+}
+
+func TestStart(t *testing.T) {
+	if config, err := Start(Config{}); err != nil || config != nil {
+		t.Errorf("tls should be disabled without cert file: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "tlsconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeCertificate(t, dir, "server", time.Now())
+	config := Config{
+		CertFile:   filepath.Join(dir, "tls.crt"),
+		KeyFile:    filepath.Join(dir, "tls.key"),
+		ClientAuth: ClientAuthRequired,
+	}
+	if _, err = Start(config); err == nil {
+		t.Errorf("client auth without client CA file should be rejected")
+	}
+	config.ClientCAFile = config.CertFile
+	c, err := Start(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handshake, err := c.GetConfigForClient(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if handshake.ClientAuth != tls.RequireAndVerifyClientCert || handshake.ClientCAs == nil {
+		t.Errorf("client certificates should be required and verified")
+	}
+}
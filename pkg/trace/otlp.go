@@ -0,0 +1,260 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/log"
+)
+
+const (
+	// maxQueuedSpans is the number of ended spans waiting for export. Spans ended when the
+	// queue is full are dropped
+	maxQueuedSpans = 2048
+	// maxBatchSpans is the maximum number of spans in an export
+	maxBatchSpans = 512
+	// exportTimeout is the timeout of an export
+	exportTimeout = 10 * time.Second
+	// scopeName is the instrumentation scope of exported spans
+	scopeName = "github.com/caicloud/helm-registry"
+)
+
+// exporter posts ended spans to an OTLP/HTTP endpoint in batches
+type exporter struct {
+	endpoint string
+	headers  map[string]string
+	resource []otlpAttribute
+	client   *http.Client
+	spans    chan *Span
+	flushes  chan chan struct{}
+}
+
+// newExporter creates an exporter of service
+func newExporter(endpoint string, headers map[string]string, service string) *exporter {
+	return &exporter{
+		endpoint: endpoint,
+		headers:  headers,
+		resource: []otlpAttribute{newAttribute("service.name", service)},
+		client:   &http.Client{Timeout: exportTimeout},
+		spans:    make(chan *Span, maxQueuedSpans),
+		flushes:  make(chan chan struct{}),
+	}
+}
+
+// enqueue queues an ended span for export
+func (e *exporter) enqueue(span *Span) {
+	select {
+	case e.spans <- span:
+	default:
+		log.Debugf("Dropped span %s of trace %s, export queue is full", span.SpanID(), span.TraceID())
+	}
+}
+
+// flush exports queued spans and waits until they are exported or ctx is done
+func (e *exporter) flush(ctx context.Context) {
+	done := make(chan struct{})
+	select {
+	case e.flushes <- done:
+	case <-ctx.Done():
+		return
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// run exports queued spans every interval, or when a batch is full
+func (e *exporter) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	batch := make([]*Span, 0, maxBatchSpans)
+	export := func() {
+		if len(batch) <= 0 {
+			return
+		}
+		if err := e.export(batch); err != nil {
+			log.Errorf("Failed to export %d spans: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case span := <-e.spans:
+			batch = append(batch, span)
+			if len(batch) >= maxBatchSpans {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case done := <-e.flushes:
+			for len(e.spans) > 0 {
+				batch = append(batch, <-e.spans)
+				if len(batch) >= maxBatchSpans {
+					export()
+				}
+			}
+			export()
+			close(done)
+		}
+	}
+}
+
+// export posts spans to endpoint
+func (e *exporter) export(spans []*Span) error {
+	request := otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: e.resource},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: scopeName},
+			Spans: make([]otlpSpan, 0, len(spans)),
+		}},
+	}}}
+	scope := &request.ResourceSpans[0].ScopeSpans[0]
+	for _, span := range spans {
+		scope.Spans = append(scope.Spans, newOTLPSpan(span))
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("collector responds with %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+// otlpRequest is the json encoding of ExportTraceServiceRequest of OTLP
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID      string          `json:"traceId"`
+	SpanID       string          `json:"spanId"`
+	ParentSpanID string          `json:"parentSpanId,omitempty"`
+	Name         string          `json:"name"`
+	Kind         Kind            `json:"kind"`
+	Start        string          `json:"startTimeUnixNano"`
+	End          string          `json:"endTimeUnixNano"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+	Status       *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+// otlpValue is an AnyValue. Integers are encoded as strings
+type otlpValue struct {
+	String *string  `json:"stringValue,omitempty"`
+	Bool   *bool    `json:"boolValue,omitempty"`
+	Int    *string  `json:"intValue,omitempty"`
+	Double *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// statusCodeError is STATUS_CODE_ERROR of OTLP
+const statusCodeError = 2
+
+// newOTLPSpan encodes an ended span
+func newOTLPSpan(s *Span) otlpSpan {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	span := otlpSpan{
+		TraceID: hex.EncodeToString(s.traceID[:]),
+		SpanID:  hex.EncodeToString(s.spanID[:]),
+		Name:    s.name,
+		Kind:    s.kind,
+		Start:   strconv.FormatInt(s.start.UnixNano(), 10),
+		End:     strconv.FormatInt(s.end.UnixNano(), 10),
+	}
+	if s.parentID != [8]byte{} {
+		span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	keys := make([]string, 0, len(s.attributes))
+	for k := range s.attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		span.Attributes = append(span.Attributes, newAttribute(k, s.attributes[k]))
+	}
+	if len(s.err) > 0 {
+		span.Status = &otlpStatus{Code: statusCodeError, Message: s.err}
+	}
+	return span
+}
+
+// newAttribute encodes an attribute
+func newAttribute(key string, value interface{}) otlpAttribute {
+	attribute := otlpAttribute{Key: key}
+	switch v := value.(type) {
+	case string:
+		attribute.Value.String = &v
+	case bool:
+		attribute.Value.Bool = &v
+	case int:
+		i := strconv.FormatInt(int64(v), 10)
+		attribute.Value.Int = &i
+	case int64:
+		i := strconv.FormatInt(v, 10)
+		attribute.Value.Int = &i
+	case float64:
+		attribute.Value.Double = &v
+	default:
+		s := fmt.Sprint(v)
+		attribute.Value.String = &s
+	}
+	return attribute
+}
@@ -0,0 +1,279 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/log"
+)
+
+const (
+	// DefaultServiceName is the default service name of exported spans
+	DefaultServiceName = "helm-registry"
+	// DefaultFlushInterval is the default period between two exports
+	DefaultFlushInterval = "5s"
+	// HeaderTraceParent is the W3C trace context header which continues traces of clients
+	HeaderTraceParent = "traceparent"
+)
+
+// Config is a config of exporting traces by OTLP over HTTP. Tracing is disabled if
+// Endpoint is empty
+type Config struct {
+	// Endpoint is the OTLP/HTTP traces endpoint of a collector, like
+	// "http://localhost:4318/v1/traces". Spans are posted as json
+	Endpoint string `yaml:"endpoint"`
+	// Headers are sent with every export, like credentials of the collector
+	Headers map[string]string `yaml:"headers"`
+	// ServiceName is the service.name of exported spans. Default to "helm-registry"
+	ServiceName string `yaml:"serviceName"`
+	// SampleRatio is the ratio of traces started by the registry to export, between 0 and
+	// 1. Default to 1 if it's 0. Traces continued from clients follow their sampling flags
+	SampleRatio float64 `yaml:"sampleRatio"`
+	// FlushInterval is the period between two exports, like "5s"
+	FlushInterval string `yaml:"flushInterval"`
+}
+
+// Kind is the kind of span
+type Kind int
+
+const (
+	// KindInternal is an operation in the registry
+	KindInternal Kind = 1
+	// KindServer is a request handled by the registry
+	KindServer Kind = 2
+)
+
+// Span is an operation of a trace. Methods of a nil Span do nothing, so callers don't
+// check whether tracing is enabled
+type Span struct {
+	lock       sync.Mutex
+	name       string
+	kind       Kind
+	traceID    [16]byte
+	spanID     [8]byte
+	parentID   [8]byte
+	sampled    bool
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+	err        string
+}
+
+// spanKey is the context key of span
+type spanKey struct{}
+
+// exp exports ended spans. It's nil if tracing is disabled
+var exp *exporter
+
+// sampleBound is the upper bound of sampled trace ids started by the registry
+var sampleBound uint64
+
+// Initialize starts exporting spans by config
+func Initialize(config Config) error {
+	if len(config.Endpoint) <= 0 {
+		return nil
+	}
+	if len(config.ServiceName) <= 0 {
+		config.ServiceName = DefaultServiceName
+	}
+	if config.SampleRatio == 0 {
+		config.SampleRatio = 1
+	}
+	if config.SampleRatio < 0 || config.SampleRatio > 1 {
+		return fmt.Errorf("trace sample ratio should be between 0 and 1, but got %v", config.SampleRatio)
+	}
+	if len(config.FlushInterval) <= 0 {
+		config.FlushInterval = DefaultFlushInterval
+	}
+	interval, err := time.ParseDuration(config.FlushInterval)
+	if err != nil {
+		return err
+	}
+	if interval <= 0 {
+		return fmt.Errorf("trace flush interval should be positive, but got %s", config.FlushInterval)
+	}
+	sampleBound = ^uint64(0)
+	if config.SampleRatio < 1 {
+		sampleBound = uint64(config.SampleRatio * float64(^uint64(0)))
+	}
+	exp = newExporter(config.Endpoint, config.Headers, config.ServiceName)
+	go exp.run(interval)
+	log.Infof("Exporting traces to %s every %s, sample ratio: %v", config.Endpoint, interval, config.SampleRatio)
+	return nil
+}
+
+// Enabled returns whether spans are exported
+func Enabled() bool {
+	return exp != nil
+}
+
+// Flush exports ended spans which are not exported yet
+func Flush(ctx context.Context) {
+	if exp != nil {
+		exp.flush(ctx)
+	}
+}
+
+// StartSpan starts an internal span which is a child of the span in ctx, or the root of
+// a new trace. The span must be ended by End. It returns nil if tracing is disabled
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	if exp == nil {
+		return ctx, nil
+	}
+	span := &Span{name: name, kind: KindInternal, start: time.Now()}
+	if parent := FromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+		span.sampled = parent.sampled
+	} else {
+		span.traceID, span.sampled = newTraceID()
+	}
+	span.spanID = newSpanID()
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// StartRequest starts a server span of a request. The trace of client is continued if
+// header has a valid traceparent. It returns nil if tracing is disabled
+func StartRequest(ctx context.Context, name string, header http.Header) (context.Context, *Span) {
+	if exp == nil {
+		return ctx, nil
+	}
+	span := &Span{name: name, kind: KindServer, start: time.Now()}
+	if traceID, parentID, sampled, ok := parseTraceParent(header.Get(HeaderTraceParent)); ok {
+		span.traceID = traceID
+		span.parentID = parentID
+		span.sampled = sampled
+	} else {
+		span.traceID, span.sampled = newTraceID()
+	}
+	span.spanID = newSpanID()
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// FromContext returns the span in ctx, or nil if there is no span
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// TraceID returns the hex id of trace of span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// SpanID returns the hex id of span
+func (s *Span) SpanID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.spanID[:])
+}
+
+// SetName changes the name of span
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.name = name
+}
+
+// SetAttribute sets an attribute of span. Values are strings, bools, integers or floats,
+// and other values are formatted as strings
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.attributes == nil {
+		s.attributes = map[string]interface{}{}
+	}
+	s.attributes[key] = value
+}
+
+// SetError marks span as failed if err is not nil
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.err = err.Error()
+}
+
+// End ends span and exports it if its trace is sampled. Span must not be changed after
+// it's ended
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	s.end = time.Now()
+	s.lock.Unlock()
+	if s.sampled && exp != nil {
+		exp.enqueue(s)
+	}
+}
+
+// newTraceID returns a random trace id and whether it's sampled
+func newTraceID() ([16]byte, bool) {
+	var id [16]byte
+	rand.Read(id[:])
+	return id, binary.BigEndian.Uint64(id[:8]) <= sampleBound
+}
+
+// newSpanID returns a random span id
+func newSpanID() [8]byte {
+	var id [8]byte
+	rand.Read(id[:])
+	return id
+}
+
+// parseTraceParent parses a W3C traceparent like
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+func parseTraceParent(value string) (traceID [16]byte, parentID [8]byte, sampled bool, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return traceID, parentID, false, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return traceID, parentID, false, false
+	}
+	if !decodeID(traceID[:], parts[1]) || !decodeID(parentID[:], parts[2]) {
+		return traceID, parentID, false, false
+	}
+	return traceID, parentID, flags[0]&1 == 1, true
+}
+
+// decodeID decodes a hex id into id. An id of zeros is invalid
+func decodeID(id []byte, value string) bool {
+	if len(value) != hex.EncodedLen(len(id)) || strings.ToLower(value) != value {
+		return false
+	}
+	if _, err := hex.Decode(id, []byte(value)); err != nil {
+		return false
+	}
+	for _, b := range id {
+		if b != 0 {
+			return true
+		}
+	}
+	return false
+}
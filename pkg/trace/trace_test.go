@@ -0,0 +1,105 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	cases := []struct {
+		value   string
+		ok      bool
+		sampled bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902-01", false, false},
+		{"", false, false},
+	}
+	for _, c := range cases {
+		_, _, sampled, ok := parseTraceParent(c.value)
+		if ok != c.ok || sampled != c.sampled {
+			t.Errorf("expected %q to be parsed as %v and sampled %v, but got %v and %v", c.value, c.ok, c.sampled, ok, sampled)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	var lock sync.Mutex
+	var spans []otlpSpan
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		request := otlpRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		for _, rs := range request.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}))
+	defer collector.Close()
+	defer func() { exp = nil }()
+
+	if ctx, span := StartSpan(context.Background(), "disabled"); span != nil || FromContext(ctx) != nil {
+		t.Fatalf("span should not be started if tracing is disabled")
+	}
+	err := Initialize(Config{
+		Endpoint:      collector.URL,
+		Headers:       map[string]string{"Authorization": "Bearer secret"},
+		FlushInterval: "1h",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{}
+	header.Set(HeaderTraceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, server := StartRequest(context.Background(), "GET /charts", header)
+	_, child := StartSpan(ctx, "storage.get_content")
+	child.SetAttribute("storage.path", "/a")
+	child.SetError(errors.New("failed"))
+	child.End()
+	server.End()
+
+	header.Set(HeaderTraceParent, "00-4bf92f3577b34da6a3ce929d0e0e4737-00f067aa0ba902b7-00")
+	_, unsampled := StartRequest(context.Background(), "GET /spaces", header)
+	unsampled.End()
+	Flush(context.Background())
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 sampled spans, but got %d", len(spans))
+	}
+	if spans[0].Name != "storage.get_content" || spans[0].TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" ||
+		spans[0].ParentSpanID != server.SpanID() || spans[0].Status == nil {
+		t.Errorf("unexpected child span: %+v", spans[0])
+	}
+	if len(spans[0].Attributes) != 1 || *spans[0].Attributes[0].Value.String != "/a" {
+		t.Errorf("unexpected attributes of child span: %+v", spans[0].Attributes)
+	}
+	if spans[1].Kind != KindServer || spans[1].ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("unexpected server span: %+v", spans[1])
+	}
+}
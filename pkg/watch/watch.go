@@ -0,0 +1,167 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package watch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/errors"
+)
+
+// DefaultHistory is the number of recent events kept if Config.History is 0
+const DefaultHistory = 1000
+
+// Config is a config of watching changes
+type Config struct {
+	// History is the number of recent events kept in memory. Watchers can resume from
+	// a resource version only if no event after it has been dropped
+	History int `yaml:"history"`
+}
+
+// Event describes a change of a version of chart
+type Event struct {
+	// ResourceVersion identifies the event. Watching from it returns events after it
+	ResourceVersion string `json:"resourceVersion"`
+	// Space name
+	Space string `json:"space"`
+	// Chart name
+	Chart string `json:"chart"`
+	// Version number
+	Version string `json:"version"`
+	// Action of the change, like create, update, updateValues or delete
+	Action string `json:"action"`
+	// Timestamp is the time when the change finished
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Broadcaster keeps recent events and wakes up watchers when an event is published.
+// Resource versions are like "{epoch}-{sequence}". The epoch changes when the registry
+// restarts, so resource versions of a previous process are expired
+type Broadcaster struct {
+	lock    sync.Mutex
+	epoch   string
+	history int
+	// sequence is the sequence of the last published event
+	sequence uint64
+	// events are recent events in the order of sequences
+	events []*Event
+	// changed is closed and replaced when an event is published
+	changed chan struct{}
+}
+
+// NewBroadcaster creates a broadcaster which keeps history events
+func NewBroadcaster(history int) *Broadcaster {
+	return &Broadcaster{
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		history: history,
+		changed: make(chan struct{}),
+	}
+}
+
+// resourceVersion formats a resource version of sequence
+func (b *Broadcaster) resourceVersion(sequence uint64) string {
+	return b.epoch + "-" + strconv.FormatUint(sequence, 10)
+}
+
+// Publish adds an event and wakes up watchers
+func (b *Broadcaster) Publish(space, chart, version, action string) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.sequence++
+	b.events = append(b.events, &Event{
+		ResourceVersion: b.resourceVersion(b.sequence),
+		Space:           space,
+		Chart:           chart,
+		Version:         version,
+		Action:          action,
+		Timestamp:       time.Now().UTC(),
+	})
+	if len(b.events) > b.history {
+		dropped := len(b.events) - b.history
+		copy(b.events, b.events[dropped:])
+		for i := len(b.events) - dropped; i < len(b.events); i++ {
+			b.events[i] = nil
+		}
+		b.events = b.events[:len(b.events)-dropped]
+	}
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// Since returns events after resourceVersion, the resource version of the last event and
+// a channel which is closed when a newer event is published. An empty resourceVersion means
+// now, so no event is returned. It returns ErrorResourceVersionExpired if resourceVersion is
+// issued by another process or events after it have been dropped
+func (b *Broadcaster) Since(resourceVersion string) ([]*Event, string, <-chan struct{}, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	current := b.resourceVersion(b.sequence)
+	if len(resourceVersion) <= 0 {
+		return nil, current, b.changed, nil
+	}
+	sequence, err := b.parse(resourceVersion)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	// the oldest event which can be returned is the one after sequence
+	oldest := b.sequence - uint64(len(b.events)) + 1
+	if sequence+1 < oldest {
+		return nil, "", nil, errors.ErrorResourceVersionExpired.Format(resourceVersion)
+	}
+	events := b.events[sequence+1-oldest:]
+	result := make([]*Event, len(events))
+	copy(result, events)
+	return result, current, b.changed, nil
+}
+
+// parse returns the sequence of resourceVersion
+func (b *Broadcaster) parse(resourceVersion string) (uint64, error) {
+	parts := strings.SplitN(resourceVersion, "-", 2)
+	if len(parts) != 2 {
+		return 0, errors.ErrorInvalidParam.Format("resourceVersion", resourceVersion)
+	}
+	sequence, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, errors.ErrorInvalidParam.Format("resourceVersion", resourceVersion)
+	}
+	if parts[0] != b.epoch {
+		return 0, errors.ErrorResourceVersionExpired.Format(resourceVersion)
+	}
+	if sequence > b.sequence {
+		return 0, errors.ErrorInvalidParam.Format("resourceVersion",
+			fmt.Sprintf("%s is newer than the last event", resourceVersion))
+	}
+	return sequence, nil
+}
+
+// globalBroadcaster is the broadcaster used by registry
+var globalBroadcaster = NewBroadcaster(DefaultHistory)
+
+// Initialize creates the global broadcaster by config. It should be called before serving
+func Initialize(config Config) error {
+	history := config.History
+	if history < 0 {
+		return fmt.Errorf("watch history should not be negative, but got %d", history)
+	}
+	if history == 0 {
+		history = DefaultHistory
+	}
+	globalBroadcaster = NewBroadcaster(history)
+	return nil
+}
+
+// Publish adds an event to the global broadcaster
+func Publish(space, chart, version, action string) {
+	globalBroadcaster.Publish(space, chart, version, action)
+}
+
+// Since returns events after resourceVersion in the global broadcaster
+func Since(resourceVersion string) ([]*Event, string, <-chan struct{}, error) {
+	return globalBroadcaster.Since(resourceVersion)
+}
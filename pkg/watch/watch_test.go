@@ -0,0 +1,74 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package watch
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/errors"
+)
+
+// code returns the status code of err
+func code(err error) int {
+	if e, ok := err.(*errors.Error); ok {
+		return e.Code
+	}
+	return 0
+}
+
+func TestBroadcaster(t *testing.T) {
+	b := NewBroadcaster(2)
+	events, start, changed, err := b.Since("")
+	if err != nil || len(events) != 0 {
+		t.Fatalf("expected no event from now, but got %v and %v", events, err)
+	}
+
+	b.Publish("library", "a", "1.0.0", "create")
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("expected watchers to be woken up by a published event")
+	}
+	events, current, _, err := b.Since(start)
+	if err != nil || len(events) != 1 || events[0].Chart != "a" || events[0].ResourceVersion != current {
+		t.Fatalf("expected the published event, but got %v, %s and %v", events, current, err)
+	}
+	events, _, _, err = b.Since(current)
+	if err != nil || len(events) != 0 {
+		t.Errorf("expected no event after the last one, but got %v and %v", events, err)
+	}
+
+	b.Publish("library", "b", "1.0.0", "create")
+	b.Publish("library", "c", "1.0.0", "delete")
+	events, _, _, err = b.Since(current)
+	if err != nil || len(events) != 2 || events[0].Chart != "b" || events[1].Chart != "c" {
+		t.Errorf("expected events after the resource version in order, but got %v and %v", events, err)
+	}
+	// the event after start has been dropped
+	if _, _, _, err = b.Since(start); code(err) != http.StatusGone {
+		t.Errorf("expected a dropped resource version to be expired, but got %v", err)
+	}
+	// resource versions of another process are expired
+	if _, _, _, err = NewBroadcaster(2).Since(current); code(err) != http.StatusGone {
+		t.Errorf("expected a resource version of another epoch to be expired, but got %v", err)
+	}
+	for _, rv := range []string{"invalid", b.epoch + "-x", b.epoch + "-100"} {
+		if _, _, _, err = b.Since(rv); code(err) != http.StatusBadRequest {
+			t.Errorf("expected resource version %s to be invalid, but got %v", rv, err)
+		}
+	}
+}
+
+func TestInitialize(t *testing.T) {
+	defer Initialize(Config{})
+	if err := Initialize(Config{History: -1}); err == nil {
+		t.Error("expected an error for a negative history")
+	}
+	if err := Initialize(Config{}); err != nil || globalBroadcaster.history != DefaultHistory {
+		t.Errorf("expected the default history, but got %v", err)
+	}
+}
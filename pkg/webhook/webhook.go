@@ -0,0 +1,303 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/log"
+	"github.com/caicloud/helm-registry/pkg/storage"
+	"github.com/caicloud/helm-registry/pkg/watch"
+)
+
+// Action is the type of an operation on a version of chart
+type Action string
+
+const (
+	// ActionCreate means a version is created
+	ActionCreate Action = "create"
+	// ActionUpdate means the chart data or metadata of a version is updated
+	ActionUpdate Action = "update"
+	// ActionUpdateValues means the values of a version are updated
+	ActionUpdateValues Action = "updateValues"
+	// ActionDelete means a version is deleted
+	ActionDelete Action = "delete"
+)
+
+// Actions are all actions of events
+var Actions = []Action{ActionCreate, ActionUpdate, ActionUpdateValues, ActionDelete}
+
+// SignatureHeader is the name of the header which contains the HMAC-SHA256 signature of body
+const SignatureHeader = "X-Registry-Signature"
+
+var (
+	// maxAttempts is the max number of attempts to deliver an event to an endpoint
+	maxAttempts = 3
+	// backoff is the waiting time before the first retry. It doubles after each failure.
+	backoff = time.Second
+	// client is used for delivering events to global endpoints
+	client = &http.Client{Timeout: 10 * time.Second}
+	// spaceClient is used for delivering events to webhooks of spaces. It refuses to connect
+	// to denied addresses, which are checked after the host is resolved
+	spaceClient = &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{Timeout: 10 * time.Second, Control: controlDial}).DialContext,
+		},
+	}
+)
+
+// deniedNetworks are loopback, private, link-local and other special networks. Webhooks of
+// spaces can't reach them, so a space can't make the registry send requests to internal
+// services, like the metadata service on 169.254.169.254
+var deniedNetworks = parseNetworks(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12",
+	"192.168.0.0/16", "224.0.0.0/4", "::/128", "::1/128", "fc00::/7", "fe80::/10", "ff00::/8",
+)
+
+// Config is a config of webhooks
+type Config struct {
+	// Secret is a shared secret for signing the body of events
+	Secret string `yaml:"secret"`
+	// Endpoints are urls which receive events
+	Endpoints []string `yaml:"endpoints"`
+	// AllowedNetworks are networks in CIDR notation which webhooks of spaces can reach even
+	// if they're denied, like a private network of CI services
+	AllowedNetworks []string `yaml:"allowedNetworks"`
+}
+
+// Event describes an operation on a version of chart
+type Event struct {
+	// Space name
+	Space string `json:"space"`
+	// Chart name
+	Chart string `json:"chart"`
+	// Version number
+	Version string `json:"version"`
+	// Action of the operation
+	Action Action `json:"action"`
+	// Timestamp is the time when the operation finished
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// global webhooks config
+var globalConfig Config
+
+// allowedNetworks are parsed AllowedNetworks of global config
+var allowedNetworks []*net.IPNet
+
+// Initialize sets the webhooks config. It should be called before serving
+func Initialize(config Config) error {
+	networks := make([]*net.IPNet, 0, len(config.AllowedNetworks))
+	for _, cidr := range config.AllowedNetworks {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid allowed network of webhooks %q: %v", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+	globalConfig = config
+	allowedNetworks = networks
+	return nil
+}
+
+// Notify publishes an event to watchers, and sends it to global endpoints and webhooks
+// of space asynchronously
+func Notify(space, chart, version string, action Action) {
+	watch.Publish(space, chart, version, string(action))
+	event := &Event{
+		Space:     space,
+		Chart:     chart,
+		Version:   version,
+		Action:    action,
+		Timestamp: time.Now().UTC(),
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Errorf("Failed to marshal webhook event %v: %v", event, err)
+		return
+	}
+	if len(globalConfig.Endpoints) > 0 {
+		signature := Sign(globalConfig.Secret, body)
+		for _, endpoint := range globalConfig.Endpoints {
+			go deliver(client, endpoint, body, signature)
+		}
+	}
+	go notifySpace(event, body)
+}
+
+// notifySpace sends body of event to the webhooks of its space which accept the action
+func notifySpace(event *Event, body []byte) {
+	ctx := context.Background()
+	// it runs in background, so a missing SpaceManager must not panic
+	manager, err := common.GetSpaceManager()
+	if err != nil {
+		log.Errorf("Failed to get space manager for webhooks of space %s: %v", event.Space, err)
+		return
+	}
+	space, err := manager.Space(ctx, event.Space)
+	if err != nil {
+		log.Errorf("Failed to get space %s for webhooks: %v", event.Space, err)
+		return
+	}
+	if !space.Exists(ctx) {
+		// the space has been deleted
+		return
+	}
+	webhooks, err := space.Webhooks(ctx)
+	if err != nil {
+		log.Errorf("Failed to get webhooks of space %s: %v", event.Space, err)
+		return
+	}
+	for _, webhook := range webhooks {
+		if accepts(&webhook, event.Action) {
+			go deliver(spaceClient, webhook.URL, body, Sign(webhook.Secret, body))
+		}
+	}
+}
+
+// accepts returns whether webhook receives events with action
+func accepts(webhook *storage.Webhook, action Action) bool {
+	if len(webhook.Actions) <= 0 {
+		return true
+	}
+	for _, a := range webhook.Actions {
+		if Action(a) == action {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateWebhook validates whether webhook is valid and all its actions are known. A url
+// with a denied address is rejected early, and other hosts are checked when they're dialed
+func ValidateWebhook(webhook *storage.Webhook) error {
+	if err := webhook.Validate(); err != nil {
+		return err
+	}
+	u, err := url.Parse(webhook.URL)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil {
+		if err = checkAddress(ip); err != nil {
+			return err
+		}
+	}
+	for _, a := range webhook.Actions {
+		known := false
+		for _, action := range Actions {
+			if Action(a) == action {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown action %q", a)
+		}
+	}
+	return nil
+}
+
+// Sign computes the signature of body with secret. If secret is empty, it returns an empty string
+func Sign(secret string, body []byte) string {
+	if len(secret) <= 0 {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkAddress checks whether webhooks of spaces can reach ip
+func checkAddress(ip net.IP) error {
+	for _, network := range allowedNetworks {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+	for _, network := range deniedNetworks {
+		if network.Contains(ip) {
+			return fmt.Errorf("address %s is not allowed for webhooks", ip)
+		}
+	}
+	return nil
+}
+
+// controlDial checks the resolved address of a connection before it's made
+func controlDial(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("invalid address %s", address)
+	}
+	return checkAddress(ip)
+}
+
+// parseNetworks parses networks in CIDR notation. It panics if a network is invalid
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// deliver posts body to endpoint by client and retries with backoff if failed
+func deliver(client *http.Client, endpoint string, body []byte, signature string) {
+	wait := backoff
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = post(client, endpoint, body, signature); err == nil {
+			return
+		}
+		log.Debugf("Attempt %d to deliver webhook event to %s failed: %v", attempt, endpoint, err)
+		if attempt < maxAttempts {
+			time.Sleep(wait)
+			wait *= 2
+		}
+	}
+	log.Errorf("Failed to deliver webhook event to %s after %d attempts: %v", endpoint, maxAttempts, err)
+}
+
+// post sends a request to endpoint by client
+func post(client *http.Client, endpoint string, body []byte, signature string) error {
+	request, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if len(signature) > 0 {
+		request.Header.Set(SignatureHeader, signature)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+	return nil
+}
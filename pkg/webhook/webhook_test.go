@@ -0,0 +1,97 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/caicloud/helm-registry/pkg/common"
+	"github.com/caicloud/helm-registry/pkg/storage"
+)
+
+func TestSign(t *testing.T) {
+	if signature := Sign("", []byte("body")); signature != "" {
+		t.Errorf("expected no signature without secret, but got %s", signature)
+	}
+	expected := "sha256=dc46983557fea127b43af721467eb9b3fde2338fe3e14f51952aa8478c13d355"
+	if signature := Sign("secret", []byte("body")); signature != expected {
+		t.Errorf("expected %s, but got %s", expected, signature)
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	all := &storage.Webhook{URL: "http://example.com"}
+	created := &storage.Webhook{URL: "http://example.com", Actions: []string{string(ActionCreate)}}
+	for _, action := range Actions {
+		if !accepts(all, action) {
+			t.Errorf("expected a webhook without actions to accept %s", action)
+		}
+		if accepts(created, action) != (action == ActionCreate) {
+			t.Errorf("unexpected acceptance of %s by %v", action, created.Actions)
+		}
+	}
+}
+
+func TestValidateWebhook(t *testing.T) {
+	for _, c := range []struct {
+		webhook storage.Webhook
+		valid   bool
+	}{
+		{storage.Webhook{URL: "https://example.com/hooks", Actions: []string{"create", "updateValues"}}, true},
+		{storage.Webhook{URL: "example.com/hooks"}, false},
+		{storage.Webhook{URL: "ftp://example.com"}, false},
+		{storage.Webhook{URL: "http://example.com", Actions: []string{"push"}}, false},
+		{storage.Webhook{URL: "http://127.0.0.1:8080/hooks"}, false},
+		{storage.Webhook{URL: "http://169.254.169.254/latest/meta-data"}, false},
+		{storage.Webhook{URL: "http://10.0.0.1/hooks"}, false},
+		{storage.Webhook{URL: "http://[::1]/hooks"}, false},
+		{storage.Webhook{URL: "http://[::ffff:192.168.0.1]/hooks"}, false},
+		{storage.Webhook{URL: "http://93.184.216.34/hooks"}, true},
+	} {
+		if err := ValidateWebhook(&c.webhook); (err == nil) != c.valid {
+			t.Errorf("expected validity of %v to be %v, but got %v", c.webhook, c.valid, err)
+		}
+	}
+}
+
+func TestNotifySpaceWithoutManager(t *testing.T) {
+	common.SetSpaceManager(nil)
+	// it must return instead of panicking in background
+	notifySpace(&Event{Space: "library", Action: ActionCreate}, []byte("{}"))
+}
+
+func TestPostDeniedAddress(t *testing.T) {
+	defer Initialize(Config{})
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	if err := post(spaceClient, server.URL, []byte("{}"), ""); err == nil {
+		t.Errorf("expected an error of posting to a loopback address")
+	}
+	if requests != 0 {
+		t.Errorf("expected no request to a loopback address, but got %d", requests)
+	}
+	// global endpoints are configured by operators, so they can be internal
+	if err := post(client, server.URL, []byte("{}"), ""); err != nil {
+		t.Errorf("unexpected error of posting to a global endpoint: %v", err)
+	}
+	if err := Initialize(Config{AllowedNetworks: []string{"127.0.0.0/8"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := post(spaceClient, server.URL, []byte("{}"), ""); err != nil {
+		t.Errorf("unexpected error of posting to an allowed network: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, but got %d", requests)
+	}
+	if err := Initialize(Config{AllowedNetworks: []string{"10.1.0.0"}}); err == nil {
+		t.Errorf("expected an error of invalid allowed network")
+	}
+}
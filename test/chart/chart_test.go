@@ -186,6 +186,12 @@ memory:
 
 	Context("create chart", func() {
 		It("should create chart", utils.Multicase(combination, func(space, chart, version string) {
+			// created charts are linted, and values of packages are replaced by configs,
+			// so packages are a chart whose templates render the configs
+			data, err := ioutil.ReadFile("./testdata/orchestration.tgz")
+			Expect(err).To(BeNil())
+			_, err = client.UploadChart(space, data)
+			Expect(err).To(BeNil())
 			config := `
 {
     "save":{         
@@ -198,7 +204,7 @@ memory:
         "package":{                    
             "independent":true,        
             "space":"library",      
-            "chart":"test",      
+            "chart":"orchestration",      
             "version":"1.0.0" 
         },
         "_config": {
@@ -208,7 +214,7 @@ memory:
             "package":{
                 "independent":true,        
                 "space":"library",
-                "chart":"test",
+                "chart":"orchestration",
                 "version":"1.0.0"
             },
             "_config": {
@@ -218,7 +224,7 @@ memory:
                 "package":{
                     "independent":true,
                     "space":"library",
-                    "chart":"test",
+                    "chart":"orchestration",
                     "version":"1.0.0"
                 },
                 "_config": {
@@ -230,7 +236,7 @@ memory:
             "package":{
                 "independent":true,
                 "space":"library",
-                "chart":"test",
+                "chart":"orchestration",
                 "version":"1.0.0"
             },
             "_config": {
@@ -263,6 +269,8 @@ memory:
 			// delete creation
 			err = client.DeleteChart(space, chartName)
 			Expect(err).To(BeNil())
+			err = client.DeleteChart(space, "orchestration")
+			Expect(err).To(BeNil())
 		}))
 	})
 
@@ -0,0 +1,77 @@
+/*
+Copyright 2017 caicloud authors. All rights reserved.
+*/
+
+package chart_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/caicloud/helm-registry/pkg/rest/v1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// Space "proxied" proxies space "upstream" of the same registry. Its proxy is configured
+// in e2e.sh
+var _ = Describe("Proxy", func() {
+	var (
+		endpoint = ""
+		client   *v1.Client
+		upstream = "upstream"
+		proxied  = "proxied"
+		chart    = "proxied"
+	)
+	// fetch gets a file of a version in proxied space
+	fetch := func(version, file string) []byte {
+		resp, err := http.Get(fmt.Sprintf("%s/api/v1/spaces/%s/charts/%s/versions/%s/%s", endpoint, proxied, chart, version, file))
+		Expect(err).To(BeNil())
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		Expect(err).To(BeNil())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK), string(data))
+		return data
+	}
+	BeforeEach(func() {
+		By("getting registry host from env")
+		endpoint = os.Getenv(EnvEndpoint)
+		Expect(endpoint).NotTo(BeEmpty())
+		cli, err := v1.NewClient(endpoint)
+		Expect(err).To(BeNil())
+		client = cli
+	})
+	It("should pull versions when their assets are fetched", func() {
+		_, err := client.CreateSpace(upstream)
+		Expect(err).To(BeNil())
+		for _, version := range []string{"1.0.0", "1.1.0", "1.2.0"} {
+			data, err := ioutil.ReadFile("./testdata/proxied-" + version + ".tgz")
+			Expect(err).To(BeNil())
+			_, err = client.UploadChart(upstream, data)
+			Expect(err).To(BeNil())
+		}
+
+		By("fetching values of a version which isn't cached")
+		values, err := client.FetchVersionValues(proxied, chart, "1.0.0")
+		Expect(err).To(BeNil())
+		obj := map[string]interface{}{}
+		Expect(json.Unmarshal(values, &obj)).To(BeNil())
+		Expect(obj["replicaCount"]).To(Equal(1.0))
+
+		By("fetching readme of a version which isn't cached")
+		Expect(string(fetch("1.1.0", "readme"))).To(Equal("# Proxied\n\nA chart pulled by proxies.\n"))
+
+		By("fetching values schema of a version which isn't cached")
+		Expect(string(fetch("1.2.0", "manifests/schema"))).To(ContainSubstring("replicaCount"))
+
+		versions, err := client.ListVersions(proxied, chart, 0, 100000)
+		Expect(err).To(BeNil())
+		Expect(versions.Items).To(ConsistOf("1.0.0", "1.1.0", "1.2.0"))
+
+		Expect(client.DeleteSpace(proxied)).To(BeNil())
+		Expect(client.DeleteSpace(upstream)).To(BeNil())
+	})
+})
@@ -34,6 +34,11 @@ manager:
     resourcelocker: memory
     storagedriver: filesystem
     rootdirectory: "${DATA_PATH}"
+mirror:
+  proxies:
+  - name: "e2e"
+    url: "http://127.0.0.1:9999/api/v1/spaces/upstream"
+    space: "proxied"
 EOF
 ${BIN_PATH} serve -c ${TMP_PATH}/config.yaml &
 REGISTRY_PID=$!